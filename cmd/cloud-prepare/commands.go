@@ -0,0 +1,192 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/spec"
+)
+
+const (
+	openPortsCommand       = "open-ports"
+	closePortsCommand      = "close-ports"
+	deployGatewaysCommand  = "deploy-gateways"
+	cleanupGatewaysCommand = "cleanup-gateways"
+)
+
+// operation runs a command against the provider built from the spec, returning its outcome to render, if any.
+type operation func(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error)
+
+// provider is the cloud and gateway deployer of the cluster, along with the spec they were built from.
+type provider struct {
+	spec       *spec.Spec
+	cloud      api.ContextCloud
+	gwDeployer api.ContextGatewayDeployer
+}
+
+func newRootCommand() *cobra.Command {
+	o := &options{}
+	o.build = o.buildProvider
+
+	return o.rootCommand()
+}
+
+func (o *options) rootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "cloud-prepare",
+		Short: "Prepare the cloud of a cluster for Submariner",
+		Long: "Prepare the cloud of a cluster for Submariner: open the internal ports used between the Submariner components " +
+			"and deploy the gateway nodes, or undo either. The cloud is described with flags, a spec file, or both.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	o.addFlags(root.PersistentFlags())
+
+	root.AddCommand(
+		o.command(openPortsCommand, "Open the internal ports", openPorts),
+		o.command(closePortsCommand, "Close the internal ports opened previously", closePorts),
+		o.command(deployGatewaysCommand, "Deploy the gateway nodes", deployGateways),
+		o.command(cleanupGatewaysCommand, "Remove the gateway nodes deployed previously", cleanupGateways),
+		o.command("status", "Show the gateways currently deployed", showStatus),
+		o.planCommand(),
+	)
+
+	return root
+}
+
+func (o *options) command(name, short string, op operation) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return o.run(cmd, op)
+		},
+	}
+}
+
+func (o *options) planCommand() *cobra.Command {
+	operations := map[string]operation{
+		openPortsCommand:       planOpenPorts,
+		closePortsCommand:      planClosePorts,
+		deployGatewaysCommand:  planDeployGateways,
+		cleanupGatewaysCommand: planCleanupGateways,
+	}
+
+	return &cobra.Command{
+		Use:       "plan " + openPortsCommand + "|" + closePortsCommand + "|" + deployGatewaysCommand + "|" + cleanupGatewaysCommand,
+		Short:     "Show the changes an operation would make, without making them",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{openPortsCommand, closePortsCommand, deployGatewaysCommand, cleanupGatewaysCommand},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(cmd, operations[args[0]])
+		},
+	}
+}
+
+// run builds the provider and runs the operation, rendering its progress and outcome in the requested format.
+func (o *options) run(cmd *cobra.Command, op operation) error {
+	p, err := newPrinter(o.output, cmd.CommandPath(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	outcome, err := o.runOperation(ctx, cmd, op, p.reporter())
+
+	return p.finish(outcome, err)
+}
+
+func (o *options) runOperation(ctx context.Context, cmd *cobra.Command, op operation, status reporter.Interface,
+) (fmt.Stringer, error) {
+	s, err := o.loadSpec(cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+
+	cloud, gwDeployer, err := o.build(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	contextCloud, ok := cloud.(api.ContextCloud)
+	if !ok {
+		return nil, errors.Errorf("the %s cloud doesn't support the cloud-prepare operations", s.Provider)
+	}
+
+	contextGWDeployer, ok := gwDeployer.(api.ContextGatewayDeployer)
+	if !ok {
+		return nil, errors.Errorf("the %s gateway deployer doesn't support the cloud-prepare operations", s.Provider)
+	}
+
+	return op(ctx, &provider{spec: s, cloud: contextCloud, gwDeployer: contextGWDeployer}, status)
+}
+
+func openPorts(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return nil, p.cloud.OpenPortsWithContext(ctx, p.spec.InternalPorts, status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func closePorts(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return nil, p.cloud.ClosePortsWithContext(ctx, status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func deployGateways(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return nil, p.gwDeployer.DeployWithContext(ctx, p.spec.GatewayDeployInput(), status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func cleanupGateways(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return nil, p.gwDeployer.CleanupWithContext(ctx, status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func planOpenPorts(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return p.cloud.PlanOpenPorts(ctx, p.spec.InternalPorts, status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func planClosePorts(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return p.cloud.PlanClosePorts(ctx, status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func planDeployGateways(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return p.gwDeployer.PlanDeploy(ctx, p.spec.GatewayDeployInput(), status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func planCleanupGateways(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	return p.gwDeployer.PlanCleanup(ctx, status) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func showStatus(ctx context.Context, p *provider, status reporter.Interface) (fmt.Stringer, error) {
+	gateways, err := p.gwDeployer.Status(ctx, status)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	return &preparationStatus{Gateways: gateways}, nil
+}
@@ -0,0 +1,328 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/spec"
+)
+
+var _ = Describe("cloud-prepare", func() {
+	var (
+		cloud      *fakeCloud
+		gwDeployer *fakeGatewayDeployer
+		builtSpec  *spec.Spec
+		buildErr   error
+		stdout     *bytes.Buffer
+		stderr     *bytes.Buffer
+		args       []string
+		err        error
+	)
+
+	BeforeEach(func() {
+		cloud = &fakeCloud{}
+		gwDeployer = &fakeGatewayDeployer{}
+		builtSpec = nil
+		buildErr = nil
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+		args = []string{"--provider", "aws", "--infra-id", "test-infra", "--region", "us-east-1"}
+	})
+
+	newCommand := func(extraArgs ...string) *cobra.Command {
+		o := &options{}
+		o.build = func(_ context.Context, s *spec.Spec) (api.Cloud, api.GatewayDeployer, error) {
+			builtSpec = s
+			return cloud, gwDeployer, buildErr
+		}
+
+		cmd := o.rootCommand()
+		cmd.SetArgs(append(extraArgs, args...))
+		cmd.SetOut(stdout)
+		cmd.SetErr(stderr)
+
+		return cmd
+	}
+
+	run := func(extraArgs ...string) {
+		err = newCommand(extraArgs...).Execute()
+	}
+
+	When("open-ports is run with flags", func() {
+		It("should open the given internal ports", func() {
+			run("open-ports", "--internal-port", "4800/udp", "--internal-port", "8080-8081/tcp", "--internal-port", "esp")
+			Expect(err).To(Succeed())
+			Expect(builtSpec.Provider).To(Equal("aws"))
+			Expect(builtSpec.InfraID).To(Equal("test-infra"))
+			Expect(cloud.openedPorts).To(Equal([]api.PortSpec{
+				{Port: 4800, Protocol: "udp"},
+				{Port: 8080, EndPort: 8081, Protocol: "tcp"},
+				{Protocol: "esp"},
+			}))
+			Expect(stdout.String()).To(ContainSubstring("Opening the ports"))
+		})
+	})
+
+	When("a spec file is given", func() {
+		var configFile string
+
+		BeforeEach(func() {
+			configFile = filepath.Join(GinkgoT().TempDir(), "spec.yaml")
+			Expect(os.WriteFile(configFile, []byte(`apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: gcp
+infraID: spec-infra
+region: us-east1
+publicPorts:
+- port: 4500
+  protocol: udp
+gateways: 2
+gcp:
+  projectID: my-project
+`), 0o600)).To(Succeed())
+			args = []string{"--config", configFile, "--gateways", "3", "--tag", "owner=team-a"}
+		})
+
+		It("should use the spec with the flags applied on top of it", func() {
+			run("deploy-gateways")
+			Expect(err).To(Succeed())
+			Expect(builtSpec.Provider).To(Equal("gcp"))
+			Expect(builtSpec.InfraID).To(Equal("spec-infra"))
+			Expect(builtSpec.GCP.ProjectID).To(Equal("my-project"))
+			Expect(builtSpec.Tags).To(Equal(map[string]string{"owner": "team-a"}))
+			Expect(gwDeployer.deployInput).To(Equal(api.GatewayDeployInput{
+				PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+				Gateways:    3,
+			}))
+		})
+	})
+
+	When("the flags don't describe a valid cloud", func() {
+		BeforeEach(func() {
+			args = []string{"--provider", "gcp", "--region", "us-east1"}
+		})
+
+		It("should return the validation errors", func() {
+			run("close-ports")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("infraID is required"))
+			Expect(err.Error()).To(ContainSubstring("gcp.projectID is required"))
+			Expect(stderr.String()).To(ContainSubstring("Error: invalid cloud preparation"))
+			Expect(cloud.closed).To(BeFalse())
+		})
+	})
+
+	When("a port flag is invalid", func() {
+		It("should return an error", func() {
+			run("open-ports", "--internal-port", "abc/udp")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid --internal-port"))
+		})
+	})
+
+	When("the provider can't be built", func() {
+		BeforeEach(func() {
+			buildErr = errors.New("fake build error")
+		})
+
+		It("should return the error", func() {
+			run("cleanup-gateways")
+			Expect(err).To(MatchError(buildErr))
+			Expect(gwDeployer.cleanedUp).To(BeFalse())
+		})
+	})
+
+	When("plan is run", func() {
+		BeforeEach(func() {
+			gwDeployer.cleanupPlan = &api.Plan{}
+			gwDeployer.cleanupPlan.Add(api.ChangeDelete, api.MachineSetResource, "test-infra-submariner-gw", "")
+		})
+
+		It("should print the plan of the given operation", func() {
+			run("plan", "cleanup-gateways")
+			Expect(err).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring(`Delete MachineSet "test-infra-submariner-gw"`))
+			Expect(gwDeployer.cleanedUp).To(BeFalse())
+		})
+
+		Context("with an unknown operation", func() {
+			It("should return an error", func() {
+				run("plan", "destroy")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	When("status is run", func() {
+		BeforeEach(func() {
+			gwDeployer.gateways = []api.GatewayStatus{{
+				Type:       api.DedicatedGateway,
+				MachineSet: "test-infra-submariner-gw-us-east-1a",
+				Zone:       "us-east-1a",
+				NodeName:   "gw-node",
+				PublicIP:   "1.2.3.4",
+			}}
+		})
+
+		It("should print the gateways", func() {
+			run("status")
+			Expect(err).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("Gateways: 1"))
+			Expect(stdout.String()).To(ContainSubstring(
+				"gw-node: Dedicated, MachineSet test-infra-submariner-gw-us-east-1a, zone us-east-1a, public IP 1.2.3.4"))
+		})
+
+		Context("with the JSON output", func() {
+			It("should render the events and the gateways as JSON", func() {
+				run("status", "-o", "json")
+				Expect(err).To(Succeed())
+
+				out := &result{}
+				Expect(json.Unmarshal(stdout.Bytes(), out)).To(Succeed())
+				Expect(out.Command).To(Equal("cloud-prepare status"))
+				Expect(out.Events).To(ContainElement(event{Level: "start", Message: "Retrieving the gateways"}))
+				Expect(out.Status.Gateways).To(Equal(gwDeployer.gateways))
+				Expect(out.Error).To(BeEmpty())
+			})
+		})
+	})
+
+	When("an operation fails", func() {
+		BeforeEach(func() {
+			cloud.openErr = errors.New("fake open error")
+		})
+
+		It("should print the error once", func() {
+			Expect(execute(newCommand("open-ports"), stderr)).To(Equal(1))
+			Expect(stdout.String()).To(ContainSubstring("ERROR: Fake open error"))
+			Expect(stderr.String()).To(BeEmpty())
+		})
+	})
+
+	When("the command line is invalid", func() {
+		It("should print the error", func() {
+			for _, extraArgs := range [][]string{{"--bogus"}, {"status", "--output", "xml"}, {"plan", "foo"}} {
+				stderr.Reset()
+
+				Expect(execute(newCommand(extraArgs...), stderr)).To(Equal(1))
+				Expect(stderr.String()).To(HavePrefix("Error: "))
+				Expect(strings.Count(stderr.String(), "Error: ")).To(Equal(1))
+			}
+		})
+	})
+
+	When("an operation fails with the JSON output", func() {
+		BeforeEach(func() {
+			cloud.openErr = errors.New("fake open error")
+		})
+
+		It("should render the error in the JSON document", func() {
+			run("open-ports", "--output", "json")
+			Expect(err).To(MatchError(cloud.openErr))
+
+			out := &result{}
+			Expect(json.Unmarshal(stdout.Bytes(), out)).To(Succeed())
+			Expect(out.Error).To(Equal("fake open error"))
+			Expect(out.Events).To(ContainElement(event{Level: "failure", Message: "Fake open error"}))
+			Expect(stderr.String()).To(BeEmpty())
+		})
+	})
+
+	When("the output format is unsupported", func() {
+		It("should return an error", func() {
+			run("open-ports", "-o", "yaml")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+type fakeCloud struct {
+	api.ContextCloud
+	openErr     error
+	openedPorts []api.PortSpec
+	openPlan    *api.Plan
+	closed      bool
+}
+
+func (f *fakeCloud) OpenPortsWithContext(_ context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	status.Start("Opening the ports")
+	defer status.End()
+
+	f.openedPorts = ports
+
+	return status.Error(f.openErr, "")
+}
+
+func (f *fakeCloud) ClosePortsWithContext(_ context.Context, _ reporter.Interface) error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeCloud) PlanOpenPorts(_ context.Context, _ []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Planning the ports")
+	defer status.End()
+
+	return f.openPlan, nil
+}
+
+type fakeGatewayDeployer struct {
+	api.ContextGatewayDeployer
+	deployInput api.GatewayDeployInput
+	deployPlan  *api.Plan
+	cleanupPlan *api.Plan
+	cleanedUp   bool
+	gateways    []api.GatewayStatus
+}
+
+func (f *fakeGatewayDeployer) Status(_ context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	status.Start("Retrieving the gateways")
+	defer status.End()
+
+	return f.gateways, nil
+}
+
+func (f *fakeGatewayDeployer) DeployWithContext(_ context.Context, input api.GatewayDeployInput, _ reporter.Interface) error {
+	f.deployInput = input
+	return nil
+}
+
+func (f *fakeGatewayDeployer) CleanupWithContext(_ context.Context, _ reporter.Interface) error {
+	f.cleanedUp = true
+	return nil
+}
+
+func (f *fakeGatewayDeployer) PlanDeploy(_ context.Context, _ api.GatewayDeployInput, _ reporter.Interface) (*api.Plan, error) {
+	return f.deployPlan, nil
+}
+
+func (f *fakeGatewayDeployer) PlanCleanup(_ context.Context, _ reporter.Interface) (*api.Plan, error) {
+	return f.cleanupPlan, nil
+}
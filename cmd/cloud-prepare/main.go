@@ -0,0 +1,48 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package main implements cloud-prepare, a command-line tool preparing the cloud of a cluster for Submariner.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	os.Exit(execute(newRootCommand(), os.Stderr))
+}
+
+// execute runs the command and returns the exit status. The errors which the command didn't render itself, such as the
+// flag and argument errors, are written to the given error output.
+func execute(cmd *cobra.Command, errOut io.Writer) int {
+	err := cmd.Execute()
+	if err == nil {
+		return 0
+	}
+
+	if !errors.As(err, &renderedError{}) {
+		fmt.Fprintln(errOut, "Error: "+err.Error())
+	}
+
+	return 1
+}
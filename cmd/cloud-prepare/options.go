@@ -0,0 +1,294 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/azure"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+	"github.com/submariner-io/cloud-prepare/pkg/spec"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// options holds the global flags, which describe the cluster and its cloud either directly or by overriding the spec
+// read from the configuration file.
+type options struct {
+	configFile string
+	kubeconfig string
+	ledgerFile string
+	output     string
+
+	spec          spec.Spec
+	internalPorts []string
+	publicPorts   []string
+	ipFamily      string
+	projectID     string
+	subscription  string
+	resourceGroup string
+	cloudName     string
+	tags          map[string]string
+	waitTimeout   time.Duration
+	healthCheck   bool
+
+	// build builds the provider from the spec; it's replaced in the unit tests.
+	build func(ctx context.Context, s *spec.Spec) (api.Cloud, api.GatewayDeployer, error)
+}
+
+func (o *options) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.configFile, "config", "c", "", "cloud preparation spec file, overridden by the other flags")
+	flags.StringVar(&o.kubeconfig, "kubeconfig", "", "kubeconfig of the cluster, defaults to the usual kubeconfig resolution")
+	flags.StringVar(&o.ledgerFile, "ledger-file", "", "file recording the created cloud resources, so that they can be undone exactly")
+	flags.StringVarP(&o.output, "output", "o", textOutput, "output format, text or json")
+
+	flags.StringVar(&o.spec.Provider, "provider", "", "cloud provider, one of "+strings.Join(api.Providers(), ", "))
+	flags.StringVar(&o.spec.InfraID, "infra-id", "", "infrastructure ID of the cluster")
+	flags.StringVar(&o.spec.Region, "region", "", "region of the cluster")
+	flags.StringVar(&o.spec.Credentials.File, "credentials-file", "", "AWS shared credentials file or GCP service account key file")
+	flags.StringVar(&o.spec.Credentials.Profile, "profile", "", "AWS profile to use from the credentials file")
+	flags.StringSliceVar(&o.internalPorts, "internal-port", nil,
+		"internal port to open, as port[-endPort]/protocol or protocol, may be repeated")
+	flags.StringSliceVar(&o.publicPorts, "public-port", nil,
+		"public gateway port to open, as port[-endPort]/protocol or protocol, may be repeated")
+	flags.IntVar(&o.spec.Gateways, "gateways", 0, "number of gateways to deploy, 0 meaning the deployer's default")
+	flags.StringVar(&o.spec.InstanceType, "instance-type", "", "instance type of the gateway nodes")
+	flags.StringVar(&o.spec.Image, "image", "", "image of the gateway nodes (gcp and rhos only)")
+	flags.StringSliceVar(&o.spec.SourceCIDRs, "source-cidr", nil, "CIDR allowed to reach the public gateway ports, may be repeated")
+	flags.StringVar(&o.ipFamily, "ip-family", "", "IP family of the rules, IPv4, IPv6 or DualStack")
+	flags.BoolVar(&o.spec.UseLoadBalancer, "use-load-balancer", false, "deploy Submariner with a service of type LoadBalancer")
+	flags.BoolVar(&o.spec.AirGapped, "air-gapped", false, "deploy the gateways without public IPs")
+	flags.DurationVar(&o.waitTimeout, "wait-timeout", 0, "how long deploying the gateways waits for their nodes to be ready, 0 not to wait")
+	flags.BoolVar(&o.healthCheck, "health-check", false, "replace the dedicated gateway nodes which become unhealthy")
+	flags.StringToStringVar(&o.tags, "tag", nil, "tag applied to the cloud resources created, as key=value, may be repeated")
+	flags.StringVar(&o.projectID, "project-id", "", "GCP or OpenStack project ID")
+	flags.StringVar(&o.subscription, "subscription-id", "", "Azure subscription ID")
+	flags.StringVar(&o.resourceGroup, "resource-group", "", "Azure base resource group name")
+	flags.StringVar(&o.cloudName, "cloud-name", "", "OpenStack cloud name")
+}
+
+// loadSpec returns the validated spec read from the configuration file, if any, with the flags set on the command line
+// applied on top of it.
+func (o *options) loadSpec(flags *pflag.FlagSet) (*spec.Spec, error) {
+	s := &spec.Spec{APIVersion: spec.APIVersion, Kind: spec.Kind}
+
+	if o.configFile != "" {
+		var err error
+
+		s, err = spec.LoadFile(o.configFile)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Let the caller wrap it.
+		}
+	}
+
+	if err := o.applyFlags(s, flags); err != nil {
+		return nil, err
+	}
+
+	return s, errors.WithMessage(s.Validate(), "invalid cloud preparation")
+}
+
+func (o *options) applyFlags(s *spec.Spec, flags *pflag.FlagSet) error {
+	set := func(name string, apply func()) {
+		if flags.Changed(name) {
+			apply()
+		}
+	}
+
+	set("provider", func() { s.Provider = o.spec.Provider })
+	set("infra-id", func() { s.InfraID = o.spec.InfraID })
+	set("region", func() { s.Region = o.spec.Region })
+	set("credentials-file", func() { s.Credentials.File = o.spec.Credentials.File })
+	set("profile", func() { s.Credentials.Profile = o.spec.Credentials.Profile })
+	set("gateways", func() { s.Gateways = o.spec.Gateways })
+	set("instance-type", func() { s.InstanceType = o.spec.InstanceType })
+	set("image", func() { s.Image = o.spec.Image })
+	set("source-cidr", func() { s.SourceCIDRs = o.spec.SourceCIDRs })
+	set("ip-family", func() { s.IPFamily = api.IPFamily(o.ipFamily) })
+	set("use-load-balancer", func() { s.UseLoadBalancer = o.spec.UseLoadBalancer })
+	set("air-gapped", func() { s.AirGapped = o.spec.AirGapped })
+	set("wait-timeout", func() { s.WaitTimeout = &metav1.Duration{Duration: o.waitTimeout} })
+	set("health-check", func() { s.HealthCheck = o.healthCheckSpec(s.HealthCheck) })
+	set("tag", func() { s.Tags = o.tags })
+
+	var err error
+
+	if flags.Changed("internal-port") {
+		if s.InternalPorts, err = parsePorts(o.internalPorts); err != nil {
+			return errors.WithMessage(err, "invalid --internal-port")
+		}
+	}
+
+	if flags.Changed("public-port") {
+		if s.PublicPorts, err = parsePorts(o.publicPorts); err != nil {
+			return errors.WithMessage(err, "invalid --public-port")
+		}
+	}
+
+	o.applyOverrideFlags(s, flags)
+
+	return nil
+}
+
+// healthCheckSpec returns the health check requested by --health-check, keeping the one of the spec if any.
+func (o *options) healthCheckSpec(current *api.HealthCheck) *api.HealthCheck {
+	if !o.healthCheck {
+		return nil
+	}
+
+	if current != nil {
+		return current
+	}
+
+	return &api.HealthCheck{}
+}
+
+// applyOverrideFlags sets the provider overrides from the provider-specific flags, creating the overrides of the spec's
+// provider only so that the spec remains valid.
+func (o *options) applyOverrideFlags(s *spec.Spec, flags *pflag.FlagSet) {
+	switch s.Provider {
+	case gcp.ProviderName:
+		if s.GCP == nil {
+			s.GCP = &spec.GCPOverrides{}
+		}
+
+		if flags.Changed("project-id") {
+			s.GCP.ProjectID = o.projectID
+		}
+	case azure.ProviderName:
+		if s.Azure == nil {
+			s.Azure = &spec.AzureOverrides{}
+		}
+
+		if flags.Changed("subscription-id") {
+			s.Azure.SubscriptionID = o.subscription
+		}
+
+		if flags.Changed("resource-group") {
+			s.Azure.BaseGroupName = o.resourceGroup
+		}
+	case rhos.ProviderName:
+		if s.RHOS == nil {
+			s.RHOS = &spec.RHOSOverrides{}
+		}
+
+		if flags.Changed("project-id") {
+			s.RHOS.ProjectID = o.projectID
+		}
+
+		if flags.Changed("cloud-name") {
+			s.RHOS.CloudName = o.cloudName
+		}
+	}
+}
+
+// parsePorts parses ports given as port/protocol, port-endPort/protocol, or only a protocol for portless protocols and
+// for all the ports of a protocol.
+func parsePorts(values []string) ([]api.PortSpec, error) {
+	ports := make([]api.PortSpec, 0, len(values))
+
+	for _, value := range values {
+		portRange, protocol, found := strings.Cut(value, "/")
+		if !found {
+			ports = append(ports, api.PortSpec{Protocol: value})
+			continue
+		}
+
+		first, last, isRange := strings.Cut(portRange, "-")
+
+		port, err := strconv.ParseUint(first, 10, 16)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid port in %q", value)
+		}
+
+		p := api.PortSpec{Port: uint16(port), Protocol: protocol}
+
+		if isRange {
+			endPort, err := strconv.ParseUint(last, 10, 16)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid end port in %q", value)
+			}
+
+			p.EndPort = uint16(endPort)
+		}
+
+		ports = append(ports, p)
+	}
+
+	return ports, nil
+}
+
+// buildProvider builds the Cloud and GatewayDeployer described by the spec, accessing the cluster through the
+// kubeconfig.
+func (o *options) buildProvider(ctx context.Context, s *spec.Spec) (api.Cloud, api.GatewayDeployer, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.kubeconfig
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, nil).ClientConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error loading the kubeconfig")
+	}
+
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the Kubernetes client")
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the dynamic client")
+	}
+
+	restMapper, err := util.BuildRestMapper(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the REST mapper")
+	}
+
+	env := &spec.Environment{
+		MachineSetDeployer: ocp.NewK8sMachinesetDeployer(restMapper, dynamicClient),
+		K8sClient:          k8s.NewInterface(clientSet, k8s.WithDynamicClient(dynamicClient)),
+	}
+
+	if o.ledgerFile != "" {
+		env.Ledger = ledger.New(ledger.NewFileStore(o.ledgerFile))
+	}
+
+	if s.Provider == azure.ProviderName {
+		env.AzureCredential, err = azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error creating the Azure credential")
+		}
+	}
+
+	return s.Build(ctx, env) //nolint:wrapcheck // Let the caller wrap it.
+}
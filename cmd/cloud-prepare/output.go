@@ -0,0 +1,205 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+)
+
+// event is a message reported by an operation, as rendered in the JSON output.
+type event struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// result is the JSON output of a command.
+type result struct {
+	Command string             `json:"command"`
+	Events  []event            `json:"events"`
+	Plan    *api.Plan          `json:"plan,omitempty"`
+	Status  *preparationStatus `json:"status,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// printer renders the progress and the outcome of a command: as it goes in the text format, or as a single JSON document
+// once the command completes in the JSON format.
+type printer struct {
+	format string
+	out    io.Writer
+	errOut io.Writer
+	result result
+
+	// failed records that a failure was reported, in which case the error it led to isn't printed again.
+	failed bool
+}
+
+// renderedError is an error which the printer has already rendered, so that main doesn't print it again.
+type renderedError struct {
+	error
+}
+
+func (e renderedError) Unwrap() error {
+	return e.error
+}
+
+func newPrinter(format, command string, out, errOut io.Writer) (*printer, error) {
+	if format != textOutput && format != jsonOutput {
+		return nil, errors.Errorf("unsupported output format %q, expected %q or %q", format, textOutput, jsonOutput)
+	}
+
+	return &printer{
+		format: format,
+		out:    out,
+		errOut: errOut,
+		result: result{Command: command, Events: []event{}},
+	}, nil
+}
+
+// reporter returns the reporter.Interface through which the operations report their progress.
+func (p *printer) reporter() reporter.Interface {
+	return &reporter.Adapter{Basic: p}
+}
+
+func (p *printer) Start(message string, args ...interface{}) {
+	p.report("start", "", message, args...)
+}
+
+func (p *printer) Success(message string, args ...interface{}) {
+	p.report("success", "", message, args...)
+}
+
+func (p *printer) Failure(message string, args ...interface{}) {
+	p.failed = true
+	p.report("failure", "ERROR: ", message, args...)
+}
+
+func (p *printer) Warning(message string, args ...interface{}) {
+	p.report("warning", "WARNING: ", message, args...)
+}
+
+func (p *printer) End() {
+	// Intentionally empty to satisfy the reporter Interface.
+}
+
+func (p *printer) report(level, prefix, message string, args ...interface{}) {
+	message = fmt.Sprintf(message, args...)
+
+	if p.format == jsonOutput {
+		p.result.Events = append(p.result.Events, event{Level: level, Message: message})
+		return
+	}
+
+	fmt.Fprintln(p.out, prefix+message)
+}
+
+// finish renders the outcome of the command, that is the plan or status it computed, if any, or its error. In the text
+// format, the error is written to the error output, unless it was already reported as a failure.
+func (p *printer) finish(outcome fmt.Stringer, err error) error {
+	if p.format == textOutput {
+		if err == nil {
+			if outcome != nil {
+				fmt.Fprintln(p.out, outcome.String())
+			}
+
+			return nil
+		}
+
+		if !p.failed {
+			fmt.Fprintln(p.errOut, "Error: "+err.Error())
+		}
+
+		return renderedError{err}
+	}
+
+	switch o := outcome.(type) {
+	case *api.Plan:
+		p.result.Plan = o
+	case *preparationStatus:
+		p.result.Status = o
+	}
+
+	if err != nil {
+		p.result.Error = err.Error()
+	}
+
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+
+	if encodeErr := encoder.Encode(&p.result); encodeErr != nil {
+		return errors.Wrap(encodeErr, "error encoding the output")
+	}
+
+	if err != nil {
+		return renderedError{err}
+	}
+
+	return nil
+}
+
+// preparationStatus is the outcome of the status command: the gateways currently deployed.
+type preparationStatus struct {
+	Gateways []api.GatewayStatus `json:"gateways"`
+}
+
+func (s *preparationStatus) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Gateways: %d\n", len(s.Gateways))
+
+	for i := range s.Gateways {
+		gw := &s.Gateways[i]
+
+		node := gw.NodeName
+		if node == "" {
+			node = "(not provisioned yet)"
+		}
+
+		details := []string{string(gw.Type)}
+
+		for _, detail := range []struct{ name, value string }{
+			{"MachineSet", gw.MachineSet},
+			{"NodePool", gw.NodePool},
+			{"zone", gw.Zone},
+			{"subnet", gw.Subnet},
+			{"instance type", gw.InstanceType},
+			{"public IP", gw.PublicIP},
+			{"security group", gw.SecurityGroup},
+		} {
+			if detail.value != "" {
+				details = append(details, detail.name+" "+detail.value)
+			}
+		}
+
+		fmt.Fprintf(&b, "  %s: %s\n", node, strings.Join(details, ", "))
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
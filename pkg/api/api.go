@@ -18,12 +18,79 @@ limitations under the License.
 
 package api
 
-import "github.com/submariner-io/admiral/pkg/reporter"
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+)
 
 // PortSpec is a specification of port+protocol to open.
 type PortSpec struct {
-	Port     uint16
-	Protocol string
+	// Port to open, or the first port of the range to open if EndPort is set. If 0, all the ports of the protocol are opened.
+	// It's ignored for protocols without ports, such as ESP.
+	Port uint16 `json:"port,omitempty"`
+
+	// EndPort, if set, is the last port of the range to open, starting at Port. It must not be lower than Port.
+	EndPort uint16 `json:"endPort,omitempty"`
+
+	// Protocol is the IP protocol name, for example "udp", "tcp" or "esp".
+	Protocol string `json:"protocol"`
+}
+
+// IsPortless returns true if the protocol doesn't use ports (anything other than TCP, UDP and SCTP, for example ESP),
+// in which case Port and EndPort are ignored.
+func (p PortSpec) IsPortless() bool {
+	switch strings.ToLower(p.Protocol) {
+	case "tcp", "udp", "sctp":
+		return false
+	}
+
+	return true
+}
+
+// PortRange returns the first and last ports to open. They're equal if a single port is specified, and cover all
+// the ports if Port is 0. The result is meaningless for portless protocols.
+func (p PortSpec) PortRange() (uint16, uint16) {
+	if p.Port == 0 {
+		return 0, math.MaxUint16
+	}
+
+	if p.EndPort > p.Port {
+		return p.Port, p.EndPort
+	}
+
+	return p.Port, p.Port
+}
+
+// String returns the spec formatted as "port/protocol", "first-last/protocol" or "protocol" if portless.
+func (p PortSpec) String() string {
+	if p.IsPortless() {
+		return p.Protocol
+	}
+
+	from, to := p.PortRange()
+	if from == to {
+		return fmt.Sprintf("%d/%s", from, p.Protocol)
+	}
+
+	return fmt.Sprintf("%d-%d/%s", from, to, p.Protocol)
+}
+
+// ValidatePorts returns an error if one of the given specs has an EndPort lower than its Port, which PortRange would
+// otherwise treat as a single port.
+func ValidatePorts(ports []PortSpec) error {
+	for i := range ports {
+		if ports[i].EndPort != 0 && ports[i].EndPort < ports[i].Port {
+			return fmt.Errorf("the end port %d of port spec %d (%s) is lower than its port %d", ports[i].EndPort, i,
+				ports[i].Protocol, ports[i].Port)
+		}
+	}
+
+	return nil
 }
 
 // Cloud is a potential cloud for installing Submariner on.
@@ -35,6 +102,34 @@ type Cloud interface {
 	ClosePorts(status reporter.Interface) error
 }
 
+// ContextCloud extends Cloud with the operations bound to a context, the plans and the validation. All the Cloud
+// implementations of this repository implement it; it's a separate interface so that the existing implementations of
+// Cloud keep compiling. Callers given a Cloud type-assert it.
+type ContextCloud interface {
+	Cloud
+
+	// OpenPortsWithContext is the same as OpenPorts but the cloud API calls are bound to the given context.
+	OpenPortsWithContext(ctx context.Context, ports []PortSpec, status reporter.Interface) error
+
+	// ClosePortsWithContext is the same as ClosePorts but the cloud API calls are bound to the given context.
+	ClosePortsWithContext(ctx context.Context, status reporter.Interface) error
+
+	// PlanOpenPorts returns the changes that OpenPorts would make, without applying them.
+	PlanOpenPorts(ctx context.Context, ports []PortSpec, status reporter.Interface) (*Plan, error)
+
+	// PlanClosePorts returns the changes that ClosePorts would make, without applying them.
+	PlanClosePorts(ctx context.Context, status reporter.Interface) (*Plan, error)
+
+	// ReconcilePorts makes the internal ports opened in the cloud match the given ones, regardless of what was opened before:
+	// the missing rules are added and the Submariner rules for the ports which are no longer requested are removed. The
+	// differences found are reported through the reporter and returned as the plan of the changes applied.
+	ReconcilePorts(ctx context.Context, ports []PortSpec, status reporter.Interface) (*Plan, error)
+
+	// Validate checks, without changing anything, that the credentials in use have the permissions needed to open and
+	// close the ports. All the missing permissions are reported at once in a MissingPermissionsError.
+	Validate(ctx context.Context, status reporter.Interface) error
+}
+
 type GatewayDeployInput struct {
 	// List of ports to open externally so that Submariner can reach and be reached by other Submariners.
 	PublicPorts []PortSpec
@@ -51,6 +146,40 @@ type GatewayDeployInput struct {
 
 	// Specifies if the underlying deployment is air-gapped.
 	AirGapped bool
+
+	// SourceCIDRs restricts the sources allowed to reach the PublicPorts, typically to the public IPs of the other clusters.
+	// If empty, the PublicPorts are reachable from any address. Deploying again with other source CIDRs replaces the gateway
+	// rules, revoking the access from the sources no longer listed.
+	SourceCIDRs []string
+
+	// IPFamily specifies the IP families the gateway firewall rules and public IPs are created for. If empty, the IP family
+	// the cloud was created with is used.
+	IPFamily IPFamily
+
+	// WaitTimeout, if not zero, makes Deploy wait, for up to the given duration, for the machines of the dedicated gateways to
+	// be running and their nodes to be Ready and labelled as gateways. Otherwise, Deploy returns once the gateways are requested.
+	WaitTimeout time.Duration
+
+	// HealthCheck, if not nil, makes Deploy create a MachineHealthCheck for each dedicated gateway MachineSet, so that the
+	// gateway machines whose nodes become unhealthy are replaced. The health checks are removed with their MachineSets, and
+	// Deploy removes those of the existing gateway MachineSets if it's nil.
+	// The HyperShift deployer enables the auto-repair of the gateway NodePool instead.
+	HealthCheck *HealthCheck
+}
+
+// PublicSourceCIDRs returns the source CIDRs the PublicPorts should be opened to, defaulting to any address of the
+// requested IP families.
+func (i *GatewayDeployInput) PublicSourceCIDRs() []string {
+	if len(i.SourceCIDRs) > 0 {
+		return i.SourceCIDRs
+	}
+
+	cidrs := []string{}
+	for _, family := range i.IPFamily.Families() {
+		cidrs = append(cidrs, family.AnyCIDR())
+	}
+
+	return cidrs
 }
 
 // GatewayDeployer will deploy and cleanup dedicated gateways according to the requested policy.
@@ -61,3 +190,29 @@ type GatewayDeployer interface {
 	// Cleanup any dedicated gateways that were previously deployed.
 	Cleanup(status reporter.Interface) error
 }
+
+// ContextGatewayDeployer extends GatewayDeployer with the operations bound to a context, the plans, the status and the
+// validation. All the GatewayDeployer implementations of this repository implement it; it's a separate interface so that
+// the existing implementations of GatewayDeployer keep compiling. Callers given a GatewayDeployer type-assert it.
+type ContextGatewayDeployer interface {
+	GatewayDeployer
+
+	// DeployWithContext is the same as Deploy but the cloud and cluster API calls are bound to the given context.
+	DeployWithContext(ctx context.Context, input GatewayDeployInput, status reporter.Interface) error
+
+	// CleanupWithContext is the same as Cleanup but the cloud and cluster API calls are bound to the given context.
+	CleanupWithContext(ctx context.Context, status reporter.Interface) error
+
+	// PlanDeploy returns the changes that Deploy would make, without applying them.
+	PlanDeploy(ctx context.Context, input GatewayDeployInput, status reporter.Interface) (*Plan, error)
+
+	// PlanCleanup returns the changes that Cleanup would make, without applying them.
+	PlanCleanup(ctx context.Context, status reporter.Interface) (*Plan, error)
+
+	// Status returns the gateways currently deployed or configured, as seen by the deployer. It doesn't make any changes.
+	Status(ctx context.Context, status reporter.Interface) ([]GatewayStatus, error)
+
+	// Validate checks, without changing anything, that the credentials in use have the permissions needed to deploy and
+	// clean up the gateways. All the missing permissions are reported at once in a MissingPermissionsError.
+	Validate(ctx context.Context, status reporter.Interface) error
+}
@@ -0,0 +1,136 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// The kinds of errors returned by the Cloud and GatewayDeployer implementations, independently of the provider.
+// Check for them with errors.Is.
+var (
+	// ErrNotFound indicates that a cloud resource doesn't exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrPermissionDenied indicates that the credentials in use aren't authorized to perform the operation.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrQuotaExceeded indicates that a quota or limit of the cloud account was reached.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrConflict indicates that a resource already exists, or is in a state which prevents the operation.
+	ErrConflict = errors.New("conflict")
+
+	// ErrTransient indicates a temporary failure, such as throttling or an unavailable service; retrying may succeed.
+	ErrTransient = errors.New("transient error")
+)
+
+// Error is a provider error classified as one of the error kinds above. It matches its kind with errors.Is,
+// while the original provider error remains reachable with errors.As.
+type Error struct {
+	// Kind is one of ErrNotFound, ErrPermissionDenied, ErrQuotaExceeded, ErrConflict or ErrTransient.
+	Kind error
+
+	// Provider is the name of the provider which returned the error.
+	Provider string
+
+	// Err is the original error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// MissingPermissionsError lists the permissions which the credentials in use lack, as reported by the Validate methods.
+// It matches ErrPermissionDenied.
+type MissingPermissionsError struct {
+	// Provider is the name of the provider the permissions belong to.
+	Provider string
+
+	// Permissions are the missing permissions, named as the provider names them.
+	Permissions []string
+}
+
+// NewMissingPermissionsError returns a MissingPermissionsError listing the given permissions, or nil if there are none.
+func NewMissingPermissionsError(provider string, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	return &MissingPermissionsError{Provider: provider, Permissions: permissions}
+}
+
+func (e *MissingPermissionsError) Error() string {
+	return fmt.Sprintf("the %s credentials are missing the following permissions: %s", e.Provider, strings.Join(e.Permissions, ", "))
+}
+
+func (e *MissingPermissionsError) Is(target error) bool {
+	return target == ErrPermissionDenied
+}
+
+// ClassifyError wraps err in an Error of the kind returned by kindOf, for the given provider. Kubernetes API errors
+// and context deadlines, which all the providers may return, are classified when kindOf doesn't recognize err.
+// err is returned as is if it's nil, already classified or of an unknown kind.
+func ClassifyError(provider string, err error, kindOf func(error) error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	kind := kindOf(err)
+	if kind == nil {
+		kind = commonErrorKind(err)
+	}
+
+	if kind == nil {
+		return err
+	}
+
+	return &Error{Kind: kind, Provider: provider, Err: err}
+}
+
+func commonErrorKind(err error) error {
+	switch {
+	case apierrors.IsNotFound(err):
+		return ErrNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return ErrPermissionDenied
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return ErrConflict
+	case apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err), errors.Is(err, context.DeadlineExceeded):
+		return ErrTransient
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// HealthCheck configures the remediation of the dedicated gateway nodes: the machines whose nodes are deemed unhealthy are
+// deleted, and replaced by their MachineSet.
+type HealthCheck struct {
+	// UnhealthyConditions are the node conditions which mark a gateway machine as unhealthy once they've lasted for their
+	// timeout. If empty, DefaultUnhealthyConditions are used.
+	UnhealthyConditions []UnhealthyCondition `json:"unhealthyConditions,omitempty"`
+
+	// MaxUnhealthy is the number, or the percentage, of unhealthy machines of a gateway MachineSet above which they're no
+	// longer remediated. If nil, the unhealthy machines are always remediated.
+	MaxUnhealthy *intstr.IntOrString `json:"maxUnhealthy,omitempty"`
+}
+
+// UnhealthyCondition is a node condition which marks a machine as unhealthy once it has lasted for the timeout.
+type UnhealthyCondition struct {
+	Type    corev1.NodeConditionType `json:"type"`
+	Status  corev1.ConditionStatus   `json:"status"`
+	Timeout metav1.Duration          `json:"timeout"`
+}
+
+// DefaultUnhealthyConditions mark a gateway machine as unhealthy once its node hasn't been Ready for 5 minutes.
+var DefaultUnhealthyConditions = []UnhealthyCondition{
+	{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Timeout: metav1.Duration{Duration: 5 * time.Minute}},
+	{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Timeout: metav1.Duration{Duration: 5 * time.Minute}},
+}
+
+// Conditions returns the unhealthy conditions, defaulting to DefaultUnhealthyConditions.
+func (h *HealthCheck) Conditions() []UnhealthyCondition {
+	if len(h.UnhealthyConditions) == 0 {
+		return DefaultUnhealthyConditions
+	}
+
+	return h.UnhealthyConditions
+}
@@ -0,0 +1,86 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import "strings"
+
+// IPFamily specifies the IP families to create firewall rules and public IPs for.
+type IPFamily string
+
+const (
+	IPv4      IPFamily = "IPv4"
+	IPv6      IPFamily = "IPv6"
+	DualStack IPFamily = "DualStack"
+)
+
+const (
+	// AnyIPv4CIDR is the CIDR matching any IPv4 address.
+	AnyIPv4CIDR = "0.0.0.0/0"
+
+	// AnyIPv6CIDR is the CIDR matching any IPv6 address.
+	AnyIPv6CIDR = "::/0"
+)
+
+// Families returns the single IP families, IPv4 and/or IPv6, making up this family. An empty family is IPv4.
+func (f IPFamily) Families() []IPFamily {
+	switch f {
+	case IPv6:
+		return []IPFamily{IPv6}
+	case DualStack:
+		return []IPFamily{IPv4, IPv6}
+	default:
+		return []IPFamily{IPv4}
+	}
+}
+
+// HasIPv6 returns true if this family includes IPv6.
+func (f IPFamily) HasIPv6() bool {
+	return f == IPv6 || f == DualStack
+}
+
+// AnyCIDR returns the CIDR matching any address of this single IP family.
+func (f IPFamily) AnyCIDR() string {
+	if f == IPv6 {
+		return AnyIPv6CIDR
+	}
+
+	return AnyIPv4CIDR
+}
+
+// CIDRFamily returns the single IP family of the given CIDR or IP address.
+func CIDRFamily(cidr string) IPFamily {
+	if strings.Contains(cidr, ":") {
+		return IPv6
+	}
+
+	return IPv4
+}
+
+// FilterCIDRs returns the given CIDRs which belong to the given single IP family.
+func FilterCIDRs(cidrs []string, family IPFamily) []string {
+	filtered := []string{}
+
+	for _, cidr := range cidrs {
+		if CIDRFamily(cidr) == family {
+			filtered = append(filtered, cidr)
+		}
+	}
+
+	return filtered
+}
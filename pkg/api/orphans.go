@@ -0,0 +1,57 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+)
+
+// Orphan is a Submariner cloud resource left behind by a cluster which no longer exists, typically by a failed or
+// interrupted run.
+type Orphan struct {
+	Resource ResourceType `json:"resource"`
+	// Name identifies the resource, using the provider's naming.
+	Name string `json:"name"`
+	// ID is the provider's identifier of the resource, if it differs from its name.
+	ID string `json:"id,omitempty"`
+	// InfraID is the infra ID of the cluster the resource was created for, if it can be determined.
+	InfraID string `json:"infraID,omitempty"`
+}
+
+func (o Orphan) String() string {
+	s := fmt.Sprintf("%s %q", o.Resource, o.Name)
+	if o.InfraID != "" {
+		s += fmt.Sprintf(" of cluster %q", o.InfraID)
+	}
+
+	return s
+}
+
+// OrphanScanner finds the Submariner resources in a cloud account which don't belong to a live cluster, and removes them.
+type OrphanScanner interface {
+	// Scan lists all the resources carrying Submariner naming or tags, and returns those which weren't created for one
+	// of the given live clusters, identified by their infra IDs.
+	Scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]Orphan, error)
+
+	// Delete removes the given orphaned resources, as returned by Scan.
+	Delete(ctx context.Context, orphans []Orphan, status reporter.Interface) error
+}
@@ -0,0 +1,116 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+)
+
+// ChangeAction is the action a planned Change would perform on a resource.
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "Create"
+	ChangeUpdate ChangeAction = "Update"
+	ChangeDelete ChangeAction = "Delete"
+)
+
+// ResourceType is the type of cloud or cluster resource a planned Change applies to.
+type ResourceType string
+
+const (
+	SecurityGroupResource     ResourceType = "SecurityGroup"
+	SecurityGroupRuleResource ResourceType = "SecurityGroupRule"
+	FirewallRuleResource      ResourceType = "FirewallRule"
+	SubnetTagsResource        ResourceType = "SubnetTags"
+	InstanceResource          ResourceType = "Instance"
+	NetworkInterfaceResource  ResourceType = "NetworkInterface"
+	PublicIPResource          ResourceType = "PublicIP"
+	MachineSetResource        ResourceType = "MachineSet"
+	NodePoolResource          ResourceType = "NodePool"
+	NodeGatewayLabelResource  ResourceType = "NodeGatewayLabel"
+)
+
+// Change is a single change that an operation would apply to a resource.
+type Change struct {
+	Action   ChangeAction `json:"action"`
+	Resource ResourceType `json:"resource"`
+	// Name identifies the resource, using the provider's naming (e.g. a security group name or ID).
+	Name string `json:"name"`
+	// Details provides additional human-readable information about the change, if any.
+	Details string `json:"details,omitempty"`
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s %q", c.Action, c.Resource, c.Name)
+	if c.Details != "" {
+		s += ": " + c.Details
+	}
+
+	return s
+}
+
+// Plan is the ordered list of changes that an operation would apply, as returned by the Plan* methods
+// on Cloud and GatewayDeployer. Computing a plan only performs read operations.
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Add appends a Change to the plan. The details are formatted using fmt.Sprintf.
+func (p *Plan) Add(action ChangeAction, resource ResourceType, name, detailsFormat string, args ...interface{}) {
+	p.Changes = append(p.Changes, Change{
+		Action:   action,
+		Resource: resource,
+		Name:     name,
+		Details:  fmt.Sprintf(detailsFormat, args...),
+	})
+}
+
+// IsEmpty returns true if the plan has no changes, that is the resources are already in the desired state.
+func (p *Plan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p *Plan) String() string {
+	if p.IsEmpty() {
+		return "No changes"
+	}
+
+	lines := make([]string, len(p.Changes))
+	for i := range p.Changes {
+		lines[i] = p.Changes[i].String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Report reports each change of the plan as a success through the given reporter, or that there are no changes.
+func (p *Plan) Report(status reporter.Interface) {
+	if p.IsEmpty() {
+		status.Success("No changes")
+		return
+	}
+
+	for i := range p.Changes {
+		status.Success("%s", p.Changes[i].String())
+	}
+}
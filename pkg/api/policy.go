@@ -0,0 +1,90 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// PolicyMode selects the operations that a generated least-privilege policy allows. The hosted modes need no permissions
+// from the providers which don't support hosted control plane clusters.
+type PolicyMode string
+
+const (
+	// OpenPortsMode allows opening the internal ports with Cloud.OpenPorts.
+	OpenPortsMode PolicyMode = "OpenPorts"
+	// ClosePortsMode allows closing the internal ports with Cloud.ClosePorts.
+	ClosePortsMode PolicyMode = "ClosePorts"
+	// ReconcilePortsMode allows reconciling the internal ports with Cloud.ReconcilePorts.
+	ReconcilePortsMode PolicyMode = "ReconcilePorts"
+	// DeployMode allows deploying the gateways with GatewayDeployer.Deploy.
+	DeployMode PolicyMode = "Deploy"
+	// CleanupMode allows cleaning up the gateways with GatewayDeployer.Cleanup.
+	CleanupMode PolicyMode = "Cleanup"
+	// ScanOrphansMode allows looking for orphaned resources with OrphanScanner.Scan.
+	ScanOrphansMode PolicyMode = "ScanOrphans"
+	// DeleteOrphansMode allows deleting orphaned resources with OrphanScanner.Delete.
+	DeleteOrphansMode PolicyMode = "DeleteOrphans"
+	// PrepareHostedGatewaysMode allows preparing the gateways of hosted control plane clusters with the PrepareGateways
+	// method of the providers' hypershift.Platform.
+	PrepareHostedGatewaysMode PolicyMode = "PrepareHostedGateways"
+	// CleanupHostedGatewaysMode allows cleaning up the gateways of hosted control plane clusters with the CleanupGateways
+	// method of the providers' hypershift.Platform.
+	CleanupHostedGatewaysMode PolicyMode = "CleanupHostedGateways"
+)
+
+// PolicyModes are all the supported policy modes.
+var PolicyModes = []PolicyMode{
+	OpenPortsMode, ClosePortsMode, ReconcilePortsMode, DeployMode, CleanupMode, ScanOrphansMode, DeleteOrphansMode,
+	PrepareHostedGatewaysMode, CleanupHostedGatewaysMode,
+}
+
+// PolicyPermissions returns the sorted permissions needed by the given modes, or by all of them if none is given.
+// modeCalls lists the provider calls made by each mode, and callPermissions maps each of these calls to the permissions
+// it requires.
+func PolicyPermissions(modeCalls map[PolicyMode][]string, callPermissions map[string][]string, modes ...PolicyMode,
+) ([]string, error) {
+	if len(modes) == 0 {
+		modes = PolicyModes
+	}
+
+	var permissions []string
+
+	for _, mode := range modes {
+		calls, found := modeCalls[mode]
+		if !found {
+			return nil, errors.Errorf("unknown policy mode %q", mode)
+		}
+
+		for _, call := range calls {
+			callPerms, found := callPermissions[call]
+			if !found {
+				return nil, errors.Errorf("no permissions are known for the call %q", call)
+			}
+
+			permissions = append(permissions, callPerms...)
+		}
+	}
+
+	slices.Sort(permissions)
+
+	return slices.Compact(permissions), nil
+}
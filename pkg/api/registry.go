@@ -0,0 +1,89 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ProviderFactory builds the Cloud and GatewayDeployer of a provider from its configuration. The configuration is of the
+// type the provider registered with, see RegisterProvider.
+type ProviderFactory func(config any) (Cloud, GatewayDeployer, error)
+
+var (
+	providersMutex sync.RWMutex
+	providers      = map[string]ProviderFactory{}
+)
+
+// RegisterProvider registers the factory of a provider under the given name, typically from the init function of the
+// provider's package. The factory is only called with configurations of type C, either by value or by pointer.
+// Registering the same name twice panics, as it's a programming error.
+func RegisterProvider[C any](name string, factory func(config *C) (Cloud, GatewayDeployer, error)) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+
+	if _, exists := providers[name]; exists {
+		panic("cloud provider " + name + " is already registered")
+	}
+
+	providers[name] = func(config any) (Cloud, GatewayDeployer, error) {
+		switch c := config.(type) {
+		case *C:
+			return factory(c)
+		case C:
+			return factory(&c)
+		}
+
+		var expected *C
+
+		return nil, nil, errors.Errorf("invalid configuration type %T for cloud provider %q, expected %T", config, name, expected)
+	}
+}
+
+// NewProvider builds the Cloud and GatewayDeployer of the provider registered under the given name from the given
+// provider-specific configuration.
+func NewProvider(name string, config any) (Cloud, GatewayDeployer, error) {
+	providersMutex.RLock()
+	factory, ok := providers[name]
+	providersMutex.RUnlock()
+
+	if !ok {
+		return nil, nil, errors.Errorf("unknown cloud provider %q, the registered providers are %q", name, Providers())
+	}
+
+	return factory(config)
+}
+
+// Providers returns the sorted names of the registered providers.
+func Providers() []string {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
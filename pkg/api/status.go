@@ -0,0 +1,53 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+// GatewayType describes how a gateway node was provisioned.
+type GatewayType string
+
+const (
+	// DedicatedGateway is a node provisioned from a gateway MachineSet or NodePool deployed by the GatewayDeployer.
+	DedicatedGateway GatewayType = "Dedicated"
+
+	// LabeledWorkerGateway is an existing worker node that was labeled and configured as a gateway.
+	LabeledWorkerGateway GatewayType = "LabeledWorker"
+)
+
+// GatewayStatus describes a single gateway, as returned by GatewayDeployer.Status.
+// Fields that don't apply to, or couldn't be determined for, a provider are left empty.
+type GatewayStatus struct {
+	Type GatewayType `json:"type"`
+
+	// MachineSet is the name of the MachineSet the gateway node belongs to, if it's a dedicated gateway.
+	MachineSet string `json:"machineSet,omitempty"`
+
+	// NodePool is the name of the HyperShift NodePool the gateway node belongs to, on hosted control plane clusters.
+	NodePool string `json:"nodePool,omitempty"`
+
+	Zone         string `json:"zone,omitempty"`
+	Subnet       string `json:"subnet,omitempty"`
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// NodeName is the name of the gateway node. It's empty if the MachineSet hasn't provisioned its node yet.
+	NodeName string `json:"nodeName,omitempty"`
+	PublicIP string `json:"publicIP,omitempty"`
+
+	// SecurityGroup is the security group, or firewall rule, opening the public ports on the gateway.
+	SecurityGroup string `json:"securityGroup,omitempty"`
+}
@@ -27,11 +27,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	awsClient "github.com/submariner-io/cloud-prepare/pkg/aws/client"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 )
 
 const (
@@ -48,6 +49,8 @@ const (
 	WorkerSecurityGroupIDKey       = "workerSecurityGroupID"
 	PublicSubnetListKey            = "PublicSubnetList"
 	VPCIDKey                       = "VPCID"
+	IPFamilyKey                    = "IPFamily"
+	TagsKey                        = "Tags"
 )
 
 func WithControlPlaneSecurityGroup(id string) CloudOption {
@@ -74,8 +77,40 @@ func WithVPCName(name string) CloudOption {
 	}
 }
 
+// WithIPFamily sets the IP families the gateway security group rules are created for by default.
+func WithIPFamily(family api.IPFamily) CloudOption {
+	return func(cloud *awsCloud) {
+		cloud.cloudConfig[IPFamilyKey] = family
+	}
+}
+
+// WithTags sets the tags applied, along with the Submariner ones, to the security groups and gateway instances created.
+func WithTags(tags map[string]string) CloudOption {
+	return func(cloud *awsCloud) {
+		cloud.cloudConfig[TagsKey] = tags
+	}
+}
+
+// WithLedger records the objects created or modified in the given ledger. ClosePorts and Cleanup then undo exactly what
+// the ledger lists instead of looking the objects up by name and description, unless it lists nothing for them.
+func WithLedger(l *ledger.Ledger) CloudOption {
+	return func(cloud *awsCloud) {
+		cloud.ledger = l
+	}
+}
+
+// WithK8sClient sets the client of the cluster, which is required for Deploy to wait for the gateway nodes, see
+// api.GatewayDeployInput.WaitTimeout.
+func WithK8sClient(client k8s.Interface) CloudOption {
+	return func(cloud *awsCloud) {
+		cloud.k8sClient = k8s.WithContext(client)
+	}
+}
+
 type awsCloud struct {
 	client               awsClient.Interface
+	ledger               *ledger.Ledger
+	k8sClient            k8s.ContextInterface
 	infraID              string
 	region               string
 	nodeSGSuffix         string
@@ -142,30 +177,30 @@ func DefaultProfile() string {
 	return "default"
 }
 
-func (ac *awsCloud) setSuffixes(vpcID string) error {
+func (ac *awsCloud) ipFamily() api.IPFamily {
+	family, _ := ac.cloudConfig[IPFamilyKey].(api.IPFamily)
+
+	return family
+}
+
+func (ac *awsCloud) tags() map[string]string {
+	tags, _ := ac.cloudConfig[TagsKey].(map[string]string)
+
+	return tags
+}
+
+func (ac *awsCloud) setSuffixes(ctx context.Context, vpcID string) error {
 	if ac.nodeSGSuffix != "" {
 		return nil
 	}
 
-	var publicSubnets []types.Subnet
-
-	if subnets, exists := ac.cloudConfig[PublicSubnetListKey]; exists {
-		if subnetIDs, ok := subnets.([]string); ok && len(subnetIDs) > 0 {
-			for _, id := range subnetIDs {
-				subnet, err := ac.getSubnetByID(id)
-				if err != nil {
-					return errors.Wrapf(err, "unable to find subnet with ID %s", id)
-				}
-
-				publicSubnets = append(publicSubnets, *subnet)
-			}
-		} else {
-			return errors.New("Subnet IDs must be a valid non-empty slice of strings")
-		}
-	} else {
-		var err error
+	publicSubnets, found, err := ac.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return err
+	}
 
-		publicSubnets, err = ac.findPublicSubnets(vpcID, ac.filterByName("{infraID}*-public-{region}*"))
+	if !found {
+		publicSubnets, err = ac.findPublicSubnets(ctx, vpcID, ac.filterByName("{infraID}*-public-{region}*"))
 		if err != nil {
 			return errors.Wrapf(err, "unable to find the public subnet")
 		}
@@ -197,16 +232,28 @@ func (ac *awsCloud) setSuffixes(vpcID string) error {
 }
 
 func (ac *awsCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface) error {
+	return ac.OpenPortsWithContext(context.TODO(), ports, status)
+}
+
+func (ac *awsCloud) OpenPortsWithContext(ctx context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	return classifyError(ac.openPorts(ctx, ports, status))
+}
+
+func (ac *awsCloud) openPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	if err := api.ValidatePorts(ports); err != nil {
+		return errors.WithMessage(err, "invalid internal ports")
+	}
+
 	status.Start(messageRetrieveVPCID)
 	defer status.End()
 
-	vpcID, err := ac.getVpcID()
+	vpcID, err := ac.getVpcID(ctx)
 	if err != nil {
 		return status.Error(err, "unable to retrieve the VPC ID")
 	}
 
 	if _, found := ac.cloudConfig[VPCIDKey]; !found {
-		err = ac.setSuffixes(vpcID)
+		err = ac.setSuffixes(ctx, vpcID)
 		if err != nil {
 			return status.Error(err, "unable to retrieve the security group names")
 		}
@@ -216,7 +263,7 @@ func (ac *awsCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface) e
 
 	status.Start(messageValidatePrerequisites)
 
-	err = ac.validatePreparePrerequisites(vpcID)
+	err = ac.validatePreparePrerequisites(ctx, vpcID)
 	if err != nil {
 		return status.Error(err, "unable to validate prerequisites")
 	}
@@ -224,34 +271,61 @@ func (ac *awsCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface) e
 	status.Success(messageValidatedPrerequisites)
 
 	for _, port := range ports {
-		status.Start("Opening port %v protocol %s for intra-cluster communications", port.Port, port.Protocol)
+		status.Start("Opening %s for intra-cluster communications", port)
 
-		err = ac.allowPortInCluster(vpcID, port.Port, port.Protocol)
+		err = ac.allowPortInCluster(ctx, vpcID, port)
 		if err != nil {
 			return status.Error(err, "unable to open port")
 		}
 
-		status.Success("Opened port %v protocol %s for intra-cluster communications", port.Port, port.Protocol)
+		status.Success("Opened %s for intra-cluster communications", port)
 	}
 
 	return nil
 }
 
-func (ac *awsCloud) validatePreparePrerequisites(vpcID string) error {
-	return ac.validateCreateSecGroupRule(vpcID)
+func (ac *awsCloud) validatePreparePrerequisites(ctx context.Context, vpcID string) error {
+	return ac.validateCreateSecGroupRule(ctx, vpcID)
 }
 
 func (ac *awsCloud) ClosePorts(status reporter.Interface) error {
+	return ac.ClosePortsWithContext(context.TODO(), status)
+}
+
+func (ac *awsCloud) ClosePortsWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(ac.closePorts(ctx, status))
+}
+
+func (ac *awsCloud) closePorts(ctx context.Context, status reporter.Interface) error {
+	recorded, err := ac.ledger.Recorded(ctx, ProviderName, ledger.OpenPorts)
+	if err != nil {
+		return status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		status.Start("Revoking the recorded intra-cluster communication permissions")
+		defer status.End()
+
+		err = ac.ledger.Undo(ctx, ProviderName, ledger.OpenPorts, ac.undo)
+		if err != nil {
+			return status.Error(err, "unable to revoke permissions")
+		}
+
+		status.Success("Revoked intra-cluster communication permissions")
+
+		return nil
+	}
+
 	status.Start(messageRetrieveVPCID)
 	defer status.End()
 
-	vpcID, err := ac.getVpcID()
+	vpcID, err := ac.getVpcID(ctx)
 	if err != nil {
 		return status.Error(err, "unable to retrieve the VPC ID")
 	}
 
 	if _, found := ac.cloudConfig[VPCIDKey]; !found {
-		err = ac.setSuffixes(vpcID)
+		err = ac.setSuffixes(ctx, vpcID)
 		if err != nil {
 			return status.Error(err, "unable to retrieve the security group names")
 		}
@@ -261,7 +335,7 @@ func (ac *awsCloud) ClosePorts(status reporter.Interface) error {
 
 	status.Start(messageValidatePrerequisites)
 
-	err = ac.validateCleanupPrerequisites(vpcID)
+	err = ac.validateCleanupPrerequisites(ctx, vpcID)
 	if err != nil {
 		return status.Error(err, "unable to validate prerequisites")
 	}
@@ -270,7 +344,7 @@ func (ac *awsCloud) ClosePorts(status reporter.Interface) error {
 
 	status.Start("Revoking intra-cluster communication permissions")
 
-	err = ac.revokePortsInCluster(vpcID)
+	err = ac.revokePortsInCluster(ctx, vpcID)
 	if err != nil {
 		return status.Error(err, "unable to revoke permissions")
 	}
@@ -280,6 +354,6 @@ func (ac *awsCloud) ClosePorts(status reporter.Interface) error {
 	return nil
 }
 
-func (ac *awsCloud) validateCleanupPrerequisites(vpcID string) error {
-	return ac.validateDeleteSecGroupRule(vpcID)
+func (ac *awsCloud) validateCleanupPrerequisites(ctx context.Context, vpcID string) error {
+	return ac.validateDeleteSecGroupRule(ctx, vpcID)
 }
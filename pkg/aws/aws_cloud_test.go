@@ -19,30 +19,43 @@ limitations under the License.
 package aws_test
 
 import (
+	"context"
 	"errors"
+	"path/filepath"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("Cloud", func() {
 	Describe("OpenPorts", testOpenPorts)
 	Describe("ClosePorts", testClosePorts)
+	Describe("ClosePorts with a ledger", testClosePortsWithLedger)
+	Describe("PlanOpenPorts", testPlanOpenPorts)
+	Describe("PlanClosePorts", testPlanClosePorts)
+	Describe("PlanClosePorts with a ledger", testPlanClosePortsWithLedger)
+	Describe("ReconcilePorts", testReconcilePorts)
+	Describe("Validate", testValidate)
 })
 
 func testOpenPorts() {
 	t := newCloudTestDriver()
 
-	var retError error
-
-	JustBeforeEach(func() {
-		t.expectDescribeVpcs(t.vpcID)
-		t.expectDescribePublicSubnets(t.subnets...)
+	var (
+		ports    []api.PortSpec
+		retError error
+	)
 
-		retError = t.cloud.OpenPorts([]api.PortSpec{
+	BeforeEach(func() {
+		ports = []api.PortSpec{
 			{
 				Port:     100,
 				Protocol: "TCP",
@@ -51,7 +64,14 @@ func testOpenPorts() {
 				Port:     200,
 				Protocol: "UDP",
 			},
-		}, reporter.Stdout())
+		}
+	})
+
+	JustBeforeEach(func() {
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+
+		retError = t.cloud.OpenPorts(ports, reporter.Stdout())
 	})
 
 	When("on success", func() {
@@ -73,11 +93,73 @@ func testOpenPorts() {
 		It("should authorize the appropriate security groups ingress", func() {
 			Expect(retError).To(Succeed())
 		})
+
+		It("should only make calls allowed by the OpenPorts policy", func() {
+			t.assertCallsAllowedBy(api.OpenPortsMode)
+		})
 	})
 
-	When("the infra ID VPC does not exist", func() {
+	When("a port range and a portless protocol are requested", func() {
 		BeforeEach(func() {
-			t.vpcID = ""
+			ports = []api.PortSpec{
+				{
+					Port:     4500,
+					EndPort:  4510,
+					Protocol: "udp",
+				},
+				{
+					Protocol: "esp",
+				},
+			}
+
+			t.expectValidateAuthorizeSecurityGroupIngress(nil)
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID)
+
+			rangeRule := newClusterSGRule(workerGroupID, 4500, "udp")
+			rangeRule.ToPort = ptr.To(int32(4510))
+			t.expectAuthorizeSecurityGroupIngress(workerGroupID, rangeRule)
+
+			rangeRule = newClusterSGRule(masterGroupID, 4500, "udp")
+			rangeRule.ToPort = ptr.To(int32(4510))
+			t.expectAuthorizeSecurityGroupIngress(workerGroupID, rangeRule)
+
+			rangeRule = newClusterSGRule(workerGroupID, 4500, "udp")
+			rangeRule.ToPort = ptr.To(int32(4510))
+			t.expectAuthorizeSecurityGroupIngress(masterGroupID, rangeRule)
+
+			for _, groupIDs := range [][2]string{{workerGroupID, workerGroupID}, {workerGroupID, masterGroupID}, {masterGroupID, workerGroupID}} {
+				espRule := newClusterSGRule(groupIDs[1], 0, "50")
+				espRule.FromPort = nil
+				espRule.ToPort = nil
+				t.expectAuthorizeSecurityGroupIngress(groupIDs[0], espRule)
+			}
+		})
+
+		It("should authorize the port range and the protocol by number without ports", func() {
+			Expect(retError).To(Succeed())
+		})
+	})
+
+	When("icmp is requested", func() {
+		BeforeEach(func() {
+			ports = []api.PortSpec{{Protocol: "icmp"}}
+
+			t.expectValidateAuthorizeSecurityGroupIngress(nil)
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID)
+
+			for _, groupIDs := range [][2]string{{workerGroupID, workerGroupID}, {workerGroupID, masterGroupID}, {masterGroupID, workerGroupID}} {
+				t.expectAuthorizeSecurityGroupIngress(groupIDs[0], newClusterSGRule(groupIDs[1], -1, "icmp"))
+			}
+		})
+
+		It("should authorize all the ICMP types and codes", func() {
+			Expect(retError).To(Succeed())
+		})
+	})
+
+	When("the end port of a port range is lower than its port", func() {
+		BeforeEach(func() {
+			ports = []api.PortSpec{{Port: 4510, EndPort: 4500, Protocol: "udp"}}
 		})
 
 		It("should return an error", func() {
@@ -85,6 +167,16 @@ func testOpenPorts() {
 		})
 	})
 
+	When("the infra ID VPC does not exist", func() {
+		BeforeEach(func() {
+			t.vpcID = ""
+		})
+
+		It("should return a not found error", func() {
+			Expect(retError).To(MatchError(api.ErrNotFound))
+		})
+	})
+
 	When("authorize security group ingress validation fails", func() {
 		BeforeEach(func() {
 			t.expectDescribeVpcs(vpcID)
@@ -97,6 +189,20 @@ func testOpenPorts() {
 		})
 	})
 
+	When("authorizing security group ingress isn't permitted", func() {
+		BeforeEach(func() {
+			t.expectValidateAuthorizeSecurityGroupIngress(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		})
+
+		It("should return a permission denied error wrapping the AWS error", func() {
+			Expect(retError).To(MatchError(api.ErrPermissionDenied))
+
+			var apiErr smithy.APIError
+			Expect(errors.As(retError, &apiErr)).To(BeTrue())
+			Expect(apiErr.ErrorCode()).To(Equal("UnauthorizedOperation"))
+		})
+	})
+
 	When("retrieval of security groups fails", func() {
 		BeforeEach(func() {
 			t.expectValidateAuthorizeSecurityGroupIngress(nil)
@@ -169,9 +275,365 @@ func testClosePorts() {
 	})
 }
 
+func testClosePortsWithLedger() {
+	t := newCloudTestDriver()
+
+	var (
+		retError              error
+		existingMasterRuleErr error
+	)
+
+	BeforeEach(func() {
+		t.cloud = aws.NewCloud(t.awsClient, infraID, region,
+			aws.WithLedger(ledger.New(ledger.NewFileStore(filepath.Join(GinkgoT().TempDir(), "ledger.json"))))).(api.ContextCloud)
+
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+		t.expectValidateAuthorizeSecurityGroupIngress(nil)
+		t.expectDescribeSecurityGroups(masterSGName, masterGroupID)
+
+		existingMasterRuleErr = nil
+	})
+
+	JustBeforeEach(func() {
+		if existingMasterRuleErr != nil {
+			t.awsClient.EXPECT().AuthorizeSecurityGroupIngress(mock.Anything, mock.MatchedBy(
+				func(in *ec2.AuthorizeSecurityGroupIngressInput) bool {
+					return ptr.Deref(in.GroupId, "") == masterGroupID && in.DryRun == nil
+				})).Return(nil, existingMasterRuleErr)
+		} else {
+			t.expectAuthorizeSecurityGroupIngress(masterGroupID, newClusterSGRule(workerGroupID, 100, "TCP"))
+		}
+
+		t.expectAuthorizeSecurityGroupIngress(workerGroupID, newClusterSGRule(workerGroupID, 100, "TCP"))
+		t.expectAuthorizeSecurityGroupIngress(workerGroupID, newClusterSGRule(masterGroupID, 100, "TCP"))
+
+		Expect(t.cloud.OpenPorts([]api.PortSpec{{Port: 100, Protocol: "TCP"}}, reporter.Stdout())).To(Succeed())
+
+		retError = t.cloud.ClosePorts(reporter.Stdout())
+	})
+
+	Context("on success", func() {
+		BeforeEach(func() {
+			t.expectRevokeClusterSGRule(workerGroupID, workerGroupID, 100)
+			t.expectRevokeClusterSGRule(workerGroupID, masterGroupID, 100)
+			t.expectRevokeClusterSGRule(masterGroupID, workerGroupID, 100)
+		})
+
+		It("should revoke exactly the recorded ingress rules", func() {
+			Expect(retError).To(Succeed())
+		})
+
+		It("should forget them and then look the rules up by description", func() {
+			Expect(retError).To(Succeed())
+
+			t.expectDescribePublicSubnetsSigs(t.subnets...)
+			t.expectValidateRevokeSecurityGroupIngress(nil)
+			Expect(t.cloud.ClosePorts(reporter.Stdout())).To(Succeed())
+		})
+	})
+
+	When("a rule was already authorized before the ports were opened", func() {
+		BeforeEach(func() {
+			existingMasterRuleErr = &smithy.GenericAPIError{Code: "InvalidPermission.Duplicate"}
+
+			t.expectRevokeClusterSGRule(workerGroupID, workerGroupID, 100)
+			t.expectRevokeClusterSGRule(workerGroupID, masterGroupID, 100)
+		})
+
+		It("should not revoke it", func() {
+			Expect(retError).To(Succeed())
+		})
+	})
+
+	When("revoking a rule fails", func() {
+		BeforeEach(func() {
+			t.expectRevokeSecurityGroupIngressFailure(errors.New("mock error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).To(HaveOccurred())
+		})
+	})
+}
+
+func testValidate() {
+	t := newCloudTestDriver()
+
+	var retError error
+
+	JustBeforeEach(func() {
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+
+		retError = t.cloud.Validate(context.TODO(), reporter.Stdout())
+	})
+
+	When("all the permissions are granted", func() {
+		BeforeEach(func() {
+			t.expectValidateAuthorizeSecurityGroupIngress(nil)
+			t.expectValidateRevokeSecurityGroupIngress(nil)
+		})
+
+		It("should succeed", func() {
+			Expect(retError).To(Succeed())
+		})
+	})
+
+	When("permissions are missing", func() {
+		BeforeEach(func() {
+			t.expectValidateAuthorizeSecurityGroupIngress(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+			t.expectValidateRevokeSecurityGroupIngress(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		})
+
+		It("should return all the missing permissions", func() {
+			Expect(retError).To(MatchError(api.ErrPermissionDenied))
+
+			var missingErr *api.MissingPermissionsError
+			Expect(errors.As(retError, &missingErr)).To(BeTrue())
+			Expect(missingErr.Permissions).To(Equal([]string{"ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress"}))
+		})
+	})
+
+	When("a permission check fails", func() {
+		BeforeEach(func() {
+			t.expectValidateAuthorizeSecurityGroupIngress(errors.New("mock error"))
+			t.expectValidateRevokeSecurityGroupIngress(nil)
+		})
+
+		It("should return an error", func() {
+			Expect(retError).To(HaveOccurred())
+			Expect(retError).ToNot(MatchError(api.ErrPermissionDenied))
+		})
+	})
+}
+
+func testPlanOpenPorts() {
+	t := newCloudTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+
+		plan, retError = t.cloud.PlanOpenPorts(context.TODO(), []api.PortSpec{
+			{
+				Port:     100,
+				Protocol: "TCP",
+			},
+		}, reporter.Stdout())
+	})
+
+	When("none of the ingress rules exist", func() {
+		BeforeEach(func() {
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID)
+		})
+
+		It("should plan to authorize all of them", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(3))
+
+			for _, c := range plan.Changes {
+				Expect(c.Action).To(Equal(api.ChangeCreate))
+				Expect(c.Resource).To(Equal(api.SecurityGroupRuleResource))
+			}
+
+			Expect(plan.Changes[0].Name).To(Equal(workerGroupID))
+			Expect(plan.Changes[1].Name).To(Equal(masterGroupID))
+			Expect(plan.Changes[2].Name).To(Equal(workerGroupID))
+		})
+	})
+
+	When("an ingress rule already exists", func() {
+		BeforeEach(func() {
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID, *newClusterSGRule(workerGroupID, 100, "TCP"))
+		})
+
+		It("should not plan to authorize it", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(2))
+
+			for _, c := range plan.Changes {
+				Expect(c.Name).To(Equal(workerGroupID))
+			}
+		})
+	})
+
+	When("retrieval of security groups fails", func() {
+		BeforeEach(func() {
+			t.expectDescribeSecurityGroupsFailure(masterSGName, errors.New("mock error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).To(HaveOccurred())
+		})
+	})
+}
+
+func testPlanClosePorts() {
+	t := newCloudTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+		t.expectDescribePublicSubnetsSigs(t.subnets...)
+
+		plan, retError = t.cloud.PlanClosePorts(context.TODO(), reporter.Stdout())
+	})
+
+	Context("on success", func() {
+		BeforeEach(func() {
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID, newIPPermission(internalTraffic+" from X to Y"),
+				newIPPermission("other"))
+		})
+
+		It("should plan to revoke only the Submariner ingress rules", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(1))
+			Expect(plan.Changes[0].Action).To(Equal(api.ChangeDelete))
+			Expect(plan.Changes[0].Resource).To(Equal(api.SecurityGroupRuleResource))
+			Expect(plan.Changes[0].Name).To(Equal(masterGroupID))
+		})
+	})
+
+	When("the infra ID VPC does not exist", func() {
+		BeforeEach(func() {
+			t.vpcID = ""
+		})
+
+		It("should return an error", func() {
+			Expect(retError).To(HaveOccurred())
+		})
+	})
+}
+
+func testPlanClosePortsWithLedger() {
+	t := newCloudTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	BeforeEach(func() {
+		t.cloud = aws.NewCloud(t.awsClient, infraID, region,
+			aws.WithLedger(ledger.New(ledger.NewFileStore(filepath.Join(GinkgoT().TempDir(), "ledger.json"))))).(api.ContextCloud)
+
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+		t.expectValidateAuthorizeSecurityGroupIngress(nil)
+		t.expectDescribeSecurityGroups(masterSGName, masterGroupID)
+		t.expectAuthorizeSecurityGroupIngress(masterGroupID, newClusterSGRule(workerGroupID, 100, "TCP"))
+		t.expectAuthorizeSecurityGroupIngress(workerGroupID, newClusterSGRule(workerGroupID, 100, "TCP"))
+		t.expectAuthorizeSecurityGroupIngress(workerGroupID, newClusterSGRule(masterGroupID, 100, "TCP"))
+
+		Expect(t.cloud.OpenPorts([]api.PortSpec{{Port: 100, Protocol: "TCP"}}, reporter.Stdout())).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		plan, retError = t.cloud.PlanClosePorts(context.TODO(), reporter.Stdout())
+	})
+
+	It("should plan to revoke exactly the recorded ingress rules", func() {
+		Expect(retError).To(Succeed())
+		Expect(plan.Changes).To(HaveLen(3))
+		Expect(plan.Changes).To(HaveEach(HaveField("Action", api.ChangeDelete)))
+		Expect(plan.Changes).To(HaveEach(HaveField("Resource", api.SecurityGroupRuleResource)))
+		Expect(plan.Changes).To(ContainElement(HaveField("Name", masterGroupID)))
+		Expect(plan.Changes).To(ContainElement(HaveField("Name", workerGroupID)))
+	})
+}
+
+func testReconcilePorts() {
+	t := newCloudTestDriver()
+
+	var (
+		ports    []api.PortSpec
+		plan     *api.Plan
+		retError error
+	)
+
+	BeforeEach(func() {
+		ports = []api.PortSpec{
+			{
+				Port:     100,
+				Protocol: "TCP",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribePublicSubnets(t.subnets...)
+
+		plan, retError = t.cloud.ReconcilePorts(context.TODO(), ports, reporter.Stdout())
+	})
+
+	When("the ingress rules differ from the requested ports", func() {
+		BeforeEach(func() {
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID, *newClusterSGRule(workerGroupID, 100, "TCP"),
+				*newClusterSGRule(workerGroupID, 300, "UDP"), newIPPermission("other"))
+
+			t.expectRevokeClusterSGRule(masterGroupID, workerGroupID, 300)
+			t.expectAuthorizeSecurityGroupIngress(workerGroupID, newClusterSGRule(workerGroupID, 100, "TCP"))
+			t.expectAuthorizeSecurityGroupIngress(workerGroupID, newClusterSGRule(masterGroupID, 100, "TCP"))
+		})
+
+		It("should revoke the stale ingress rules and authorize the missing ones", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(3))
+
+			Expect(plan.Changes[0].Action).To(Equal(api.ChangeCreate))
+			Expect(plan.Changes[0].Name).To(Equal(workerGroupID))
+			Expect(plan.Changes[1].Action).To(Equal(api.ChangeDelete))
+			Expect(plan.Changes[1].Name).To(Equal(masterGroupID))
+			Expect(plan.Changes[1].Details).To(ContainSubstring("300-300/UDP"))
+			Expect(plan.Changes[2].Action).To(Equal(api.ChangeCreate))
+			Expect(plan.Changes[2].Name).To(Equal(workerGroupID))
+		})
+
+		It("should only make calls allowed by the ReconcilePorts policy", func() {
+			t.assertCallsAllowedBy(api.ReconcilePortsMode)
+		})
+	})
+
+	When("no ports are requested and there are no internal ingress rules", func() {
+		BeforeEach(func() {
+			ports = nil
+
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID, newIPPermission("other"))
+		})
+
+		It("should not change anything", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.IsEmpty()).To(BeTrue())
+		})
+	})
+
+	When("revoking the stale ingress rules fails", func() {
+		BeforeEach(func() {
+			t.expectDescribeSecurityGroups(masterSGName, masterGroupID, *newClusterSGRule(workerGroupID, 300, "UDP"))
+			t.expectRevokeSecurityGroupIngressFailure(errors.New("mock error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).To(HaveOccurred())
+		})
+	})
+}
+
 type cloudTestDriver struct {
 	fakeAWSClientBase
-	cloud api.Cloud
+	cloud api.ContextCloud
 }
 
 func newCloudTestDriver() *cloudTestDriver {
@@ -180,7 +642,7 @@ func newCloudTestDriver() *cloudTestDriver {
 	BeforeEach(func() {
 		t.beforeEach()
 
-		t.cloud = aws.NewCloud(t.awsClient, infraID, region)
+		t.cloud = aws.NewCloud(t.awsClient, infraID, region).(api.ContextCloud)
 
 		t.expectDescribeSecurityGroups(workerSGName, workerGroupID)
 	})
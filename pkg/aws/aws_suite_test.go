@@ -29,6 +29,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
 	"github.com/submariner-io/cloud-prepare/pkg/aws/client/fake"
 	"k8s.io/utils/ptr"
 )
@@ -85,6 +87,16 @@ func (f *fakeAWSClientBase) afterEach() {
 	f.awsClient.AssertExpectations(GinkgoT())
 }
 
+// assertCallsAllowedBy asserts that all the EC2 calls made so far are allowed by the policy of the given mode.
+func (f *fakeAWSClientBase) assertCallsAllowedBy(mode api.PolicyMode) {
+	permissions, err := aws.Permissions(mode)
+	Expect(err).To(Succeed())
+
+	for i := range f.awsClient.Calls {
+		Expect(permissions).To(ContainElement("ec2:"+f.awsClient.Calls[i].Method), "%s policy", mode)
+	}
+}
+
 func (f *fakeAWSClientBase) expectDescribeSecurityGroups(name, groupID string, ipPermissions ...types.IpPermission) {
 	f.awsClient.EXPECT().DescribeSecurityGroups(mock.Anything, newDescribeSecurityGroupsInput(f.vpcID, name)).
 		Return(newDescribeSecurityGroupsOutput(groupID, ipPermissions...), nil).Maybe()
@@ -141,6 +153,20 @@ func (f *fakeAWSClientBase) expectRevokeSecurityGroupIngress(groupID string, ipP
 	}).Return(&ec2.RevokeSecurityGroupIngressOutput{}, nil)
 }
 
+func (f *fakeAWSClientBase) expectRevokeClusterSGRule(groupID, srcGroupID string, port int32) {
+	f.awsClient.EXPECT().RevokeSecurityGroupIngress(mock.Anything, mock.MatchedBy(func(in *ec2.RevokeSecurityGroupIngressInput) bool {
+		return ptr.Deref(in.GroupId, "") == groupID && len(in.IpPermissions) == 1 &&
+			ptr.Deref(in.IpPermissions[0].FromPort, 0) == port && len(in.IpPermissions[0].UserIdGroupPairs) == 1 &&
+			ptr.Deref(in.IpPermissions[0].UserIdGroupPairs[0].GroupId, "") == srcGroupID
+	})).Return(&ec2.RevokeSecurityGroupIngressOutput{}, nil).Once()
+}
+
+func (f *fakeAWSClientBase) expectRevokeSecurityGroupIngressFailure(err error) {
+	f.awsClient.EXPECT().RevokeSecurityGroupIngress(mock.Anything, mock.MatchedBy(func(in *ec2.RevokeSecurityGroupIngressInput) bool {
+		return in.DryRun == nil
+	})).Return(nil, err).Once()
+}
+
 func (f *fakeAWSClientBase) expectValidateRevokeSecurityGroupIngress(retErr error) {
 	f.awsClient.EXPECT().RevokeSecurityGroupIngress(mock.Anything, &ec2.RevokeSecurityGroupIngressInput{
 		DryRun:  ptr.To(true),
@@ -193,7 +219,7 @@ func (f *fakeAWSClientBase) expectValidateCreateSecurityGroup() *mock.Call {
 	})).Return(&ec2.CreateSecurityGroupOutput{}, nil).Call
 }
 
-func (f *fakeAWSClientBase) expectCreateSecurityGroup(name, retGroupID string) {
+func (f *fakeAWSClientBase) expectCreateSecurityGroup(name, retGroupID string, extraTags ...types.Tag) {
 	f.awsClient.EXPECT().CreateSecurityGroup(mock.Anything, &ec2.CreateSecurityGroupInput{
 		Description: ptr.To("Submariner Gateway"),
 		GroupName:   ptr.To(name),
@@ -201,12 +227,12 @@ func (f *fakeAWSClientBase) expectCreateSecurityGroup(name, retGroupID string) {
 		TagSpecifications: []types.TagSpecification{
 			{
 				ResourceType: types.ResourceTypeSecurityGroup,
-				Tags: []types.Tag{
+				Tags: append([]types.Tag{
 					{
 						Key:   ptr.To("Name"),
 						Value: ptr.To(name),
 					},
-				},
+				}, extraTags...),
 			},
 		},
 	}).Return(&ec2.CreateSecurityGroupOutput{GroupId: ptr.To(retGroupID)}, nil)
@@ -387,16 +413,23 @@ func newClusterSGRule(groupID string, port int32, protocol string) *types.IpPerm
 }
 
 func newPublicSGRule(port int32, protocol string) *types.IpPermission {
-	return &types.IpPermission{
+	return newPublicSGRuleFrom(port, protocol, "0.0.0.0/0")
+}
+
+func newPublicSGRuleFrom(port int32, protocol, cidr string) *types.IpPermission {
+	perm := &types.IpPermission{
 		FromPort:   ptr.To(port),
 		ToPort:     ptr.To(port),
 		IpProtocol: ptr.To(protocol),
-		IpRanges: []types.IpRange{
-			{
-				CidrIp: ptr.To("0.0.0.0/0"),
-			},
-		},
 	}
+
+	if strings.Contains(cidr, ":") {
+		perm.Ipv6Ranges = []types.Ipv6Range{{CidrIpv6: ptr.To(cidr)}}
+	} else {
+		perm.IpRanges = []types.IpRange{{CidrIp: ptr.To(cidr)}}
+	}
+
+	return perm
 }
 
 type authorizeSecurityGroupIngressInputMatcher struct {
@@ -446,6 +479,15 @@ func (m *authorizeSecurityGroupIngressInputMatcher) Matches(i interface{}) bool
 			}
 		}
 
+		if in.Ipv6Ranges != nil {
+			out.Ipv6Ranges = make([]types.Ipv6Range, len(in.Ipv6Ranges))
+			copy(out.Ipv6Ranges, in.Ipv6Ranges)
+
+			for i := range out.Ipv6Ranges {
+				out.Ipv6Ranges[i].Description = nil
+			}
+		}
+
 		return &out
 	}
 
@@ -24,6 +24,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"k8s.io/utils/ptr"
+	"k8s.io/utils/set"
 )
 
 func ec2Filter(name, value string) types.Filter {
@@ -40,6 +41,20 @@ func ec2Tag(key, value string) types.Tag {
 	}
 }
 
+// ec2TagsWithName returns the Name tag followed by the user tags, sorted by key. The Name tag, which is used to look the
+// resources up, can't be overridden.
+func ec2TagsWithName(name string, userTags map[string]string) []types.Tag {
+	tags := []types.Tag{ec2Tag("Name", name)}
+
+	for _, key := range set.KeySet(userTags).SortedList() {
+		if key != "Name" {
+			tags = append(tags, ec2Tag(key, userTags[key]))
+		}
+	}
+
+	return tags
+}
+
 func ec2FilterByTag(tag types.Tag) types.Filter {
 	return ec2Filter(fmt.Sprintf("tag:%s", *tag.Key), *tag.Value)
 }
@@ -21,8 +21,10 @@ package aws
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/aws/smithy-go"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 )
 
 type notFoundError struct {
@@ -59,3 +61,38 @@ func isAWSError(err error, code string) bool {
 
 	return false
 }
+
+func classifyError(err error) error {
+	return api.ClassifyError(ProviderName, err, errorKind)
+}
+
+// errorKind maps the EC2 error codes, see https://docs.aws.amazon.com/AWSEC2/latest/APIReference/errors-overview.html.
+func errorKind(err error) error {
+	if isNotFoundError(err) {
+		return api.ErrNotFound
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	code := apiErr.ErrorCode()
+
+	switch {
+	case strings.HasSuffix(code, "NotFound"):
+		return api.ErrNotFound
+	case code == "UnauthorizedOperation" || code == "AuthFailure" || code == "OptInRequired" || strings.HasPrefix(code, "AccessDenied"):
+		return api.ErrPermissionDenied
+	case code == "RequestLimitExceeded" || strings.HasPrefix(code, "Throttling") || strings.HasPrefix(code, "Insufficient") ||
+		strings.HasPrefix(code, "Internal") || strings.HasSuffix(code, "Unavailable"):
+		return api.ErrTransient
+	case strings.HasSuffix(code, "LimitExceeded") || strings.HasPrefix(code, "Max"):
+		return api.ErrQuotaExceeded
+	case strings.HasSuffix(code, ".Duplicate") || strings.HasSuffix(code, ".InUse") || code == "DependencyViolation" ||
+		code == "IncorrectState":
+		return api.ErrConflict
+	}
+
+	return nil
+}
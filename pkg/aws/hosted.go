@@ -0,0 +1,296 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/hypershift"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/set"
+)
+
+type hostedPlatform struct {
+	aws          *awsCloud
+	instanceType string
+}
+
+// NewHostedPlatform returns the hypershift.Platform preparing AWS for the gateway NodePool of a hosted cluster: the
+// gateway security group opening the public ports is created, and the gateway nodes are placed in a public subnet.
+// If instanceType is empty, the instance type of the worker NodePool is used.
+// If the supplied cloud is not an awsCloud, an error is returned.
+func NewHostedPlatform(cloud api.Cloud, instanceType string) (hypershift.Platform, error) {
+	aws, ok := cloud.(*awsCloud)
+	if !ok {
+		return nil, errors.New("the cloud must be AWS")
+	}
+
+	return &hostedPlatform{
+		aws:          aws,
+		instanceType: instanceType,
+	}, nil
+}
+
+func (p *hostedPlatform) PrepareGateways(ctx context.Context, input api.GatewayDeployInput, platform map[string]interface{},
+	status reporter.Interface,
+) error {
+	return classifyError(p.prepareGateways(ctx, input, platform, status))
+}
+
+func (p *hostedPlatform) prepareGateways(ctx context.Context, input api.GatewayDeployInput, platform map[string]interface{},
+	status reporter.Interface,
+) error {
+	if err := api.ValidatePorts(input.PublicPorts); err != nil {
+		return errors.WithMessage(err, "invalid public ports")
+	}
+
+	if input.IPFamily == "" {
+		input.IPFamily = p.aws.ipFamily()
+	}
+
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := p.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the public subnet for the gateway nodes")
+
+	subnet, err := p.publicSubnet(ctx, vpcID)
+	if err != nil {
+		return status.Error(err, "unable to find a public subnet")
+	}
+
+	status.Success("Retrieved the public subnet %s", extractName(subnet.Tags))
+
+	status.Start("Creating Submariner gateway security group")
+
+	groupName, err := p.aws.createGatewaySG(ctx, vpcID, input.PublicPorts, input.PublicSourceCIDRs())
+	if err != nil {
+		return status.Error(err, "unable to create gateway")
+	}
+
+	groupID, err := p.aws.getSecurityGroupName(ctx, vpcID, groupName)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	status.Success("Created Submariner gateway security group %s", groupName)
+
+	return errors.Wrap(p.setPlatform(platform, *subnet.SubnetId, *groupID), "error setting the NodePool platform")
+}
+
+// publicSubnet returns the first public subnet, among the configured or the cluster's ones, supporting the instance type.
+func (p *hostedPlatform) publicSubnet(ctx context.Context, vpcID string) (*types.Subnet, error) {
+	publicSubnets, found, err := p.aws.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		publicSubnets, err = p.aws.findPublicSubnets(ctx, vpcID, p.aws.filterByName("{infraID}*-public-{region}*"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.instanceType != "" {
+		publicSubnets, err = p.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, p.instanceType)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get subnets supporting instance type")
+		}
+	}
+
+	if len(publicSubnets) == 0 {
+		return nil, newNotFoundError("public subnets")
+	}
+
+	return &publicSubnets[0], nil
+}
+
+// setPlatform places the gateway nodes in the given subnet and adds the gateway security group and the custom tags to
+// the AWS platform of the NodePool.
+func (p *hostedPlatform) setPlatform(platform map[string]interface{}, subnetID, groupID string) error {
+	aws, _, _ := unstructured.NestedMap(platform, "aws")
+	if aws == nil {
+		aws = map[string]interface{}{}
+	}
+
+	if p.instanceType != "" {
+		aws["instanceType"] = p.instanceType
+	}
+
+	aws["subnet"] = map[string]interface{}{"id": subnetID}
+
+	securityGroups, _, _ := unstructured.NestedSlice(aws, "securityGroups")
+	aws["securityGroups"] = append(securityGroups, map[string]interface{}{"id": groupID})
+
+	tags, _, _ := unstructured.NestedSlice(aws, "resourceTags")
+	existing := set.New[string]()
+
+	for _, tag := range tags {
+		if tag, ok := tag.(map[string]interface{}); ok {
+			existing.Insert(tag["key"].(string))
+		}
+	}
+
+	userTags := p.aws.tags()
+	for _, key := range set.KeySet(userTags).SortedList() {
+		if !existing.Has(key) {
+			tags = append(tags, map[string]interface{}{"key": key, "value": userTags[key]})
+		}
+	}
+
+	if len(tags) > 0 {
+		aws["resourceTags"] = tags
+	}
+
+	platform["type"] = "AWS"
+
+	return unstructured.SetNestedMap(platform, aws, "aws")
+}
+
+func (p *hostedPlatform) PlanPrepareGateways(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface,
+) (*api.Plan, error) {
+	plan, err := p.planPrepareGateways(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (p *hostedPlatform) planPrepareGateways(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface,
+) (*api.Plan, error) {
+	if input.IPFamily == "" {
+		input.IPFamily = p.aws.ipFamily()
+	}
+
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := p.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	plan := &api.Plan{}
+
+	err = p.aws.planGatewaySG(ctx, plan, vpcID, input.PublicPorts, input.PublicSourceCIDRs())
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	return plan, nil
+}
+
+func (p *hostedPlatform) CleanupGateways(ctx context.Context, status reporter.Interface) error {
+	return classifyError(p.cleanupGateways(ctx, status))
+}
+
+func (p *hostedPlatform) cleanupGateways(ctx context.Context, status reporter.Interface) error {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := p.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Deleting Submariner gateway security group")
+
+	err = p.aws.deleteGatewaySG(ctx, vpcID)
+	if err != nil {
+		return status.Error(err, "unable to delete gateway")
+	}
+
+	status.Success("Deleted Submariner gateway security group")
+
+	return nil
+}
+
+func (p *hostedPlatform) PlanCleanupGateways(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := p.planCleanupGateways(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (p *hostedPlatform) planCleanupGateways(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := p.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	plan := &api.Plan{}
+	groupName := p.aws.withAWSInfo(withInfraIDPrefix(gatewaySGSuffix))
+
+	_, err = p.aws.getSecurityGroup(ctx, vpcID, groupName)
+	if err == nil {
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	} else if !isNotFoundError(err) {
+		return nil, status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	return plan, nil
+}
+
+func (p *hostedPlatform) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(p.validate(ctx, status))
+}
+
+func (p *hostedPlatform) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := p.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Validating the permissions to prepare and clean up the gateway security group")
+
+	err = checkPermissions(
+		permissionCheck{"ec2:CreateSecurityGroup", func() error { return p.aws.validateCreateSecGroup(ctx, vpcID) }},
+		permissionCheck{"ec2:AuthorizeSecurityGroupIngress", func() error { return p.aws.validateCreateSecGroupRule(ctx, vpcID) }},
+		permissionCheck{"ec2:DeleteSecurityGroup", func() error { return p.aws.validateDeleteSecGroup(ctx, vpcID) }},
+	)
+	if err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to prepare and clean up the gateway security group")
+
+	return nil
+}
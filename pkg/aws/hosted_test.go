@@ -0,0 +1,139 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/hypershift"
+)
+
+var _ = Describe("Hosted platform", func() {
+	const instanceType = "test-instance-type"
+
+	t := &fakeAWSClientBase{}
+
+	var (
+		tags     map[string]string
+		platform hypershift.Platform
+	)
+
+	BeforeEach(func() {
+		t.beforeEach()
+
+		tags = nil
+	})
+
+	JustBeforeEach(func() {
+		t.expectDescribeVpcs(t.vpcID)
+		t.expectDescribeSecurityGroups(gatewaySGName, gatewayGroupID)
+		t.expectDescribeSecurityGroups(workerSGName, workerGroupID)
+		t.expectDescribePublicSubnets(t.subnets...)
+
+		var err error
+
+		platform, err = aws.NewHostedPlatform(aws.NewCloud(t.awsClient, infraID, region, aws.WithTags(tags)), instanceType)
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(t.afterEach)
+
+	Context("on PrepareGateways", func() {
+		var nodePoolPlatform map[string]interface{}
+
+		BeforeEach(func() {
+			nodePoolPlatform = map[string]interface{}{
+				"type": "AWS",
+				"aws": map[string]interface{}{
+					"instanceType":   "m5.xlarge",
+					"securityGroups": []interface{}{map[string]interface{}{"id": "sg-default"}},
+				},
+			}
+
+			t.expectDescribeInstanceTypeOfferings(instanceType, availabilityZone1)
+			t.expectDescribeInstanceTypeOfferings(instanceType, availabilityZone2, types.InstanceTypeOffering{})
+			t.expectAuthorizeSecurityGroupIngress(gatewayGroupID, newPublicSGRule(100, "TCP"))
+		})
+
+		JustBeforeEach(func() {
+			Expect(platform.PrepareGateways(context.Background(), api.GatewayDeployInput{
+				PublicPorts: []api.PortSpec{{Port: 100, Protocol: "TCP"}},
+			}, nodePoolPlatform, reporter.Stdout())).To(Succeed())
+		})
+
+		It("should place the gateway nodes in a public subnet supporting the instance type", func() {
+			Expect(nodePoolPlatform).To(HaveKeyWithValue("aws", And(
+				HaveKeyWithValue("instanceType", instanceType),
+				HaveKeyWithValue("subnet", map[string]interface{}{"id": subnetID2}))))
+		})
+
+		It("should add the gateway security group", func() {
+			Expect(nodePoolPlatform).To(HaveKeyWithValue("aws", HaveKeyWithValue("securityGroups", []interface{}{
+				map[string]interface{}{"id": "sg-default"},
+				map[string]interface{}{"id": gatewayGroupID},
+			})))
+		})
+
+		It("should only make calls allowed by the PrepareHostedGateways policy", func() {
+			t.assertCallsAllowedBy(api.PrepareHostedGatewaysMode)
+		})
+
+		Context("and custom tags are configured", func() {
+			BeforeEach(func() {
+				tags = map[string]string{"owner": "team-a", "cost-center": "1234"}
+			})
+
+			It("should add them to the resource tags", func() {
+				Expect(nodePoolPlatform).To(HaveKeyWithValue("aws", HaveKeyWithValue("resourceTags", []interface{}{
+					map[string]interface{}{"key": "cost-center", "value": "1234"},
+					map[string]interface{}{"key": "owner", "value": "team-a"},
+				})))
+			})
+		})
+	})
+
+	Context("on CleanupGateways", func() {
+		BeforeEach(func() {
+			t.expectDeleteSecurityGroup(gatewayGroupID)
+		})
+
+		It("should delete the gateway security group", func() {
+			Expect(platform.CleanupGateways(context.Background(), reporter.Stdout())).To(Succeed())
+			t.assertCallsAllowedBy(api.CleanupHostedGatewaysMode)
+		})
+	})
+
+	Context("on PlanCleanupGateways", func() {
+		It("should plan the deletion of the gateway security group", func() {
+			plan, err := platform.PlanCleanupGateways(context.Background(), reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(Equal([]api.Change{{
+				Action:   api.ChangeDelete,
+				Resource: api.SecurityGroupResource,
+				Name:     gatewaySGName,
+			}}))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"k8s.io/utils/ptr"
+)
+
+// permissionKey is the key of the ledger entry data holding the authorized IpPermission, in JSON.
+const permissionKey = "permission"
+
+func (ac *awsCloud) recordPermission(ctx context.Context, groupID, id string, permission *types.IpPermission) error {
+	if ac.ledger == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(permission)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the IP permission")
+	}
+
+	return ac.ledger.Record(ctx, ledger.Entry{
+		Provider:  ProviderName,
+		Operation: ledger.OpenPorts,
+		Resource:  api.SecurityGroupRuleResource,
+		ID:        id,
+		Parent:    groupID,
+		Data:      map[string]string{permissionKey: string(data)},
+	})
+}
+
+// undo undoes the change recorded in the given ledger entry. Objects which no longer exist are ignored.
+func (ac *awsCloud) undo(ctx context.Context, entry *ledger.Entry) error {
+	switch entry.Resource {
+	case api.SecurityGroupRuleResource:
+		var permission types.IpPermission
+
+		if err := json.Unmarshal([]byte(entry.Data[permissionKey]), &permission); err != nil {
+			return errors.Wrap(err, "error unmarshaling the IP permission")
+		}
+
+		_, err := ac.client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       ptr.To(entry.Parent),
+			IpPermissions: []types.IpPermission{permission},
+		})
+		if isAWSError(err, "InvalidPermission.NotFound") || isAWSError(err, "InvalidGroup.NotFound") {
+			return nil
+		}
+
+		return errors.Wrap(err, "error revoking AWS security group ingress")
+	case api.SecurityGroupResource:
+		err := ac.deleteSecurityGroup(ctx, ptr.To(entry.ID))
+		if isAWSError(err, "InvalidGroup.NotFound") {
+			return nil
+		}
+
+		return err
+	case api.SubnetTagsResource:
+		return ac.untagPublicSubnet(ctx, ptr.To(entry.ID))
+	}
+
+	return errors.Errorf("unsupported resource %q", entry.Resource)
+}
+
+func (d *ocpGatewayDeployer) undo(ctx context.Context, entry *ledger.Entry) error {
+	if entry.Resource == api.MachineSetResource {
+		return errors.Wrapf(d.msDeployer.DeleteByNameWithContext(ctx, entry.ID, entry.Parent), "error deleting machine set %q", entry.ID)
+	}
+
+	return d.aws.undo(ctx, entry)
+}
+
+// undoChange returns the planned change undoing the given ledger entry, as undo would.
+func undoChange(entry *ledger.Entry) api.Change {
+	switch entry.Resource {
+	case api.SecurityGroupRuleResource:
+		return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.Parent, Details: "revoke ingress " + entry.ID}
+	case api.SubnetTagsResource:
+		return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID, Details: "untag public subnet"}
+	}
+
+	return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID}
+}
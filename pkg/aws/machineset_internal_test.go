@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Gateway MachineSet", func() {
+	var tags map[string]string
+
+	BeforeEach(func() {
+		tags = nil
+	})
+
+	build := func() *unstructured.Unstructured {
+		d := &ocpGatewayDeployer{
+			aws: &awsCloud{
+				infraID:      "test-infra",
+				region:       "us-east-1",
+				nodeSGSuffix: "-node",
+				cloudConfig:  map[string]interface{}{TagsKey: tags},
+			},
+			instanceType: "c5d.large",
+		}
+
+		machineSet, err := d.initMachineSet(context.TODO(), "test-infra-submariner-gw-sg", "ami-1234", &types.Subnet{
+			AvailabilityZone: aws.String("us-east-1a"),
+			Tags:             []types.Tag{{Key: aws.String("Name"), Value: aws.String("test-infra-public-us-east-1a")}},
+		})
+		Expect(err).To(Succeed())
+
+		return machineSet
+	}
+
+	It("should match the golden file", func() {
+		Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-default.yaml")))
+	})
+
+	When("custom tags are configured", func() {
+		BeforeEach(func() {
+			tags = map[string]string{"team": "a: b", "cost-center": "42", "Name": "x"}
+		})
+
+		It("should match the golden file", func() {
+			Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-tags.yaml")))
+		})
+	})
+})
+
+func loadGoldenMachineSet(path string) *unstructured.Unstructured {
+	data, err := os.ReadFile(path)
+	Expect(err).To(Succeed())
+
+	machineSet := &unstructured.Unstructured{}
+
+	_, _, err = yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(data, nil, machineSet)
+	Expect(err).To(Succeed())
+
+	return machineSet
+}
@@ -19,25 +19,26 @@ limitations under the License.
 package aws
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"text/template"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/ptr"
+	"k8s.io/utils/set"
 )
 
 type ocpGatewayDeployer struct {
 	aws          *awsCloud
-	msDeployer   ocp.MachineSetDeployer
+	msDeployer   ocp.ContextMachineSetDeployer
 	instanceType string
 }
 
@@ -53,16 +54,41 @@ func NewOcpGatewayDeployer(cloud api.Cloud, msDeployer ocp.MachineSetDeployer, i
 
 	return &ocpGatewayDeployer{
 		aws:          aws,
-		msDeployer:   msDeployer,
+		msDeployer:   ocp.WithContext(msDeployer),
 		instanceType: instanceType,
 	}, nil
 }
 
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporter.Interface) error {
+	return d.DeployWithContext(context.TODO(), input, status)
+}
+
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	err := d.deploy(ctx, input, status)
+	if err == nil {
+		err = ocp.DeployHealthChecks(ctx, d.msDeployer, input.HealthCheck, status)
+	}
+
+	if err == nil {
+		err = ocp.WaitForGateways(ctx, d.msDeployer, d.aws.k8sClient, input.WaitTimeout, status)
+	}
+
+	return classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	if err := api.ValidatePorts(input.PublicPorts); err != nil {
+		return errors.WithMessage(err, "invalid public ports")
+	}
+
+	if input.IPFamily == "" {
+		input.IPFamily = d.aws.ipFamily()
+	}
+
 	status.Start(messageRetrieveVPCID)
 	defer status.End()
 
-	vpcID, err := d.aws.getVpcID()
+	vpcID, err := d.aws.getVpcID(ctx)
 	if err != nil {
 		return status.Error(err, "unable to retrieve the VPC ID")
 	}
@@ -70,7 +96,7 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 	status.Success(messageRetrievedVPCID, vpcID)
 
 	if _, found := d.aws.cloudConfig[VPCIDKey]; !found {
-		err = d.aws.setSuffixes(vpcID)
+		err = d.aws.setSuffixes(ctx, vpcID)
 		if err != nil {
 			return status.Error(err, "unable to retrieve the security group names")
 		}
@@ -78,29 +104,19 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 
 	status.Start(messageValidatePrerequisites)
 
-	var publicSubnets []types.Subnet
-
-	if subnets, exists := d.aws.cloudConfig[PublicSubnetListKey]; exists {
-		if subnetIDs, ok := subnets.([]string); ok && len(subnetIDs) > 0 {
-			for _, id := range subnetIDs {
-				subnet, err := d.aws.getSubnetByID(id)
-				if err != nil {
-					return errors.Wrapf(err, "unable to find subnet with ID %s", id)
-				}
+	publicSubnets, found, err := d.aws.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return err
+	}
 
-				publicSubnets = append(publicSubnets, *subnet)
-			}
-		} else {
-			return errors.New("Subnet IDs must be a valid non-empty slice of strings")
-		}
-	} else {
-		publicSubnets, err = d.aws.findPublicSubnets(vpcID, d.aws.filterByName("{infraID}*-public-{region}*"))
+	if !found {
+		publicSubnets, err = d.aws.findPublicSubnets(ctx, vpcID, d.aws.filterByName("{infraID}*-public-{region}*"))
 		if err != nil {
 			return status.Error(err, "unable to find public subnets")
 		}
 	}
 
-	err = d.validateDeployPrerequisites(vpcID, input, publicSubnets)
+	err = d.validateDeployPrerequisites(ctx, vpcID, input, publicSubnets)
 	if err != nil {
 		return status.Error(err, "unable to validate prerequisites")
 	}
@@ -109,20 +125,20 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 
 	status.Start("Creating Submariner gateway security group")
 
-	gatewaySG, err := d.aws.createGatewaySG(vpcID, input.PublicPorts)
+	gatewaySG, err := d.aws.createGatewaySG(ctx, vpcID, input.PublicPorts, input.PublicSourceCIDRs())
 	if err != nil {
 		return status.Error(err, "unable to create gateway")
 	}
 
 	status.Success("Created Submariner gateway security group %s", gatewaySG)
 
-	return d.processSubnets(vpcID, gatewaySG, publicSubnets, input, status)
+	return d.processSubnets(ctx, vpcID, gatewaySG, publicSubnets, input, status)
 }
 
-func (d *ocpGatewayDeployer) processSubnets(vpcID, gatewaySG string, publicSubnets []types.Subnet,
+func (d *ocpGatewayDeployer) processSubnets(ctx context.Context, vpcID, gatewaySG string, publicSubnets []types.Subnet,
 	input api.GatewayDeployInput, status reporter.Interface,
 ) error {
-	subnets, err := d.aws.getSubnetsSupportingInstanceType(publicSubnets, d.instanceType)
+	subnets, err := d.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, d.instanceType)
 	if err != nil {
 		return status.Error(err, "unable to get subnets supporting instance type")
 	}
@@ -134,18 +150,22 @@ func (d *ocpGatewayDeployer) processSubnets(vpcID, gatewaySG string, publicSubne
 		return !subnetTagged(subnet), nil
 	})
 
-	for i := range untaggedSubnets {
-		subnet := &untaggedSubnets[i]
+	untaggedSubnets = subnetsToTag(taggedSubnets, untaggedSubnets, input.Gateways)
 
-		if input.Gateways > 0 && len(taggedSubnets) == input.Gateways {
-			break
+	if input.Gateways > 0 && len(taggedSubnets) > input.Gateways {
+		taggedSubnets, err = d.removeSurplusGateways(ctx, taggedSubnets, len(taggedSubnets)-input.Gateways, status)
+		if err != nil {
+			return err
 		}
+	}
 
+	for i := range untaggedSubnets {
+		subnet := &untaggedSubnets[i]
 		subnetName := extractName(subnet.Tags)
 
 		status.Start("Adjusting public subnet %s to support Submariner", subnetName)
 
-		err = d.aws.tagPublicSubnet(subnet.SubnetId)
+		err = d.aws.tagPublicSubnet(ctx, subnet.SubnetId)
 		if err != nil {
 			return status.Error(err, "unable to tag public subnet")
 		}
@@ -161,7 +181,7 @@ func (d *ocpGatewayDeployer) processSubnets(vpcID, gatewaySG string, publicSubne
 
 		status.Start("Deploying gateway node for public subnet %s", subnetName)
 
-		err = d.deployGateway(vpcID, gatewaySG, subnet)
+		err = d.deployGateway(ctx, vpcID, gatewaySG, subnet)
 		if err != nil {
 			return status.Error(err, "unable to deploy gateway")
 		}
@@ -172,41 +192,193 @@ func (d *ocpGatewayDeployer) processSubnets(vpcID, gatewaySG string, publicSubne
 	return nil
 }
 
-func (d *ocpGatewayDeployer) validateDeployPrerequisites(vpcID string, input api.GatewayDeployInput,
+// surplusGateway is a gateway to remove when scaling down: the public subnet it's in, along with its dedicated gateway
+// machine set, which is nil if the subnet is tagged but has no gateway machine set.
+type surplusGateway struct {
+	machineSet *unstructured.Unstructured
+	subnet     *types.Subnet
+}
+
+// removeSurplusGateways removes up to count of the gateways of the given tagged subnets, as selected by
+// selectSurplusGateways, deleting their machine sets and untagging their subnets. It returns the subnets which are kept.
+func (d *ocpGatewayDeployer) removeSurplusGateways(ctx context.Context, taggedSubnets []types.Subnet, count int,
+	status reporter.Interface,
+) ([]types.Subnet, error) {
+	surplus, err := d.selectSurplusGateways(ctx, taggedSubnets, count)
+	activeGatewayUnknown := errors.Is(err, k8s.ErrActiveGatewayUnknown)
+
+	if err != nil && !activeGatewayUnknown {
+		return nil, status.Error(err, "error selecting the surplus gateways")
+	}
+
+	for i := range surplus {
+		err := d.removeSurplusGateway(ctx, &surplus[i], status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if activeGatewayUnknown {
+		status.Warning("Only %d of the %d surplus gateways were removed: %v", len(surplus), count, err)
+	} else if len(surplus) < count {
+		status.Warning("Only %d of the %d surplus gateways were removed as the active gateway is kept", len(surplus), count)
+	}
+
+	return withoutSurplusGateways(taggedSubnets, surplus), nil
+}
+
+func (d *ocpGatewayDeployer) removeSurplusGateway(ctx context.Context, surplus *surplusGateway, status reporter.Interface) error {
+	subnetName := extractName(surplus.subnet.Tags)
+	entries := []ledger.Entry{{
+		Provider:  ProviderName,
+		Operation: ledger.Deploy,
+		Resource:  api.SubnetTagsResource,
+		ID:        *surplus.subnet.SubnetId,
+	}}
+
+	if surplus.machineSet != nil {
+		status.Start("Deleting the surplus gateway node %q", surplus.machineSet.GetName())
+
+		err := d.msDeployer.DeleteWithContext(ctx, surplus.machineSet)
+		if err != nil {
+			return status.Error(err, "error deleting machine set %q", surplus.machineSet.GetName())
+		}
+
+		entries = append(entries, ledger.Entry{
+			Provider:  ProviderName,
+			Operation: ledger.Deploy,
+			Resource:  api.MachineSetResource,
+			ID:        surplus.machineSet.GetName(),
+			Parent:    surplus.machineSet.GetNamespace(),
+		})
+	} else {
+		status.Start("Untagging the public subnet %s which has no gateway node", subnetName)
+	}
+
+	err := d.aws.untagPublicSubnet(ctx, surplus.subnet.SubnetId)
+	if err != nil {
+		return status.Error(err, "unable to untag subnet")
+	}
+
+	err = d.aws.ledger.Forget(ctx, entries...)
+	if err != nil {
+		return status.Error(err, "error updating the ledger")
+	}
+
+	if surplus.machineSet != nil {
+		status.Success("Deleted the surplus gateway node %q", surplus.machineSet.GetName())
+	} else {
+		status.Success("Untagged the public subnet %s", subnetName)
+	}
+
+	return nil
+}
+
+// withoutSurplusGateways returns the given tagged subnets except those of the given surplus gateways.
+func withoutSurplusGateways(taggedSubnets []types.Subnet, surplus []surplusGateway) []types.Subnet {
+	removed := set.New[string]()
+	for i := range surplus {
+		removed.Insert(*surplus[i].subnet.SubnetId)
+	}
+
+	kept, _ := filterSubnets(taggedSubnets, func(subnet *types.Subnet) (bool, error) {
+		return !removed.Has(*subnet.SubnetId), nil
+	})
+
+	return kept
+}
+
+// selectSurplusGateways returns up to count of the gateways of the given tagged subnets to remove. The tagged subnets
+// without a gateway machine set come first, then the gateways selected by ocp.SelectSurplusMachineSets. If more gateways
+// must be selected but the active gateway can't be determined, in particular if no Kubernetes client was provided, it
+// returns the tagged subnets without a gateway machine set along with an error wrapping k8s.ErrActiveGatewayUnknown.
+func (d *ocpGatewayDeployer) selectSurplusGateways(ctx context.Context, taggedSubnets []types.Subnet, count int,
+) ([]surplusGateway, error) {
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway machine sets")
+	}
+
+	existing := set.New[string]()
+	for i := range machineSets {
+		existing.Insert(machineSets[i].GetName())
+	}
+
+	// Only the machine sets of the tagged subnets are considered, the gateway machine sets being named after their zone.
+	subnetsByMachineSet := map[string]*types.Subnet{}
+
+	var surplus []surplusGateway
+
+	for i := range taggedSubnets {
+		name := d.aws.infraID + "-submariner-gw-" + ptr.Deref(taggedSubnets[i].AvailabilityZone, "")
+		subnetsByMachineSet[name] = &taggedSubnets[i]
+
+		if !existing.Has(name) && len(surplus) < count {
+			surplus = append(surplus, surplusGateway{subnet: &taggedSubnets[i]})
+		}
+	}
+
+	var candidates []unstructured.Unstructured
+
+	for i := range machineSets {
+		if _, found := subnetsByMachineSet[machineSets[i].GetName()]; found {
+			candidates = append(candidates, machineSets[i])
+		}
+	}
+
+	if len(surplus) == count || len(candidates) == 0 {
+		return surplus, nil
+	}
+
+	if d.aws.k8sClient == nil {
+		return surplus, errors.WithMessage(k8s.ErrActiveGatewayUnknown,
+			"a Kubernetes client is required to retrieve the active gateway")
+	}
+
+	gwNodes, err := d.aws.k8sClient.ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, candidates, gwNodes.Items)
+	if err != nil {
+		return nil, errors.Wrap(err, "error mapping the gateway nodes to their machine sets")
+	}
+
+	activeNode, err := d.aws.k8sClient.GetActiveGatewayNodeWithContext(ctx)
+	if err != nil {
+		return surplus, errors.Wrap(err, "error determining the active gateway")
+	}
+
+	selected := ocp.SelectSurplusMachineSets(candidates, mapping, activeNode, count-len(surplus))
+	for i := range selected {
+		surplus = append(surplus, surplusGateway{machineSet: &selected[i], subnet: subnetsByMachineSet[selected[i].GetName()]})
+	}
+
+	return surplus, nil
+}
+
+func (d *ocpGatewayDeployer) validateDeployPrerequisites(ctx context.Context, vpcID string, input api.GatewayDeployInput,
 	publicSubnets []types.Subnet,
 ) error {
 	var errs []error
-	var subnets []types.Subnet
 
-	errs = appendIfError(errs, d.aws.validateCreateSecGroup(vpcID))
-	errs = appendIfError(errs, d.aws.validateCreateSecGroupRule(vpcID))
-	err := d.aws.validateDescribeInstanceTypeOfferings()
+	errs = appendIfError(errs, d.aws.validateCreateSecGroup(ctx, vpcID))
+	errs = appendIfError(errs, d.aws.validateCreateSecGroupRule(ctx, vpcID))
+	err := d.aws.validateDescribeInstanceTypeOfferings(ctx)
 	errs = appendIfError(errs, err)
 
 	if err != nil {
 		return utilerrors.NewAggregate(errs)
 	}
 
-	// If instanceType is not specified, auto-select the most suitable one.
-	if d.instanceType == "" {
-		for _, instanceType := range PreferredInstances {
-			subnets, err = d.aws.getSubnetsSupportingInstanceType(publicSubnets, instanceType)
-			if err != nil {
-				return err
-			}
-
-			if len(subnets) != 0 {
-				d.instanceType = instanceType
-				break
-			}
-		}
-	} else {
-		subnets, err = d.aws.getSubnetsSupportingInstanceType(publicSubnets, d.instanceType)
-		if err != nil {
-			return err
-		}
+	instanceType, subnets, err := d.selectInstanceType(ctx, publicSubnets)
+	if err != nil {
+		return err
 	}
 
+	d.instanceType = instanceType
+
 	subnetsCount := len(subnets)
 	if input.Gateways > 0 && subnetsCount < input.Gateways {
 		errs = append(errs, fmt.Errorf("insufficient number of public subnets (%d) to deploy %v Submariner gateway(s)",
@@ -214,30 +386,41 @@ func (d *ocpGatewayDeployer) validateDeployPrerequisites(vpcID string, input api
 	}
 
 	if len(subnets) > 0 {
-		errs = appendIfError(errs, d.aws.validateCreateTag(*subnets[0].SubnetId))
+		errs = appendIfError(errs, d.aws.validateCreateTag(ctx, *subnets[0].SubnetId))
 	}
 
 	return utilerrors.NewAggregate(errs)
 }
 
-type machineSetConfig struct {
-	AZ            string
-	AMIId         string
-	InfraID       string
-	InstanceType  string
-	Region        string
-	SecurityGroup string
-	PublicSubnet  string
-	NodeSG        string
+// selectInstanceType returns the instance type to use for the gateways, along with the public subnets supporting it.
+// If no instance type is specified, the first of the PreferredInstances supported by a public subnet is selected.
+func (d *ocpGatewayDeployer) selectInstanceType(ctx context.Context, publicSubnets []types.Subnet) (string, []types.Subnet, error) {
+	if d.instanceType != "" {
+		subnets, err := d.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, d.instanceType)
+		return d.instanceType, subnets, err
+	}
+
+	for _, instanceType := range PreferredInstances {
+		subnets, err := d.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, instanceType)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if len(subnets) != 0 {
+			return instanceType, subnets, nil
+		}
+	}
+
+	return "", nil, nil
 }
 
-func (d *ocpGatewayDeployer) findAMIID(vpcID string) (string, error) {
+func (d *ocpGatewayDeployer) findAMIID(ctx context.Context, vpcID string) (string, error) {
 	ownedFilters := d.aws.filterByCurrentCluster()
 	var err error
 	var result *ec2.DescribeInstancesOutput
 
 	for i := range ownedFilters {
-		result, err = d.aws.client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{
+		result, err = d.aws.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
 			Filters: []types.Filter{
 				ec2Filter("vpc-id", vpcID),
 				d.aws.filterByName("{infraID}-worker*"),
@@ -268,88 +451,123 @@ func (d *ocpGatewayDeployer) findAMIID(vpcID string) (string, error) {
 	return *result.Reservations[0].Instances[0].ImageId, nil
 }
 
-func (d *ocpGatewayDeployer) loadGatewayYAML(gatewaySecurityGroup, amiID string, publicSubnet *types.Subnet) ([]byte, error) {
-	var buf bytes.Buffer
-
-	tpl, err := template.New("").Parse(machineSetYAML)
-	if err != nil {
-		return nil, errors.Wrap(err, "error parsing machine set YAML")
+func (d *ocpGatewayDeployer) nodeSecurityGroupName(ctx context.Context) (string, error) {
+	id, exists := d.aws.cloudConfig[WorkerSecurityGroupIDKey]
+	if !exists {
+		return d.aws.infraID + d.aws.nodeSGSuffix, nil
 	}
 
-	tplVars := machineSetConfig{
-		AZ:            *publicSubnet.AvailabilityZone,
-		AMIId:         amiID,
-		InfraID:       d.aws.infraID,
-		InstanceType:  d.instanceType,
-		Region:        d.aws.region,
-		SecurityGroup: gatewaySecurityGroup,
-		PublicSubnet:  extractName(publicSubnet.Tags),
+	workerGroupIDStr, ok := id.(string)
+	if !ok || workerGroupIDStr == "" {
+		return "", errors.New("worker Security Group ID must be a valid non-empty string")
 	}
 
-	if id, exists := d.aws.cloudConfig[WorkerSecurityGroupIDKey]; exists {
-		if workerGroupIDStr, ok := id.(string); ok && workerGroupIDStr != "" {
-			workerSecurityGroup, err := d.aws.getSecurityGroupByID(workerGroupIDStr)
-			if err != nil {
-				return nil, errors.Wrapf(err, "error finding the worker security group with ID %s", workerGroupIDStr)
-			}
-
-			if workerSecurityGroup.GroupName == nil {
-				return nil, errors.Errorf("security group with ID %s has no group name", workerGroupIDStr)
-			}
-
-			tplVars.NodeSG = *workerSecurityGroup.GroupName
-		} else {
-			return nil, errors.New("worker Security Group ID must be a valid non-empty string")
-		}
-	} else {
-		tplVars.NodeSG = d.aws.infraID + d.aws.nodeSGSuffix
+	workerSecurityGroup, err := d.aws.getSecurityGroupByID(ctx, workerGroupIDStr)
+	if err != nil {
+		return "", errors.Wrapf(err, "error finding the worker security group with ID %s", workerGroupIDStr)
 	}
 
-	err = tpl.Execute(&buf, tplVars)
-	if err != nil {
-		return nil, errors.Wrap(err, "error executing the template")
+	if workerSecurityGroup.GroupName == nil {
+		return "", errors.Errorf("security group with ID %s has no group name", workerGroupIDStr)
 	}
 
-	return buf.Bytes(), nil
+	return *workerSecurityGroup.GroupName, nil
 }
 
-func (d *ocpGatewayDeployer) initMachineSet(gwSecurityGroup, amiID string, publicSubnet *types.Subnet) (*unstructured.Unstructured, error) {
-	gatewayYAML, err := d.loadGatewayYAML(gwSecurityGroup, amiID, publicSubnet)
+func (d *ocpGatewayDeployer) initMachineSet(ctx context.Context, gwSecurityGroup, amiID string, publicSubnet *types.Subnet,
+) (*unstructured.Unstructured, error) {
+	nodeSG, err := d.nodeSecurityGroupName(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	unstructDecoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	az := *publicSubnet.AvailabilityZone
 
-	machineSet := &unstructured.Unstructured{}
+	providerSpec := ocp.NewAWSMachineProviderConfig()
+	providerSpec.AMI = ocp.AWSResourceReference{ID: amiID}
+	providerSpec.CredentialsSecret = &ocp.SecretReference{Name: "aws-cloud-credentials"}
+	providerSpec.IAMInstanceProfile = &ocp.AWSResourceReference{ID: d.aws.infraID + "-worker-profile"}
+	providerSpec.InstanceType = d.instanceType
+	providerSpec.Placement = ocp.AWSPlacement{AvailabilityZone: az, Region: d.aws.region}
+	providerSpec.SecurityGroups = []ocp.AWSResourceReference{{
+		Filters: []ocp.AWSFilter{{Name: "tag:Name", Values: []string{nodeSG, gwSecurityGroup}}},
+	}}
+	providerSpec.Subnet = ocp.AWSResourceReference{
+		Filters: []ocp.AWSFilter{{Name: "tag:Name", Values: []string{extractName(publicSubnet.Tags)}}},
+	}
+	providerSpec.Tags = []ocp.AWSTag{
+		{Name: "kubernetes.io/cluster/" + d.aws.infraID, Value: "owned"},
+		{Name: "submariner.io", Value: "gateway"},
+	}
 
-	_, _, err = unstructDecoder.Decode(gatewayYAML, nil, machineSet)
-	if err != nil {
-		return nil, errors.Wrap(err, "error converting YAML to machine set")
+	tags := d.aws.tags()
+	for _, name := range set.KeySet(tags).SortedList() {
+		providerSpec.Tags = append(providerSpec.Tags, ocp.AWSTag{Name: name, Value: tags[name]})
 	}
 
-	return machineSet, nil
+	providerSpec.UserDataSecret = &ocp.SecretReference{Name: "worker-user-data"}
+	providerSpec.PublicIP = true
+
+	return ocp.NewGatewayMachineSet(d.aws.infraID, d.aws.infraID+"-submariner-gw-"+az, providerSpec).ToUnstructured()
 }
 
-func (d *ocpGatewayDeployer) deployGateway(vpcID, gatewaySecurityGroup string, publicSubnet *types.Subnet) error {
-	amiID, err := d.findAMIID(vpcID)
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, vpcID, gatewaySecurityGroup string, publicSubnet *types.Subnet) error {
+	amiID, err := d.findAMIID(ctx, vpcID)
 	if err != nil {
 		return err
 	}
 
-	machineSet, err := d.initMachineSet(gatewaySecurityGroup, amiID, publicSubnet)
+	machineSet, err := d.initMachineSet(ctx, gatewaySecurityGroup, amiID, publicSubnet)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Deploy(machineSet), "error deploying machine set %q", machineSet.GetName())
+	err = d.msDeployer.DeployWithContext(ctx, machineSet)
+	if err != nil {
+		return errors.Wrapf(err, "error deploying machine set %q", machineSet.GetName())
+	}
+
+	return d.aws.ledger.Record(ctx, ledger.Entry{
+		Provider:  ProviderName,
+		Operation: ledger.Deploy,
+		Resource:  api.MachineSetResource,
+		ID:        machineSet.GetName(),
+		Parent:    machineSet.GetNamespace(),
+	})
 }
 
 func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
+	return d.CleanupWithContext(context.TODO(), status)
+}
+
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.cleanup(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) cleanup(ctx context.Context, status reporter.Interface) error {
+	recorded, err := d.aws.ledger.Recorded(ctx, ProviderName, ledger.Deploy)
+	if err != nil {
+		return status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		status.Start("Removing the recorded Submariner gateway resources")
+		defer status.End()
+
+		err = d.aws.ledger.Undo(ctx, ProviderName, ledger.Deploy, d.undo)
+		if err != nil {
+			return status.Error(err, "unable to remove the gateway resources")
+		}
+
+		status.Success("Removed the Submariner gateway resources")
+
+		return nil
+	}
+
 	status.Start(messageRetrieveVPCID)
 	defer status.End()
 
-	vpcID, err := d.aws.getVpcID()
+	vpcID, err := d.aws.getVpcID(ctx)
 	if err != nil {
 		return status.Error(err, "unable to retrieve the VPC ID")
 	}
@@ -357,7 +575,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 	status.Success(messageRetrievedVPCID, vpcID)
 
 	if _, found := d.aws.cloudConfig[VPCIDKey]; !found {
-		err = d.aws.setSuffixes(vpcID)
+		err = d.aws.setSuffixes(ctx, vpcID)
 		if err != nil {
 			return status.Error(err, "unable to retrieve the security group names")
 		}
@@ -365,30 +583,20 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 	status.Start(messageValidatePrerequisites)
 
-	err = d.validateCleanupPrerequisites(vpcID)
+	err = d.validateCleanupPrerequisites(ctx, vpcID)
 	if err != nil {
 		return status.Error(err, "unable to validate prerequisites")
 	}
 
 	status.Success(messageValidatedPrerequisites)
 
-	var publicSubnets []types.Subnet
-
-	if subnets, exists := d.aws.cloudConfig[PublicSubnetListKey]; exists {
-		if subnetIDs, ok := subnets.([]string); ok && len(subnetIDs) > 0 {
-			for _, id := range subnetIDs {
-				subnet, err := d.aws.getSubnetByID(id)
-				if err != nil {
-					return errors.Wrapf(err, "unable to find subnet with ID %s", id)
-				}
+	publicSubnets, found, err := d.aws.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return err
+	}
 
-				publicSubnets = append(publicSubnets, *subnet)
-			}
-		} else {
-			return errors.New("Subnet IDs must be a valid non-empty slice of strings")
-		}
-	} else {
-		publicSubnets, err = d.aws.getTaggedPublicSubnets(vpcID)
+	if !found {
+		publicSubnets, err = d.aws.getTaggedPublicSubnets(ctx, vpcID)
 		if err != nil {
 			return err
 		}
@@ -400,7 +608,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 		status.Start("Removing gateway node for public subnet %s", subnetName)
 
-		err = d.deleteGateway(subnet)
+		err = d.deleteGateway(ctx, subnet)
 		if err != nil {
 			return status.Error(err, "unable to remove gateway node")
 		}
@@ -409,7 +617,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 		status.Start("Untagging public subnet %s from supporting Submariner", subnetName)
 
-		err = d.aws.untagPublicSubnet(subnet.SubnetId)
+		err = d.aws.untagPublicSubnet(ctx, subnet.SubnetId)
 		if err != nil {
 			return status.Error(err, "unable to untag subnet")
 		}
@@ -419,7 +627,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 	status.Start("Deleting Submariner gateway security group")
 
-	err = d.aws.deleteGatewaySG(vpcID)
+	err = d.aws.deleteGatewaySG(ctx, vpcID)
 	if err != nil {
 		return status.Error(err, "unable to delete gateway")
 	}
@@ -429,28 +637,28 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 	return nil
 }
 
-func (d *ocpGatewayDeployer) validateCleanupPrerequisites(vpcID string) error {
+func (d *ocpGatewayDeployer) validateCleanupPrerequisites(ctx context.Context, vpcID string) error {
 	var errs []error
 
-	errs = appendIfError(errs, d.aws.validateDeleteSecGroup(vpcID))
+	errs = appendIfError(errs, d.aws.validateDeleteSecGroup(ctx, vpcID))
 
-	subnets, err := d.aws.getTaggedPublicSubnets(vpcID)
+	subnets, err := d.aws.getTaggedPublicSubnets(ctx, vpcID)
 	if err != nil {
 		return err
 	}
 
 	if len(subnets) > 0 {
-		errs = appendIfError(errs, d.aws.validateRemoveTag(subnets[0].SubnetId))
+		errs = appendIfError(errs, d.aws.validateRemoveTag(ctx, subnets[0].SubnetId))
 	}
 
 	return utilerrors.NewAggregate(errs)
 }
 
-func (d *ocpGatewayDeployer) deleteGateway(publicSubnet *types.Subnet) error {
-	machineSet, err := d.initMachineSet("", "", publicSubnet)
+func (d *ocpGatewayDeployer) deleteGateway(ctx context.Context, publicSubnet *types.Subnet) error {
+	machineSet, err := d.initMachineSet(ctx, "", "", publicSubnet)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Delete(machineSet), "error deleting machine set %q", machineSet.GetName())
+	return errors.Wrapf(d.msDeployer.DeleteWithContext(ctx, machineSet), "error deleting machine set %q", machineSet.GetName())
 }
@@ -19,8 +19,10 @@ limitations under the License.
 package aws_test
 
 import (
+	"context"
 	"errors"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -28,8 +30,16 @@ import (
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicFake "k8s.io/client-go/dynamic/fake"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/utils/ptr"
 	"k8s.io/utils/set"
 )
@@ -37,6 +47,9 @@ import (
 var _ = Describe("OCP GatewayDeployer", func() {
 	Context("on Deploy", testDeploy)
 	Context("on Cleanup", testCleanup)
+	Context("on PlanDeploy", testPlanDeploy)
+	Context("on PlanCleanup", testPlanCleanup)
+	Context("on Status", testStatus)
 })
 
 func testDeploy() {
@@ -45,7 +58,9 @@ func testDeploy() {
 	var deployCall *mock.Call
 
 	JustBeforeEach(func() {
-		deployCall = t.msDeployer.EXPECT().Deploy(mock.Anything).RunAndReturn(machineSetFn(&t.machineSets)).Call
+		deployCall = t.msDeployer.EXPECT().DeployWithContext(mock.Anything, mock.Anything).RunAndReturn(machineSetFn(&t.machineSets)).Call
+		t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return(nil, nil).Maybe()
+		t.msDeployer.EXPECT().DeployHealthCheckWithContext(mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 		t.expectDescribePublicSubnets(t.subnets...)
 
@@ -67,7 +82,7 @@ func testDeploy() {
 		})
 
 		JustBeforeEach(func() {
-			deployCall.Times(t.numGateways)
+			deployCall.Times(len(t.expectedSubnetsDeployed))
 
 			for i := range t.expectedSubnetsTagged {
 				t.expectCreateGatewayTags(*t.expectedSubnetsTagged[i].SubnetId)
@@ -78,6 +93,10 @@ func testDeploy() {
 
 		t.testDeploySuccess("", "")
 
+		It("should only make calls allowed by the Deploy policy", func() {
+			t.assertCallsAllowedBy(api.DeployMode)
+		})
+
 		Context("and the gateway security group doesn't initially exist", func() {
 			BeforeEach(func() {
 				t.gatewayGroupID = ""
@@ -87,6 +106,29 @@ func testDeploy() {
 			t.testDeploySuccess("should create it and", "")
 		})
 
+		Context("and custom tags are configured", func() {
+			BeforeEach(func() {
+				t.tags = map[string]string{"owner": "team-a", "cost-center": "1234", "Name": "ignored"}
+				t.gatewayGroupID = ""
+				t.expectCreateSecurityGroup(gatewaySGName, gatewayGroupID,
+					types.Tag{Key: ptr.To("cost-center"), Value: ptr.To("1234")},
+					types.Tag{Key: ptr.To("owner"), Value: ptr.To("team-a")})
+			})
+
+			It("should apply them to the gateway security group and machine set", func() {
+				Expect(t.retError).To(Succeed())
+
+				ms := t.machineSets[*t.expectedSubnetsDeployed[0].AvailabilityZone]
+				Expect(ms).ToNot(BeNil())
+
+				tags, _, _ := unstructured.NestedSlice(ms.Object, "spec", "template", "spec", "providerSpec", "value", "tags")
+				Expect(tags).To(ContainElements(
+					map[string]interface{}{"name": "submariner.io", "value": "gateway"},
+					map[string]interface{}{"name": "cost-center", "value": "1234"},
+					map[string]interface{}{"name": "owner", "value": "team-a"}))
+			})
+		})
+
 		Context("and the first subnet doesn't have an instance type offering", func() {
 			BeforeEach(func() {
 				t.zonesWithInstanceTypeOfferings = set.New(availabilityZone2)
@@ -109,6 +151,98 @@ func testDeploy() {
 			t.testDeploySuccess("", " without retagging it")
 		})
 
+		Context("and more public subnets are tagged than the requested gateways", func() {
+			BeforeEach(func() {
+				t.expectedSubnetsTagged = nil
+
+				for i := range t.subnets {
+					t.subnets[i].Tags = append(t.subnets[i].Tags, types.Tag{
+						Key:   ptr.To("submariner.io/gateway"),
+						Value: ptr.To(""),
+					})
+				}
+			})
+
+			Context("and the active gateway is known", func() {
+				var deleted map[string]*unstructured.Unstructured
+
+				BeforeEach(func() {
+					node1 := infraID + "-submariner-gw-" + availabilityZone1 + "-abcde"
+					node2 := infraID + "-submariner-gw-" + availabilityZone2 + "-fghij"
+
+					t.k8sClient = newK8sClient([]string{node1, node2}, map[string]string{node1: "passive", node2: "active"})
+					t.expectedSubnetsDeployed = []types.Subnet{t.subnets[1]}
+
+					var ms1, ms2 unstructured.Unstructured
+
+					ms1.SetName(infraID + "-submariner-gw-" + availabilityZone1)
+					ms2.SetName(infraID + "-submariner-gw-" + availabilityZone2)
+
+					t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms1, ms2}, nil)
+					t.msDeployer.EXPECT().ListMachinesWithContext(mock.Anything, mock.Anything).RunAndReturn(
+						func(_ context.Context, ms *unstructured.Unstructured) ([]ocp.Machine, error) {
+							nodeName := map[string]string{ms1.GetName(): node1, ms2.GetName(): node2}[ms.GetName()]
+							return []ocp.Machine{{Name: ms.GetName() + "-machine", Phase: "Running", NodeName: nodeName}}, nil
+						})
+					t.msDeployer.EXPECT().DeleteWithContext(mock.Anything, mock.Anything).RunAndReturn(
+						func(_ context.Context, ms *unstructured.Unstructured) error {
+							deleted[ms.GetName()] = ms
+							return nil
+						})
+					t.expectDeleteGatewayTags(subnetID1)
+
+					deleted = map[string]*unstructured.Unstructured{}
+				})
+
+				It("should remove the passive gateway and untag its subnet", func() {
+					Expect(t.retError).To(Succeed())
+					Expect(deleted).To(HaveLen(1))
+					Expect(deleted).To(HaveKey(infraID + "-submariner-gw-" + availabilityZone1))
+				})
+
+				It("should only make calls allowed by the Deploy policy", func() {
+					t.assertCallsAllowedBy(api.DeployMode)
+				})
+
+				t.testDeploySuccess("should keep the active gateway and", "")
+			})
+
+			Context("and no Kubernetes client is provided", func() {
+				BeforeEach(func() {
+					t.expectedSubnetsDeployed = t.subnets
+
+					var ms1, ms2 unstructured.Unstructured
+
+					ms1.SetName(infraID + "-submariner-gw-" + availabilityZone1)
+					ms2.SetName(infraID + "-submariner-gw-" + availabilityZone2)
+
+					t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms1, ms2}, nil)
+				})
+
+				t.testDeploySuccess("should keep the surplus gateways and", "")
+			})
+
+			Context("and a tagged subnet has no gateway machine set", func() {
+				BeforeEach(func() {
+					t.expectedSubnetsDeployed = []types.Subnet{t.subnets[1]}
+
+					var ms unstructured.Unstructured
+
+					ms.SetName(infraID + "-submariner-gw-" + availabilityZone2)
+
+					t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms}, nil)
+					t.expectDeleteGatewayTags(subnetID1)
+				})
+
+				It("should untag its subnet without deleting any gateway", func() {
+					Expect(t.retError).To(Succeed())
+					t.msDeployer.AssertNotCalled(GinkgoT(), "DeleteWithContext", mock.Anything, mock.Anything)
+				})
+
+				t.testDeploySuccess("should keep the other gateway and", "")
+			})
+		})
+
 		Context("and a desired instance type is not provided", func() {
 			BeforeEach(func() {
 				t.instanceType = ""
@@ -118,6 +252,70 @@ func testDeploy() {
 		})
 	})
 
+	When("source CIDRs are specified", func() {
+		BeforeEach(func() {
+			t.expectDeployValidations(true)
+
+			t.sourceCIDRs = []string{"10.1.0.0/16", "192.168.1.1/32"}
+
+			for _, cidr := range t.sourceCIDRs {
+				t.expectAuthorizeSecurityGroupIngress(gatewayGroupID, newPublicSGRuleFrom(100, "TCP", cidr))
+				t.expectAuthorizeSecurityGroupIngress(gatewayGroupID, newPublicSGRuleFrom(200, "UDP", cidr))
+			}
+		})
+
+		JustBeforeEach(func() {
+			deployCall.Times(t.numGateways)
+			t.expectCreateGatewayTags(*t.expectedSubnetsTagged[0].SubnetId)
+			t.doDeploy()
+		})
+
+		It("should only authorize the public ports from them", func() {
+			Expect(t.retError).To(Succeed())
+		})
+
+		Context("and the gateway security group already authorizes the public ports from any address", func() {
+			BeforeEach(func() {
+				t.gatewayPermissions = []types.IpPermission{
+					*newPublicSGRuleFrom(100, "TCP", t.sourceCIDRs[0]), *newPublicSGRule(100, "TCP"), *newPublicSGRule(200, "UDP"),
+				}
+
+				t.expectRevokeSecurityGroupIngress(gatewayGroupID, *newPublicSGRule(100, "TCP"), *newPublicSGRule(200, "UDP"))
+			})
+
+			It("should revoke the access from any address", func() {
+				Expect(t.retError).To(Succeed())
+			})
+
+			It("should only make calls allowed by the Deploy policy", func() {
+				t.assertCallsAllowedBy(api.DeployMode)
+			})
+		})
+	})
+
+	When("dual-stack is requested", func() {
+		BeforeEach(func() {
+			t.expectDeployValidations(true)
+
+			t.ipFamily = api.DualStack
+
+			for _, cidr := range []string{api.AnyIPv4CIDR, api.AnyIPv6CIDR} {
+				t.expectAuthorizeSecurityGroupIngress(gatewayGroupID, newPublicSGRuleFrom(100, "TCP", cidr))
+				t.expectAuthorizeSecurityGroupIngress(gatewayGroupID, newPublicSGRuleFrom(200, "UDP", cidr))
+			}
+		})
+
+		JustBeforeEach(func() {
+			deployCall.Times(t.numGateways)
+			t.expectCreateGatewayTags(*t.expectedSubnetsTagged[0].SubnetId)
+			t.doDeploy()
+		})
+
+		It("should authorize the public ports from any IPv4 and IPv6 address", func() {
+			Expect(t.retError).To(Succeed())
+		})
+	})
+
 	Context("", func() {
 		JustBeforeEach(func() {
 			deployCall.Maybe()
@@ -195,7 +393,7 @@ func testCleanup() {
 	When("on success", func() {
 		BeforeEach(func() {
 			t.expectCleanupValidations(true)
-			t.msDeployer.EXPECT().Delete(mock.Anything).RunAndReturn(machineSetFn(&t.machineSets)).Times(len(t.subnets))
+			t.msDeployer.EXPECT().DeleteWithContext(mock.Anything, mock.Anything).RunAndReturn(machineSetFn(&t.machineSets)).Times(len(t.subnets))
 			t.expectDeleteSecurityGroup(gatewayGroupID)
 
 			for i := range t.subnets {
@@ -214,6 +412,10 @@ func testCleanup() {
 
 			Expect(t.machineSets).To(HaveLen(0), "Unexpected machine sets deleted: %#v", t.machineSets)
 		})
+
+		It("should only make calls allowed by the Cleanup policy", func() {
+			t.assertCallsAllowedBy(api.CleanupMode)
+		})
 	})
 
 	Context("", func() {
@@ -243,19 +445,234 @@ func testCleanup() {
 	})
 }
 
+func testPlanDeploy() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		plan        *api.Plan
+		machineSets []unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		machineSets = nil
+	})
+
+	JustBeforeEach(func() {
+		t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return(machineSets, nil).Maybe()
+
+		for i := range t.subnets {
+			t.expectDescribeInstanceTypeOfferings(t.expectedInstanceType(), *t.subnets[i].AvailabilityZone, types.InstanceTypeOffering{})
+		}
+
+		plan, t.retError = t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{
+			Gateways: t.numGateways,
+			PublicPorts: []api.PortSpec{
+				{
+					Port:     100,
+					Protocol: "TCP",
+				},
+			},
+		}, reporter.Stdout())
+	})
+
+	When("no resources exist", func() {
+		BeforeEach(func() {
+			t.gatewayGroupID = ""
+		})
+
+		It("should plan to create them all", func() {
+			Expect(t.retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(
+				HaveField("Resource", api.SecurityGroupResource),
+				HaveField("Resource", api.SecurityGroupRuleResource),
+				HaveField("Resource", api.SubnetTagsResource),
+				HaveField("Resource", api.MachineSetResource),
+			))
+
+			Expect(plan.Changes[2].Name).To(Equal(subnetID1))
+			Expect(plan.Changes[3].Action).To(Equal(api.ChangeCreate))
+			Expect(plan.Changes[3].Name).To(Equal(infraID + "-submariner-gw-" + availabilityZone1))
+		})
+	})
+
+	When("the gateway machine set already exists", func() {
+		BeforeEach(func() {
+			ms := unstructured.Unstructured{}
+			ms.SetName(infraID + "-submariner-gw-" + availabilityZone1)
+			machineSets = []unstructured.Unstructured{ms}
+		})
+
+		It("should plan to update it", func() {
+			Expect(t.retError).To(Succeed())
+			Expect(plan.Changes).To(ContainElement(And(HaveField("Resource", api.MachineSetResource),
+				HaveField("Action", api.ChangeUpdate))))
+			Expect(plan.Changes).ToNot(ContainElement(HaveField("Resource", api.SecurityGroupResource)))
+		})
+	})
+
+	When("there's an insufficient number of public subnets", func() {
+		BeforeEach(func() {
+			t.subnets = nil
+		})
+
+		It("should return an error", func() {
+			Expect(t.retError).To(HaveOccurred())
+		})
+	})
+}
+
+func testPlanCleanup() {
+	t := newGatewayDeployerTestDriver()
+
+	var plan *api.Plan
+
+	JustBeforeEach(func() {
+		t.expectDescribeGatewaySubnets(t.subnets...)
+
+		plan, t.retError = t.gwDeployer.PlanCleanup(context.TODO(), reporter.Stdout())
+	})
+
+	When("the gateway resources exist", func() {
+		BeforeEach(func() {
+			ms := unstructured.Unstructured{}
+			ms.SetName(infraID + "-submariner-gw-" + availabilityZone1)
+			t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms}, nil)
+
+			t.subnets[0].Tags = append(t.subnets[0].Tags, types.Tag{
+				Key:   ptr.To("submariner.io/gateway"),
+				Value: ptr.To(""),
+			})
+		})
+
+		It("should plan to delete them", func() {
+			Expect(t.retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(
+				And(HaveField("Resource", api.MachineSetResource), HaveField("Name", infraID+"-submariner-gw-"+availabilityZone1)),
+				And(HaveField("Resource", api.SubnetTagsResource), HaveField("Name", subnetID1)),
+				And(HaveField("Resource", api.SecurityGroupResource), HaveField("Name", gatewaySGName)),
+			))
+		})
+	})
+
+	When("listing the machine sets fails", func() {
+		BeforeEach(func() {
+			t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return(nil, errors.New("mock error"))
+		})
+
+		It("should return an error", func() {
+			Expect(t.retError).To(HaveOccurred())
+		})
+	})
+}
+
+func testStatus() {
+	t := newGatewayDeployerTestDriver()
+
+	const machineSetName = infraID + "-submariner-gw-" + availabilityZone1
+
+	var (
+		gateways  []api.GatewayStatus
+		instances []types.Instance
+	)
+
+	BeforeEach(func() {
+		instances = nil
+
+		ms := unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"providerSpec": map[string]interface{}{
+							"value": map[string]interface{}{
+								"instanceType": "test-instance-type",
+								"placement": map[string]interface{}{
+									"availabilityZone": availabilityZone1,
+								},
+								"subnet": map[string]interface{}{
+									"filters": []interface{}{
+										map[string]interface{}{
+											"name":   "tag:Name",
+											"values": []interface{}{subnetName(subnetID1)},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}}
+		ms.SetName(machineSetName)
+
+		t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms}, nil)
+	})
+
+	JustBeforeEach(func() {
+		t.awsClient.EXPECT().DescribeInstances(mock.Anything, mock.MatchedBy(((&filtersMatcher{expectedFilters: []types.Filter{{
+			Name:   ptr.To("vpc-id"),
+			Values: []string{vpcID},
+		}, {
+			Name:   ptr.To("tag:Name"),
+			Values: []string{machineSetName + "-*"},
+		}, {
+			Name:   ptr.To("instance-state-name"),
+			Values: []string{"pending", "running"},
+		}}}).Matches))).Return(&ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: instances}}}, nil)
+
+		gateways, t.retError = t.gwDeployer.Status(context.TODO(), reporter.Stdout())
+	})
+
+	When("the machine set has provisioned its instance", func() {
+		BeforeEach(func() {
+			instances = []types.Instance{{
+				PrivateDnsName:  ptr.To("ip-10-0-0-1.ec2.internal"),
+				PublicIpAddress: ptr.To("1.2.3.4"),
+				InstanceType:    "test-instance-type",
+				Placement:       &types.Placement{AvailabilityZone: ptr.To(availabilityZone1)},
+			}}
+		})
+
+		It("should return the gateway details", func() {
+			Expect(t.retError).To(Succeed())
+			Expect(gateways).To(Equal([]api.GatewayStatus{{
+				Type:          api.DedicatedGateway,
+				MachineSet:    machineSetName,
+				Zone:          availabilityZone1,
+				Subnet:        subnetName(subnetID1),
+				InstanceType:  "test-instance-type",
+				NodeName:      "ip-10-0-0-1.ec2.internal",
+				PublicIP:      "1.2.3.4",
+				SecurityGroup: gatewaySGName,
+			}}))
+		})
+	})
+
+	When("the machine set hasn't provisioned its instance yet", func() {
+		It("should return the gateway without node details", func() {
+			Expect(t.retError).To(Succeed())
+			Expect(gateways).To(HaveExactElements(And(HaveField("MachineSet", machineSetName), HaveField("NodeName", ""))))
+		})
+	})
+}
+
 type gatewayDeployerTestDriver struct {
 	fakeAWSClientBase
 	numGateways                    int
 	instanceType                   string
+	sourceCIDRs                    []string
+	ipFamily                       api.IPFamily
 	subnets                        []types.Subnet
 	expectedSubnetsDeployed        []types.Subnet
 	expectedSubnetsTagged          []types.Subnet
 	gatewayGroupID                 string
+	gatewayPermissions             []types.IpPermission
+	tags                           map[string]string
 	zonesWithInstanceTypeOfferings set.Set[string]
 	machineSets                    map[string]*unstructured.Unstructured
 	retError                       error
 	msDeployer                     *ocpFake.MockMachineSetDeployer
-	gwDeployer                     api.GatewayDeployer
+	gwDeployer                     api.ContextGatewayDeployer
+	k8sClient                      k8s.Interface
 }
 
 func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
@@ -267,10 +684,15 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 		t.msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
 		t.numGateways = 1
 		t.instanceType = "test-instance-type"
+		t.sourceCIDRs = nil
+		t.ipFamily = ""
 		t.subnets = []types.Subnet{newSubnet(availabilityZone1, subnetID1), newSubnet(availabilityZone2, subnetID2)}
 		t.expectedSubnetsDeployed = []types.Subnet{t.subnets[0]}
 		t.expectedSubnetsTagged = []types.Subnet{t.subnets[0]}
 		t.gatewayGroupID = gatewayGroupID
+		t.gatewayPermissions = nil
+		t.tags = nil
+		t.k8sClient = nil
 		t.zonesWithInstanceTypeOfferings = set.New[string]()
 
 		for i := range t.subnets {
@@ -280,16 +702,18 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 
 	JustBeforeEach(func() {
 		t.expectDescribeVpcs(t.vpcID)
-		t.expectDescribeSecurityGroups(gatewaySGName, t.gatewayGroupID)
+		t.expectDescribeSecurityGroups(gatewaySGName, t.gatewayGroupID, t.gatewayPermissions...)
 		t.expectDescribeInstances(instanceImageID)
 		t.expectDescribeSecurityGroups(workerSGName, workerGroupID)
 		t.expectDescribePublicSubnets(t.subnets...)
 		t.expectDescribePublicSubnetsSigs(t.subnets...)
 
-		var err error
-
-		t.gwDeployer, err = aws.NewOcpGatewayDeployer(aws.NewCloud(t.awsClient, infraID, region), t.msDeployer, t.instanceType)
+		gwDeployer, err := aws.NewOcpGatewayDeployer(aws.NewCloud(t.awsClient, infraID, region, aws.WithTags(t.tags),
+			aws.WithK8sClient(t.k8sClient)), t.msDeployer,
+			t.instanceType)
 		Expect(err).To(Succeed())
+
+		t.gwDeployer = gwDeployer.(api.ContextGatewayDeployer)
 	})
 
 	return t
@@ -305,7 +729,9 @@ func (t *gatewayDeployerTestDriver) expectedInstanceType() string {
 
 func (t *gatewayDeployerTestDriver) doDeploy() {
 	t.retError = t.gwDeployer.Deploy(api.GatewayDeployInput{
-		Gateways: t.numGateways,
+		Gateways:    t.numGateways,
+		SourceCIDRs: t.sourceCIDRs,
+		IPFamily:    t.ipFamily,
 		PublicPorts: []api.PortSpec{
 			{
 				Port:     100,
@@ -372,11 +798,41 @@ func (t *gatewayDeployerTestDriver) testDeploySuccess(msgPrefix, msgSuffix strin
 	})
 }
 
+// newK8sClient returns a k8s.Interface with the given gateway nodes, whose Submariner gateways have the given HA status.
+func newK8sClient(nodeNames []string, haStatus map[string]string) k8s.Interface {
+	kubeClient := kubeFake.NewClientset()
+	dynamicClient := dynamicFake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.GatewayGVR: "GatewayList"})
+
+	for _, name := range nodeNames {
+		_, err := kubeClient.CoreV1().Nodes().Create(context.TODO(), &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"submariner.io/gateway": "true"},
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).To(Succeed())
+
+		gateway := &unstructured.Unstructured{}
+		gateway.SetAPIVersion("submariner.io/v1")
+		gateway.SetKind("Gateway")
+		gateway.SetName(name)
+		gateway.SetNamespace("submariner-operator")
+		Expect(unstructured.SetNestedField(gateway.Object, haStatus[name], "status", "haStatus")).To(Succeed())
+
+		_, err = dynamicClient.Resource(k8s.GatewayGVR).Namespace(gateway.GetNamespace()).Create(context.TODO(), gateway,
+			metav1.CreateOptions{})
+		Expect(err).To(Succeed())
+	}
+
+	return k8s.NewInterface(kubeClient, k8s.WithDynamicClient(dynamicClient))
+}
+
 //nolint:gocritic // Error: "consider `machineSets' to be of non-pointer type"
-func machineSetFn(machineSets *map[string]*unstructured.Unstructured) func(ms *unstructured.Unstructured) error {
+func machineSetFn(machineSets *map[string]*unstructured.Unstructured) func(context.Context, *unstructured.Unstructured) error {
 	*machineSets = map[string]*unstructured.Unstructured{}
 
-	return func(ms *unstructured.Unstructured) error {
+	return func(_ context.Context, ms *unstructured.Unstructured) error {
 		zone, ok, _ := unstructured.NestedString(ms.Object, "spec", "template", "spec", "providerSpec", "value",
 			"placement", "availabilityZone")
 		Expect(ok).To(BeTrue())
@@ -0,0 +1,264 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	awsClient "github.com/submariner-io/cloud-prepare/pkg/aws/client"
+	"k8s.io/utils/ptr"
+)
+
+var clusterTagPrefixes = []string{"kubernetes.io/cluster/", "sigs.k8s.io/cluster-api-provider-aws/cluster/"}
+
+type orphanScanner struct {
+	aws *awsCloud
+}
+
+// NewOrphanScanner returns an OrphanScanner finding the Submariner gateway security groups and the subnets tagged for the
+// Submariner gateways in the region which don't belong to a live cluster. Besides the given live clusters, a cluster is
+// considered live as long as it has instances which aren't terminated. The resources whose cluster can't be identified
+// are never reported.
+func NewOrphanScanner(client awsClient.Interface, region string) api.OrphanScanner {
+	return &orphanScanner{
+		aws: &awsCloud{
+			client:      client,
+			region:      region,
+			cloudConfig: make(map[string]interface{}),
+		},
+	}
+}
+
+func (s *orphanScanner) Scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	orphans, err := s.scan(ctx, liveInfraIDs, status)
+
+	return orphans, classifyError(err)
+}
+
+func (s *orphanScanner) scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	status.Start("Scanning for orphaned Submariner security groups in region %s", s.aws.region)
+	defer status.End()
+
+	live := map[string]bool{}
+	for _, infraID := range liveInfraIDs {
+		live[infraID] = true
+	}
+
+	orphans, err := s.scanSecurityGroups(ctx, live)
+	if err != nil {
+		return nil, status.Error(err, "unable to scan the security groups")
+	}
+
+	status.Success("Found %d orphaned Submariner security groups", len(orphans))
+
+	status.Start("Scanning for orphaned Submariner subnet tags in region %s", s.aws.region)
+
+	subnetOrphans, err := s.scanSubnets(ctx, live)
+	if err != nil {
+		return nil, status.Error(err, "unable to scan the subnets")
+	}
+
+	status.Success("Found %d subnets tagged for the gateways of orphaned clusters", len(subnetOrphans))
+
+	return append(orphans, subnetOrphans...), nil
+}
+
+func (s *orphanScanner) scanSecurityGroups(ctx context.Context, live map[string]bool) ([]api.Orphan, error) {
+	var orphans []api.Orphan
+
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{ec2Filter("group-name", "*"+gatewaySGSuffix)},
+	}
+
+	for {
+		output, err := s.aws.client.DescribeSecurityGroups(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "error describing AWS security groups")
+		}
+
+		for i := range output.SecurityGroups {
+			name := ptr.Deref(output.SecurityGroups[i].GroupName, "")
+
+			infraID, found := strings.CutSuffix(name, gatewaySGSuffix)
+			if !found || infraID == "" {
+				continue
+			}
+
+			isLive, err := s.clusterIsLive(ctx, infraID, live)
+			if err != nil {
+				return nil, err
+			}
+
+			if !isLive {
+				orphans = append(orphans, api.Orphan{
+					Resource: api.SecurityGroupResource,
+					Name:     name,
+					ID:       ptr.Deref(output.SecurityGroups[i].GroupId, ""),
+					InfraID:  infraID,
+				})
+			}
+		}
+
+		if output.NextToken == nil {
+			return orphans, nil
+		}
+
+		input.NextToken = output.NextToken
+	}
+}
+
+func (s *orphanScanner) scanSubnets(ctx context.Context, live map[string]bool) ([]api.Orphan, error) {
+	var orphans []api.Orphan
+
+	input := &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{ec2Filter("tag-key", *tagSubmarinerGateway.Key)},
+	}
+
+	for {
+		output, err := s.aws.client.DescribeSubnets(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "error describing AWS subnets")
+		}
+
+		for i := range output.Subnets {
+			// A subnet which isn't tagged for any cluster can't be attributed, so it's left alone.
+			infraIDs := clusterInfraIDs(output.Subnets[i].Tags)
+			if len(infraIDs) == 0 {
+				continue
+			}
+
+			isLive := false
+
+			for _, infraID := range infraIDs {
+				isLive, err = s.clusterIsLive(ctx, infraID, live)
+				if err != nil {
+					return nil, err
+				}
+
+				if isLive {
+					break
+				}
+			}
+
+			if isLive {
+				continue
+			}
+
+			orphans = append(orphans, api.Orphan{
+				Resource: api.SubnetTagsResource,
+				Name:     extractName(output.Subnets[i].Tags),
+				ID:       ptr.Deref(output.Subnets[i].SubnetId, ""),
+				InfraID:  infraIDs[0],
+			})
+		}
+
+		if output.NextToken == nil {
+			return orphans, nil
+		}
+
+		input.NextToken = output.NextToken
+	}
+}
+
+// clusterIsLive returns true if the cluster with the given infra ID is known to be live or has instances which aren't
+// terminated. The outcome is cached in the given map.
+func (s *orphanScanner) clusterIsLive(ctx context.Context, infraID string, live map[string]bool) (bool, error) {
+	if isLive, found := live[infraID]; found {
+		return isLive, nil
+	}
+
+	tagKeys := make([]string, 0, len(clusterTagPrefixes))
+	for _, prefix := range clusterTagPrefixes {
+		tagKeys = append(tagKeys, prefix+infraID)
+	}
+
+	output, err := s.aws.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{Name: ptr.To("tag-key"), Values: tagKeys},
+			{Name: ptr.To("instance-state-name"), Values: []string{
+				string(types.InstanceStateNamePending), string(types.InstanceStateNameRunning),
+				string(types.InstanceStateNameShuttingDown), string(types.InstanceStateNameStopping),
+				string(types.InstanceStateNameStopped),
+			}},
+		},
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "error describing the instances of cluster %q", infraID)
+	}
+
+	// Further pages may hold instances, so their presence is enough to consider the cluster live.
+	live[infraID] = output.NextToken != nil || slices.ContainsFunc(output.Reservations, func(reservation types.Reservation) bool {
+		return len(reservation.Instances) > 0
+	})
+
+	return live[infraID], nil
+}
+
+// clusterInfraIDs returns the infra IDs of the clusters which the given tags mark as using the resource.
+func clusterInfraIDs(tags []types.Tag) []string {
+	var infraIDs []string
+
+	for _, tag := range tags {
+		for _, prefix := range clusterTagPrefixes {
+			if infraID, found := strings.CutPrefix(ptr.Deref(tag.Key, ""), prefix); found && !slices.Contains(infraIDs, infraID) {
+				infraIDs = append(infraIDs, infraID)
+			}
+		}
+	}
+
+	return infraIDs
+}
+
+func (s *orphanScanner) Delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	return classifyError(s.delete(ctx, orphans, status))
+}
+
+func (s *orphanScanner) delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	defer status.End()
+
+	for i := range orphans {
+		status.Start("Deleting the orphaned %s", orphans[i])
+
+		var err error
+
+		switch orphans[i].Resource {
+		case api.SecurityGroupResource:
+			err = s.aws.deleteSecurityGroup(ctx, ptr.To(orphans[i].ID))
+		case api.SubnetTagsResource:
+			err = s.aws.untagPublicSubnet(ctx, ptr.To(orphans[i].ID))
+		default:
+			err = errors.Errorf("unsupported resource %q", orphans[i].Resource)
+		}
+
+		if err != nil {
+			return status.Error(err, "unable to delete the orphaned %s", orphans[i])
+		}
+
+		status.Success("Deleted the orphaned %s", orphans[i])
+	}
+
+	return nil
+}
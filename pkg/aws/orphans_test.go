@@ -0,0 +1,154 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"k8s.io/utils/ptr"
+)
+
+const orphanedInfraID = "orphaned-infra"
+
+var _ = Describe("OrphanScanner", func() {
+	t := &fakeAWSClientBase{}
+
+	var scanner api.OrphanScanner
+
+	BeforeEach(func() {
+		t.beforeEach()
+
+		scanner = aws.NewOrphanScanner(t.awsClient, region)
+	})
+
+	AfterEach(t.afterEach)
+
+	Context("on Scan", func() {
+		var describeSubnetsErr error
+
+		BeforeEach(func() {
+			describeSubnetsErr = nil
+
+			expectDescribeClusterInstances(t, infraID, true)
+			expectDescribeClusterInstances(t, orphanedInfraID, false)
+
+			t.awsClient.EXPECT().DescribeSecurityGroups(mock.Anything, mock.MatchedBy((&filtersMatcher{expectedFilters: []types.Filter{{
+				Name:   ptr.To("group-name"),
+				Values: []string{"*-submariner-gw-sg"},
+			}}}).Matches)).Return(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{
+				{GroupName: ptr.To(gatewaySGName), GroupId: ptr.To(gatewayGroupID)},
+				{GroupName: ptr.To(orphanedInfraID + "-submariner-gw-sg"), GroupId: ptr.To("orphaned-group")},
+			}}, nil)
+		})
+
+		JustBeforeEach(func() {
+			t.awsClient.EXPECT().DescribeSubnets(mock.Anything, mock.MatchedBy((&filtersMatcher{expectedFilters: []types.Filter{{
+				Name:   ptr.To("tag-key"),
+				Values: []string{"submariner.io/gateway"},
+			}}}).Matches)).Return(&ec2.DescribeSubnetsOutput{Subnets: []types.Subnet{
+				newSubnetForCluster(subnetID1, "kubernetes.io/cluster/"+infraID),
+				newSubnetForCluster(subnetID2, "sigs.k8s.io/cluster-api-provider-aws/cluster/"+orphanedInfraID),
+				newSubnet(availabilityZone2, "untagged-subnet"),
+			}}, describeSubnetsErr).Maybe()
+		})
+
+		It("should return the resources of the clusters which aren't live", func() {
+			orphans, err := scanner.Scan(context.TODO(), []string{infraID}, reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(orphans).To(Equal([]api.Orphan{
+				{
+					Resource: api.SecurityGroupResource,
+					Name:     orphanedInfraID + "-submariner-gw-sg",
+					ID:       "orphaned-group",
+					InfraID:  orphanedInfraID,
+				},
+				{
+					Resource: api.SubnetTagsResource,
+					Name:     subnetName(subnetID2),
+					ID:       subnetID2,
+					InfraID:  orphanedInfraID,
+				},
+			}))
+
+			t.assertCallsAllowedBy(api.ScanOrphansMode)
+		})
+
+		When("a cluster which still has instances isn't listed as live", func() {
+			It("should not return its resources", func() {
+				orphans, err := scanner.Scan(context.TODO(), nil, reporter.Stdout())
+				Expect(err).To(Succeed())
+				Expect(orphans).To(HaveLen(2))
+				Expect(orphans).To(HaveEach(HaveField("InfraID", orphanedInfraID)))
+			})
+		})
+
+		When("describing the subnets fails", func() {
+			BeforeEach(func() {
+				describeSubnetsErr = errors.New("mock error")
+			})
+
+			It("should return an error", func() {
+				_, err := scanner.Scan(context.TODO(), []string{infraID}, reporter.Stdout())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("on Delete", func() {
+		It("should delete the security groups and untag the subnets", func() {
+			t.expectDeleteSecurityGroup("orphaned-group")
+			t.expectDeleteGatewayTags(subnetID2)
+
+			Expect(scanner.Delete(context.TODO(), []api.Orphan{
+				{Resource: api.SecurityGroupResource, Name: orphanedInfraID + "-submariner-gw-sg", ID: "orphaned-group"},
+				{Resource: api.SubnetTagsResource, Name: subnetName(subnetID2), ID: subnetID2},
+			}, reporter.Stdout())).To(Succeed())
+
+			t.assertCallsAllowedBy(api.DeleteOrphansMode)
+		})
+	})
+})
+
+func expectDescribeClusterInstances(t *fakeAWSClientBase, clusterInfraID string, live bool) {
+	output := &ec2.DescribeInstancesOutput{}
+	if live {
+		output.Reservations = []types.Reservation{{Instances: []types.Instance{{InstanceId: ptr.To("instance-" + clusterInfraID)}}}}
+	}
+
+	t.awsClient.EXPECT().DescribeInstances(mock.Anything, mock.MatchedBy(func(in *ec2.DescribeInstancesInput) bool {
+		return len(in.Filters) == 2 && slices.Contains(in.Filters[0].Values, "kubernetes.io/cluster/"+clusterInfraID)
+	})).Return(output, nil).Maybe()
+}
+
+func newSubnetForCluster(subnetID, clusterTag string) types.Subnet {
+	subnet := newSubnet(availabilityZone1, subnetID)
+	subnet.Tags = append(subnet.Tags, types.Tag{Key: ptr.To(clusterTag), Value: ptr.To("owned")})
+
+	return subnet
+}
@@ -0,0 +1,418 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"k8s.io/utils/ptr"
+	"k8s.io/utils/set"
+)
+
+func (ac *awsCloud) PlanOpenPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	plan, err := ac.planOpenPorts(ctx, ports, status)
+
+	return plan, classifyError(err)
+}
+
+func (ac *awsCloud) planOpenPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := ac.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the cluster security groups")
+
+	workerGroup, controlPlaneGroup, err := ac.getClusterSecurityGroups(ctx, vpcID)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the cluster security groups")
+	}
+
+	status.Success("Retrieved the cluster security groups")
+
+	plan := &api.Plan{}
+
+	for _, port := range ports {
+		planClusterSGRule(plan, &workerGroup, &workerGroup, port)
+		planClusterSGRule(plan, &workerGroup, &controlPlaneGroup, port)
+		planClusterSGRule(plan, &controlPlaneGroup, &workerGroup, port)
+	}
+
+	return plan, nil
+}
+
+func (ac *awsCloud) PlanClosePorts(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := ac.planClosePorts(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (ac *awsCloud) planClosePorts(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	recorded, err := ac.ledger.PlanUndo(ctx, plan, ProviderName, ledger.OpenPorts, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		return plan, nil
+	}
+
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := ac.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the cluster security groups")
+
+	workerGroup, controlPlaneGroup, err := ac.getClusterSecurityGroups(ctx, vpcID)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the cluster security groups")
+	}
+
+	status.Success("Retrieved the cluster security groups")
+
+	for _, group := range []*types.SecurityGroup{&workerGroup, &controlPlaneGroup} {
+		permissions := internalPermissions(group)
+		for i := range permissions {
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, ptr.Deref(group.GroupId, ""),
+				"revoke ingress %s", formatIPPermission(&permissions[i]))
+		}
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planDeploy(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	if input.IPFamily == "" {
+		input.IPFamily = d.aws.ipFamily()
+	}
+
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := d.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the public subnets")
+
+	publicSubnets, found, err := d.aws.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the configured public subnets")
+	}
+
+	if !found {
+		publicSubnets, err = d.aws.findPublicSubnets(ctx, vpcID, d.aws.filterByName("{infraID}*-public-{region}*"))
+		if err != nil {
+			return nil, status.Error(err, "unable to find public subnets")
+		}
+	}
+
+	instanceType, subnets, err := d.selectInstanceType(ctx, publicSubnets)
+	if err != nil {
+		return nil, status.Error(err, "unable to get subnets supporting instance type")
+	}
+
+	if input.Gateways > 0 && len(subnets) < input.Gateways {
+		return nil, status.Error(fmt.Errorf("insufficient number of public subnets (%d) to deploy %v Submariner gateway(s)",
+			len(subnets), input.Gateways), "unable to validate prerequisites")
+	}
+
+	status.Success("Retrieved %d public subnet(s) supporting instance type %q", len(subnets), instanceType)
+
+	plan := &api.Plan{}
+
+	status.Start("Retrieving the Submariner gateway security group")
+
+	err = d.aws.planGatewaySG(ctx, plan, vpcID, input.PublicPorts, input.PublicSourceCIDRs())
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	status.Success("Retrieved the Submariner gateway security group")
+
+	taggedSubnets, _ := filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
+		return subnetTagged(subnet), nil
+	})
+	untaggedSubnets, _ := filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
+		return !subnetTagged(subnet), nil
+	})
+
+	untaggedSubnets = subnetsToTag(taggedSubnets, untaggedSubnets, input.Gateways)
+
+	if input.Gateways > 0 && len(taggedSubnets) > input.Gateways {
+		taggedSubnets, err = d.planRemoveSurplusGateways(ctx, plan, taggedSubnets, len(taggedSubnets)-input.Gateways, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range untaggedSubnets {
+		plan.Add(api.ChangeCreate, api.SubnetTagsResource, *untaggedSubnets[i].SubnetId, "tag public subnet %q with %q and %q",
+			extractName(untaggedSubnets[i].Tags), *tagInternalELB.Key, *tagSubmarinerGateway.Key)
+	}
+
+	existing, err := d.listMachineSetNames(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to list the gateway machine sets")
+	}
+
+	taggedSubnets = append(taggedSubnets, untaggedSubnets...)
+
+	for i := range taggedSubnets {
+		machineSet, err := d.initMachineSet(ctx, "", "", &taggedSubnets[i])
+		if err != nil {
+			return nil, status.Error(err, "unable to initialize the gateway machine set")
+		}
+
+		action := api.ChangeCreate
+		if existing.Has(machineSet.GetName()) {
+			action = api.ChangeUpdate
+		}
+
+		plan.Add(action, api.MachineSetResource, machineSet.GetName(), "gateway node of type %q in public subnet %q",
+			instanceType, extractName(taggedSubnets[i].Tags))
+	}
+
+	return plan, nil
+}
+
+// planRemoveSurplusGateways adds the removal of up to count of the gateways of the given tagged subnets to the plan, and
+// returns the subnets which are kept.
+func (d *ocpGatewayDeployer) planRemoveSurplusGateways(ctx context.Context, plan *api.Plan, taggedSubnets []types.Subnet, count int,
+	status reporter.Interface,
+) ([]types.Subnet, error) {
+	surplus, err := d.selectSurplusGateways(ctx, taggedSubnets, count)
+	if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+		status.Warning("Only %d of the %d surplus gateways will be removed: %v", len(surplus), count, err)
+	} else if err != nil {
+		return nil, status.Error(err, "error selecting the surplus gateways")
+	}
+
+	for i := range surplus {
+		if surplus[i].machineSet != nil {
+			plan.Add(api.ChangeDelete, api.MachineSetResource, surplus[i].machineSet.GetName(), "surplus passive gateway")
+		}
+
+		plan.Add(api.ChangeDelete, api.SubnetTagsResource, *surplus[i].subnet.SubnetId, "untag public subnet %q",
+			extractName(surplus[i].subnet.Tags))
+	}
+
+	return withoutSurplusGateways(taggedSubnets, surplus), nil
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planCleanup(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	recorded, err := d.aws.ledger.PlanUndo(ctx, plan, ProviderName, ledger.Deploy, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		return plan, nil
+	}
+
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := d.aws.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the Submariner gateway subnets")
+
+	publicSubnets, found, err := d.aws.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the configured public subnets")
+	}
+
+	if !found {
+		publicSubnets, err = d.aws.getTaggedPublicSubnets(ctx, vpcID)
+		if err != nil {
+			return nil, status.Error(err, "unable to find the tagged public subnets")
+		}
+	}
+
+	existing, err := d.listMachineSetNames(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to list the gateway machine sets")
+	}
+
+	status.Success("Retrieved %d Submariner gateway subnet(s)", len(publicSubnets))
+
+	for i := range publicSubnets {
+		subnet := &publicSubnets[i]
+
+		machineSet, err := d.initMachineSet(ctx, "", "", subnet)
+		if err != nil {
+			return nil, status.Error(err, "unable to initialize the gateway machine set")
+		}
+
+		if existing.Has(machineSet.GetName()) {
+			plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "")
+		}
+
+		if subnetTagged(subnet) {
+			plan.Add(api.ChangeDelete, api.SubnetTagsResource, *subnet.SubnetId, "untag public subnet %q", extractName(subnet.Tags))
+		}
+	}
+
+	groupName := d.aws.withAWSInfo(withInfraIDPrefix(gatewaySGSuffix))
+
+	_, err = d.aws.getSecurityGroup(ctx, vpcID, groupName)
+	if err == nil {
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	} else if !isNotFoundError(err) {
+		return nil, status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	return plan, nil
+}
+
+// retrieveVpcID returns the VPC ID and initializes the security group suffixes, as the start of every operation does.
+func (ac *awsCloud) retrieveVpcID(ctx context.Context) (string, error) {
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if _, found := ac.cloudConfig[VPCIDKey]; !found {
+		err = ac.setSuffixes(ctx, vpcID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return vpcID, nil
+}
+
+func (ac *awsCloud) planGatewaySG(ctx context.Context, plan *api.Plan, vpcID string, ports []api.PortSpec, sourceCIDRs []string,
+) error {
+	groupName := ac.withAWSInfo(withInfraIDPrefix(gatewaySGSuffix))
+
+	group, err := ac.getSecurityGroup(ctx, vpcID, groupName)
+	if err != nil {
+		if !isNotFoundError(err) {
+			return err
+		}
+
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "in VPC %q", vpcID)
+	}
+
+	for _, port := range ports {
+		for _, cidr := range sourceCIDRs {
+			if !hasIngressPermission(&group, port, "", cidr) {
+				plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "authorize ingress %s from %s", port, cidr)
+			}
+		}
+	}
+
+	stale := stalePublicPermissions(&group, ports, sourceCIDRs)
+	for i := range stale {
+		for _, cidr := range cidrsOf(&stale[i]) {
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, groupName, "revoke ingress %s from %s",
+				formatIPPermission(&stale[i]), cidr)
+		}
+	}
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) listMachineSetNames(ctx context.Context) (set.Set[string], error) {
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	names := set.New[string]()
+	for i := range machineSets {
+		names.Insert(machineSets[i].GetName())
+	}
+
+	return names, nil
+}
+
+func planClusterSGRule(plan *api.Plan, srcGroup, destGroup *types.SecurityGroup, port api.PortSpec) {
+	srcGroupID := ptr.Deref(srcGroup.GroupId, "")
+
+	if !hasIngressPermission(destGroup, port, srcGroupID, "") {
+		plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, ptr.Deref(destGroup.GroupId, ""),
+			"authorize ingress %s from security group %q", port, srcGroupID)
+	}
+}
+
+func cidrsOf(perm *types.IpPermission) []string {
+	cidrs := make([]string, 0, len(perm.IpRanges)+len(perm.Ipv6Ranges))
+
+	for i := range perm.IpRanges {
+		cidrs = append(cidrs, ptr.Deref(perm.IpRanges[i].CidrIp, ""))
+	}
+
+	for i := range perm.Ipv6Ranges {
+		cidrs = append(cidrs, ptr.Deref(perm.Ipv6Ranges[i].CidrIpv6, ""))
+	}
+
+	return cidrs
+}
+
+func formatIPPermission(perm *types.IpPermission) string {
+	if ptr.Deref(perm.FromPort, -1) == -1 {
+		return ptr.Deref(perm.IpProtocol, "")
+	}
+
+	return fmt.Sprintf("%d-%d/%s", *perm.FromPort, ptr.Deref(perm.ToPort, 0), ptr.Deref(perm.IpProtocol, ""))
+}
@@ -0,0 +1,106 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// policyModeCalls are the client.Interface methods called by each policy mode, including the dry-run validations.
+var policyModeCalls = map[api.PolicyMode][]string{
+	api.OpenPortsMode: {
+		"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "AuthorizeSecurityGroupIngress",
+	},
+	api.ClosePortsMode: {
+		"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "RevokeSecurityGroupIngress",
+	},
+	api.ReconcilePortsMode: {
+		"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "AuthorizeSecurityGroupIngress", "RevokeSecurityGroupIngress",
+	},
+	api.DeployMode: {
+		"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "CreateSecurityGroup", "AuthorizeSecurityGroupIngress",
+		"RevokeSecurityGroupIngress", "DescribeInstanceTypeOfferings", "CreateTags", "DescribeInstances", "DeleteTags",
+	},
+	api.CleanupMode: {
+		"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "DeleteSecurityGroup", "RevokeSecurityGroupIngress",
+		"DeleteTags",
+	},
+	api.ScanOrphansMode:   {"DescribeSecurityGroups", "DescribeSubnets", "DescribeInstances"},
+	api.DeleteOrphansMode: {"DeleteSecurityGroup", "DeleteTags"},
+	api.PrepareHostedGatewaysMode: {
+		"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "CreateSecurityGroup", "CreateTags",
+		"AuthorizeSecurityGroupIngress", "RevokeSecurityGroupIngress", "DescribeInstanceTypeOfferings",
+	},
+	api.CleanupHostedGatewaysMode: {"DescribeVpcs", "DescribeSubnets", "DescribeSecurityGroups", "DeleteSecurityGroup"},
+}
+
+// callPermissions maps each client.Interface method to the IAM actions it requires. The EC2 actions are named after the
+// API operations.
+var callPermissions = map[string][]string{
+	"AuthorizeSecurityGroupIngress": {"ec2:AuthorizeSecurityGroupIngress"},
+	"CreateSecurityGroup":           {"ec2:CreateSecurityGroup"},
+	"CreateTags":                    {"ec2:CreateTags"},
+	"DeleteSecurityGroup":           {"ec2:DeleteSecurityGroup"},
+	"DeleteTags":                    {"ec2:DeleteTags"},
+	"DescribeInstanceTypeOfferings": {"ec2:DescribeInstanceTypeOfferings"},
+	"DescribeInstances":             {"ec2:DescribeInstances"},
+	"DescribeSecurityGroups":        {"ec2:DescribeSecurityGroups"},
+	"DescribeSubnets":               {"ec2:DescribeSubnets"},
+	"DescribeVpcs":                  {"ec2:DescribeVpcs"},
+	"RevokeSecurityGroupIngress":    {"ec2:RevokeSecurityGroupIngress"},
+}
+
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource string   `json:"Resource"`
+}
+
+// Permissions returns the IAM actions needed by the given policy modes, or by all of them if none is given.
+func Permissions(modes ...api.PolicyMode) ([]string, error) {
+	return api.PolicyPermissions(policyModeCalls, callPermissions, modes...)
+}
+
+// Policy returns an IAM JSON policy document granting the least privileges needed by the given policy modes, or by all
+// of them if none is given.
+func Policy(modes ...api.PolicyMode) ([]byte, error) {
+	actions, err := Permissions(modes...)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := json.MarshalIndent(&policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{{
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: "*",
+		}},
+	}, "", "  ")
+
+	return policy, errors.Wrap(err, "error marshalling the IAM policy")
+}
@@ -0,0 +1,93 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client"
+)
+
+var _ = Describe("Policy", func() {
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource string
+	}
+
+	parsePolicy := func(modes ...api.PolicyMode) []statement {
+		policy, err := aws.Policy(modes...)
+		Expect(err).To(Succeed())
+
+		document := struct {
+			Version   string
+			Statement []statement
+		}{}
+
+		Expect(json.Unmarshal(policy, &document)).To(Succeed())
+		Expect(document.Version).To(Equal("2012-10-17"))
+
+		return document.Statement
+	}
+
+	When("the OpenPorts mode is requested", func() {
+		It("should only allow opening the internal ports", func() {
+			Expect(parsePolicy(api.OpenPortsMode)).To(Equal([]statement{{
+				Effect: "Allow",
+				Action: []string{
+					"ec2:AuthorizeSecurityGroupIngress", "ec2:DescribeSecurityGroups", "ec2:DescribeSubnets", "ec2:DescribeVpcs",
+				},
+				Resource: "*",
+			}}))
+		})
+	})
+
+	When("the Deploy and Cleanup modes are requested", func() {
+		It("should allow deploying and cleaning up the gateways", func() {
+			statements := parsePolicy(api.DeployMode, api.CleanupMode)
+			Expect(statements).To(HaveLen(1))
+			Expect(statements[0].Action).To(ContainElements("ec2:CreateSecurityGroup", "ec2:DeleteSecurityGroup",
+				"ec2:CreateTags", "ec2:DeleteTags", "ec2:DescribeInstances"))
+		})
+	})
+
+	When("no mode is requested", func() {
+		It("should allow all the operations of the EC2 client", func() {
+			permissions, err := aws.Permissions()
+			Expect(err).To(Succeed())
+
+			clientType := reflect.TypeOf((*client.Interface)(nil)).Elem()
+			for i := range clientType.NumMethod() {
+				Expect(permissions).To(ContainElement("ec2:" + clientType.Method(i).Name))
+			}
+		})
+	})
+
+	When("an unknown mode is requested", func() {
+		It("should return an error", func() {
+			_, err := aws.Policy("Unknown")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
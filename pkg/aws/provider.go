@@ -0,0 +1,83 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	awsClient "github.com/submariner-io/cloud-prepare/pkg/aws/client"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+)
+
+// ProviderName is the name the AWS provider is registered under, see api.NewProvider.
+const ProviderName = "aws"
+
+// ProviderConfig is the configuration of the AWS provider.
+type ProviderConfig struct {
+	InfraID string
+	Region  string
+
+	// Client is the EC2 client to use. If nil, one is created from the CredentialsFile and Profile, which default to
+	// DefaultCredentialsFile and DefaultProfile.
+	Client          awsClient.Interface
+	CredentialsFile string
+	Profile         string
+
+	CloudOptions []CloudOption
+
+	MachineSetDeployer ocp.MachineSetDeployer
+	InstanceType       string
+}
+
+func init() {
+	api.RegisterProvider(ProviderName, newProvider)
+}
+
+func newProvider(config *ProviderConfig) (api.Cloud, api.GatewayDeployer, error) {
+	if config.MachineSetDeployer == nil {
+		return nil, nil, errors.New("a MachineSetDeployer is required")
+	}
+
+	var cloud api.Cloud
+
+	if config.Client != nil {
+		cloud = NewCloud(config.Client, config.InfraID, config.Region, config.CloudOptions...)
+	} else {
+		credentialsFile := config.CredentialsFile
+		if credentialsFile == "" {
+			credentialsFile = DefaultCredentialsFile()
+		}
+
+		profile := config.Profile
+		if profile == "" {
+			profile = DefaultProfile()
+		}
+
+		var err error
+
+		cloud, err = NewCloudFromSettings(credentialsFile, profile, config.InfraID, config.Region, config.CloudOptions...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	gwDeployer, err := NewOcpGatewayDeployer(cloud, config.MachineSetDeployer, config.InstanceType)
+
+	return cloud, gwDeployer, err
+}
@@ -0,0 +1,170 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package aws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/utils/ptr"
+)
+
+// clusterSGRule allows the internal Submariner traffic from a cluster security group to another one, or to itself.
+type clusterSGRule struct {
+	src         *types.SecurityGroup
+	dest        *types.SecurityGroup
+	description string
+
+	// missing are the ports not allowed yet and stale the permissions allowing ports which are no longer requested.
+	missing []api.PortSpec
+	stale   []types.IpPermission
+}
+
+func (ac *awsCloud) ReconcilePorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	plan, err := ac.reconcilePorts(ctx, ports, status)
+
+	return plan, classifyError(err)
+}
+
+func (ac *awsCloud) reconcilePorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	if err := api.ValidatePorts(ports); err != nil {
+		return nil, errors.WithMessage(err, "invalid internal ports")
+	}
+
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := ac.retrieveVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the cluster security groups")
+
+	workerGroup, controlPlaneGroup, err := ac.getClusterSecurityGroups(ctx, vpcID)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the cluster security groups")
+	}
+
+	status.Success("Retrieved the cluster security groups")
+
+	status.Start("Comparing the internal ports with the cluster security group rules")
+
+	sgRules := []clusterSGRule{
+		{src: &workerGroup, dest: &workerGroup, description: "between the workers"},
+		{src: &workerGroup, dest: &controlPlaneGroup, description: "from worker to control plane nodes"},
+		{src: &controlPlaneGroup, dest: &workerGroup, description: "from control plane to worker nodes"},
+	}
+
+	plan := &api.Plan{}
+
+	for i := range sgRules {
+		diffClusterSGRule(plan, &sgRules[i], ports)
+	}
+
+	plan.Report(status)
+
+	// The stale permissions are revoked first so that they can't conflict with the missing ones.
+	for i := range sgRules {
+		rule := &sgRules[i]
+		if len(rule.stale) == 0 {
+			continue
+		}
+
+		status.Start("Revoking the stale internal ports from security group %q", ptr.Deref(rule.dest.GroupId, ""))
+
+		_, err = ac.client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       rule.dest.GroupId,
+			IpPermissions: rule.stale,
+		})
+		if err != nil {
+			return nil, status.Error(errors.Wrap(err, "error revoking AWS security group ingress"), "unable to revoke the stale ports")
+		}
+
+		status.Success("Revoked the stale internal ports from security group %q", ptr.Deref(rule.dest.GroupId, ""))
+	}
+
+	for i := range sgRules {
+		rule := &sgRules[i]
+
+		for _, port := range rule.missing {
+			status.Start("Opening %s for intra-cluster communications", port)
+
+			err = ac.createClusterSGRule(ctx, rule.src.GroupId, rule.dest.GroupId, port,
+				fmt.Sprintf("%s %s", internalTraffic, rule.description))
+			if err != nil {
+				return nil, status.Error(err, "unable to open port")
+			}
+
+			status.Success("Opened %s for intra-cluster communications", port)
+		}
+	}
+
+	return plan, nil
+}
+
+// diffClusterSGRule fills in the missing ports and the stale permissions of the given rule, and adds the changes they
+// require to the plan.
+func diffClusterSGRule(plan *api.Plan, rule *clusterSGRule, ports []api.PortSpec) {
+	srcGroupID := ptr.Deref(rule.src.GroupId, "")
+	destGroupID := ptr.Deref(rule.dest.GroupId, "")
+
+	for i := range rule.dest.IpPermissions {
+		perm := &rule.dest.IpPermissions[i]
+
+		if slices.ContainsFunc(ports, func(port api.PortSpec) bool {
+			return permissionMatches(perm, port)
+		}) {
+			continue
+		}
+
+		for j := range perm.UserIdGroupPairs {
+			pair := &perm.UserIdGroupPairs[j]
+
+			if ptr.Deref(pair.GroupId, "") == srcGroupID && strings.Contains(ptr.Deref(pair.Description, ""), internalTraffic) {
+				rule.stale = append(rule.stale, types.IpPermission{
+					IpProtocol:       perm.IpProtocol,
+					FromPort:         perm.FromPort,
+					ToPort:           perm.ToPort,
+					UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: pair.GroupId}},
+				})
+
+				plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, destGroupID,
+					"revoke ingress %s from security group %q", formatIPPermission(perm), srcGroupID)
+			}
+		}
+	}
+
+	for _, port := range ports {
+		if !hasIngressPermission(rule.dest, port, srcGroupID, "") {
+			rule.missing = append(rule.missing, port)
+
+			plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, destGroupID,
+				"authorize ingress %s from security group %q", port, srcGroupID)
+		}
+	}
+}
@@ -21,6 +21,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,15 +29,25 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/ptr"
 )
 
-const internalTraffic = "Internal Submariner traffic"
+const (
+	internalTraffic = "Internal Submariner traffic"
+	gatewaySGSuffix = "-submariner-gw-sg"
+)
+
+var ipProtocolNumbers = map[string]string{
+	"esp":  "50",
+	"ah":   "51",
+	"sctp": "132",
+}
 
-func (ac *awsCloud) getSecurityGroupName(vpcID, name string) (*string, error) {
-	group, err := ac.getSecurityGroup(vpcID, name)
+func (ac *awsCloud) getSecurityGroupName(ctx context.Context, vpcID, name string) (*string, error) {
+	group, err := ac.getSecurityGroup(ctx, vpcID, name)
 	if err != nil {
 		return nil, err
 	}
@@ -44,8 +55,8 @@ func (ac *awsCloud) getSecurityGroupName(vpcID, name string) (*string, error) {
 	return group.GroupId, nil
 }
 
-func (ac *awsCloud) getSecurityGroupByID(groupID string) (types.SecurityGroup, error) {
-	output, err := ac.client.DescribeSecurityGroups(context.TODO(), &ec2.DescribeSecurityGroupsInput{
+func (ac *awsCloud) getSecurityGroupByID(ctx context.Context, groupID string) (types.SecurityGroup, error) {
+	output, err := ac.client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
 		GroupIds: []string{groupID},
 	})
 	if err != nil {
@@ -59,13 +70,13 @@ func (ac *awsCloud) getSecurityGroupByID(groupID string) (types.SecurityGroup, e
 	return output.SecurityGroups[0], nil
 }
 
-func (ac *awsCloud) getSecurityGroup(vpcID, name string) (types.SecurityGroup, error) {
+func (ac *awsCloud) getSecurityGroup(ctx context.Context, vpcID, name string) (types.SecurityGroup, error) {
 	filters := []types.Filter{
 		ec2Filter("vpc-id", vpcID),
 		ac.filterByName(name),
 	}
 
-	result, err := ac.client.DescribeSecurityGroups(context.TODO(), &ec2.DescribeSecurityGroupsInput{
+	result, err := ac.client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
 		Filters: filters,
 	})
 	if err != nil {
@@ -79,39 +90,73 @@ func (ac *awsCloud) getSecurityGroup(vpcID, name string) (types.SecurityGroup, e
 	return result.SecurityGroups[0], nil
 }
 
-func (ac *awsCloud) authorizeSecurityGroupIngress(groupID *string, ipPermissions []types.IpPermission) error {
+// authorizeSecurityGroupIngress authorizes the given ingress, and returns whether it was added: it isn't if it already was
+// authorized.
+func (ac *awsCloud) authorizeSecurityGroupIngress(ctx context.Context, groupID *string, ipPermissions []types.IpPermission,
+) (bool, error) {
 	input := &ec2.AuthorizeSecurityGroupIngressInput{
 		GroupId:       groupID,
 		IpPermissions: ipPermissions,
 	}
 
-	_, err := ac.client.AuthorizeSecurityGroupIngress(context.TODO(), input)
+	_, err := ac.client.AuthorizeSecurityGroupIngress(ctx, input)
 	if isAWSError(err, "InvalidPermission.Duplicate") {
-		return nil
+		return false, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrap(err, "error authorizing AWS security groups ingress")
+	}
+
+	return true, nil
+}
+
+// newIPPermission returns an IpPermission for the given port spec. AWS only accepts the tcp, udp, icmp and icmpv6 protocol
+// names, other protocols are given by number; ports are left unset for portless protocols, except ICMP whose type and
+// code are both set to -1 to allow all of them, as AWS requires.
+func newIPPermission(port api.PortSpec) types.IpPermission {
+	protocol := port.Protocol
+	if number, ok := ipProtocolNumbers[strings.ToLower(protocol)]; ok {
+		protocol = number
+	}
+
+	permission := types.IpPermission{
+		IpProtocol: ptr.To(protocol),
+	}
+
+	switch {
+	case strings.EqualFold(protocol, "icmp") || strings.EqualFold(protocol, "icmpv6"):
+		permission.FromPort = ptr.To(int32(-1))
+		permission.ToPort = ptr.To(int32(-1))
+	case !port.IsPortless():
+		from, to := port.PortRange()
+		permission.FromPort = ptr.To(int32(from))
+		permission.ToPort = ptr.To(int32(to))
 	}
 
-	return errors.Wrap(err, "error authorizing AWS security groups ingress")
+	return permission
 }
 
-func (ac *awsCloud) createClusterSGRule(srcGroup, destGroup *string, port uint16, protocol, description string) error {
-	ipPermissions := []types.IpPermission{
+func (ac *awsCloud) createClusterSGRule(ctx context.Context, srcGroup, destGroup *string, port api.PortSpec, description string,
+) error {
+	ipPermission := newIPPermission(port)
+	ipPermission.UserIdGroupPairs = []types.UserIdGroupPair{
 		{
-			FromPort:   ptr.To(int32(port)),
-			ToPort:     ptr.To(int32(port)),
-			IpProtocol: ptr.To(protocol),
-			UserIdGroupPairs: []types.UserIdGroupPair{
-				{
-					Description: ptr.To(description),
-					GroupId:     srcGroup,
-				},
-			},
+			Description: ptr.To(description),
+			GroupId:     srcGroup,
 		},
 	}
 
-	return ac.authorizeSecurityGroupIngress(destGroup, ipPermissions)
+	added, err := ac.authorizeSecurityGroupIngress(ctx, destGroup, []types.IpPermission{ipPermission})
+	if err != nil || !added {
+		// The rules which were already there aren't recorded, so that ClosePorts doesn't revoke them.
+		return err
+	}
+
+	return ac.recordPermission(ctx, *destGroup, fmt.Sprintf("%s from %s", port, *srcGroup), &ipPermission)
 }
 
-func (ac *awsCloud) allowPortInCluster(vpcID string, port uint16, protocol string) error {
+func (ac *awsCloud) allowPortInCluster(ctx context.Context, vpcID string, port api.PortSpec) error {
 	var workerGroupID, controlPlaneGroupID *string
 	var err error
 
@@ -124,7 +169,7 @@ func (ac *awsCloud) allowPortInCluster(vpcID string, port uint16, protocol strin
 	} else {
 		workerGroupName := withInfraIDPrefix(ac.nodeSGSuffix)
 
-		workerGroupID, err = ac.getSecurityGroupName(vpcID, workerGroupName)
+		workerGroupID, err = ac.getSecurityGroupName(ctx, vpcID, workerGroupName)
 		if err != nil {
 			return err
 		}
@@ -139,49 +184,59 @@ func (ac *awsCloud) allowPortInCluster(vpcID string, port uint16, protocol strin
 	} else {
 		controlPlaneGroupName := withInfraIDPrefix(ac.controlPlaneSGSuffix)
 
-		controlPlaneGroupID, err = ac.getSecurityGroupName(vpcID, controlPlaneGroupName)
+		controlPlaneGroupID, err = ac.getSecurityGroupName(ctx, vpcID, controlPlaneGroupName)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = ac.createClusterSGRule(workerGroupID, workerGroupID, port, protocol, fmt.Sprintf("%s between the workers", internalTraffic))
+	err = ac.createClusterSGRule(ctx, workerGroupID, workerGroupID, port,
+		fmt.Sprintf("%s between the workers", internalTraffic))
 	if err != nil {
 		return err
 	}
 
-	err = ac.createClusterSGRule(workerGroupID, controlPlaneGroupID, port, protocol,
+	err = ac.createClusterSGRule(ctx, workerGroupID, controlPlaneGroupID, port,
 		fmt.Sprintf("%s from worker to control plane nodes", internalTraffic))
 	if err != nil {
 		return err
 	}
 
-	return ac.createClusterSGRule(controlPlaneGroupID, workerGroupID, port, protocol,
+	return ac.createClusterSGRule(ctx, controlPlaneGroupID, workerGroupID, port,
 		fmt.Sprintf("%s from control plane to worker nodes", internalTraffic))
 }
 
-func (ac *awsCloud) createPublicSGRule(groupID *string, port uint16, protocol, description string) error {
-	ipPermissions := []types.IpPermission{
-		{
-			FromPort:   ptr.To(int32(port)),
-			ToPort:     ptr.To(int32(port)),
-			IpProtocol: ptr.To(protocol),
-			IpRanges: []types.IpRange{
-				{
-					CidrIp:      ptr.To("0.0.0.0/0"),
-					Description: ptr.To(description),
-				},
+func (ac *awsCloud) createPublicSGRule(ctx context.Context, groupID *string, port api.PortSpec, cidr, description string) error {
+	ipPermission := newIPPermission(port)
+
+	if api.CIDRFamily(cidr) == api.IPv6 {
+		ipPermission.Ipv6Ranges = []types.Ipv6Range{
+			{
+				CidrIpv6:    ptr.To(cidr),
+				Description: ptr.To(description),
 			},
-		},
+		}
+	} else {
+		ipPermission.IpRanges = []types.IpRange{
+			{
+				CidrIp:      ptr.To(cidr),
+				Description: ptr.To(description),
+			},
+		}
 	}
 
-	return ac.authorizeSecurityGroupIngress(groupID, ipPermissions)
+	// The gateway rules aren't recorded: they're removed with the recorded gateway group.
+	_, err := ac.authorizeSecurityGroupIngress(ctx, groupID, []types.IpPermission{ipPermission})
+
+	return err
 }
 
-func (ac *awsCloud) createGatewaySG(vpcID string, ports []api.PortSpec) (string, error) {
-	groupName := ac.withAWSInfo(withInfraIDPrefix("-submariner-gw-sg"))
+func (ac *awsCloud) createGatewaySG(ctx context.Context, vpcID string, ports []api.PortSpec, sourceCIDRs []string) (string, error) {
+	groupName := ac.withAWSInfo(withInfraIDPrefix(gatewaySGSuffix))
+
+	group, err := ac.getSecurityGroup(ctx, vpcID, groupName)
+	gatewayGroupID := group.GroupId
 
-	gatewayGroupID, err := ac.getSecurityGroupName(vpcID, groupName)
 	if err != nil {
 		if !isNotFoundError(err) {
 			return "", err
@@ -194,14 +249,12 @@ func (ac *awsCloud) createGatewaySG(vpcID string, ports []api.PortSpec) (string,
 			TagSpecifications: []types.TagSpecification{
 				{
 					ResourceType: types.ResourceTypeSecurityGroup,
-					Tags: []types.Tag{
-						ec2Tag("Name", groupName),
-					},
+					Tags:         ec2TagsWithName(groupName, ac.tags()),
 				},
 			},
 		}
 
-		result, err := ac.client.CreateSecurityGroup(context.TODO(), input)
+		result, err := ac.client.CreateSecurityGroup(ctx, input)
 
 		if err != nil && !isAWSError(err, "InvalidGroup.Duplicate") {
 			return "", errors.Wrap(err, "error creating AWS security group")
@@ -210,24 +263,90 @@ func (ac *awsCloud) createGatewaySG(vpcID string, ports []api.PortSpec) (string,
 		gatewayGroupID = result.GroupId
 	}
 
+	err = ac.ledger.Record(ctx, ledger.Entry{
+		Provider:  ProviderName,
+		Operation: ledger.Deploy,
+		Resource:  api.SecurityGroupResource,
+		ID:        *gatewayGroupID,
+		Data:      map[string]string{"name": groupName},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Each CIDR is authorized separately so that an already authorized one doesn't prevent the others from being added.
 	for _, port := range ports {
-		err = ac.createPublicSGRule(gatewayGroupID, port.Port, port.Protocol, "Public Submariner traffic")
+		for _, cidr := range sourceCIDRs {
+			err = ac.createPublicSGRule(ctx, gatewayGroupID, port, cidr, "Public Submariner traffic")
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// The ingress no longer requested, such as the access from any address once SourceCIDRs is set, is revoked so that the
+	// gateway rules converge to the requested ones.
+	stale := stalePublicPermissions(&group, ports, sourceCIDRs)
+	if len(stale) > 0 {
+		_, err = ac.client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       gatewayGroupID,
+			IpPermissions: stale,
+		})
 		if err != nil {
-			return "", err
+			return "", errors.Wrap(err, "error revoking AWS security group ingress")
 		}
 	}
 
 	return groupName, nil
 }
 
+// stalePublicPermissions returns the CIDR ingress permissions of the given gateway group which are not for one of the
+// given ports from one of the given source CIDRs.
+func stalePublicPermissions(group *types.SecurityGroup, ports []api.PortSpec, sourceCIDRs []string) []types.IpPermission {
+	var stale []types.IpPermission
+
+	for i := range group.IpPermissions {
+		perm := &group.IpPermissions[i]
+
+		requested := false
+
+		for _, port := range ports {
+			if permissionMatches(perm, port) {
+				requested = true
+				break
+			}
+		}
+
+		stalePerm := types.IpPermission{IpProtocol: perm.IpProtocol, FromPort: perm.FromPort, ToPort: perm.ToPort}
+
+		for j := range perm.IpRanges {
+			if !requested || !slices.Contains(sourceCIDRs, ptr.Deref(perm.IpRanges[j].CidrIp, "")) {
+				stalePerm.IpRanges = append(stalePerm.IpRanges, types.IpRange{CidrIp: perm.IpRanges[j].CidrIp})
+			}
+		}
+
+		for j := range perm.Ipv6Ranges {
+			if !requested || !slices.Contains(sourceCIDRs, ptr.Deref(perm.Ipv6Ranges[j].CidrIpv6, "")) {
+				stalePerm.Ipv6Ranges = append(stalePerm.Ipv6Ranges, types.Ipv6Range{CidrIpv6: perm.Ipv6Ranges[j].CidrIpv6})
+			}
+		}
+
+		if len(stalePerm.IpRanges) > 0 || len(stalePerm.Ipv6Ranges) > 0 {
+			stale = append(stale, stalePerm)
+		}
+	}
+
+	return stale
+}
+
 func gatewayDeletionRetriable(err error) bool {
 	return isAWSError(err, "DependencyViolation")
 }
 
-func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
-	groupName := ac.withAWSInfo(withInfraIDPrefix("-submariner-gw-sg"))
+func (ac *awsCloud) deleteGatewaySG(ctx context.Context, vpcID string) error {
+	groupName := ac.withAWSInfo(withInfraIDPrefix(gatewaySGSuffix))
 
-	gatewayGroupID, err := ac.getSecurityGroupName(vpcID, groupName)
+	gatewayGroupID, err := ac.getSecurityGroupName(ctx, vpcID, groupName)
 	if err != nil {
 		if isNotFoundError(err) {
 			return nil
@@ -236,6 +355,10 @@ func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
 		return err
 	}
 
+	return ac.deleteSecurityGroup(ctx, gatewayGroupID)
+}
+
+func (ac *awsCloud) deleteSecurityGroup(ctx context.Context, groupID *string) error {
 	backoff := wait.Backoff{
 		Steps:    30,
 		Duration: 500 * time.Millisecond,
@@ -243,9 +366,9 @@ func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
 		Cap:      10 * time.Minute,
 	}
 
-	err = retry.OnError(backoff, gatewayDeletionRetriable, func() error {
-		_, err = ac.client.DeleteSecurityGroup(context.TODO(), &ec2.DeleteSecurityGroupInput{
-			GroupId: gatewayGroupID,
+	err := retry.OnError(backoff, gatewayDeletionRetriable, func() error {
+		_, err := ac.client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
+			GroupId: groupID,
 		})
 
 		return err //nolint:wrapcheck // Let the caller wrap it.
@@ -258,79 +381,135 @@ func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
 	return errors.Wrap(err, "error deleting AWS security group")
 }
 
-func (ac *awsCloud) revokePortsInCluster(vpcID string) error {
+func (ac *awsCloud) revokePortsInCluster(ctx context.Context, vpcID string) error {
+	workerGroup, controlPlaneGroup, err := ac.getClusterSecurityGroups(ctx, vpcID)
+	if err != nil {
+		return err
+	}
+
+	err = ac.revokePortsFromGroup(ctx, &workerGroup)
+	if err != nil {
+		return err
+	}
+
+	return ac.revokePortsFromGroup(ctx, &controlPlaneGroup)
+}
+
+func (ac *awsCloud) getClusterSecurityGroups(ctx context.Context, vpcID string) (types.SecurityGroup, types.SecurityGroup, error) {
 	var workerGroup, controlPlaneGroup types.SecurityGroup
 	var err error
 
 	if id, exists := ac.cloudConfig[WorkerSecurityGroupIDKey]; exists {
 		if workerGroupIDStr, ok := id.(string); ok && workerGroupIDStr != "" {
-			workerGroup, err = ac.getSecurityGroupByID(workerGroupIDStr)
+			workerGroup, err = ac.getSecurityGroupByID(ctx, workerGroupIDStr)
 			if err != nil {
-				return errors.Wrap(err, "unable to get Worker Security Group by ID")
+				return workerGroup, controlPlaneGroup, errors.Wrap(err, "unable to get Worker Security Group by ID")
 			}
 		} else {
-			return errors.New("Worker Security Group ID must be a valid non-empty string")
+			return workerGroup, controlPlaneGroup, errors.New("Worker Security Group ID must be a valid non-empty string")
 		}
 	} else {
 		workerGroupName := withInfraIDPrefix(ac.nodeSGSuffix)
 
-		workerGroup, err = ac.getSecurityGroup(vpcID, workerGroupName)
+		workerGroup, err = ac.getSecurityGroup(ctx, vpcID, workerGroupName)
 		if err != nil {
-			return err
+			return workerGroup, controlPlaneGroup, err
 		}
 	}
 
 	if id, exists := ac.cloudConfig[ControlPlaneSecurityGroupIDKey]; exists {
 		if controlPlaneGroupIDStr, ok := id.(string); ok && controlPlaneGroupIDStr != "" {
-			controlPlaneGroup, err = ac.getSecurityGroupByID(controlPlaneGroupIDStr)
+			controlPlaneGroup, err = ac.getSecurityGroupByID(ctx, controlPlaneGroupIDStr)
 			if err != nil {
-				return errors.Wrap(err, "unable to get Control Plane Security Group by ID")
+				return workerGroup, controlPlaneGroup, errors.Wrap(err, "unable to get Control Plane Security Group by ID")
 			}
 		} else {
-			return errors.New("Control Plane Security Group ID must be a valid non-empty string")
+			return workerGroup, controlPlaneGroup, errors.New("Control Plane Security Group ID must be a valid non-empty string")
 		}
 	} else {
 		controlPlaneGroupName := withInfraIDPrefix(ac.controlPlaneSGSuffix)
 
-		controlPlaneGroup, err = ac.getSecurityGroup(vpcID, controlPlaneGroupName)
+		controlPlaneGroup, err = ac.getSecurityGroup(ctx, vpcID, controlPlaneGroupName)
 		if err != nil {
-			return err
+			return workerGroup, controlPlaneGroup, err
 		}
 	}
 
-	err = ac.revokePortsFromGroup(&workerGroup)
-	if err != nil {
-		return err
+	return workerGroup, controlPlaneGroup, nil
+}
+
+func (ac *awsCloud) revokePortsFromGroup(ctx context.Context, group *types.SecurityGroup) error {
+	permissionsToRevoke := internalPermissions(group)
+	if len(permissionsToRevoke) == 0 {
+		return nil
 	}
 
-	return ac.revokePortsFromGroup(&controlPlaneGroup)
+	input := &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       group.GroupId,
+		IpPermissions: permissionsToRevoke,
+	}
+
+	_, err := ac.client.RevokeSecurityGroupIngress(ctx, input)
+
+	return errors.Wrap(err, "error revoking AWS security group ingress")
 }
 
-func (ac *awsCloud) revokePortsFromGroup(group *types.SecurityGroup) error {
-	var permissionsToRevoke []types.IpPermission
+// internalPermissions returns the ingress permissions of the given group that were created for internal Submariner traffic.
+func internalPermissions(group *types.SecurityGroup) []types.IpPermission {
+	var permissions []types.IpPermission
 
 	for perm := range group.IpPermissions {
 		for i := range group.IpPermissions[perm].UserIdGroupPairs {
 			groupPair := group.IpPermissions[perm].UserIdGroupPairs[i]
 			if groupPair.Description != nil && strings.Contains(*groupPair.Description, internalTraffic) {
-				permissionsToRevoke = append(permissionsToRevoke, group.IpPermissions[perm])
+				permissions = append(permissions, group.IpPermissions[perm])
 				break
 			}
 		}
 	}
 
-	if len(permissionsToRevoke) == 0 {
-		return nil
-	}
+	return permissions
+}
 
-	input := &ec2.RevokeSecurityGroupIngressInput{
-		GroupId:       group.GroupId,
-		IpPermissions: permissionsToRevoke,
+// hasIngressPermission returns true if the given group already allows ingress on the given port spec,
+// either from the source group or from the CIDR, whichever is specified.
+func hasIngressPermission(group *types.SecurityGroup, port api.PortSpec, srcGroupID, cidr string) bool {
+	for i := range group.IpPermissions {
+		perm := &group.IpPermissions[i]
+
+		if !permissionMatches(perm, port) {
+			continue
+		}
+
+		for j := range perm.UserIdGroupPairs {
+			if srcGroupID != "" && ptr.Deref(perm.UserIdGroupPairs[j].GroupId, "") == srcGroupID {
+				return true
+			}
+		}
+
+		for j := range perm.IpRanges {
+			if cidr != "" && ptr.Deref(perm.IpRanges[j].CidrIp, "") == cidr {
+				return true
+			}
+		}
+
+		for j := range perm.Ipv6Ranges {
+			if cidr != "" && ptr.Deref(perm.Ipv6Ranges[j].CidrIpv6, "") == cidr {
+				return true
+			}
+		}
 	}
 
-	_, err := ac.client.RevokeSecurityGroupIngress(context.TODO(), input)
+	return false
+}
 
-	return errors.Wrap(err, "error revoking AWS security group ingress")
+// permissionMatches returns true if the given permission is for the protocol and port range of the given port spec.
+func permissionMatches(perm *types.IpPermission, port api.PortSpec) bool {
+	expected := newIPPermission(port)
+
+	return perm.IpProtocol != nil && strings.EqualFold(*perm.IpProtocol, *expected.IpProtocol) &&
+		ptr.Deref(perm.FromPort, -1) == ptr.Deref(expected.FromPort, -1) &&
+		ptr.Deref(perm.ToPort, -1) == ptr.Deref(expected.ToPort, -1)
 }
 
 func withInfraIDPrefix(s string) string {
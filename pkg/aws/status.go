@@ -0,0 +1,143 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	statuses, err := d.gatewayStatus(ctx, status)
+
+	return statuses, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) gatewayStatus(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := d.aws.getVpcID(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Retrieving the Submariner gateways")
+
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to list the gateway machine sets")
+	}
+
+	gateways := []api.GatewayStatus{}
+
+	for i := range machineSets {
+		machineSetGateways, err := d.machineSetStatus(ctx, vpcID, &machineSets[i])
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the instances of machine set %q", machineSets[i].GetName())
+		}
+
+		gateways = append(gateways, machineSetGateways...)
+	}
+
+	status.Success("Retrieved %d Submariner gateway(s)", len(gateways))
+
+	return gateways, nil
+}
+
+// machineSetStatus returns a GatewayStatus for each instance provisioned by the given machine set, or a single one
+// without node information if no instance has been provisioned yet.
+func (d *ocpGatewayDeployer) machineSetStatus(ctx context.Context, vpcID string, machineSet *unstructured.Unstructured,
+) ([]api.GatewayStatus, error) {
+	gateway := api.GatewayStatus{
+		Type:          api.DedicatedGateway,
+		MachineSet:    machineSet.GetName(),
+		Zone:          ocp.ProviderSpecString(machineSet, "placement", "availabilityZone"),
+		Subnet:        machineSetSubnet(machineSet),
+		InstanceType:  ocp.ProviderSpecString(machineSet, "instanceType"),
+		SecurityGroup: d.aws.withAWSInfo(withInfraIDPrefix(gatewaySGSuffix)),
+	}
+
+	// Machines, and thus their instances, are named after the machine set.
+	result, err := d.aws.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			ec2Filter("vpc-id", vpcID),
+			ec2Filter("tag:Name", machineSet.GetName()+"-*"),
+			{
+				Name:   ptr.To("instance-state-name"),
+				Values: []string{string(types.InstanceStateNamePending), string(types.InstanceStateNameRunning)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error describing the instances")
+	}
+
+	var gateways []api.GatewayStatus
+
+	for i := range result.Reservations {
+		for j := range result.Reservations[i].Instances {
+			instance := &result.Reservations[i].Instances[j]
+
+			instanceGateway := gateway
+			instanceGateway.NodeName = ptr.Deref(instance.PrivateDnsName, "")
+			instanceGateway.PublicIP = ptr.Deref(instance.PublicIpAddress, "")
+			instanceGateway.InstanceType = string(instance.InstanceType)
+
+			if instance.Placement != nil && instance.Placement.AvailabilityZone != nil {
+				instanceGateway.Zone = *instance.Placement.AvailabilityZone
+			}
+
+			gateways = append(gateways, instanceGateway)
+		}
+	}
+
+	if len(gateways) == 0 {
+		gateways = append(gateways, gateway)
+	}
+
+	return gateways, nil
+}
+
+func machineSetSubnet(machineSet *unstructured.Unstructured) string {
+	filters, _, _ := unstructured.NestedSlice(machineSet.Object, "spec", "template", "spec", "providerSpec", "value", "subnet", "filters")
+	for _, f := range filters {
+		filter, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		values, _, _ := unstructured.NestedStringSlice(filter, "values")
+		if len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 )
 
 var (
@@ -54,7 +56,7 @@ func subnetTagged(subnet *types.Subnet) bool {
 	return hasTag(subnet.Tags, tagSubmarinerGateway)
 }
 
-func (ac *awsCloud) findPublicSubnets(vpcID string, filter types.Filter) ([]types.Subnet, error) {
+func (ac *awsCloud) findPublicSubnets(ctx context.Context, vpcID string, filter types.Filter) ([]types.Subnet, error) {
 	ownedFilters := ac.filterByCurrentCluster()
 	var err error
 	var result *ec2.DescribeSubnetsOutput
@@ -66,7 +68,7 @@ func (ac *awsCloud) findPublicSubnets(vpcID string, filter types.Filter) ([]type
 			filter,
 		}
 
-		result, err = ac.client.DescribeSubnets(context.TODO(), &ec2.DescribeSubnetsInput{Filters: filters})
+		result, err = ac.client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: filters})
 		if err != nil {
 			return nil, errors.Wrap(err, "error describing AWS subnets")
 		}
@@ -79,9 +81,10 @@ func (ac *awsCloud) findPublicSubnets(vpcID string, filter types.Filter) ([]type
 	return result.Subnets, nil
 }
 
-func (ac *awsCloud) getSubnetsSupportingInstanceType(subnets []types.Subnet, instanceType string) ([]types.Subnet, error) {
+func (ac *awsCloud) getSubnetsSupportingInstanceType(ctx context.Context, subnets []types.Subnet, instanceType string,
+) ([]types.Subnet, error) {
 	return filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
-		output, err := ac.client.DescribeInstanceTypeOfferings(context.TODO(), &ec2.DescribeInstanceTypeOfferingsInput{
+		output, err := ac.client.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
 			LocationType: types.LocationTypeAvailabilityZone,
 			Filters: []types.Filter{
 				ec2Filter("location", *subnet.AvailabilityZone),
@@ -96,12 +99,12 @@ func (ac *awsCloud) getSubnetsSupportingInstanceType(subnets []types.Subnet, ins
 	})
 }
 
-func (ac *awsCloud) getTaggedPublicSubnets(vpcID string) ([]types.Subnet, error) {
-	return ac.findPublicSubnets(vpcID, ec2FilterByTag(tagSubmarinerGateway))
+func (ac *awsCloud) getTaggedPublicSubnets(ctx context.Context, vpcID string) ([]types.Subnet, error) {
+	return ac.findPublicSubnets(ctx, vpcID, ec2FilterByTag(tagSubmarinerGateway))
 }
 
-func (ac *awsCloud) tagPublicSubnet(subnetID *string) error {
-	_, err := ac.client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
+func (ac *awsCloud) tagPublicSubnet(ctx context.Context, subnetID *string) error {
+	_, err := ac.client.CreateTags(ctx, &ec2.CreateTagsInput{
 		Resources: []string{*subnetID},
 		Tags: []types.Tag{
 			tagInternalELB,
@@ -109,11 +112,20 @@ func (ac *awsCloud) tagPublicSubnet(subnetID *string) error {
 		},
 	})
 
-	return errors.Wrap(err, "error creating AWS tag")
+	if err != nil {
+		return errors.Wrap(err, "error creating AWS tag")
+	}
+
+	return ac.ledger.Record(ctx, ledger.Entry{
+		Provider:  ProviderName,
+		Operation: ledger.Deploy,
+		Resource:  api.SubnetTagsResource,
+		ID:        *subnetID,
+	})
 }
 
-func (ac *awsCloud) untagPublicSubnet(subnetID *string) error {
-	_, err := ac.client.DeleteTags(context.TODO(), &ec2.DeleteTagsInput{
+func (ac *awsCloud) untagPublicSubnet(ctx context.Context, subnetID *string) error {
+	_, err := ac.client.DeleteTags(ctx, &ec2.DeleteTagsInput{
 		Resources: []string{*subnetID},
 		Tags: []types.Tag{
 			tagInternalELB,
@@ -124,8 +136,50 @@ func (ac *awsCloud) untagPublicSubnet(subnetID *string) error {
 	return errors.Wrap(err, "error deleting AWS tag")
 }
 
-func (ac *awsCloud) getSubnetByID(subnetID string) (*types.Subnet, error) {
-	output, err := ac.client.DescribeSubnets(context.TODO(), &ec2.DescribeSubnetsInput{
+// getConfiguredPublicSubnets returns the public subnets supplied via WithPublicSubnetList, if any. The returned bool
+// indicates whether the option was specified.
+func (ac *awsCloud) getConfiguredPublicSubnets(ctx context.Context) ([]types.Subnet, bool, error) {
+	subnets, exists := ac.cloudConfig[PublicSubnetListKey]
+	if !exists {
+		return nil, false, nil
+	}
+
+	subnetIDs, ok := subnets.([]string)
+	if !ok || len(subnetIDs) == 0 {
+		return nil, true, errors.New("Subnet IDs must be a valid non-empty slice of strings")
+	}
+
+	publicSubnets := make([]types.Subnet, 0, len(subnetIDs))
+
+	for _, id := range subnetIDs {
+		subnet, err := ac.getSubnetByID(ctx, id)
+		if err != nil {
+			return nil, true, errors.Wrapf(err, "unable to find subnet with ID %s", id)
+		}
+
+		publicSubnets = append(publicSubnets, *subnet)
+	}
+
+	return publicSubnets, true, nil
+}
+
+// subnetsToTag returns the untagged subnets that need to be tagged so that the requested number of gateways
+// can be deployed. If no specific number of gateways is requested, all the untagged subnets are returned.
+func subnetsToTag(taggedSubnets, untaggedSubnets []types.Subnet, gateways int) []types.Subnet {
+	if gateways <= 0 {
+		return untaggedSubnets
+	}
+
+	needed := gateways - len(taggedSubnets)
+	if needed <= 0 {
+		return nil
+	}
+
+	return untaggedSubnets[:min(needed, len(untaggedSubnets))]
+}
+
+func (ac *awsCloud) getSubnetByID(ctx context.Context, subnetID string) (*types.Subnet, error) {
+	output, err := ac.client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
 		SubnetIds: []string{subnetID},
 	})
 	if err != nil {
@@ -20,11 +20,13 @@ package aws
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/utils/ptr"
 )
 
@@ -34,13 +36,13 @@ func determinePermissionError(err error, operation string) error {
 	if err == nil || isAWSError(err, "DryRunOperation") {
 		return nil
 	} else if isAWSError(err, "UnauthorizedOperation") {
-		return fmt.Errorf("no permission to %s", operation)
+		return errors.WithMessagef(err, "no permission to %s", operation)
 	}
 
 	return errors.Wrapf(err, "error while checking permissions for %s", operation)
 }
 
-func (ac *awsCloud) validateCreateSecGroup(vpcID string) error {
+func (ac *awsCloud) validateCreateSecGroup(ctx context.Context, vpcID string) error {
 	input := &ec2.CreateSecurityGroupInput{
 		DryRun:      ptr.To(true),
 		GroupName:   ptr.To(permissionsTest),
@@ -48,12 +50,12 @@ func (ac *awsCloud) validateCreateSecGroup(vpcID string) error {
 		VpcId:       ptr.To(vpcID),
 	}
 
-	_, err := ac.client.CreateSecurityGroup(context.TODO(), input)
+	_, err := ac.client.CreateSecurityGroup(ctx, input)
 
 	return determinePermissionError(err, "create security group")
 }
 
-func (ac *awsCloud) validateCreateSecGroupRule(vpcID string) error {
+func (ac *awsCloud) validateCreateSecGroupRule(ctx context.Context, vpcID string) error {
 	var workerGroupID *string
 
 	if id, exists := ac.cloudConfig[WorkerSecurityGroupIDKey]; exists {
@@ -65,7 +67,7 @@ func (ac *awsCloud) validateCreateSecGroupRule(vpcID string) error {
 	} else {
 		var err error
 
-		workerGroupID, err = ac.getSecurityGroupName(vpcID, withInfraIDPrefix(ac.nodeSGSuffix))
+		workerGroupID, err = ac.getSecurityGroupName(ctx, vpcID, withInfraIDPrefix(ac.nodeSGSuffix))
 		if err != nil {
 			return err
 		}
@@ -76,13 +78,13 @@ func (ac *awsCloud) validateCreateSecGroupRule(vpcID string) error {
 		GroupId: workerGroupID,
 	}
 
-	_, err := ac.client.AuthorizeSecurityGroupIngress(context.TODO(), input)
+	_, err := ac.client.AuthorizeSecurityGroupIngress(ctx, input)
 
 	return determinePermissionError(err, "authorize security group ingress")
 }
 
-func (ac *awsCloud) validateCreateTag(subnetID string) error {
-	_, err := ac.client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
+func (ac *awsCloud) validateCreateTag(ctx context.Context, subnetID string) error {
+	_, err := ac.client.CreateTags(ctx, &ec2.CreateTagsInput{
 		DryRun:    ptr.To(true),
 		Resources: []string{subnetID},
 		Tags: []types.Tag{
@@ -93,15 +95,15 @@ func (ac *awsCloud) validateCreateTag(subnetID string) error {
 	return determinePermissionError(err, "create tags on subnets")
 }
 
-func (ac *awsCloud) validateDescribeInstanceTypeOfferings() error {
-	_, err := ac.client.DescribeInstanceTypeOfferings(context.TODO(), &ec2.DescribeInstanceTypeOfferingsInput{
+func (ac *awsCloud) validateDescribeInstanceTypeOfferings(ctx context.Context) error {
+	_, err := ac.client.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
 		DryRun: ptr.To(true),
 	})
 
 	return determinePermissionError(err, "describe instance type offerings")
 }
 
-func (ac *awsCloud) validateDeleteSecGroup(vpcID string) error {
+func (ac *awsCloud) validateDeleteSecGroup(ctx context.Context, vpcID string) error {
 	var workerGroupID *string
 
 	if id, exists := ac.cloudConfig[WorkerSecurityGroupIDKey]; exists {
@@ -113,7 +115,7 @@ func (ac *awsCloud) validateDeleteSecGroup(vpcID string) error {
 	} else {
 		var err error
 
-		workerGroupID, err = ac.getSecurityGroupName(vpcID, withInfraIDPrefix(ac.nodeSGSuffix))
+		workerGroupID, err = ac.getSecurityGroupName(ctx, vpcID, withInfraIDPrefix(ac.nodeSGSuffix))
 		if err != nil {
 			return err
 		}
@@ -124,12 +126,12 @@ func (ac *awsCloud) validateDeleteSecGroup(vpcID string) error {
 		GroupId: workerGroupID,
 	}
 
-	_, err := ac.client.DeleteSecurityGroup(context.TODO(), input)
+	_, err := ac.client.DeleteSecurityGroup(ctx, input)
 
 	return determinePermissionError(err, "delete security group")
 }
 
-func (ac *awsCloud) validateDeleteSecGroupRule(vpcID string) error {
+func (ac *awsCloud) validateDeleteSecGroupRule(ctx context.Context, vpcID string) error {
 	var workerGroupID *string
 
 	if id, exists := ac.cloudConfig[WorkerSecurityGroupIDKey]; exists {
@@ -141,7 +143,7 @@ func (ac *awsCloud) validateDeleteSecGroupRule(vpcID string) error {
 	} else {
 		var err error
 
-		workerGroupID, err = ac.getSecurityGroupName(vpcID, withInfraIDPrefix(ac.nodeSGSuffix))
+		workerGroupID, err = ac.getSecurityGroupName(ctx, vpcID, withInfraIDPrefix(ac.nodeSGSuffix))
 		if err != nil {
 			return err
 		}
@@ -152,13 +154,13 @@ func (ac *awsCloud) validateDeleteSecGroupRule(vpcID string) error {
 		GroupId: workerGroupID,
 	}
 
-	_, err := ac.client.RevokeSecurityGroupIngress(context.TODO(), input)
+	_, err := ac.client.RevokeSecurityGroupIngress(ctx, input)
 
 	return determinePermissionError(err, "revoke security group ingress")
 }
 
-func (ac *awsCloud) validateRemoveTag(subnetID *string) error {
-	_, err := ac.client.DeleteTags(context.TODO(), &ec2.DeleteTagsInput{
+func (ac *awsCloud) validateRemoveTag(ctx context.Context, subnetID *string) error {
+	_, err := ac.client.DeleteTags(ctx, &ec2.DeleteTagsInput{
 		DryRun:    ptr.To(true),
 		Resources: []string{*subnetID},
 		Tags: []types.Tag{
@@ -168,3 +170,129 @@ func (ac *awsCloud) validateRemoveTag(subnetID *string) error {
 
 	return determinePermissionError(err, "delete tags from subnets")
 }
+
+// permissionCheck validates the permission for an EC2 action with a dry-run call.
+type permissionCheck struct {
+	action   string
+	validate func() error
+}
+
+// checkPermissions runs all the given checks and returns a MissingPermissionsError listing the unauthorized actions, or the
+// errors which prevented some checks from completing.
+func checkPermissions(checks ...permissionCheck) error {
+	var (
+		missing []string
+		errs    []error
+	)
+
+	for _, check := range checks {
+		err := check.validate()
+		if isAWSError(err, "UnauthorizedOperation") {
+			missing = append(missing, check.action)
+		} else {
+			errs = appendIfError(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(appendIfError(errs, api.NewMissingPermissionsError(ProviderName, missing)))
+	}
+
+	return api.NewMissingPermissionsError(ProviderName, missing)
+}
+
+func (ac *awsCloud) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(ac.validate(ctx, status))
+}
+
+func (ac *awsCloud) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	if _, found := ac.cloudConfig[VPCIDKey]; !found {
+		err = ac.setSuffixes(ctx, vpcID)
+		if err != nil {
+			return status.Error(err, "unable to retrieve the security group names")
+		}
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Validating the permissions to open and close the internal ports")
+
+	err = checkPermissions(
+		permissionCheck{"ec2:AuthorizeSecurityGroupIngress", func() error { return ac.validateCreateSecGroupRule(ctx, vpcID) }},
+		permissionCheck{"ec2:RevokeSecurityGroupIngress", func() error { return ac.validateDeleteSecGroupRule(ctx, vpcID) }},
+	)
+	if err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to open and close the internal ports")
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.validate(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start(messageRetrieveVPCID)
+	defer status.End()
+
+	vpcID, err := d.aws.getVpcID(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the VPC ID")
+	}
+
+	if _, found := d.aws.cloudConfig[VPCIDKey]; !found {
+		err = d.aws.setSuffixes(ctx, vpcID)
+		if err != nil {
+			return status.Error(err, "unable to retrieve the security group names")
+		}
+	}
+
+	status.Success(messageRetrievedVPCID, vpcID)
+
+	status.Start("Validating the permissions to deploy and clean up the gateways")
+
+	publicSubnets, found, err := d.aws.getConfiguredPublicSubnets(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the configured public subnets")
+	}
+
+	if !found {
+		publicSubnets, err = d.aws.findPublicSubnets(ctx, vpcID, d.aws.filterByName("{infraID}*-public-{region}*"))
+		if err != nil {
+			return status.Error(err, "unable to find public subnets")
+		}
+	}
+
+	checks := []permissionCheck{
+		{"ec2:CreateSecurityGroup", func() error { return d.aws.validateCreateSecGroup(ctx, vpcID) }},
+		{"ec2:AuthorizeSecurityGroupIngress", func() error { return d.aws.validateCreateSecGroupRule(ctx, vpcID) }},
+		{"ec2:DeleteSecurityGroup", func() error { return d.aws.validateDeleteSecGroup(ctx, vpcID) }},
+		{"ec2:DescribeInstanceTypeOfferings", func() error { return d.aws.validateDescribeInstanceTypeOfferings(ctx) }},
+	}
+
+	if len(publicSubnets) > 0 {
+		checks = append(checks,
+			permissionCheck{"ec2:CreateTags", func() error { return d.aws.validateCreateTag(ctx, *publicSubnets[0].SubnetId) }},
+			permissionCheck{"ec2:DeleteTags", func() error { return d.aws.validateRemoveTag(ctx, publicSubnets[0].SubnetId) }})
+	}
+
+	err = checkPermissions(checks...)
+	if err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to deploy and clean up the gateways")
+
+	return nil
+}
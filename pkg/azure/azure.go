@@ -19,11 +19,13 @@ limitations under the License.
 package azure
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
+	"github.com/pkg/errors"
 	reporterInterface "github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 )
 
 type azureCloud struct {
@@ -38,6 +40,18 @@ func NewCloud(info *CloudInfo) api.Cloud {
 }
 
 func (az *azureCloud) OpenPorts(ports []api.PortSpec, reporter reporterInterface.Interface) error {
+	return az.OpenPortsWithContext(context.TODO(), ports, reporter)
+}
+
+func (az *azureCloud) OpenPortsWithContext(ctx context.Context, ports []api.PortSpec, reporter reporterInterface.Interface) error {
+	return classifyError(az.openPorts(ctx, ports, reporter))
+}
+
+func (az *azureCloud) openPorts(ctx context.Context, ports []api.PortSpec, reporter reporterInterface.Interface) error {
+	if err := api.ValidatePorts(ports); err != nil {
+		return errors.WithMessage(err, "invalid internal ports")
+	}
+
 	reporter.Start("Opening internal ports for intra-cluster communications on Azure")
 
 	nsgClient, err := az.getNsgClient()
@@ -45,7 +59,7 @@ func (az *azureCloud) OpenPorts(ports []api.PortSpec, reporter reporterInterface
 		return reporter.Error(err, "Failed to get network security groups client")
 	}
 
-	if err := az.openInternalPorts(az.InfraID, ports, nsgClient); err != nil {
+	if err := az.openInternalPorts(ctx, az.InfraID, ports, nsgClient); err != nil {
 		return reporter.Error(err, "Failed to open internal ports")
 	}
 
@@ -55,14 +69,37 @@ func (az *azureCloud) OpenPorts(ports []api.PortSpec, reporter reporterInterface
 }
 
 func (az *azureCloud) ClosePorts(reporter reporterInterface.Interface) error {
+	return az.ClosePortsWithContext(context.TODO(), reporter)
+}
+
+func (az *azureCloud) ClosePortsWithContext(ctx context.Context, reporter reporterInterface.Interface) error {
+	return classifyError(az.closePorts(ctx, reporter))
+}
+
+func (az *azureCloud) closePorts(ctx context.Context, reporter reporterInterface.Interface) error {
 	reporter.Start("Revoking intra-cluster communication permissions")
 
+	recorded, err := az.Ledger.Recorded(ctx, ProviderName, ledger.OpenPorts)
+	if err != nil {
+		return reporter.Error(err, "Failed to read the ledger")
+	}
+
+	if recorded {
+		if err := az.Ledger.Undo(ctx, ProviderName, ledger.OpenPorts, az.undo); err != nil {
+			return reporter.Error(err, "Failed to revoke intra-cluster communication permissions")
+		}
+
+		reporter.Success("Revoked intra-cluster communication permissions")
+
+		return nil
+	}
+
 	nsgClient, err := az.getNsgClient()
 	if err != nil {
 		return reporter.Error(err, "Failed to get network security groups client")
 	}
 
-	if err := az.removeInternalFirewallRules(az.InfraID, nsgClient); err != nil {
+	if err := az.removeInternalFirewallRules(ctx, az.InfraID, nsgClient); err != nil {
 		return reporter.Error(err, "Failed to revoke intra-cluster communication permissions")
 	}
 
@@ -74,7 +111,7 @@ func (az *azureCloud) ClosePorts(reporter reporterInterface.Interface) error {
 func formatPorts(ports []api.PortSpec) string {
 	portStrs := []string{}
 	for _, port := range ports {
-		portStrs = append(portStrs, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+		portStrs = append(portStrs, port.String())
 	}
 
 	return strings.Join(portStrs, ", ")
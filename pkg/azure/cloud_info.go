@@ -30,6 +30,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"k8s.io/utils/ptr"
 )
 
@@ -39,9 +40,13 @@ const (
 	internalSecurityRulePrefix        = "Submariner-Internal-"
 	externalSecurityRulePrefix        = "Submariner-External-"
 	publicIPNameSuffix                = "-pub"
+	publicIPv6NameSuffix              = "-pub-v6"
+	ipv6SecurityRuleInfix             = "IPv6-"
 	allNetworkCIDR                    = "0.0.0.0/0"
 	basePriorityInternal        int32 = 2500
 	baseExternalInternal        int32 = 3500
+	ipv6PriorityOffset          int32 = 500
+	operationTimeout                  = 300 * time.Second
 )
 
 type CloudInfo struct {
@@ -51,6 +56,21 @@ type CloudInfo struct {
 	BaseGroupName   string
 	TokenCredential azcore.TokenCredential
 	K8sClient       k8s.Interface
+
+	// IPFamily specifies the IP families the security rules and gateway public IPs are created for.
+	IPFamily api.IPFamily
+
+	// Tags are applied to the security groups and public IPs, created or existing, and to the gateway virtual machines
+	// created.
+	Tags map[string]string
+
+	// Ledger, if set, records the security groups and rules, public IPs, interface changes and machine sets. ClosePorts and
+	// Cleanup then undo exactly what it lists instead of looking the objects up by name, unless it lists nothing for them.
+	Ledger *ledger.Ledger
+}
+
+func (c *CloudInfo) k8sClient() k8s.ContextInterface {
+	return k8s.WithContext(c.K8sClient)
 }
 
 //nolint:wrapcheck // Let the caller wrap it.
@@ -58,6 +78,11 @@ func (c *CloudInfo) getNsgClient() (*armnetwork.SecurityGroupsClient, error) {
 	return armnetwork.NewSecurityGroupsClient(c.SubscriptionID, c.TokenCredential, nil)
 }
 
+//nolint:wrapcheck // Let the caller wrap it.
+func (c *CloudInfo) getSecurityRulesClient() (*armnetwork.SecurityRulesClient, error) {
+	return armnetwork.NewSecurityRulesClient(c.SubscriptionID, c.TokenCredential, nil)
+}
+
 //nolint:wrapcheck // Let the caller wrap it.
 func (c *CloudInfo) getInterfacesClient() (*armnetwork.InterfacesClient, error) {
 	return armnetwork.NewInterfacesClient(c.SubscriptionID, c.TokenCredential, nil)
@@ -73,10 +98,12 @@ func (c *CloudInfo) getResourceSKUClient() (*armcompute.ResourceSKUsClient, erro
 	return armcompute.NewResourceSKUsClient(c.SubscriptionID, c.TokenCredential, nil)
 }
 
-func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec, nsgClient *armnetwork.SecurityGroupsClient) error {
+func (c *CloudInfo) openInternalPorts(ctx context.Context, infraID string, ports []api.PortSpec,
+	nsgClient *armnetwork.SecurityGroupsClient,
+) error {
 	groupName := infraID + internalSecurityGroupSuffix
 
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
 	nwSecurityGroup, err := nsgClient.Get(ctx, c.BaseGroupName, groupName, nil)
@@ -93,14 +120,23 @@ func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec, nsgC
 		return nil
 	}
 
+	var entries []ledger.Entry
+
 	for i, port := range ports {
 		p := int32(i) //nolint:gosec // Ignore integer overflow conversion
 
-		nwSecurityGroup.Properties.SecurityRules = append(nwSecurityGroup.Properties.SecurityRules,
-			c.createSecurityRule(internalSecurityRulePrefix, armnetwork.SecurityRuleProtocol(port.Protocol), port.Port,
-				basePriorityInternal+p, armnetwork.SecurityRuleDirectionInbound),
-			c.createSecurityRule(internalSecurityRulePrefix, armnetwork.SecurityRuleProtocol(port.Protocol), port.Port,
-				basePriorityInternal+p, armnetwork.SecurityRuleDirectionOutbound))
+		rules := c.createSecurityRules(internalSecurityRulePrefix, port, basePriorityInternal+p, c.anyCIDRs())
+		for _, rule := range rules {
+			entries = append(entries, ledger.Entry{
+				Provider:  ProviderName,
+				Operation: ledger.OpenPorts,
+				Resource:  api.SecurityGroupRuleResource,
+				ID:        *rule.Name,
+				Parent:    groupName,
+			})
+		}
+
+		nwSecurityGroup.Properties.SecurityRules = append(nwSecurityGroup.Properties.SecurityRules, rules...)
 	}
 
 	poller, err := nsgClient.BeginCreateOrUpdate(ctx, c.BaseGroupName, groupName, nwSecurityGroup.SecurityGroup, nil)
@@ -109,14 +145,18 @@ func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec, nsgC
 	}
 
 	_, err = poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "error updating  security group %q with submariner rules", groupName)
+	}
 
-	return errors.Wrapf(err, "error updating  security group %q with submariner rules", groupName)
+	return c.Ledger.Record(ctx, entries...)
 }
 
-func (c *CloudInfo) removeInternalFirewallRules(infraID string, nsgClient *armnetwork.SecurityGroupsClient) error {
+func (c *CloudInfo) removeInternalFirewallRules(ctx context.Context, infraID string, nsgClient *armnetwork.SecurityGroupsClient,
+) error {
 	groupName := infraID + internalSecurityGroupSuffix
 
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
 	nwSecurityGroup, err := nsgClient.Get(ctx, c.BaseGroupName, groupName, nil)
@@ -158,49 +198,173 @@ func checkIfSecurityRulesPresent(securityRules []*armnetwork.SecurityRule) bool
 	return false
 }
 
-func (c *CloudInfo) createSecurityRule(securityRulePrfix string, protocol armnetwork.SecurityRuleProtocol, port uint16, priority int32,
-	ruleDirection armnetwork.SecurityRuleDirection,
+// createSecurityRules creates the inbound and outbound rules for the given port for each IP family of the given source
+// CIDRs, the inbound rules only allowing the source CIDRs of their family. The IPv6 rules are named and prioritized apart
+// from the IPv4 ones as rule names and priorities must be unique.
+func (c *CloudInfo) createSecurityRules(prefix string, port api.PortSpec, priority int32, sourceCIDRs []string,
+) []*armnetwork.SecurityRule {
+	securityRules := []*armnetwork.SecurityRule{}
+
+	for _, family := range []api.IPFamily{api.IPv4, api.IPv6} {
+		cidrs := api.FilterCIDRs(sourceCIDRs, family)
+		if len(cidrs) == 0 {
+			continue
+		}
+
+		rulePrefix, rulePriority := prefix, priority
+		if family == api.IPv6 {
+			rulePrefix += ipv6SecurityRuleInfix
+			rulePriority += ipv6PriorityOffset
+		}
+
+		securityRules = append(securityRules,
+			c.createSecurityRule(rulePrefix, port, rulePriority, armnetwork.SecurityRuleDirectionInbound, cidrs),
+			c.createSecurityRule(rulePrefix, port, rulePriority, armnetwork.SecurityRuleDirectionOutbound, []string{family.AnyCIDR()}))
+	}
+
+	return securityRules
+}
+
+// anyCIDRs returns the CIDRs matching any address of the configured IP families.
+func (c *CloudInfo) anyCIDRs() []string {
+	cidrs := []string{}
+	for _, family := range c.IPFamily.Families() {
+		cidrs = append(cidrs, family.AnyCIDR())
+	}
+
+	return cidrs
+}
+
+// createSecurityRule creates a rule for the given port, restricting the remote end (the source of inbound traffic or
+// the destination of outbound traffic) to the given CIDRs.
+func (c *CloudInfo) createSecurityRule(securityRulePrfix string, port api.PortSpec, priority int32,
+	ruleDirection armnetwork.SecurityRuleDirection, remoteCIDRs []string,
 ) *armnetwork.SecurityRule {
 	access := armnetwork.SecurityRuleAccessAllow
+	protocol := securityRuleProtocol(port)
+
+	// Single port rules keep the "<protocol>-<port>" naming used before port ranges were supported.
+	name := securityRulePrfix + port.Protocol + "-"
+	portRange := "*"
+
+	if !port.IsPortless() {
+		from, to := port.PortRange()
+		portRange = strconv.Itoa(int(from)) + "-" + strconv.Itoa(int(to))
+
+		if from == to {
+			name += strconv.Itoa(int(from)) + "-"
+		} else {
+			name += portRange + "-"
+		}
+	}
 
-	return &armnetwork.SecurityRule{
-		Name: ptr.To(securityRulePrfix + string(protocol) + "-" + strconv.Itoa(int(port)) + "-" + string(ruleDirection)),
+	// The local end allows any address of the remote CIDRs' family.
+	localCIDR := allNetworkCIDR
+	if len(remoteCIDRs) > 0 {
+		localCIDR = api.CIDRFamily(remoteCIDRs[0]).AnyCIDR()
+	}
+
+	rule := &armnetwork.SecurityRule{
+		Name: ptr.To(name + string(ruleDirection)),
 		Properties: &armnetwork.SecurityRulePropertiesFormat{
 			Protocol:                 &protocol,
-			DestinationPortRange:     ptr.To(strconv.Itoa(int(port)) + "-" + strconv.Itoa(int(port))),
-			SourceAddressPrefix:      ptr.To(allNetworkCIDR),
-			DestinationAddressPrefix: ptr.To(allNetworkCIDR),
+			DestinationPortRange:     ptr.To(portRange),
+			SourceAddressPrefix:      ptr.To(localCIDR),
+			DestinationAddressPrefix: ptr.To(localCIDR),
 			SourcePortRange:          ptr.To("*"),
 			Access:                   &access,
 			Direction:                &ruleDirection,
 			Priority:                 ptr.To(priority),
 		},
 	}
+
+	// A single prefix must be set through the singular field, multiple ones through the plural field.
+	var remotePrefix *string
+	var remotePrefixes []*string
+
+	if len(remoteCIDRs) == 1 {
+		remotePrefix = ptr.To(remoteCIDRs[0])
+	} else {
+		for i := range remoteCIDRs {
+			remotePrefixes = append(remotePrefixes, ptr.To(remoteCIDRs[i]))
+		}
+	}
+
+	if ruleDirection == armnetwork.SecurityRuleDirectionInbound {
+		rule.Properties.SourceAddressPrefix = remotePrefix
+		rule.Properties.SourceAddressPrefixes = remotePrefixes
+	} else {
+		rule.Properties.DestinationAddressPrefix = remotePrefix
+		rule.Properties.DestinationAddressPrefixes = remotePrefixes
+	}
+
+	return rule
+}
+
+// securityRuleProtocol returns the Azure protocol for the given port spec, matching the protocol name case-insensitively.
+func securityRuleProtocol(port api.PortSpec) armnetwork.SecurityRuleProtocol {
+	for _, protocol := range armnetwork.PossibleSecurityRuleProtocolValues() {
+		if strings.EqualFold(string(protocol), port.Protocol) {
+			return protocol
+		}
+	}
+
+	return armnetwork.SecurityRuleProtocol(port.Protocol)
 }
 
-func (c *CloudInfo) createGWSecurityGroup(groupName string, ports []api.PortSpec, nsgClient *armnetwork.SecurityGroupsClient) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+func (c *CloudInfo) createGWSecurityGroup(ctx context.Context, groupName string, ports []api.PortSpec, sourceCIDRs []string,
+	nsgClient *armnetwork.SecurityGroupsClient,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
-	isFound := c.checkIfSecurityGroupPresent(ctx, groupName, nsgClient)
-	if isFound {
-		return nil
+	err := c.createOrUpdateGWSecurityGroup(ctx, groupName, ports, sourceCIDRs, nsgClient)
+	if err != nil {
+		return err
 	}
 
-	securityRules := []*armnetwork.SecurityRule{}
+	return c.Ledger.Record(ctx, c.deployEntry(api.SecurityGroupResource, groupName, ""))
+}
 
-	for i, port := range ports {
-		p := int32(i) //nolint:gosec // Ignore integer overflow conversion
-		securityRules = append(securityRules,
-			c.createSecurityRule(externalSecurityRulePrefix, armnetwork.SecurityRuleProtocol(port.Protocol), port.Port,
-				baseExternalInternal+p, armnetwork.SecurityRuleDirectionInbound),
-			c.createSecurityRule(externalSecurityRulePrefix, armnetwork.SecurityRuleProtocol(port.Protocol), port.Port,
-				baseExternalInternal+p, armnetwork.SecurityRuleDirectionOutbound))
+func (c *CloudInfo) createOrUpdateGWSecurityGroup(ctx context.Context, groupName string, ports []api.PortSpec, sourceCIDRs []string,
+	nsgClient *armnetwork.SecurityGroupsClient,
+) error {
+	securityRules := c.gwSecurityRules(ports, sourceCIDRs)
+
+	existing, err := nsgClient.Get(ctx, c.BaseGroupName, groupName, nil)
+	if err == nil {
+		// The existing group's Submariner rules are replaced so that changes to the public ports or their source CIDRs
+		// are applied.
+		if existing.Properties == nil {
+			existing.Properties = &armnetwork.SecurityGroupPropertiesFormat{}
+		}
+
+		plan := &api.Plan{}
+
+		existing.Properties.SecurityRules, _ = reconcileSecurityRules(plan, groupName, externalSecurityRulePrefix,
+			existing.Properties.SecurityRules, securityRules)
+
+		var tagsChanged bool
+
+		existing.Tags, tagsChanged = c.mergeResourceTags(existing.Tags)
+		if plan.IsEmpty() && !tagsChanged {
+			return nil
+		}
+
+		poller, err := nsgClient.BeginCreateOrUpdate(ctx, c.BaseGroupName, groupName, existing.SecurityGroup, nil)
+		if err != nil {
+			return errors.Wrapf(err, "updating security group %q failed", groupName)
+		}
+
+		_, err = poller.PollUntilDone(ctx, nil)
+
+		return errors.Wrapf(err, "error updating security group %q", groupName)
 	}
 
 	nwSecurityGroup := armnetwork.SecurityGroup{
 		Name:     &groupName,
 		Location: ptr.To(c.Region),
+		Tags:     c.resourceTags(),
 		Properties: &armnetwork.SecurityGroupPropertiesFormat{
 			SecurityRules: securityRules,
 		},
@@ -216,10 +380,22 @@ func (c *CloudInfo) createGWSecurityGroup(groupName string, ports []api.PortSpec
 	return errors.Wrapf(err, "Error creating  security group %v ", groupName)
 }
 
-func (c *CloudInfo) prepareGWInterface(nodeName, groupName string, nsgClient *armnetwork.SecurityGroupsClient,
-	nwClient *armnetwork.InterfacesClient, pubIPClient *armnetwork.PublicIPAddressesClient,
+// gwSecurityRules returns the rules of the gateway security group for the given public ports and source CIDRs.
+func (c *CloudInfo) gwSecurityRules(ports []api.PortSpec, sourceCIDRs []string) []*armnetwork.SecurityRule {
+	securityRules := []*armnetwork.SecurityRule{}
+
+	for i, port := range ports {
+		p := int32(i) //nolint:gosec // Ignore integer overflow conversion
+		securityRules = append(securityRules, c.createSecurityRules(externalSecurityRulePrefix, port, baseExternalInternal+p, sourceCIDRs)...)
+	}
+
+	return securityRules
+}
+
+func (c *CloudInfo) prepareGWInterface(ctx context.Context, nodeName, groupName string, family api.IPFamily,
+	nsgClient *armnetwork.SecurityGroupsClient, nwClient *armnetwork.InterfacesClient, pubIPClient *armnetwork.PublicIPAddressesClient,
 ) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
 	nwSecurityGroup, err := nsgClient.Get(ctx, c.BaseGroupName, groupName, nil)
@@ -227,14 +403,21 @@ func (c *CloudInfo) prepareGWInterface(nodeName, groupName string, nsgClient *ar
 		return errors.Wrapf(err, "error getting the submariner gateway security group %q", groupName)
 	}
 
-	publicIPName := nodeName + publicIPNameSuffix
-
-	pubIP, err := c.getPublicIP(ctx, publicIPName, pubIPClient)
+	// Azure interfaces always have a primary IPv4 configuration, so the IPv4 public IP is needed even for IPv6.
+	pubIP, err := c.getOrCreatePublicIP(ctx, nodeName+publicIPNameSuffix, armnetwork.IPVersionIPv4, pubIPClient)
 	if err != nil {
-		pubIP, err = c.createPublicIP(ctx, publicIPName, pubIPClient)
+		return err
+	}
+
+	var pubIPv6 *armnetwork.PublicIPAddress
+
+	if family.HasIPv6() {
+		ip, err := c.getOrCreatePublicIP(ctx, nodeName+publicIPv6NameSuffix, armnetwork.IPVersionIPv6, pubIPClient)
 		if err != nil {
-			return errors.Wrapf(err, "failed to create public IP %q", publicIPName)
+			return err
 		}
+
+		pubIPv6 = &ip
 	}
 
 	interfaceName := nodeName + "-nic"
@@ -250,14 +433,26 @@ func (c *CloudInfo) prepareGWInterface(nodeName, groupName string, nsgClient *ar
 
 	nwInterface.Properties.NetworkSecurityGroup = &nwSecurityGroup.SecurityGroup
 
+	ipv6Assigned := pubIPv6 == nil
+
 	for i := range nwInterface.Properties.IPConfigurations {
 		props := nwInterface.Properties.IPConfigurations[i].Properties
-		if props != nil && props.Primary != nil && *props.Primary {
-			nwInterface.Properties.IPConfigurations[i].Properties.PublicIPAddress = &pubIP
-			break
+		if props == nil {
+			continue
+		}
+
+		if props.Primary != nil && *props.Primary {
+			props.PublicIPAddress = &pubIP
+		} else if !ipv6Assigned && isIPv6Configuration(props) {
+			props.PublicIPAddress = pubIPv6
+			ipv6Assigned = true
 		}
 	}
 
+	if !ipv6Assigned {
+		return errors.Errorf("interface %q has no IPv6 IP configuration to assign the public IP %q to", *nwInterface.Name, *pubIPv6.Name)
+	}
+
 	poller, err := nwClient.BeginCreateOrUpdate(ctx, c.BaseGroupName, *nwInterface.Name, nwInterface.Interface, nil)
 	if err != nil {
 		return errors.Wrapf(err, "adding security group %q and public IP %q to interface %q failed", *nwSecurityGroup.Name,
@@ -265,16 +460,19 @@ func (c *CloudInfo) prepareGWInterface(nodeName, groupName string, nsgClient *ar
 	}
 
 	_, err = poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "updating interface %q failed", *nwInterface.Name)
+	}
 
-	return errors.Wrapf(err, "updating interface %q failed", *nwInterface.Name)
+	return c.Ledger.Record(ctx, c.deployEntry(api.NetworkInterfaceResource, interfaceName, groupName))
 }
 
-func (c *CloudInfo) cleanupGWInterface(infraID string, nsgClient *armnetwork.SecurityGroupsClient,
+func (c *CloudInfo) cleanupGWInterface(ctx context.Context, infraID string, nsgClient *armnetwork.SecurityGroupsClient,
 	nwClient *armnetwork.InterfacesClient,
 ) error {
 	groupName := infraID + externalSecurityGroupSuffix
 
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
 	isFound := c.checkIfSecurityGroupPresent(ctx, groupName, nsgClient)
@@ -347,14 +545,17 @@ func (c *CloudInfo) cleanupGWInterface(infraID string, nsgClient *armnetwork.Sec
 
 func removePublicIP(nwInterfaceIPConfiguration []*armnetwork.InterfaceIPConfiguration) {
 	for i := range nwInterfaceIPConfiguration {
-		if nwInterfaceIPConfiguration[i].Properties != nil && nwInterfaceIPConfiguration[i].Properties.Primary != nil &&
-			*nwInterfaceIPConfiguration[i].Properties.Primary {
-			nwInterfaceIPConfiguration[i].Properties.PublicIPAddress = nil
-			break
+		props := nwInterfaceIPConfiguration[i].Properties
+		if props != nil && ((props.Primary != nil && *props.Primary) || isIPv6Configuration(props)) {
+			props.PublicIPAddress = nil
 		}
 	}
 }
 
+func isIPv6Configuration(props *armnetwork.InterfaceIPConfigurationPropertiesFormat) bool {
+	return props.PrivateIPAddressVersion != nil && *props.PrivateIPAddressVersion == armnetwork.IPVersionIPv6
+}
+
 func (c *CloudInfo) checkIfSecurityGroupPresent(ctx context.Context, groupName string, nsgClient *armnetwork.SecurityGroupsClient) bool {
 	_, err := nsgClient.Get(ctx, c.BaseGroupName, groupName, nil)
 
@@ -368,9 +569,30 @@ func (c *CloudInfo) getPublicIP(ctx context.Context, publicIPName string, pubIPC
 	return resp.PublicIPAddress, errors.Wrapf(err, "error getting public ip: %q", publicIPName)
 }
 
-func (c *CloudInfo) createPublicIP(ctx context.Context, ipName string, ipClient *armnetwork.PublicIPAddressesClient,
+func (c *CloudInfo) getOrCreatePublicIP(ctx context.Context, ipName string, ipVersion armnetwork.IPVersion,
+	ipClient *armnetwork.PublicIPAddressesClient,
+) (armnetwork.PublicIPAddress, error) {
+	pubIP, err := c.getPublicIP(ctx, ipName, ipClient)
+	if err != nil {
+		pubIP, err = c.createPublicIP(ctx, ipName, ipVersion, ipClient)
+		if err != nil {
+			return pubIP, errors.Wrapf(err, "failed to create public IP %q", ipName)
+		}
+	} else if tags, changed := c.mergeResourceTags(pubIP.Tags); changed {
+		pubIP.Tags = tags
+
+		pubIP, err = c.updatePublicIP(ctx, &pubIP, ipClient)
+		if err != nil {
+			return pubIP, err
+		}
+	}
+
+	return pubIP, c.Ledger.Record(ctx, c.deployEntry(api.PublicIPResource, ipName, ""))
+}
+
+func (c *CloudInfo) createPublicIP(ctx context.Context, ipName string, ipVersion armnetwork.IPVersion,
+	ipClient *armnetwork.PublicIPAddressesClient,
 ) (ip armnetwork.PublicIPAddress, err error) {
-	ipVersion := armnetwork.IPVersionIPv4
 	ipAllocMethod := armnetwork.IPAllocationMethodStatic
 	skuName := armnetwork.PublicIPAddressSKUNameStandard
 
@@ -388,6 +610,7 @@ func (c *CloudInfo) createPublicIP(ctx context.Context, ipName string, ipClient
 			SKU: &armnetwork.PublicIPAddressSKU{
 				Name: &skuName,
 			},
+			Tags: c.resourceTags(),
 		}, nil)
 	if err != nil {
 		return ip, errors.Wrapf(err, "cannot create public ip address: %q", ipName)
@@ -401,6 +624,58 @@ func (c *CloudInfo) createPublicIP(ctx context.Context, ipName string, ipClient
 	return resp.PublicIPAddress, nil
 }
 
+func (c *CloudInfo) updatePublicIP(ctx context.Context, pubIP *armnetwork.PublicIPAddress, ipClient *armnetwork.PublicIPAddressesClient,
+) (armnetwork.PublicIPAddress, error) {
+	ipName := ptr.Deref(pubIP.Name, "")
+
+	poller, err := ipClient.BeginCreateOrUpdate(ctx, c.BaseGroupName, ipName, *pubIP, nil)
+	if err != nil {
+		return *pubIP, errors.Wrapf(err, "cannot update public ip address: %q", ipName)
+	}
+
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return *pubIP, errors.Wrapf(err, "cannot get public ip address update response: %q", ipName)
+	}
+
+	return resp.PublicIPAddress, nil
+}
+
+// resourceTags returns the user tags in the form expected by the Azure resources, or nil if there are none.
+func (c *CloudInfo) resourceTags() map[string]*string {
+	if len(c.Tags) == 0 {
+		return nil
+	}
+
+	tags := make(map[string]*string, len(c.Tags))
+	for key, value := range c.Tags {
+		tags[key] = ptr.To(value)
+	}
+
+	return tags
+}
+
+// mergeResourceTags returns the given tags of an existing resource with the user tags added or updated, and whether that
+// changed them. The other tags of the resource are kept.
+func (c *CloudInfo) mergeResourceTags(tags map[string]*string) (map[string]*string, bool) {
+	changed := false
+
+	for key, value := range c.Tags {
+		if existing, found := tags[key]; found && ptr.Deref(existing, "") == value {
+			continue
+		}
+
+		if tags == nil {
+			tags = make(map[string]*string, len(c.Tags))
+		}
+
+		tags[key] = ptr.To(value)
+		changed = true
+	}
+
+	return tags, changed
+}
+
 func (c *CloudInfo) deletePublicIP(ctx context.Context, ipClient *armnetwork.PublicIPAddressesClient, ipName string) (err error) {
 	poller, err := ipClient.BeginDelete(ctx, c.BaseGroupName, ipName, nil)
 	if err != nil {
@@ -411,3 +686,17 @@ func (c *CloudInfo) deletePublicIP(ctx context.Context, ipClient *armnetwork.Pub
 
 	return errors.Wrapf(err, "failed to delete public ip : %q", ipName)
 }
+
+// deletePublicIPs deletes the IPv4 and, if any, IPv6 public IPs of the given node.
+func (c *CloudInfo) deletePublicIPs(ctx context.Context, ipClient *armnetwork.PublicIPAddressesClient, nodeName string) error {
+	if err := c.deletePublicIP(ctx, ipClient, nodeName+publicIPNameSuffix); err != nil {
+		return err
+	}
+
+	// Check for the IPv6 public IP whatever the configured IP family, as the gateways may have been deployed with another one.
+	if _, err := c.getPublicIP(ctx, nodeName+publicIPv6NameSuffix, ipClient); err != nil {
+		return nil //nolint:nilerr // There's no IPv6 public IP to delete.
+	}
+
+	return c.deletePublicIP(ctx, ipClient, nodeName+publicIPv6NameSuffix)
+}
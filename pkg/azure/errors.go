@@ -0,0 +1,65 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+func classifyError(err error) error {
+	return api.ClassifyError(ProviderName, err, errorKind)
+}
+
+// errorKind maps the Azure Resource Manager errors, by error code for quotas and otherwise by HTTP status code.
+func errorKind(err error) error {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return nil
+	}
+
+	code := strings.ToLower(respErr.ErrorCode)
+	if strings.Contains(code, "quota") || strings.HasSuffix(code, "limitreached") || strings.HasSuffix(code, "limitexceeded") {
+		return api.ErrQuotaExceeded
+	}
+
+	switch respErr.StatusCode {
+	case http.StatusNotFound:
+		return api.ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return api.ErrPermissionDenied
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return api.ErrConflict
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return api.ErrTransient
+	}
+
+	return nil
+}
+
+func isNotFoundError(err error) bool {
+	var respErr *azcore.ResponseError
+
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,314 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/hypershift"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type hostedPlatform struct {
+	CloudInfo
+	azure        *azureCloud
+	instanceType string
+}
+
+// NewHostedPlatform returns the hypershift.Platform preparing Azure for the gateway NodePool of a hosted cluster: the
+// gateway network security group is created, and attached with public IPs to the interfaces of the gateway nodes.
+// As the NodePool nodes only join the hosted cluster after it is created, the preparation is re-run to configure them.
+// If instanceType is empty, the VM size of the worker NodePool is used.
+// If the supplied cloud is not an azureCloud, an error is returned.
+func NewHostedPlatform(info *CloudInfo, cloud api.Cloud, instanceType string) (hypershift.Platform, error) {
+	azure, ok := cloud.(*azureCloud)
+	if !ok {
+		return nil, errors.New("the cloud must be Azure")
+	}
+
+	return &hostedPlatform{
+		CloudInfo:    *info,
+		azure:        azure,
+		instanceType: instanceType,
+	}, nil
+}
+
+func (p *hostedPlatform) PrepareGateways(ctx context.Context, input api.GatewayDeployInput, platform map[string]interface{},
+	status reporter.Interface,
+) error {
+	return classifyError(p.prepareGateways(ctx, input, platform, status))
+}
+
+func (p *hostedPlatform) prepareGateways(ctx context.Context, input api.GatewayDeployInput, platform map[string]interface{},
+	status reporter.Interface,
+) error {
+	if err := api.ValidatePorts(input.PublicPorts); err != nil {
+		return errors.WithMessage(err, "invalid public ports")
+	}
+
+	if input.IPFamily == "" {
+		input.IPFamily = p.IPFamily
+	}
+
+	status.Start("Preparing the gateway security group and nodes")
+	defer status.End()
+
+	nsgClient, err := p.getNsgClient()
+	if err != nil {
+		return status.Error(err, "Failed to get network security groups client")
+	}
+
+	nwClient, err := p.getInterfacesClient()
+	if err != nil {
+		return status.Error(err, "Failed to get network interfaces client")
+	}
+
+	pubIPClient, err := p.getPublicIPClient()
+	if err != nil {
+		return status.Error(err, "Failed to get network public IP addresses client")
+	}
+
+	groupName := p.InfraID + externalSecurityGroupSuffix
+
+	if err := p.createGWSecurityGroup(ctx, groupName, input.PublicPorts, input.PublicSourceCIDRs(), nsgClient); err != nil {
+		return status.Error(err, "creating gateway security group failed")
+	}
+
+	gwNodes, err := p.azure.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return status.Error(err, "error getting the gateway nodes")
+	}
+
+	for i := range gwNodes.Items {
+		if err = p.prepareGWInterface(ctx, gwNodes.Items[i].GetName(), groupName, input.IPFamily, nsgClient, nwClient, pubIPClient); err != nil {
+			return status.Error(err, "failed to open the Submariner gateway port for node %q", gwNodes.Items[i].GetName())
+		}
+	}
+
+	status.Success("Prepared the gateway security group and %d gateway node(s)", len(gwNodes.Items))
+
+	platform["type"] = "Azure"
+
+	if p.instanceType != "" {
+		return errors.Wrap(unstructured.SetNestedField(platform, p.instanceType, "azure", "vmSize"), "error setting the VM size")
+	}
+
+	return nil
+}
+
+func (p *hostedPlatform) PlanPrepareGateways(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface,
+) (*api.Plan, error) {
+	plan, err := p.planPrepareGateways(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (p *hostedPlatform) planPrepareGateways(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface,
+) (*api.Plan, error) {
+	if input.IPFamily == "" {
+		input.IPFamily = p.IPFamily
+	}
+
+	status.Start("Retrieving the gateway security group and nodes")
+	defer status.End()
+
+	nsgClient, err := p.getNsgClient()
+	if err != nil {
+		return nil, status.Error(err, "Failed to get network security groups client")
+	}
+
+	pubIPClient, err := p.getPublicIPClient()
+	if err != nil {
+		return nil, status.Error(err, "Failed to get network public IP addresses client")
+	}
+
+	gwNodes, err := p.azure.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway nodes")
+	}
+
+	status.Success("Retrieved %d gateway node(s)", len(gwNodes.Items))
+
+	plan := &api.Plan{}
+	groupName := p.InfraID + externalSecurityGroupSuffix
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	p.planGWSecurityGroup(timeoutCtx, plan, groupName, input.PublicPorts, input.PublicSourceCIDRs(), nsgClient)
+
+	for i := range gwNodes.Items {
+		nodeName := gwNodes.Items[i].GetName()
+		publicIPNames := []string{nodeName + publicIPNameSuffix}
+
+		if input.IPFamily.HasIPv6() {
+			publicIPNames = append(publicIPNames, nodeName+publicIPv6NameSuffix)
+		}
+
+		for _, publicIPName := range publicIPNames {
+			if _, err := p.getPublicIP(timeoutCtx, publicIPName, pubIPClient); err != nil {
+				plan.Add(api.ChangeCreate, api.PublicIPResource, publicIPName, "for gateway node %q", nodeName)
+			}
+		}
+
+		plan.Add(api.ChangeUpdate, api.NetworkInterfaceResource, nodeName+"-nic", "attach security group %q and public IPs %q",
+			groupName, strings.Join(publicIPNames, ", "))
+	}
+
+	return plan, nil
+}
+
+func (p *hostedPlatform) CleanupGateways(ctx context.Context, status reporter.Interface) error {
+	return classifyError(p.cleanupGateways(ctx, status))
+}
+
+func (p *hostedPlatform) cleanupGateways(ctx context.Context, status reporter.Interface) error {
+	status.Start("Removing the gateway security group and public IPs")
+	defer status.End()
+
+	recorded, err := p.Ledger.Recorded(ctx, ProviderName, ledger.Deploy)
+	if err != nil {
+		return status.Error(err, "reading the ledger failed")
+	}
+
+	if recorded {
+		if err := p.Ledger.Undo(ctx, ProviderName, ledger.Deploy, p.undo); err != nil {
+			return status.Error(err, "removing the recorded gateway resources failed")
+		}
+
+		status.Success("Removed the gateway security group and public IPs")
+
+		return nil
+	}
+
+	nsgClient, err := p.getNsgClient()
+	if err != nil {
+		return status.Error(err, "Failed to get network security groups client")
+	}
+
+	nwClient, err := p.getInterfacesClient()
+	if err != nil {
+		return status.Error(err, "Failed to get network interfaces client")
+	}
+
+	pubIPClient, err := p.getPublicIPClient()
+	if err != nil {
+		return status.Error(err, "Failed to get network public IP addresses client")
+	}
+
+	gwNodes, err := p.azure.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	if err := p.cleanupGWInterface(ctx, p.InfraID, nsgClient, nwClient); err != nil {
+		return status.Error(err, "deleting gateway security group failed")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	for i := range gwNodes.Items {
+		if err := p.deletePublicIPs(ctx, pubIPClient, gwNodes.Items[i].Name); err != nil {
+			return status.Error(err, "failed to delete the public IPs of %q", gwNodes.Items[i].Name)
+		}
+	}
+
+	status.Success("Removed the gateway security group and public IPs")
+
+	return nil
+}
+
+func (p *hostedPlatform) PlanCleanupGateways(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := p.planCleanupGateways(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (p *hostedPlatform) planCleanupGateways(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the gateway security group and nodes")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	recorded, err := p.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.Deploy, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "reading the ledger failed")
+	}
+
+	if recorded {
+		status.Success("Retrieved the recorded gateway resources")
+
+		return plan, nil
+	}
+
+	nsgClient, err := p.getNsgClient()
+	if err != nil {
+		return nil, status.Error(err, "Failed to get network security groups client")
+	}
+
+	pubIPClient, err := p.getPublicIPClient()
+	if err != nil {
+		return nil, status.Error(err, "Failed to get network public IP addresses client")
+	}
+
+	gwNodes, err := p.azure.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	groupName := p.InfraID + externalSecurityGroupSuffix
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	if p.checkIfSecurityGroupPresent(timeoutCtx, groupName, nsgClient) {
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	}
+
+	for i := range gwNodes.Items {
+		p.planDeletePublicIPs(timeoutCtx, plan, gwNodes.Items[i].Name, pubIPClient)
+	}
+
+	status.Success("Retrieved the gateway security group and nodes")
+
+	return plan, nil
+}
+
+func (p *hostedPlatform) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(p.validate(ctx, status))
+}
+
+func (p *hostedPlatform) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start("Validating the permissions to prepare and clean up the gateway nodes")
+	defer status.End()
+
+	if err := p.validatePermissions(ctx, api.PrepareHostedGatewaysMode, api.CleanupHostedGatewaysMode); err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to prepare and clean up the gateway nodes")
+
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+)
+
+func (c *CloudInfo) deployEntry(resource api.ResourceType, id, parent string) ledger.Entry {
+	return ledger.Entry{
+		Provider:  ProviderName,
+		Operation: ledger.Deploy,
+		Resource:  resource,
+		ID:        id,
+		Parent:    parent,
+	}
+}
+
+// undo undoes the change recorded in the given ledger entry. Objects which no longer exist are ignored.
+func (c *CloudInfo) undo(ctx context.Context, entry *ledger.Entry) error {
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	var err error
+
+	switch entry.Resource {
+	case api.SecurityGroupRuleResource:
+		err = c.deleteSecurityRule(ctx, entry.Parent, entry.ID)
+	case api.SecurityGroupResource:
+		err = c.deleteSecurityGroup(ctx, entry.ID)
+	case api.PublicIPResource:
+		var ipClient *armnetwork.PublicIPAddressesClient
+
+		ipClient, err = c.getPublicIPClient()
+		if err == nil {
+			err = c.deletePublicIP(ctx, ipClient, entry.ID)
+		}
+	case api.NetworkInterfaceResource:
+		err = c.detachGWInterface(ctx, entry.ID)
+	default:
+		return errors.Errorf("unsupported resource %q", entry.Resource)
+	}
+
+	if isNotFoundError(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *CloudInfo) deleteSecurityRule(ctx context.Context, groupName, ruleName string) error {
+	rulesClient, err := c.getSecurityRulesClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the security rules client")
+	}
+
+	poller, err := rulesClient.BeginDelete(ctx, c.BaseGroupName, groupName, ruleName, nil)
+	if err != nil {
+		return errors.Wrapf(err, "deleting security rule %q from security group %q failed", ruleName, groupName)
+	}
+
+	_, err = poller.PollUntilDone(ctx, nil)
+
+	return errors.Wrapf(err, "deleting security rule %q from security group %q failed", ruleName, groupName)
+}
+
+func (c *CloudInfo) deleteSecurityGroup(ctx context.Context, groupName string) error {
+	nsgClient, err := c.getNsgClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the network security groups client")
+	}
+
+	poller, err := nsgClient.BeginDelete(ctx, c.BaseGroupName, groupName, nil)
+	if err != nil {
+		return errors.Wrapf(err, "deleting security group %q failed", groupName)
+	}
+
+	_, err = poller.PollUntilDone(ctx, nil)
+
+	return errors.Wrapf(err, "deleting security group %q failed", groupName)
+}
+
+// detachGWInterface removes the gateway security group and public IPs from the given interface.
+func (c *CloudInfo) detachGWInterface(ctx context.Context, interfaceName string) error {
+	nwClient, err := c.getInterfacesClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the network interfaces client")
+	}
+
+	nwInterface, err := nwClient.Get(ctx, c.BaseGroupName, interfaceName, nil)
+	if err != nil {
+		return errors.Wrapf(err, "error getting the interface %q", interfaceName)
+	}
+
+	if nwInterface.Properties != nil {
+		nwInterface.Properties.NetworkSecurityGroup = nil
+		removePublicIP(nwInterface.Properties.IPConfigurations)
+	}
+
+	poller, err := nwClient.BeginCreateOrUpdate(ctx, c.BaseGroupName, interfaceName, nwInterface.Interface, nil)
+	if err != nil {
+		return errors.Wrapf(err, "removing the gateway security group from interface %q failed", interfaceName)
+	}
+
+	_, err = poller.PollUntilDone(ctx, nil)
+
+	return errors.Wrapf(err, "updating interface %q failed", interfaceName)
+}
+
+func (d *ocpGatewayDeployer) undo(ctx context.Context, entry *ledger.Entry) error {
+	if entry.Resource != api.MachineSetResource {
+		return d.CloudInfo.undo(ctx, entry)
+	}
+
+	err := d.msDeployer.DeleteByNameWithContext(ctx, entry.ID, entry.Parent)
+	if err != nil {
+		return errors.Wrapf(err, "error deleting machine set %q", entry.ID)
+	}
+
+	pubIPClient, err := d.getPublicIPClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the network public IP addresses client")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	err = d.deletePublicIPs(ctx, pubIPClient, entry.ID)
+	if isNotFoundError(err) {
+		return nil
+	}
+
+	return err
+}
+
+// undoChange returns the planned change undoing the given ledger entry, as undo would.
+func undoChange(entry *ledger.Entry) api.Change {
+	switch entry.Resource {
+	case api.SecurityGroupRuleResource:
+		return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID,
+			Details: fmt.Sprintf("in security group %q", entry.Parent)}
+	case api.NetworkInterfaceResource:
+		return api.Change{Action: api.ChangeUpdate, Resource: entry.Resource, Name: entry.ID,
+			Details: fmt.Sprintf("detach security group %q and the public IP", entry.Parent)}
+	case api.MachineSetResource:
+		return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID, Details: "and its public IPs"}
+	}
+
+	return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID}
+}
@@ -19,20 +19,17 @@ limitations under the License.
 package azure
 
 import (
-	"bytes"
 	"context"
-	"strconv"
-	"text/template"
-	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/utils/ptr"
 	"k8s.io/utils/set"
@@ -47,7 +44,7 @@ const (
 type ocpGatewayDeployer struct {
 	CloudInfo
 	azure        *azureCloud
-	msDeployer   ocp.MachineSetDeployer
+	msDeployer   ocp.ContextMachineSetDeployer
 	instanceType string
 }
 
@@ -63,16 +60,41 @@ func NewOcpGatewayDeployer(info *CloudInfo, cloud api.Cloud, msDeployer ocp.Mach
 	return &ocpGatewayDeployer{
 		CloudInfo:    *info,
 		azure:        azure,
-		msDeployer:   msDeployer,
+		msDeployer:   ocp.WithContext(msDeployer),
 		instanceType: instanceType,
 	}, nil
 }
 
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporter.Interface) error {
+	return d.DeployWithContext(context.TODO(), input, status)
+}
+
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	err := d.deploy(ctx, input, status)
+	if err == nil {
+		err = ocp.DeployHealthChecks(ctx, d.msDeployer, input.HealthCheck, status)
+	}
+
+	if err == nil {
+		err = ocp.WaitForGateways(ctx, d.msDeployer, d.K8sClient, input.WaitTimeout, status)
+	}
+
+	return classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	if err := api.ValidatePorts(input.PublicPorts); err != nil {
+		return errors.WithMessage(err, "invalid public ports")
+	}
+
 	if input.Gateways == 0 {
 		return nil
 	}
 
+	if input.IPFamily == "" {
+		input.IPFamily = d.IPFamily
+	}
+
 	status.Start("Deploying gateway node")
 
 	nsgClient, nwClient, pubIPClient, err := d.getClients(status)
@@ -82,29 +104,34 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 
 	groupName := d.InfraID + externalSecurityGroupSuffix
 
-	machineSets, err := d.msDeployer.List()
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
 	if err != nil {
 		return status.Error(err, "error getting the gateway machinesets")
 	}
 
-	gwNodes, err := d.azure.K8sClient.ListGatewayNodes()
+	gwNodes, err := d.azure.k8sClient().ListGatewayNodesWithContext(ctx)
 	if err != nil {
 		return errors.Wrap(err, "error getting the gateway node")
 	}
 
 	gwNodeItems := gwNodes.Items
-	taggedExistingNodes := ocp.RemoveDuplicates(machineSets, gwNodeItems)
-	gatewayNodesToDeploy := input.Gateways - len(machineSets) - len(taggedExistingNodes)
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodeItems)
+	if err != nil {
+		return status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	gatewayNodesToDeploy := input.Gateways - len(machineSets) - len(mapping.Labeled)
 
 	if len(machineSets) != 0 || gatewayNodesToDeploy != 0 {
-		if err := d.createGWSecurityGroup(groupName, input.PublicPorts, nsgClient); err != nil {
+		if err := d.createGWSecurityGroup(ctx, groupName, input.PublicPorts, input.PublicSourceCIDRs(), nsgClient); err != nil {
 			return status.Error(err, "creating gateway security group failed")
 		}
 	}
 
 	// Open the g/w ports and assign public-ip if not already done for manually tagged nodes if any
 	for i := range gwNodeItems {
-		if err = d.prepareGWInterface(gwNodeItems[i].GetName(), groupName, nsgClient, nwClient, pubIPClient); err != nil {
+		if err = d.prepareGWInterface(ctx, gwNodeItems[i].GetName(), groupName, input.IPFamily, nsgClient, nwClient, pubIPClient); err != nil {
 			return status.Error(err, "failed to open the Submariner gateway port for already existing nodes")
 		}
 	}
@@ -114,20 +141,18 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 		return nil
 	}
 
-	// Currently, we only support increasing the number of Gateway nodes which could be a valid use-case
-	// to convert a non-HA deployment to an HA deployment. We are not supporting decreasing the Gateway
-	// nodes (for now) as it might impact the datapath if we accidentally delete the active GW node.
+	// When decreasing the number of Gateway nodes, only the dedicated ones are removed, and never the active one
+	// as that would impact the datapath.
 	if gatewayNodesToDeploy < 0 {
-		status.Failure("Decreasing the number of Gateway nodes is not currently supported")
-		return nil
+		return d.removeSurplusGateways(ctx, machineSets, mapping, -gatewayNodesToDeploy, pubIPClient, status)
 	}
 
-	image, imageErr := d.msDeployer.GetWorkerNodeImage(nil, d.InfraID)
+	image, imageErr := d.msDeployer.GetWorkerNodeImageWithContext(ctx, nil, d.InfraID)
 	if imageErr != nil {
 		return errors.Wrap(imageErr, "error retrieving worker node image")
 	}
 
-	err = d.deployDedicatedGWNode(machineSets, gatewayNodesToDeploy, input.AirGapped, image, status)
+	err = d.deployDedicatedGWNode(ctx, machineSets, gatewayNodesToDeploy, input.AirGapped, image, status)
 	if err != nil {
 		status.Success("Deployed gateway node")
 	}
@@ -135,18 +160,18 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 	return err
 }
 
-func (d *ocpGatewayDeployer) deployDedicatedGWNode(gwNodes []unstructured.Unstructured, gatewayNodesToDeploy int,
-	airGapped bool, image string, status reporter.Interface,
+func (d *ocpGatewayDeployer) deployDedicatedGWNode(ctx context.Context, gwNodes []unstructured.Unstructured,
+	gatewayNodesToDeploy int, airGapped bool, image string, status reporter.Interface,
 ) error {
-	az, err := d.getAvailabilityZones(gwNodes)
+	az, err := d.getAvailabilityZones(ctx, gwNodes)
 	if err != nil || az.Len() == 0 {
 		return status.Error(err, "error getting the availability zones for region %q", d.Region)
 	}
 
-	for _, zone := range az.UnsortedList() {
+	for _, zone := range az.SortedList() {
 		status.Start("Deploying dedicated gateway node")
 
-		err := d.deployGateway(zone, image, airGapped)
+		err := d.deployGateway(ctx, zone, image, airGapped)
 		if err != nil {
 			return status.Error(err, "error deploying gateway for zone %q", zone)
 		}
@@ -165,67 +190,99 @@ func (d *ocpGatewayDeployer) deployDedicatedGWNode(gwNodes []unstructured.Unstru
 	return nil
 }
 
-type machineSetConfig struct {
-	Name         string
-	AZ           string
-	InfraID      string
-	InstanceType string
-	Region       string
-	Image        string
-	PublicIP     string
-}
-
-func (d *ocpGatewayDeployer) loadGatewayYAML(name, zone, image string, airGapped bool) ([]byte, error) {
-	var buf bytes.Buffer
-
-	tpl, err := template.New("").Parse(machineSetYAML)
-	if err != nil {
-		return nil, errors.Wrap(err, "error parsing machine set YAML")
+// removeSurplusGateways deletes up to count dedicated gateway machine sets and their public IPs, preferring the passive
+// gateways and never deleting the active one.
+func (d *ocpGatewayDeployer) removeSurplusGateways(ctx context.Context, machineSets []unstructured.Unstructured, gwNodes *ocp.GatewayNodes,
+	count int, pubIPClient *armnetwork.PublicIPAddressesClient, status reporter.Interface,
+) error {
+	if len(machineSets) == 0 {
+		status.Warning("None of the %d surplus gateways were removed as the labeled worker nodes are kept", count)
+		return nil
 	}
 
-	tplVars := machineSetConfig{
-		Name:         name,
-		InfraID:      d.azure.InfraID,
-		InstanceType: d.instanceType,
-		Region:       d.azure.Region,
-		AZ:           zone,
-		Image:        image,
-		PublicIP:     strconv.FormatBool(!airGapped),
+	activeNode, err := d.azure.k8sClient().GetActiveGatewayNodeWithContext(ctx)
+	if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+		status.Warning("None of the %d surplus gateways were removed: %v", count, err)
+		return nil
 	}
 
-	err = tpl.Execute(&buf, tplVars)
 	if err != nil {
-		return nil, errors.Wrap(err, "error executing the template")
+		return status.Error(err, "unable to determine the active gateway")
 	}
 
-	return buf.Bytes(), nil
-}
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
 
-func (d *ocpGatewayDeployer) initMachineSet(name, zone, image string, airGapped bool) (*unstructured.Unstructured, error) {
-	gatewayYAML, err := d.loadGatewayYAML(name, zone, image, airGapped)
-	if err != nil {
-		return nil, err
-	}
+	surplus := ocp.SelectSurplusMachineSets(machineSets, gwNodes, activeNode, count)
 
-	unStructDecoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	for i := range surplus {
+		status.Start("Deleting the surplus gateway instance %q", surplus[i].GetName())
 
-	machineSet := &unstructured.Unstructured{}
+		err = d.msDeployer.DeleteByNameWithContext(ctx, surplus[i].GetName(), surplus[i].GetNamespace())
+		if err != nil {
+			return status.Error(err, "error deleting the gateway instance %q", surplus[i].GetName())
+		}
 
-	_, _, err = unStructDecoder.Decode(gatewayYAML, nil, machineSet)
-	if err != nil {
-		return nil, errors.Wrap(err, "error converting YAML to machine set")
+		err = d.deletePublicIPs(ctx, pubIPClient, surplus[i].GetName())
+		if err != nil {
+			return status.Error(err, "failed to delete the public IPs of %q", surplus[i].GetName())
+		}
+
+		err = d.Ledger.Forget(ctx, d.deployEntry(api.MachineSetResource, surplus[i].GetName(), surplus[i].GetNamespace()),
+			d.deployEntry(api.PublicIPResource, surplus[i].GetName()+publicIPNameSuffix, ""),
+			d.deployEntry(api.PublicIPResource, surplus[i].GetName()+publicIPv6NameSuffix, ""))
+		if err != nil {
+			return status.Error(err, "error updating the ledger")
+		}
+
+		status.Success("Deleted the surplus gateway instance %q", surplus[i].GetName())
 	}
 
-	return machineSet, nil
+	if len(surplus) < count {
+		status.Warning("Only %d of the %d surplus gateways were removed as the active gateway and the labeled worker nodes are kept",
+			len(surplus), count)
+	}
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) initMachineSet(name, zone, image string, airGapped bool) (*unstructured.Unstructured, error) {
+	providerSpec := ocp.NewAzureMachineProviderSpec()
+	providerSpec.CredentialsSecret = &ocp.SecretReference{Name: "azure-cloud-credentials", Namespace: ocp.MachineAPINamespace}
+	providerSpec.Image = ocp.AzureImage{ResourceID: image}
+	providerSpec.Location = d.azure.Region
+	providerSpec.ManagedIdentity = d.azure.InfraID + "-identity"
+	providerSpec.NetworkResourceGroup = d.azure.InfraID + "-rg"
+	providerSpec.OSDisk = ocp.AzureOSDisk{
+		DiskSizeGB:  128,
+		ManagedDisk: ocp.AzureManagedDisk{StorageAccountType: "Premium_LRS"},
+		OSType:      "Linux",
+	}
+	providerSpec.PublicIP = !airGapped
+	providerSpec.ResourceGroup = d.azure.InfraID + "-rg"
+	providerSpec.SecurityGroup = d.azure.InfraID + externalSecurityGroupSuffix
+	providerSpec.Subnet = d.azure.InfraID + "-worker-subnet"
+	providerSpec.Tags = d.azure.Tags
+	providerSpec.UserDataSecret = &ocp.SecretReference{Name: "worker-user-data"}
+	providerSpec.VMSize = d.instanceType
+	providerSpec.Vnet = d.azure.InfraID + "-vnet"
+	providerSpec.Zone = zone
+
+	return ocp.NewGatewayMachineSet(d.azure.InfraID, name, providerSpec).WithWorkerRoleLabels().ToUnstructured()
 }
 
-func (d *ocpGatewayDeployer) deployGateway(zone, image string, airGapped bool) error {
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, zone, image string, airGapped bool) error {
 	machineSet, err := d.initMachineSet(MachineName(d.azure.Region), zone, image, airGapped)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Deploy(machineSet), "error deploying machine set %q", machineSet.GetName())
+	err = d.msDeployer.DeployWithContext(ctx, machineSet)
+	if err != nil {
+		return errors.Wrapf(err, "error deploying machine set %q", machineSet.GetName())
+	}
+
+	return d.Ledger.Record(ctx, d.deployEntry(api.MachineSetResource, machineSet.GetName(), machineSet.GetNamespace()))
 }
 
 // MachineName generates a machine name for the gateway.
@@ -242,7 +299,8 @@ func MachineName(region string) string {
 	return submarinerGatewayGW + region + "-" + string(uuid.NewUUID())[0:6]
 }
 
-func (d *ocpGatewayDeployer) getAvailabilityZones(gwNodes []unstructured.Unstructured) (set.Set[string], error) {
+func (d *ocpGatewayDeployer) getAvailabilityZones(ctx context.Context, gwNodes []unstructured.Unstructured,
+) (set.Set[string], error) {
 	zonesWithSubmarinerGW := set.New[string]()
 
 	for i := range gwNodes {
@@ -254,7 +312,7 @@ func (d *ocpGatewayDeployer) getAvailabilityZones(gwNodes []unstructured.Unstruc
 		zonesWithSubmarinerGW.Insert(zone)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
 	resourceSKUClient, err := d.getResourceSKUClient()
@@ -289,8 +347,36 @@ func (d *ocpGatewayDeployer) getAvailabilityZones(gwNodes []unstructured.Unstruc
 }
 
 func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
+	return d.CleanupWithContext(context.TODO(), status)
+}
+
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.cleanup(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) cleanup(ctx context.Context, status reporter.Interface) error {
 	status.Start("Removing gateway node")
 
+	recorded, err := d.Ledger.Recorded(ctx, ProviderName, ledger.Deploy)
+	if err != nil {
+		return status.Error(err, "reading the ledger failed")
+	}
+
+	if recorded {
+		if err := d.Ledger.Undo(ctx, ProviderName, ledger.Deploy, d.undo); err != nil {
+			return status.Error(err, "removing the recorded gateway resources failed")
+		}
+
+		// The gateway label isn't added by the deployer, so it isn't recorded.
+		if err := d.k8sClient().RemoveGWLabelFromWorkerNodesWithContext(ctx); err != nil {
+			return status.Error(err, "error removing the gateway label from worker nodes")
+		}
+
+		status.Success("Removed gateway node")
+
+		return nil
+	}
+
 	nsgClient, err := d.getNsgClient()
 	if err != nil {
 		return status.Error(err, "Failed to get network security groups client")
@@ -301,11 +387,11 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 		return status.Error(err, "Failed to get network interfaces client")
 	}
 
-	if err := d.cleanupGWInterface(d.InfraID, nsgClient, nwClient); err != nil {
+	if err := d.cleanupGWInterface(ctx, d.InfraID, nsgClient, nwClient); err != nil {
 		return status.Error(err, "deleting gateway security group failed")
 	}
 
-	err = d.deleteGateway(status)
+	err = d.deleteGateway(ctx, status)
 	if err != nil {
 		return err
 	}
@@ -315,56 +401,58 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 	return nil
 }
 
-func (d *ocpGatewayDeployer) deleteGateway(status reporter.Interface) error {
-	machineSetList, err := d.msDeployer.List()
+func (d *ocpGatewayDeployer) deleteGateway(ctx context.Context, status reporter.Interface) error {
+	machineSetList, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	// Map the gateway nodes before deleting the machine sets, while their machines still reference the nodes.
+	gwNodesList, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
 	if err != nil {
 		return status.Error(err, "error listing the Submariner gateway nodes")
 	}
 
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSetList, gwNodesList.Items)
+	if err != nil {
+		return status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
 	pubIPClient, err := d.getPublicIPClient()
 	if err != nil {
 		return errors.Wrapf(err, "Failed to get network public IP addresses client")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
 	defer cancel()
 
 	for i := range machineSetList {
 		status.Start("Deleting the gateway instance %q", machineSetList[i].GetName())
 
-		err = d.msDeployer.DeleteByName(machineSetList[i].GetName(), machineSetList[i].GetNamespace())
+		err = d.msDeployer.DeleteByNameWithContext(ctx, machineSetList[i].GetName(), machineSetList[i].GetNamespace())
 		if err != nil {
 			return status.Error(err, "error deleting the gateway instance from node: %q",
 				machineSetList[i].GetName())
 		}
 
-		publicIPName := machineSetList[i].GetName() + publicIPNameSuffix
-
-		err = d.deletePublicIP(ctx, pubIPClient, publicIPName)
+		err = d.deletePublicIPs(ctx, pubIPClient, machineSetList[i].GetName())
 		if err != nil {
-			return status.Error(err, "failed to delete public-ip %q", publicIPName)
+			return status.Error(err, "failed to delete the public IPs of %q", machineSetList[i].GetName())
 		}
 
 		status.Success("Successfully deleted the instance")
 	}
 
 	// Cleanup nodes that are not dedicated gateway nodes.
-	gwNodesList, err := d.K8sClient.ListGatewayNodes()
-	if err != nil {
-		return status.Error(err, "error listing the Submariner gateway nodes")
-	}
-
-	gwNodes := ocp.RemoveDuplicates(machineSetList, gwNodesList.Items)
+	gwNodes := mapping.Labeled
 
 	for i := range gwNodes {
-		err = d.K8sClient.RemoveGWLabelFromWorkerNode(&gwNodes[i])
+		err = d.k8sClient().RemoveGWLabelFromWorkerNodeWithContext(ctx, &gwNodes[i])
 		if err != nil {
 			return status.Error(err, "failed to cleanup node %q", gwNodes[i].Name)
 		}
 
-		publicIPName := gwNodes[i].Name + publicIPNameSuffix
-
-		err = d.deletePublicIP(ctx, pubIPClient, publicIPName)
+		err = d.deletePublicIPs(ctx, pubIPClient, gwNodes[i].Name)
 		if err != nil {
 			return status.Error(err, "failed to delete public-ip")
 		}
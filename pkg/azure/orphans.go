@@ -0,0 +1,226 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/utils/ptr"
+)
+
+// resourceGroupSuffix is the suffix of the resource groups created by the OpenShift installer for the clusters.
+const resourceGroupSuffix = "-rg"
+
+// publicIPName matches the names of the public IPs created for the gateway nodes, capturing the infra ID of the
+// labelled worker nodes. The names of the dedicated gateway nodes don't include the infra ID.
+var publicIPName = regexp.MustCompile("^(?:" + submarinerGatewayGW + "|(.+)-worker-).*(?:" + publicIPNameSuffix + "|" +
+	publicIPv6NameSuffix + ")$")
+
+type orphanScanner struct {
+	CloudInfo
+}
+
+// NewOrphanScanner returns an OrphanScanner finding, in the given subscription, the Submariner network security groups,
+// security rules and detached gateway public IPs which don't belong to a live cluster.
+func NewOrphanScanner(subscriptionID string, credential azcore.TokenCredential) api.OrphanScanner {
+	return &orphanScanner{
+		CloudInfo: CloudInfo{
+			SubscriptionID:  subscriptionID,
+			TokenCredential: credential,
+		},
+	}
+}
+
+func (s *orphanScanner) Scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	orphans, err := s.scan(ctx, liveInfraIDs, status)
+
+	return orphans, classifyError(err)
+}
+
+func (s *orphanScanner) scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	status.Start("Scanning for orphaned Submariner network security groups and rules")
+	defer status.End()
+
+	nsgClient, err := s.getNsgClient()
+	if err != nil {
+		return nil, status.Error(err, "Failed to get network security groups client")
+	}
+
+	var orphans []api.Orphan
+
+	nsgPager := nsgClient.NewListAllPager(nil)
+	for nsgPager.More() {
+		page, err := nsgPager.NextPage(ctx)
+		if err != nil {
+			return nil, status.Error(err, "unable to list the network security groups")
+		}
+
+		for _, nsg := range page.Value {
+			orphans = append(orphans, securityGroupOrphans(nsg, liveInfraIDs)...)
+		}
+	}
+
+	status.Success("Found %d orphaned Submariner network security groups and rules", len(orphans))
+
+	status.Start("Scanning for orphaned Submariner public IPs")
+
+	pubIPClient, err := s.getPublicIPClient()
+	if err != nil {
+		return nil, status.Error(err, "Failed to get network public IP addresses client")
+	}
+
+	count := len(orphans)
+
+	ipPager := pubIPClient.NewListAllPager(nil)
+	for ipPager.More() {
+		page, err := ipPager.NextPage(ctx)
+		if err != nil {
+			return nil, status.Error(err, "unable to list the public IPs")
+		}
+
+		for _, ip := range page.Value {
+			if orphan, found := publicIPOrphan(ip, liveInfraIDs); found {
+				orphans = append(orphans, orphan)
+			}
+		}
+	}
+
+	status.Success("Found %d orphaned Submariner public IPs", len(orphans)-count)
+
+	return orphans, nil
+}
+
+// securityGroupOrphans returns the given network security group if it's a gateway security group of a cluster which isn't
+// live, or its Submariner security rules if it's the security group of a cluster which isn't live.
+func securityGroupOrphans(nsg *armnetwork.SecurityGroup, liveInfraIDs []string) []api.Orphan {
+	name := ptr.Deref(nsg.Name, "")
+
+	if infraID, found := strings.CutSuffix(name, externalSecurityGroupSuffix); found {
+		if slices.Contains(liveInfraIDs, infraID) {
+			return nil
+		}
+
+		return []api.Orphan{{Resource: api.SecurityGroupResource, Name: name, ID: ptr.Deref(nsg.ID, ""), InfraID: infraID}}
+	}
+
+	infraID, found := strings.CutSuffix(name, internalSecurityGroupSuffix)
+	if !found || slices.Contains(liveInfraIDs, infraID) || nsg.Properties == nil {
+		return nil
+	}
+
+	var orphans []api.Orphan
+
+	for _, rule := range nsg.Properties.SecurityRules {
+		ruleName := ptr.Deref(rule.Name, "")
+
+		if strings.HasPrefix(ruleName, internalSecurityRulePrefix) || strings.HasPrefix(ruleName, externalSecurityRulePrefix) {
+			orphans = append(orphans, api.Orphan{
+				Resource: api.SecurityGroupRuleResource,
+				Name:     ruleName,
+				ID:       ptr.Deref(rule.ID, ""),
+				InfraID:  infraID,
+			})
+		}
+	}
+
+	return orphans
+}
+
+// publicIPOrphan returns the given public IP if it's a gateway public IP which isn't attached to an interface and doesn't
+// belong to a live cluster. Its cluster is identified by its resource group, or by its name for the labelled worker nodes.
+func publicIPOrphan(ip *armnetwork.PublicIPAddress, liveInfraIDs []string) (api.Orphan, bool) {
+	name := ptr.Deref(ip.Name, "")
+
+	matches := publicIPName.FindStringSubmatch(name)
+	if matches == nil || (ip.Properties != nil && ip.Properties.IPConfiguration != nil) {
+		return api.Orphan{}, false
+	}
+
+	infraID := matches[1]
+
+	if id, err := arm.ParseResourceID(ptr.Deref(ip.ID, "")); err == nil && strings.HasSuffix(id.ResourceGroupName, resourceGroupSuffix) {
+		infraID = strings.TrimSuffix(id.ResourceGroupName, resourceGroupSuffix)
+	}
+
+	if slices.Contains(liveInfraIDs, infraID) {
+		return api.Orphan{}, false
+	}
+
+	return api.Orphan{Resource: api.PublicIPResource, Name: name, ID: ptr.Deref(ip.ID, ""), InfraID: infraID}, true
+}
+
+func (s *orphanScanner) Delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	return classifyError(s.delete(ctx, orphans, status))
+}
+
+func (s *orphanScanner) delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	defer status.End()
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	for i := range orphans {
+		status.Start("Deleting the orphaned %s", orphans[i])
+
+		err := s.deleteOrphan(ctx, &orphans[i])
+		if err != nil && !isNotFoundError(err) {
+			return status.Error(err, "unable to delete the orphaned %s", orphans[i])
+		}
+
+		status.Success("Deleted the orphaned %s", orphans[i])
+	}
+
+	return nil
+}
+
+func (s *orphanScanner) deleteOrphan(ctx context.Context, orphan *api.Orphan) error {
+	id, err := arm.ParseResourceID(orphan.ID)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing the resource ID %q", orphan.ID)
+	}
+
+	// The resources are deleted from the resource group they belong to.
+	info := s.CloudInfo
+	info.BaseGroupName = id.ResourceGroupName
+
+	switch orphan.Resource {
+	case api.SecurityGroupResource:
+		return info.deleteSecurityGroup(ctx, id.Name)
+	case api.SecurityGroupRuleResource:
+		return info.deleteSecurityRule(ctx, id.Parent.Name, id.Name)
+	case api.PublicIPResource:
+		ipClient, err := info.getPublicIPClient()
+		if err != nil {
+			return errors.Wrap(err, "failed to get the public IP addresses client")
+		}
+
+		return info.deletePublicIP(ctx, ipClient, id.Name)
+	}
+
+	return errors.Errorf("unsupported resource %q", orphan.Resource)
+}
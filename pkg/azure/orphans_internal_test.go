@@ -0,0 +1,135 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Orphan detection", func() {
+	const (
+		liveInfraID     = "live-infra"
+		orphanedInfraID = "orphaned-infra"
+		groupsPrefix    = "/subscriptions/sub/resourceGroups/"
+	)
+
+	liveInfraIDs := []string{liveInfraID}
+
+	newSecurityGroup := func(infraID, suffix string, ruleNames ...string) *armnetwork.SecurityGroup {
+		nsg := &armnetwork.SecurityGroup{
+			Name:       ptr.To(infraID + suffix),
+			ID:         ptr.To(groupsPrefix + infraID + "-rg/providers/Microsoft.Network/networkSecurityGroups/" + infraID + suffix),
+			Properties: &armnetwork.SecurityGroupPropertiesFormat{},
+		}
+
+		for _, ruleName := range ruleNames {
+			nsg.Properties.SecurityRules = append(nsg.Properties.SecurityRules, &armnetwork.SecurityRule{
+				Name: ptr.To(ruleName),
+				ID:   ptr.To(*nsg.ID + "/securityRules/" + ruleName),
+			})
+		}
+
+		return nsg
+	}
+
+	newPublicIP := func(resourceGroup, name string, attached bool) *armnetwork.PublicIPAddress {
+		ip := &armnetwork.PublicIPAddress{
+			Name:       ptr.To(name),
+			ID:         ptr.To(groupsPrefix + resourceGroup + "/providers/Microsoft.Network/publicIPAddresses/" + name),
+			Properties: &armnetwork.PublicIPAddressPropertiesFormat{},
+		}
+
+		if attached {
+			ip.Properties.IPConfiguration = &armnetwork.IPConfiguration{}
+		}
+
+		return ip
+	}
+
+	Context("for a gateway security group", func() {
+		It("should only return it if its cluster isn't live", func() {
+			Expect(securityGroupOrphans(newSecurityGroup(liveInfraID, externalSecurityGroupSuffix), liveInfraIDs)).To(BeEmpty())
+
+			nsg := newSecurityGroup(orphanedInfraID, externalSecurityGroupSuffix)
+			Expect(securityGroupOrphans(nsg, liveInfraIDs)).To(Equal([]api.Orphan{{
+				Resource: api.SecurityGroupResource,
+				Name:     *nsg.Name,
+				ID:       *nsg.ID,
+				InfraID:  orphanedInfraID,
+			}}))
+		})
+	})
+
+	Context("for a cluster security group", func() {
+		It("should return its Submariner rules if its cluster isn't live", func() {
+			Expect(securityGroupOrphans(newSecurityGroup(liveInfraID, internalSecurityGroupSuffix,
+				internalSecurityRulePrefix+"tcp-100"), liveInfraIDs)).To(BeEmpty())
+
+			nsg := newSecurityGroup(orphanedInfraID, internalSecurityGroupSuffix, internalSecurityRulePrefix+"tcp-100", "other-rule")
+			Expect(securityGroupOrphans(nsg, liveInfraIDs)).To(Equal([]api.Orphan{{
+				Resource: api.SecurityGroupRuleResource,
+				Name:     internalSecurityRulePrefix + "tcp-100",
+				ID:       *nsg.Properties.SecurityRules[0].ID,
+				InfraID:  orphanedInfraID,
+			}}))
+		})
+	})
+
+	Context("for a public IP", func() {
+		It("should return a detached gateway public IP of a cluster which isn't live", func() {
+			ip := newPublicIP(orphanedInfraID+"-rg", "subgw-eastus-abcdef-xyz12"+publicIPNameSuffix, false)
+
+			orphan, found := publicIPOrphan(ip, liveInfraIDs)
+			Expect(found).To(BeTrue())
+			Expect(orphan).To(Equal(api.Orphan{
+				Resource: api.PublicIPResource,
+				Name:     *ip.Name,
+				ID:       *ip.ID,
+				InfraID:  orphanedInfraID,
+			}))
+		})
+
+		It("should identify the cluster of a worker node public IP from its name", func() {
+			orphan, found := publicIPOrphan(newPublicIP("shared", orphanedInfraID+"-worker-eastus1-abcde"+publicIPv6NameSuffix, false),
+				liveInfraIDs)
+			Expect(found).To(BeTrue())
+			Expect(orphan.InfraID).To(Equal(orphanedInfraID))
+
+			_, found = publicIPOrphan(newPublicIP("shared", liveInfraID+"-worker-eastus1-abcde"+publicIPNameSuffix, false), liveInfraIDs)
+			Expect(found).To(BeFalse())
+		})
+
+		It("should not return an attached, live or non-Submariner public IP", func() {
+			_, found := publicIPOrphan(newPublicIP(orphanedInfraID+"-rg", "subgw-eastus-abcdef-xyz12"+publicIPNameSuffix, true),
+				liveInfraIDs)
+			Expect(found).To(BeFalse())
+
+			_, found = publicIPOrphan(newPublicIP(liveInfraID+"-rg", "subgw-eastus-abcdef-xyz12"+publicIPNameSuffix, false),
+				liveInfraIDs)
+			Expect(found).To(BeFalse())
+
+			_, found = publicIPOrphan(newPublicIP(orphanedInfraID+"-rg", "bastion-pub", false), liveInfraIDs)
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,397 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/pkg/errors"
+	reporterInterface "github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func (az *azureCloud) PlanOpenPorts(ctx context.Context, ports []api.PortSpec, reporter reporterInterface.Interface,
+) (*api.Plan, error) {
+	plan, err := az.planOpenPorts(ctx, ports, reporter)
+
+	return plan, classifyError(err)
+}
+
+func (az *azureCloud) planOpenPorts(ctx context.Context, ports []api.PortSpec, reporter reporterInterface.Interface,
+) (*api.Plan, error) {
+	reporter.Start("Retrieving the internal security group on Azure")
+	defer reporter.End()
+
+	nsgClient, err := az.getNsgClient()
+	if err != nil {
+		return nil, reporter.Error(err, "Failed to get network security groups client")
+	}
+
+	groupName := az.InfraID + internalSecurityGroupSuffix
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	nwSecurityGroup, err := nsgClient.Get(ctx, az.BaseGroupName, groupName, nil)
+	if err != nil {
+		return nil, reporter.Error(err, "error getting the security group %q", groupName)
+	}
+
+	reporter.Success("Retrieved the internal security group %q", groupName)
+
+	plan := &api.Plan{}
+
+	if nwSecurityGroup.Properties != nil && checkIfSecurityRulesPresent(nwSecurityGroup.Properties.SecurityRules) {
+		return plan, nil
+	}
+
+	az.planSecurityRules(plan, groupName, internalSecurityRulePrefix, basePriorityInternal, ports, az.anyCIDRs())
+
+	return plan, nil
+}
+
+func (az *azureCloud) PlanClosePorts(ctx context.Context, reporter reporterInterface.Interface) (*api.Plan, error) {
+	plan, err := az.planClosePorts(ctx, reporter)
+
+	return plan, classifyError(err)
+}
+
+func (az *azureCloud) planClosePorts(ctx context.Context, reporter reporterInterface.Interface) (*api.Plan, error) {
+	reporter.Start("Retrieving the internal security group on Azure")
+	defer reporter.End()
+
+	plan := &api.Plan{}
+
+	recorded, err := az.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.OpenPorts, undoChange)
+	if err != nil {
+		return nil, reporter.Error(err, "Failed to read the ledger")
+	}
+
+	if recorded {
+		reporter.Success("Retrieved the recorded intra-cluster communication permissions")
+
+		return plan, nil
+	}
+
+	nsgClient, err := az.getNsgClient()
+	if err != nil {
+		return nil, reporter.Error(err, "Failed to get network security groups client")
+	}
+
+	groupName := az.InfraID + internalSecurityGroupSuffix
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	nwSecurityGroup, err := nsgClient.Get(ctx, az.BaseGroupName, groupName, nil)
+	if err != nil {
+		return nil, reporter.Error(err, "error getting the security group %q", groupName)
+	}
+
+	reporter.Success("Retrieved the internal security group %q", groupName)
+
+	if nwSecurityGroup.Properties == nil {
+		return plan, nil
+	}
+
+	for _, rule := range nwSecurityGroup.Properties.SecurityRules {
+		if rule.Name != nil && strings.Contains(*rule.Name, internalSecurityRulePrefix) {
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, *rule.Name, "in security group %q", groupName)
+		}
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput, status reporterInterface.Interface,
+) (*api.Plan, error) {
+	plan, err := d.planDeploy(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planDeploy(ctx context.Context, input api.GatewayDeployInput, status reporterInterface.Interface,
+) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if input.Gateways == 0 {
+		return plan, nil
+	}
+
+	if input.IPFamily == "" {
+		input.IPFamily = d.IPFamily
+	}
+
+	status.Start("Retrieving the current gateway nodes")
+	defer status.End()
+
+	nsgClient, _, pubIPClient, err := d.getClients(status)
+	if err != nil {
+		return nil, err
+	}
+
+	groupName := d.InfraID + externalSecurityGroupSuffix
+
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway machinesets")
+	}
+
+	gwNodes, err := d.azure.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway nodes")
+	}
+
+	status.Success("Retrieved %d gateway machineset(s) and %d gateway node(s)", len(machineSets), len(gwNodes.Items))
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodes.Items)
+	if err != nil {
+		return nil, status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	gatewayNodesToDeploy := input.Gateways - len(machineSets) - len(mapping.Labeled)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	if len(machineSets) != 0 || gatewayNodesToDeploy != 0 {
+		d.planGWSecurityGroup(timeoutCtx, plan, groupName, input.PublicPorts, input.PublicSourceCIDRs(), nsgClient)
+	}
+
+	for i := range gwNodes.Items {
+		nodeName := gwNodes.Items[i].GetName()
+		publicIPNames := []string{nodeName + publicIPNameSuffix}
+
+		if input.IPFamily.HasIPv6() {
+			publicIPNames = append(publicIPNames, nodeName+publicIPv6NameSuffix)
+		}
+
+		for _, publicIPName := range publicIPNames {
+			if _, err := d.getPublicIP(timeoutCtx, publicIPName, pubIPClient); err != nil {
+				plan.Add(api.ChangeCreate, api.PublicIPResource, publicIPName, "for gateway node %q", nodeName)
+			}
+		}
+
+		plan.Add(api.ChangeUpdate, api.NetworkInterfaceResource, nodeName+"-nic", "attach security group %q and public IPs %q",
+			groupName, strings.Join(publicIPNames, ", "))
+	}
+
+	if gatewayNodesToDeploy < 0 && len(machineSets) != 0 {
+		activeNode, err := d.azure.k8sClient().GetActiveGatewayNodeWithContext(ctx)
+		if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+			status.Warning("The surplus gateways won't be removed: %v", err)
+		} else if err != nil {
+			return nil, status.Error(err, "unable to determine the active gateway")
+		}
+
+		var surplus []unstructured.Unstructured
+		if err == nil {
+			surplus = ocp.SelectSurplusMachineSets(machineSets, mapping, activeNode, -gatewayNodesToDeploy)
+		}
+
+		for _, machineSet := range surplus {
+			plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "surplus passive gateway")
+			d.planDeletePublicIPs(timeoutCtx, plan, machineSet.GetName(), pubIPClient)
+		}
+	}
+
+	if gatewayNodesToDeploy <= 0 {
+		return plan, nil
+	}
+
+	zones, err := d.getAvailabilityZones(ctx, machineSets)
+	if err != nil {
+		return nil, status.Error(err, "error getting the availability zones for region %q", d.Region)
+	}
+
+	if zones.Len() < gatewayNodesToDeploy {
+		return nil, status.Error(fmt.Errorf("only %d zone(s) available to deploy %d gateway node(s)", zones.Len(), gatewayNodesToDeploy),
+			"not enough zones available in the region %q to deploy required number of gateway nodes", d.Region)
+	}
+
+	for _, zone := range zones.SortedList()[:gatewayNodesToDeploy] {
+		plan.Add(api.ChangeCreate, api.MachineSetResource, MachineName(d.azure.Region), "dedicated gateway node in zone %q (public IP: %t)",
+			zone, !input.AirGapped)
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context, status reporterInterface.Interface) (*api.Plan, error) {
+	plan, err := d.planCleanup(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planCleanup(ctx context.Context, status reporterInterface.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the gateway security group and nodes")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	recorded, err := d.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.Deploy, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "reading the ledger failed")
+	}
+
+	if recorded {
+		// The gateway label isn't added by the deployer, so it isn't recorded.
+		gwNodes, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+		if err != nil {
+			return nil, status.Error(err, "error listing the Submariner gateway nodes")
+		}
+
+		for i := range gwNodes.Items {
+			plan.Add(api.ChangeDelete, api.NodeGatewayLabelResource, gwNodes.Items[i].Name, "")
+		}
+
+		status.Success("Retrieved the recorded gateway resources and nodes")
+
+		return plan, nil
+	}
+
+	nsgClient, _, pubIPClient, err := d.getClients(status)
+	if err != nil {
+		return nil, err
+	}
+
+	groupName := d.InfraID + externalSecurityGroupSuffix
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	nwSecurityGroup, err := nsgClient.Get(timeoutCtx, d.BaseGroupName, groupName, nil)
+	if err == nil {
+		if nwSecurityGroup.Properties != nil {
+			for _, nwInterface := range nwSecurityGroup.Properties.NetworkInterfaces {
+				if nwInterface.ID != nil {
+					plan.Add(api.ChangeUpdate, api.NetworkInterfaceResource, path.Base(*nwInterface.ID),
+						"detach security group %q and the public IP", groupName)
+				}
+			}
+		}
+
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	}
+
+	machineSetList, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	for i := range machineSetList {
+		plan.Add(api.ChangeDelete, api.MachineSetResource, machineSetList[i].GetName(), "")
+		d.planDeletePublicIPs(timeoutCtx, plan, machineSetList[i].GetName(), pubIPClient)
+	}
+
+	gwNodesList, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSetList, gwNodesList.Items)
+	if err != nil {
+		return nil, status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	gwNodes := mapping.Labeled
+
+	for i := range gwNodes {
+		plan.Add(api.ChangeDelete, api.NodeGatewayLabelResource, gwNodes[i].Name, "")
+		d.planDeletePublicIPs(timeoutCtx, plan, gwNodes[i].Name, pubIPClient)
+	}
+
+	status.Success("Retrieved the gateway security group and nodes")
+
+	return plan, nil
+}
+
+func (c *CloudInfo) planSecurityRules(plan *api.Plan, groupName, prefix string, basePriority int32, ports []api.PortSpec,
+	sourceCIDRs []string,
+) {
+	for i, port := range ports {
+		p := int32(i) //nolint:gosec // Ignore integer overflow conversion
+
+		for _, rule := range c.createSecurityRules(prefix, port, basePriority+p, sourceCIDRs) {
+			plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, *rule.Name, "in security group %q, %s", groupName,
+				describeRemote(rule))
+		}
+	}
+}
+
+// planGWSecurityGroup adds the creation of the gateway security group to the plan or, if it exists, the changes to its
+// Submariner rules.
+func (c *CloudInfo) planGWSecurityGroup(ctx context.Context, plan *api.Plan, groupName string, ports []api.PortSpec,
+	sourceCIDRs []string, nsgClient *armnetwork.SecurityGroupsClient,
+) {
+	existing, err := nsgClient.Get(ctx, c.BaseGroupName, groupName, nil)
+	if err != nil {
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "in resource group %q", c.BaseGroupName)
+		c.planSecurityRules(plan, groupName, externalSecurityRulePrefix, baseExternalInternal, ports, sourceCIDRs)
+
+		return
+	}
+
+	var existingRules []*armnetwork.SecurityRule
+	if existing.Properties != nil {
+		existingRules = existing.Properties.SecurityRules
+	}
+
+	reconcileSecurityRules(plan, groupName, externalSecurityRulePrefix, existingRules, c.gwSecurityRules(ports, sourceCIDRs))
+}
+
+// describeRemote describes the addresses allowed at the remote end of the given rule.
+func describeRemote(rule *armnetwork.SecurityRule) string {
+	if *rule.Properties.Direction == armnetwork.SecurityRuleDirectionOutbound {
+		return "to " + joinPrefixes(rule.Properties.DestinationAddressPrefix, rule.Properties.DestinationAddressPrefixes)
+	}
+
+	return "from " + joinPrefixes(rule.Properties.SourceAddressPrefix, rule.Properties.SourceAddressPrefixes)
+}
+
+func joinPrefixes(prefix *string, prefixes []*string) string {
+	if prefix != nil {
+		return *prefix
+	}
+
+	values := make([]string, len(prefixes))
+	for i := range prefixes {
+		values[i] = *prefixes[i]
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (c *CloudInfo) planDeletePublicIPs(ctx context.Context, plan *api.Plan, nodeName string,
+	pubIPClient *armnetwork.PublicIPAddressesClient,
+) {
+	for _, publicIPName := range []string{nodeName + publicIPNameSuffix, nodeName + publicIPv6NameSuffix} {
+		if _, err := c.getPublicIP(ctx, publicIPName, pubIPClient); err == nil {
+			plan.Add(api.ChangeDelete, api.PublicIPResource, publicIPName, "")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// policyModeCalls are the armnetwork and armcompute client calls made by each policy mode, including those made when
+// undoing the ledger entries.
+var policyModeCalls = map[api.PolicyMode][]string{
+	api.OpenPortsMode: {"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginCreateOrUpdate"},
+	api.ClosePortsMode: {
+		"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginCreateOrUpdate", "SecurityRulesClient.BeginDelete",
+	},
+	api.ReconcilePortsMode: {"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginCreateOrUpdate"},
+	api.DeployMode: {
+		"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginCreateOrUpdate", "InterfacesClient.Get",
+		"InterfacesClient.BeginCreateOrUpdate", "PublicIPAddressesClient.Get", "PublicIPAddressesClient.BeginCreateOrUpdate",
+		"PublicIPAddressesClient.BeginDelete", "ResourceSKUsClient.NewListPager",
+	},
+	api.CleanupMode: {
+		"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginDelete", "InterfacesClient.Get", "InterfacesClient.NewListPager",
+		"InterfacesClient.BeginCreateOrUpdate", "PublicIPAddressesClient.Get", "PublicIPAddressesClient.BeginDelete",
+	},
+	api.ScanOrphansMode: {"SecurityGroupsClient.NewListAllPager", "PublicIPAddressesClient.NewListAllPager"},
+	api.DeleteOrphansMode: {
+		"SecurityGroupsClient.BeginDelete", "SecurityRulesClient.BeginDelete", "PublicIPAddressesClient.BeginDelete",
+	},
+	api.PrepareHostedGatewaysMode: {
+		"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginCreateOrUpdate", "InterfacesClient.Get",
+		"InterfacesClient.BeginCreateOrUpdate", "PublicIPAddressesClient.Get", "PublicIPAddressesClient.BeginCreateOrUpdate",
+	},
+	api.CleanupHostedGatewaysMode: {
+		"SecurityGroupsClient.Get", "SecurityGroupsClient.BeginDelete", "InterfacesClient.Get", "InterfacesClient.NewListPager",
+		"InterfacesClient.BeginCreateOrUpdate", "PublicIPAddressesClient.Get", "PublicIPAddressesClient.BeginDelete",
+	},
+}
+
+// callPermissions maps each client call to the resource provider operations it requires.
+var callPermissions = map[string][]string{
+	"SecurityGroupsClient.Get": {"Microsoft.Network/networkSecurityGroups/read"},
+	"SecurityGroupsClient.BeginCreateOrUpdate": {
+		"Microsoft.Network/networkSecurityGroups/write", "Microsoft.Network/networkSecurityGroups/securityRules/write",
+	},
+	"SecurityGroupsClient.BeginDelete":     {"Microsoft.Network/networkSecurityGroups/delete"},
+	"SecurityGroupsClient.NewListAllPager": {"Microsoft.Network/networkSecurityGroups/read"},
+	"SecurityRulesClient.BeginDelete":      {"Microsoft.Network/networkSecurityGroups/securityRules/delete"},
+	"InterfacesClient.Get":                 {"Microsoft.Network/networkInterfaces/read"},
+	"InterfacesClient.NewListPager":        {"Microsoft.Network/networkInterfaces/read"},
+	"InterfacesClient.BeginCreateOrUpdate": {
+		"Microsoft.Network/networkInterfaces/write", "Microsoft.Network/networkSecurityGroups/join/action",
+		"Microsoft.Network/publicIPAddresses/join/action",
+	},
+	"PublicIPAddressesClient.Get":                 {"Microsoft.Network/publicIPAddresses/read"},
+	"PublicIPAddressesClient.BeginCreateOrUpdate": {"Microsoft.Network/publicIPAddresses/write"},
+	"PublicIPAddressesClient.BeginDelete":         {"Microsoft.Network/publicIPAddresses/delete"},
+	"PublicIPAddressesClient.NewListAllPager":     {"Microsoft.Network/publicIPAddresses/read"},
+	"ResourceSKUsClient.NewListPager":             {"Microsoft.Compute/skus/read"},
+}
+
+// roleDefinition is a custom role definition, in the format taken by "az role definition create".
+type roleDefinition struct {
+	Name             string   `json:"Name"`
+	IsCustom         bool     `json:"IsCustom"`
+	Description      string   `json:"Description"`
+	Actions          []string `json:"Actions"`
+	NotActions       []string `json:"NotActions"`
+	AssignableScopes []string `json:"AssignableScopes"`
+}
+
+// Permissions returns the resource provider operations needed by the given policy modes, or by all of them if none is
+// given.
+func Permissions(modes ...api.PolicyMode) ([]string, error) {
+	return api.PolicyPermissions(policyModeCalls, callPermissions, modes...)
+}
+
+// RoleDefinition returns the JSON definition of a custom role, assignable to the given resource group, granting the least
+// privileges needed by the given policy modes, or by all of them if none is given.
+func RoleDefinition(subscriptionID, resourceGroup string, modes ...api.PolicyMode) ([]byte, error) {
+	actions, err := Permissions(modes...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(modes) == 0 {
+		modes = api.PolicyModes
+	}
+
+	role, err := json.MarshalIndent(&roleDefinition{
+		Name:             "Submariner cloud preparation",
+		IsCustom:         true,
+		Description:      fmt.Sprintf("Permissions needed by the Submariner cloud preparation for %v", modes),
+		Actions:          actions,
+		NotActions:       []string{},
+		AssignableScopes: []string{fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscriptionID, resourceGroup)},
+	}, "", "  ")
+
+	return role, errors.Wrap(err, "error marshalling the role definition")
+}
@@ -0,0 +1,55 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+var _ = Describe("RoleDefinition", func() {
+	It("should grant the actions needed by the requested modes on the resource group", func() {
+		data, err := RoleDefinition("test-subscription", "test-group", api.OpenPortsMode)
+		Expect(err).To(Succeed())
+
+		role := &roleDefinition{}
+		Expect(json.Unmarshal(data, role)).To(Succeed())
+		Expect(role.IsCustom).To(BeTrue())
+		Expect(role.AssignableScopes).To(Equal([]string{"/subscriptions/test-subscription/resourceGroups/test-group"}))
+		Expect(role.Actions).To(Equal([]string{
+			"Microsoft.Network/networkSecurityGroups/read",
+			"Microsoft.Network/networkSecurityGroups/securityRules/write",
+			"Microsoft.Network/networkSecurityGroups/write",
+		}))
+	})
+
+	It("should know the actions needed by all the calls of all the modes", func() {
+		actions, err := Permissions()
+		Expect(err).To(Succeed())
+		Expect(actions).To(ContainElements("Microsoft.Compute/skus/read", "Microsoft.Network/publicIPAddresses/delete"))
+	})
+
+	It("should reject an unknown mode", func() {
+		_, err := RoleDefinition("test-subscription", "test-group", "Unknown")
+		Expect(err).To(HaveOccurred())
+	})
+})
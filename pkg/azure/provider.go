@@ -0,0 +1,56 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+)
+
+// ProviderName is the name the Azure provider is registered under, see api.NewProvider.
+const ProviderName = "azure"
+
+// ProviderConfig is the configuration of the Azure provider.
+type ProviderConfig struct {
+	CloudInfo
+
+	MachineSetDeployer ocp.MachineSetDeployer
+	InstanceType       string
+}
+
+func init() {
+	api.RegisterProvider(ProviderName, newProvider)
+}
+
+func newProvider(config *ProviderConfig) (api.Cloud, api.GatewayDeployer, error) {
+	if config.TokenCredential == nil {
+		return nil, nil, errors.New("an Azure token credential is required")
+	}
+
+	if config.MachineSetDeployer == nil || config.K8sClient == nil {
+		return nil, nil, errors.New("a MachineSetDeployer and a K8sClient are required")
+	}
+
+	cloud := NewCloud(&config.CloudInfo)
+
+	gwDeployer, err := NewOcpGatewayDeployer(&config.CloudInfo, cloud, config.MachineSetDeployer, config.InstanceType)
+
+	return cloud, gwDeployer, err
+}
@@ -0,0 +1,190 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package azure
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/pkg/errors"
+	reporterInterface "github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"k8s.io/utils/ptr"
+	"k8s.io/utils/set"
+)
+
+func (az *azureCloud) ReconcilePorts(ctx context.Context, ports []api.PortSpec, reporter reporterInterface.Interface,
+) (*api.Plan, error) {
+	plan, err := az.reconcilePorts(ctx, ports, reporter)
+
+	return plan, classifyError(err)
+}
+
+func (az *azureCloud) reconcilePorts(ctx context.Context, ports []api.PortSpec, reporter reporterInterface.Interface,
+) (*api.Plan, error) {
+	if err := api.ValidatePorts(ports); err != nil {
+		return nil, errors.WithMessage(err, "invalid internal ports")
+	}
+
+	reporter.Start("Comparing the internal ports with the internal security group rules on Azure")
+	defer reporter.End()
+
+	nsgClient, err := az.getNsgClient()
+	if err != nil {
+		return nil, reporter.Error(err, "Failed to get network security groups client")
+	}
+
+	groupName := az.InfraID + internalSecurityGroupSuffix
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	nwSecurityGroup, err := nsgClient.Get(ctx, az.BaseGroupName, groupName, nil)
+	if err != nil {
+		return nil, reporter.Error(err, "error getting the security group %q", groupName)
+	}
+
+	if nwSecurityGroup.Properties == nil {
+		nwSecurityGroup.Properties = &armnetwork.SecurityGroupPropertiesFormat{}
+	}
+
+	desired := []*armnetwork.SecurityRule{}
+
+	for i, port := range ports {
+		p := int32(i) //nolint:gosec // Ignore integer overflow conversion
+
+		desired = append(desired, az.createSecurityRules(internalSecurityRulePrefix, port, basePriorityInternal+p, az.anyCIDRs())...)
+	}
+
+	plan := &api.Plan{}
+	securityRules, changed := reconcileSecurityRules(plan, groupName, internalSecurityRulePrefix,
+		nwSecurityGroup.Properties.SecurityRules, desired)
+
+	plan.Report(reporter)
+
+	if plan.IsEmpty() {
+		return plan, nil
+	}
+
+	reporter.Start("Updating the security group %q on Azure", groupName)
+
+	for _, rule := range changed {
+		err = az.Ledger.Record(ctx, ledger.Entry{
+			Provider:  ProviderName,
+			Operation: ledger.OpenPorts,
+			Resource:  api.SecurityGroupRuleResource,
+			ID:        *rule.Name,
+			Parent:    groupName,
+		})
+		if err != nil {
+			return nil, reporter.Error(err, "Failed to record the security rules")
+		}
+	}
+
+	nwSecurityGroup.Properties.SecurityRules = securityRules
+
+	poller, err := nsgClient.BeginCreateOrUpdate(ctx, az.BaseGroupName, groupName, nwSecurityGroup.SecurityGroup, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+
+	if err != nil {
+		return nil, reporter.Error(errors.Wrapf(err, "error updating security group %q with submariner rules", groupName),
+			"Failed to reconcile the internal ports")
+	}
+
+	reporter.Success("Reconciled the internal ports %q for intra-cluster communications on Azure", formatPorts(ports))
+
+	return plan, nil
+}
+
+// reconcileSecurityRules returns the given existing rules of a security group with the rules named with the given prefix
+// replaced by the desired ones, along with the desired rules which are added or changed. The existing rules are matched
+// to the desired ones by name and are changed if their priority or addresses differ. The changes are added to the plan.
+func reconcileSecurityRules(plan *api.Plan, groupName, prefix string, existing, desired []*armnetwork.SecurityRule,
+) ([]*armnetwork.SecurityRule, []*armnetwork.SecurityRule) {
+	desiredNames := set.New[string]()
+	for _, rule := range desired {
+		desiredNames.Insert(*rule.Name)
+	}
+
+	securityRules := []*armnetwork.SecurityRule{}
+	current := map[string]*armnetwork.SecurityRule{}
+
+	for _, rule := range existing {
+		switch {
+		case rule.Name == nil || !strings.Contains(*rule.Name, prefix):
+			securityRules = append(securityRules, rule)
+		case desiredNames.Has(*rule.Name):
+			current[*rule.Name] = rule
+		default:
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, *rule.Name, "in security group %q", groupName)
+		}
+	}
+
+	var changed []*armnetwork.SecurityRule
+
+	for _, rule := range desired {
+		securityRules = append(securityRules, rule)
+
+		existingRule, found := current[*rule.Name]
+
+		switch {
+		case !found:
+			plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, *rule.Name, "in security group %q with priority %d",
+				groupName, *rule.Properties.Priority)
+		case securityRuleChanged(existingRule, rule):
+			plan.Add(api.ChangeUpdate, api.SecurityGroupRuleResource, *rule.Name, "in security group %q with priority %d",
+				groupName, *rule.Properties.Priority)
+		default:
+			continue
+		}
+
+		changed = append(changed, rule)
+	}
+
+	return securityRules, changed
+}
+
+// securityRuleChanged returns true if the existing rule differs from the desired one in its priority, ports or addresses.
+func securityRuleChanged(existing, desired *armnetwork.SecurityRule) bool {
+	if existing.Properties == nil {
+		return true
+	}
+
+	e, d := existing.Properties, desired.Properties
+
+	return ptr.Deref(e.Priority, 0) != ptr.Deref(d.Priority, 0) ||
+		ptr.Deref(e.DestinationPortRange, "") != ptr.Deref(d.DestinationPortRange, "") ||
+		ptr.Deref(e.SourceAddressPrefix, "") != ptr.Deref(d.SourceAddressPrefix, "") ||
+		ptr.Deref(e.DestinationAddressPrefix, "") != ptr.Deref(d.DestinationAddressPrefix, "") ||
+		!slices.Equal(derefAll(e.SourceAddressPrefixes), derefAll(d.SourceAddressPrefixes)) ||
+		!slices.Equal(derefAll(e.DestinationAddressPrefixes), derefAll(d.DestinationAddressPrefixes))
+}
+
+func derefAll(values []*string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, ptr.Deref(value, ""))
+	}
+
+	return result
+}
@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package azure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Security rules reconciliation", func() {
+	const groupName = "test-infra-nsg"
+
+	cloudInfo := &CloudInfo{IPFamily: api.IPv4}
+
+	internalRules := func(ports ...api.PortSpec) []*armnetwork.SecurityRule {
+		rules := []*armnetwork.SecurityRule{}
+
+		for i, port := range ports {
+			p := int32(i) //nolint:gosec // Ignore integer overflow conversion
+
+			rules = append(rules, cloudInfo.createSecurityRules(internalSecurityRulePrefix, port, basePriorityInternal+p,
+				cloudInfo.anyCIDRs())...)
+		}
+
+		return rules
+	}
+
+	vxlanPort := api.PortSpec{Port: 4800, Protocol: "udp"}
+	metricsPort := api.PortSpec{Port: 8080, Protocol: "tcp"}
+	otherRule := &armnetwork.SecurityRule{Name: ptr.To("other-rule")}
+
+	It("should add the missing rules, remove the stale ones and keep the other ones", func() {
+		existing := append([]*armnetwork.SecurityRule{otherRule}, internalRules(vxlanPort)...)
+		desired := internalRules(metricsPort)
+		plan := &api.Plan{}
+
+		securityRules, changed := reconcileSecurityRules(plan, groupName, internalSecurityRulePrefix, existing, desired)
+
+		Expect(securityRules).To(HaveExactElements(otherRule, desired[0], desired[1]))
+		Expect(changed).To(HaveExactElements(desired[0], desired[1]))
+		Expect(plan.Changes).To(HaveExactElements(
+			api.Change{
+				Action: api.ChangeDelete, Resource: api.SecurityGroupRuleResource, Name: *existing[1].Name,
+				Details: "in security group \"" + groupName + "\"",
+			},
+			And(HaveField("Action", api.ChangeDelete), HaveField("Name", *existing[2].Name)),
+			And(HaveField("Action", api.ChangeCreate), HaveField("Name", *desired[0].Name)),
+			And(HaveField("Action", api.ChangeCreate), HaveField("Name", *desired[1].Name)),
+		))
+	})
+
+	It("should update the rules whose priority changed", func() {
+		existing := internalRules(vxlanPort)
+		desired := internalRules(metricsPort, vxlanPort)
+		plan := &api.Plan{}
+
+		_, changed := reconcileSecurityRules(plan, groupName, internalSecurityRulePrefix, existing, desired)
+
+		Expect(changed).To(HaveExactElements(desired[0], desired[1], desired[2], desired[3]))
+		Expect(plan.Changes).To(HaveExactElements(
+			HaveField("Action", api.ChangeCreate),
+			HaveField("Action", api.ChangeCreate),
+			HaveField("Action", api.ChangeUpdate),
+			HaveField("Action", api.ChangeUpdate),
+		))
+	})
+
+	It("should update the rules whose source CIDRs changed", func() {
+		existing := cloudInfo.gwSecurityRules([]api.PortSpec{vxlanPort}, []string{api.AnyIPv4CIDR})
+		desired := cloudInfo.gwSecurityRules([]api.PortSpec{vxlanPort}, []string{"10.1.0.0/16"})
+		plan := &api.Plan{}
+
+		_, changed := reconcileSecurityRules(plan, groupName, externalSecurityRulePrefix, existing, desired)
+
+		Expect(changed).To(HaveExactElements(desired[0]))
+		Expect(plan.Changes).To(HaveExactElements(
+			And(HaveField("Action", api.ChangeUpdate), HaveField("Name", *desired[0].Name)),
+		))
+	})
+
+	It("should not change anything if the rules are up to date", func() {
+		plan := &api.Plan{}
+
+		_, changed := reconcileSecurityRules(plan, groupName, internalSecurityRulePrefix,
+			append([]*armnetwork.SecurityRule{otherRule}, internalRules(vxlanPort)...), internalRules(vxlanPort))
+
+		Expect(changed).To(BeEmpty())
+		Expect(plan.IsEmpty()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Resource tags reconciliation", func() {
+	cloudInfo := &CloudInfo{Tags: map[string]string{"owner": "team-a", "cost-center": "1234"}}
+
+	It("should add the missing tags, update the changed ones and keep the other ones", func() {
+		tags, changed := cloudInfo.mergeResourceTags(map[string]*string{"owner": ptr.To("team-b"), "other": ptr.To("value")})
+		Expect(changed).To(BeTrue())
+		Expect(tags).To(Equal(map[string]*string{
+			"owner": ptr.To("team-a"), "cost-center": ptr.To("1234"), "other": ptr.To("value"),
+		}))
+	})
+
+	It("should add the tags to a resource without any", func() {
+		tags, changed := cloudInfo.mergeResourceTags(nil)
+		Expect(changed).To(BeTrue())
+		Expect(tags).To(Equal(map[string]*string{"owner": ptr.To("team-a"), "cost-center": ptr.To("1234")}))
+	})
+
+	It("should not change anything if the tags are up to date", func() {
+		tags, changed := cloudInfo.mergeResourceTags(map[string]*string{"owner": ptr.To("team-a"), "cost-center": ptr.To("1234")})
+		Expect(changed).To(BeFalse())
+		Expect(tags).To(HaveLen(2))
+	})
+})
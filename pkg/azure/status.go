@@ -0,0 +1,118 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	statuses, err := d.gatewayStatus(ctx, status)
+
+	return statuses, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) gatewayStatus(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	status.Start("Retrieving the Submariner gateways")
+	defer status.End()
+
+	_, _, pubIPClient, err := d.getClients(status)
+	if err != nil {
+		return nil, err
+	}
+
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway machinesets")
+	}
+
+	gwNodes, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway nodes")
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodes.Items)
+	if err != nil {
+		return nil, status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	groupName := d.InfraID + externalSecurityGroupSuffix
+	gateways := []api.GatewayStatus{}
+
+	for i := range machineSets {
+		gateway := api.GatewayStatus{
+			Type:          api.DedicatedGateway,
+			MachineSet:    machineSets[i].GetName(),
+			Zone:          ocp.ProviderSpecString(&machineSets[i], "zone"),
+			Subnet:        ocp.ProviderSpecString(&machineSets[i], "subnet"),
+			InstanceType:  ocp.ProviderSpecString(&machineSets[i], "vmSize"),
+			SecurityGroup: groupName,
+		}
+
+		nodes := mapping.ByMachineSet[machineSets[i].GetName()]
+		if len(nodes) == 0 {
+			gateways = append(gateways, gateway)
+			continue
+		}
+
+		for j := range nodes {
+			nodeGateway := gateway
+			nodeGateway.NodeName = nodes[j].Name
+			nodeGateway.PublicIP = d.publicIPAddress(timeoutCtx, nodes[j].Name, pubIPClient)
+			gateways = append(gateways, nodeGateway)
+		}
+	}
+
+	taggedExistingNodes := mapping.Labeled
+
+	for i := range taggedExistingNodes {
+		gateways = append(gateways, api.GatewayStatus{
+			Type:          api.LabeledWorkerGateway,
+			Zone:          taggedExistingNodes[i].Labels[corev1.LabelTopologyZone],
+			InstanceType:  taggedExistingNodes[i].Labels[corev1.LabelInstanceTypeStable],
+			NodeName:      taggedExistingNodes[i].Name,
+			PublicIP:      d.publicIPAddress(timeoutCtx, taggedExistingNodes[i].Name, pubIPClient),
+			SecurityGroup: groupName,
+		})
+	}
+
+	status.Success("Retrieved %d Submariner gateway(s)", len(gateways))
+
+	return gateways, nil
+}
+
+// publicIPAddress returns the address of the public IP assigned to the given gateway node, if any.
+func (c *CloudInfo) publicIPAddress(ctx context.Context, nodeName string, pubIPClient *armnetwork.PublicIPAddressesClient) string {
+	publicIP, err := c.getPublicIP(ctx, nodeName+publicIPNameSuffix, pubIPClient)
+	if err != nil || publicIP.Properties == nil {
+		return ""
+	}
+
+	return ptr.Deref(publicIP.Properties.IPAddress, "")
+}
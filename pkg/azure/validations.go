@@ -0,0 +1,170 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/pkg/errors"
+	reporterInterface "github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const permissionsAPIVersion = "2022-04-01"
+
+// permission is a set of actions granted by a role assignment, as returned by the Microsoft.Authorization permissions API.
+type permission struct {
+	Actions    []string `json:"actions"`
+	NotActions []string `json:"notActions"`
+}
+
+type permissionListResult struct {
+	Value    []permission `json:"value"`
+	NextLink string       `json:"nextLink"`
+}
+
+func (az *azureCloud) Validate(ctx context.Context, reporter reporterInterface.Interface) error {
+	return classifyError(az.validate(ctx, reporter))
+}
+
+func (az *azureCloud) validate(ctx context.Context, reporter reporterInterface.Interface) error {
+	reporter.Start("Validating the permissions to open and close the internal ports")
+	defer reporter.End()
+
+	if err := az.validatePermissions(ctx, api.OpenPortsMode, api.ClosePortsMode); err != nil {
+		return reporter.Error(err, "permission validation failed")
+	}
+
+	reporter.Success("Validated the permissions to open and close the internal ports")
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) Validate(ctx context.Context, status reporterInterface.Interface) error {
+	return classifyError(d.validate(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) validate(ctx context.Context, status reporterInterface.Interface) error {
+	status.Start("Validating the permissions to deploy and clean up the gateways")
+	defer status.End()
+
+	if err := d.validatePermissions(ctx, api.DeployMode, api.CleanupMode); err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to deploy and clean up the gateways")
+
+	return nil
+}
+
+// validatePermissions lists the permissions of the caller on the resource group and returns a MissingPermissionsError
+// listing the actions needed by the given policy modes which they don't grant.
+func (c *CloudInfo) validatePermissions(ctx context.Context, modes ...api.PolicyMode) error {
+	actions, err := Permissions(modes...)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := c.listPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	return api.NewMissingPermissionsError(ProviderName, missingActions(permissions, actions))
+}
+
+func (c *CloudInfo) listPermissions(ctx context.Context) ([]permission, error) {
+	client, err := arm.NewClient("armauthorization", "", c.TokenCredential, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{Telemetry: policy.TelemetryOptions{Disabled: true}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the authorization client")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, operationTimeout)
+	defer cancel()
+
+	endpoint := runtime.JoinPaths(client.Endpoint(), "subscriptions", url.PathEscape(c.SubscriptionID), "resourceGroups",
+		url.PathEscape(c.BaseGroupName), "providers/Microsoft.Authorization/permissions") + "?api-version=" + permissionsAPIVersion
+
+	var permissions []permission
+
+	for endpoint != "" {
+		req, err := runtime.NewRequest(ctx, http.MethodGet, endpoint)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the permissions request")
+		}
+
+		req.Raw().Header.Set("Accept", "application/json")
+
+		resp, err := client.Pipeline().Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing the permissions on resource group %q", c.BaseGroupName)
+		}
+
+		if !runtime.HasStatusCode(resp, http.StatusOK) {
+			return nil, errors.Wrapf(runtime.NewResponseError(resp), "error listing the permissions on resource group %q",
+				c.BaseGroupName)
+		}
+
+		var result permissionListResult
+
+		if err := runtime.UnmarshalAsJSON(resp, &result); err != nil {
+			return nil, errors.Wrap(err, "error decoding the permissions")
+		}
+
+		permissions = append(permissions, result.Value...)
+		endpoint = result.NextLink
+	}
+
+	return permissions, nil
+}
+
+// missingActions returns the given actions which none of the permissions grant. Azure action patterns may contain
+// wildcards and are case-insensitive.
+func missingActions(permissions []permission, actions []string) []string {
+	var missing []string
+
+	for _, action := range actions {
+		granted := slices.ContainsFunc(permissions, func(p permission) bool {
+			return slices.ContainsFunc(p.Actions, matchesAction(action)) && !slices.ContainsFunc(p.NotActions, matchesAction(action))
+		})
+
+		if !granted {
+			missing = append(missing, action)
+		}
+	}
+
+	return missing
+}
+
+func matchesAction(action string) func(string) bool {
+	return func(pattern string) bool {
+		expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		return regexp.MustCompile(expr).MatchString(action)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("missingActions", func() {
+	actions := []string{
+		"Microsoft.Network/networkSecurityGroups/read",
+		"Microsoft.Network/networkSecurityGroups/securityRules/delete",
+		"Microsoft.Compute/skus/read",
+	}
+
+	It("should return the actions which aren't granted", func() {
+		Expect(missingActions([]permission{{Actions: []string{"Microsoft.Network/networkSecurityGroups/read"}}}, actions)).
+			To(Equal(actions[1:]))
+	})
+
+	It("should match wildcards case-insensitively", func() {
+		Expect(missingActions([]permission{{Actions: []string{"microsoft.network/*", "*/read"}}}, actions)).To(BeEmpty())
+	})
+
+	It("should exclude the not actions", func() {
+		Expect(missingActions([]permission{{
+			Actions:    []string{"*"},
+			NotActions: []string{"Microsoft.Network/*/delete"},
+		}}, actions)).To(Equal([]string{"Microsoft.Network/networkSecurityGroups/securityRules/delete"}))
+	})
+
+	It("should combine the permissions of all the role assignments", func() {
+		Expect(missingActions([]permission{
+			{Actions: []string{"*"}, NotActions: []string{"Microsoft.Network/*/delete"}},
+			{Actions: []string{"Microsoft.Network/networkSecurityGroups/securityRules/*"}},
+		}, actions)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,555 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capi provides a MachineSetDeployer which deploys the gateway nodes of clusters managed by Cluster API, using
+// MachineDeployments instead of OpenShift MachineSets.
+package capi
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/resource"
+	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/utils/set"
+)
+
+const (
+	ClusterNameLabel    = "cluster.x-k8s.io/cluster-name"
+	DeploymentNameLabel = "cluster.x-k8s.io/deployment-name"
+
+	// MachineSetAnnotation holds the MachineSet a MachineDeployment was created from, so that List returns the
+	// MachineSets the providers deployed.
+	MachineSetAnnotation = "submariner.io/machineset"
+)
+
+var (
+	MachineDeploymentGVR = schema.GroupVersionResource{
+		Group:    "cluster.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "machinedeployments",
+	}
+
+	KubeadmConfigTemplateGVR = schema.GroupVersionResource{
+		Group:    "bootstrap.cluster.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "kubeadmconfigtemplates",
+	}
+
+	MachineGVR = schema.GroupVersionResource{
+		Group:    "cluster.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "machines",
+	}
+
+	MachineHealthCheckGVR = schema.GroupVersionResource{
+		Group:    "cluster.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "machinehealthchecks",
+	}
+
+	// Cluster API only propagates the labels of these domains from the machines to the nodes; the kubelet can't set
+	// them itself.
+	machineLabelDomains = []string{"node-role.kubernetes.io", "node-restriction.kubernetes.io", "node.cluster.x-k8s.io"}
+)
+
+// Config identifies the Cluster API cluster the gateway nodes are deployed in.
+type Config struct {
+	// ClusterName is the name of the Cluster.
+	ClusterName string
+
+	// Namespace is the namespace of the Cluster, where the gateway MachineDeployments and templates are created. The
+	// namespace of the MachineSets is ignored.
+	Namespace string
+
+	// BootstrapConfigTemplate is the name of the KubeadmConfigTemplate of the worker nodes. The gateway nodes use a copy
+	// which adds the gateway node labels and taints.
+	BootstrapConfigTemplate string
+
+	// Version is the Kubernetes version of the gateway nodes. If empty, the Cluster API defaults apply.
+	Version string
+}
+
+type machineDeploymentDeployer struct {
+	dynamicClient dynamic.Interface
+	config        Config
+}
+
+// NewMachineDeploymentDeployer returns a MachineSetDeployer which converts the gateway MachineSets to Cluster API
+// MachineDeployments. Each MachineDeployment uses an infrastructure template derived from the MachineSet's providerSpec
+// (AWSMachineTemplate, GCPMachineTemplate, AzureMachineTemplate or OpenStackMachineTemplate) and a bootstrap template
+// carrying the gateway node labels and taints, all named after the MachineSet.
+func NewMachineDeploymentDeployer(dynamicClient dynamic.Interface, config Config) ocp.MachineSetDeployer {
+	return &machineDeploymentDeployer{
+		dynamicClient: dynamicClient,
+		config:        config,
+	}
+}
+
+func (d *machineDeploymentDeployer) clientFor(gvr schema.GroupVersionResource) dynamic.ResourceInterface {
+	return d.dynamicClient.Resource(gvr).Namespace(d.config.Namespace)
+}
+
+func (d *machineDeploymentDeployer) Deploy(machineSet *unstructured.Unstructured) error {
+	return d.DeployWithContext(context.TODO(), machineSet)
+}
+
+func (d *machineDeploymentDeployer) DeployWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	infra, providerSpec, err := infrastructureFor(machineSet)
+	if err != nil {
+		return err
+	}
+
+	infraSpec, failureDomain, err := infra.machineSpec(providerSpec)
+	if err != nil {
+		return errors.Wrapf(err, "error converting the providerSpec of machine set %q", machineSet.GetName())
+	}
+
+	infraTemplate, err := d.newObject(infra.gvk, machineSet.GetName(), map[string]interface{}{
+		"template": map[string]interface{}{"spec": infraSpec},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = d.createOrUpdate(ctx, infra.gvr(), infraTemplate)
+	if err != nil {
+		return err
+	}
+
+	nodeLabels, _, _ := unstructured.NestedStringMap(machineSet.Object, "spec", "template", "spec", "metadata", "labels")
+	machineLabels, kubeletLabels := splitNodeLabels(nodeLabels)
+
+	bootstrapTemplate, err := d.newBootstrapTemplate(ctx, machineSet, kubeletLabels)
+	if err != nil {
+		return err
+	}
+
+	err = d.createOrUpdate(ctx, KubeadmConfigTemplateGVR, bootstrapTemplate)
+	if err != nil {
+		return err
+	}
+
+	machineDeployment, err := d.newMachineDeployment(machineSet, machineLabels, infraTemplate, bootstrapTemplate, failureDomain)
+	if err != nil {
+		return err
+	}
+
+	return d.createOrUpdate(ctx, MachineDeploymentGVR, machineDeployment)
+}
+
+func (d *machineDeploymentDeployer) createOrUpdate(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured,
+) error {
+	_, err := util.CreateOrUpdate(ctx, resource.ForDynamic(d.clientFor(gvr)), obj, util.Replace[*unstructured.Unstructured](obj))
+
+	return errors.Wrapf(err, "error creating %s %q", obj.GetKind(), obj.GetName())
+}
+
+func (d *machineDeploymentDeployer) newObject(gvk schema.GroupVersionKind, name string, spec map[string]interface{},
+) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(d.config.Namespace)
+	obj.SetLabels(map[string]string{
+		ClusterNameLabel:           d.config.ClusterName,
+		ocp.SubmarinerGatewayLabel: "true",
+	})
+
+	value, err := toUnstructuredValue(spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error converting the spec of %s %q", gvk.Kind, name)
+	}
+
+	obj.Object["spec"] = value
+
+	return obj, nil
+}
+
+func (d *machineDeploymentDeployer) newBootstrapTemplate(ctx context.Context, machineSet *unstructured.Unstructured,
+	kubeletLabels map[string]string,
+) (*unstructured.Unstructured, error) {
+	workers, err := d.clientFor(KubeadmConfigTemplateGVR).Get(ctx, d.config.BootstrapConfigTemplate, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving the worker KubeadmConfigTemplate %q", d.config.BootstrapConfigTemplate)
+	}
+
+	spec, _, _ := unstructured.NestedMap(workers.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+
+	nodeRegistration := []string{"template", "spec", "joinConfiguration", "nodeRegistration"}
+
+	kubeletArgs, _, _ := unstructured.NestedStringMap(spec, append(nodeRegistration, "kubeletExtraArgs")...)
+	if kubeletArgs == nil {
+		kubeletArgs = map[string]string{}
+	}
+
+	nodeLabelArgs := []string{}
+	if kubeletArgs["node-labels"] != "" {
+		nodeLabelArgs = append(nodeLabelArgs, kubeletArgs["node-labels"])
+	}
+
+	for _, key := range set.KeySet(kubeletLabels).SortedList() {
+		nodeLabelArgs = append(nodeLabelArgs, key+"="+kubeletLabels[key])
+	}
+
+	kubeletArgs["node-labels"] = strings.Join(nodeLabelArgs, ",")
+
+	err = unstructured.SetNestedStringMap(spec, kubeletArgs, append(nodeRegistration, "kubeletExtraArgs")...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error setting the gateway node labels")
+	}
+
+	taints, _, _ := unstructured.NestedSlice(machineSet.Object, "spec", "template", "spec", "taints")
+	if len(taints) > 0 {
+		existing, _, _ := unstructured.NestedSlice(spec, append(nodeRegistration, "taints")...)
+
+		err = unstructured.SetNestedSlice(spec, append(existing, taints...), append(nodeRegistration, "taints")...)
+		if err != nil {
+			return nil, errors.Wrap(err, "error setting the gateway node taints")
+		}
+	}
+
+	return d.newObject(schema.GroupVersionKind{
+		Group:   KubeadmConfigTemplateGVR.Group,
+		Version: KubeadmConfigTemplateGVR.Version,
+		Kind:    "KubeadmConfigTemplate",
+	}, machineSet.GetName(), spec)
+}
+
+func (d *machineDeploymentDeployer) newMachineDeployment(machineSet *unstructured.Unstructured, machineLabels map[string]string,
+	infraTemplate, bootstrapTemplate *unstructured.Unstructured, failureDomain string,
+) (*unstructured.Unstructured, error) {
+	replicas, found, _ := unstructured.NestedInt64(machineSet.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	templateLabels := map[string]string{
+		ClusterNameLabel:    d.config.ClusterName,
+		DeploymentNameLabel: machineSet.GetName(),
+	}
+
+	for key, value := range machineLabels {
+		templateLabels[key] = value
+	}
+
+	machineSpec := map[string]interface{}{
+		"clusterName": d.config.ClusterName,
+		"bootstrap": map[string]interface{}{
+			"configRef": objectReference(bootstrapTemplate),
+		},
+		"infrastructureRef": objectReference(infraTemplate),
+	}
+
+	if d.config.Version != "" {
+		machineSpec["version"] = d.config.Version
+	}
+
+	if failureDomain != "" {
+		machineSpec["failureDomain"] = failureDomain
+	}
+
+	machineDeployment, err := d.newObject(schema.GroupVersionKind{
+		Group:   MachineDeploymentGVR.Group,
+		Version: MachineDeploymentGVR.Version,
+		Kind:    "MachineDeployment",
+	}, machineSet.GetName(), map[string]interface{}{
+		"clusterName": d.config.ClusterName,
+		"replicas":    replicas,
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]string{
+				ClusterNameLabel:    d.config.ClusterName,
+				DeploymentNameLabel: machineSet.GetName(),
+			},
+		},
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": templateLabels},
+			"spec":     machineSpec,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(machineSet.Object)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling machine set %q", machineSet.GetName())
+	}
+
+	machineDeployment.SetAnnotations(map[string]string{MachineSetAnnotation: string(original)})
+
+	return machineDeployment, nil
+}
+
+func (d *machineDeploymentDeployer) GetWorkerNodeImage(machineSet *unstructured.Unstructured, infraID string) (string, error) {
+	return d.GetWorkerNodeImageWithContext(context.TODO(), machineSet, infraID)
+}
+
+// GetWorkerNodeImageWithContext returns the image of the first infrastructure template, of the kind matching the given
+// MachineSet, which isn't used by a gateway.
+func (d *machineDeploymentDeployer) GetWorkerNodeImageWithContext(ctx context.Context, machineSet *unstructured.Unstructured,
+	infraID string,
+) (string, error) {
+	infra, _, err := infrastructureFor(machineSet)
+	if err != nil {
+		return "", err
+	}
+
+	templates, err := d.clientFor(infra.gvr()).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "error listing the %s templates", infra.gvk.Kind)
+	}
+
+	for i := range templates.Items {
+		if templates.Items[i].GetLabels()[ocp.SubmarinerGatewayLabel] == "true" {
+			continue
+		}
+
+		image, _, _ := unstructured.NestedString(templates.Items[i].Object, append([]string{"spec", "template", "spec"}, infra.imagePath...)...)
+		if image != "" {
+			return image, nil
+		}
+	}
+
+	return "", errors.Errorf("could not retrieve the image of one of the worker nodes from the infra %q", infraID)
+}
+
+func (d *machineDeploymentDeployer) List() ([]unstructured.Unstructured, error) {
+	return d.ListWithContext(context.TODO())
+}
+
+// ListWithContext returns the MachineSets the gateway MachineDeployments of the cluster were created from.
+func (d *machineDeploymentDeployer) ListWithContext(ctx context.Context) ([]unstructured.Unstructured, error) {
+	list, err := d.clientFor(MachineDeploymentGVR).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			ClusterNameLabel:           d.config.ClusterName,
+			ocp.SubmarinerGatewayLabel: "true",
+		}).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the machine deployments")
+	}
+
+	var machineSets []unstructured.Unstructured
+
+	for i := range list.Items {
+		original, found := list.Items[i].GetAnnotations()[MachineSetAnnotation]
+		if !found {
+			continue
+		}
+
+		machineSet := unstructured.Unstructured{}
+
+		err = machineSet.UnmarshalJSON([]byte(original))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding the machine set of machine deployment %q", list.Items[i].GetName())
+		}
+
+		machineSets = append(machineSets, machineSet)
+	}
+
+	return machineSets, nil
+}
+
+// ListMachines returns the machines of the MachineDeployment created for the given MachineSet.
+func (d *machineDeploymentDeployer) ListMachines(machineSet *unstructured.Unstructured) ([]ocp.Machine, error) {
+	return d.ListMachinesWithContext(context.TODO(), machineSet)
+}
+
+func (d *machineDeploymentDeployer) ListMachinesWithContext(ctx context.Context, machineSet *unstructured.Unstructured,
+) ([]ocp.Machine, error) {
+	machineList, err := d.clientFor(MachineGVR).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			ClusterNameLabel:    d.config.ClusterName,
+			DeploymentNameLabel: machineSet.GetName(),
+		}).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing the machines of MachineDeployment %q", machineSet.GetName())
+	}
+
+	machines := make([]ocp.Machine, len(machineList.Items))
+
+	for i := range machineList.Items {
+		machines[i] = ocp.Machine{
+			Name:           machineList.Items[i].GetName(),
+			Phase:          machineStatus(&machineList.Items[i], "phase"),
+			FailureReason:  machineStatus(&machineList.Items[i], "failureReason"),
+			FailureMessage: machineStatus(&machineList.Items[i], "failureMessage"),
+			NodeName:       machineStatus(&machineList.Items[i], "nodeRef", "name"),
+		}
+	}
+
+	return machines, nil
+}
+
+// DeployHealthCheck deploys a MachineHealthCheck targeting the machines of the MachineDeployment created for the given
+// MachineSet, or deletes it if the health check is nil.
+func (d *machineDeploymentDeployer) DeployHealthCheck(machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error {
+	return d.DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)
+}
+
+func (d *machineDeploymentDeployer) DeployHealthCheckWithContext(ctx context.Context, machineSet *unstructured.Unstructured,
+	healthCheck *api.HealthCheck,
+) error {
+	if healthCheck == nil {
+		return ocp.DeleteHealthCheck(ctx, d.clientFor(MachineHealthCheckGVR), machineSet.GetName())
+	}
+
+	spec := ocp.HealthCheckSpec(healthCheck, map[string]interface{}{
+		"matchLabels": map[string]interface{}{
+			ClusterNameLabel:    d.config.ClusterName,
+			DeploymentNameLabel: machineSet.GetName(),
+		},
+	})
+	spec["clusterName"] = d.config.ClusterName
+
+	machineHealthCheck, err := d.newObject(schema.GroupVersionKind{
+		Group:   MachineHealthCheckGVR.Group,
+		Version: MachineHealthCheckGVR.Version,
+		Kind:    "MachineHealthCheck",
+	}, machineSet.GetName(), spec)
+	if err != nil {
+		return err
+	}
+
+	return d.createOrUpdate(ctx, MachineHealthCheckGVR, machineHealthCheck)
+}
+
+func (d *machineDeploymentDeployer) Delete(machineSet *unstructured.Unstructured) error {
+	return d.DeleteWithContext(context.TODO(), machineSet)
+}
+
+func (d *machineDeploymentDeployer) DeleteWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	return d.DeleteByNameWithContext(ctx, machineSet.GetName(), machineSet.GetNamespace())
+}
+
+func (d *machineDeploymentDeployer) DeleteByName(name, namespace string) error {
+	return d.DeleteByNameWithContext(context.TODO(), name, namespace)
+}
+
+// DeleteByNameWithContext deletes the MachineDeployment created for the named MachineSet, its MachineHealthCheck and the
+// templates it refers to.
+func (d *machineDeploymentDeployer) DeleteByNameWithContext(ctx context.Context, name, _ string) error {
+	err := ocp.DeleteHealthCheck(ctx, d.clientFor(MachineHealthCheckGVR), name)
+	if err != nil {
+		return err
+	}
+
+	machineDeployment, err := d.clientFor(MachineDeploymentGVR).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving machine deployment %q", name)
+	}
+
+	err = d.deleteIfExists(ctx, MachineDeploymentGVR, name)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range [][]string{{"bootstrap", "configRef"}, {"infrastructureRef"}} {
+		refMap, _, _ := unstructured.NestedStringMap(machineDeployment.Object, append([]string{"spec", "template", "spec"}, ref...)...)
+		if refMap == nil {
+			continue
+		}
+
+		err = d.deleteIfExists(ctx, templateResource(schema.FromAPIVersionAndKind(refMap["apiVersion"], refMap["kind"])), refMap["name"])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *machineDeploymentDeployer) deleteIfExists(ctx context.Context, gvr schema.GroupVersionResource, name string) error {
+	err := d.clientFor(gvr).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "error deleting %s %q", gvr.Resource, name)
+}
+
+func machineStatus(machine *unstructured.Unstructured, fields ...string) string {
+	value, _, _ := unstructured.NestedString(machine.Object, append([]string{"status"}, fields...)...)
+
+	return value
+}
+
+// templateResource returns the resource of the given template kind; the plural of all the template kinds created is
+// regular.
+func templateResource(gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	return gvk.GroupVersion().WithResource(strings.ToLower(gvk.Kind) + "s")
+}
+
+func objectReference(obj *unstructured.Unstructured) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": obj.GetAPIVersion(),
+		"kind":       obj.GetKind(),
+		"name":       obj.GetName(),
+	}
+}
+
+// splitNodeLabels separates the node labels which Cluster API propagates from the machines from those which the kubelet
+// sets.
+func splitNodeLabels(nodeLabels map[string]string) (machineLabels, kubeletLabels map[string]string) {
+	machineLabels = map[string]string{}
+	kubeletLabels = map[string]string{}
+
+	for key, value := range nodeLabels {
+		domain, _, _ := strings.Cut(key, "/")
+		if slices.ContainsFunc(machineLabelDomains, func(d string) bool { return domain == d || strings.HasSuffix(domain, "."+d) }) {
+			machineLabels[key] = value
+		} else {
+			kubeletLabels[key] = value
+		}
+	}
+
+	return machineLabels, kubeletLabels
+}
+
+// toUnstructuredValue converts the given value to its unstructured form, using its JSON representation.
+func toUnstructuredValue(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling the value")
+	}
+
+	result := map[string]interface{}{}
+
+	return result, errors.Wrap(utiljson.Unmarshal(data, &result), "error unmarshalling the value")
+}
@@ -0,0 +1,421 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/capi"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	fakeClient "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	clusterName       = "test-cluster"
+	namespace         = "test-ns"
+	workerTemplate    = "test-cluster-md-0"
+	machineSetName    = "test-infra-submariner-gw-us-east-1a"
+	workerImage       = "ami-worker"
+	bootstrapTemplate = machineSetName
+)
+
+var awsMachineTemplateGVR = schema.GroupVersionResource{
+	Group:    "infrastructure.cluster.x-k8s.io",
+	Version:  "v1beta2",
+	Resource: "awsmachinetemplates",
+}
+
+var _ = Describe("MachineDeployment deployer", func() {
+	var (
+		dynClient  *fakeClient.FakeDynamicClient
+		deployer   ocp.MachineSetDeployer
+		machineSet *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		dynClient = fakeClient.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme, map[schema.GroupVersionResource]string{
+			capi.MachineDeploymentGVR:     "MachineDeploymentList",
+			capi.KubeadmConfigTemplateGVR: "KubeadmConfigTemplateList",
+			awsMachineTemplateGVR:         "AWSMachineTemplateList",
+			capi.MachineGVR:               "MachineList",
+		})
+
+		deployer = capi.NewMachineDeploymentDeployer(dynClient, capi.Config{
+			ClusterName:             clusterName,
+			Namespace:               namespace,
+			BootstrapConfigTemplate: workerTemplate,
+			Version:                 "v1.30.0",
+		})
+
+		providerSpec := ocp.NewAWSMachineProviderConfig()
+		providerSpec.AMI = ocp.AWSResourceReference{ID: "ami-1234"}
+		providerSpec.InstanceType = "c5d.large"
+		providerSpec.Placement = ocp.AWSPlacement{AvailabilityZone: "us-east-1a", Region: "us-east-1"}
+		providerSpec.Tags = []ocp.AWSTag{{Name: "submariner.io", Value: "gateway"}}
+		providerSpec.PublicIP = true
+
+		var err error
+
+		machineSet, err = ocp.NewGatewayMachineSet("test-infra", machineSetName, providerSpec).ToUnstructured()
+		Expect(err).To(Succeed())
+
+		create(dynClient, capi.KubeadmConfigTemplateGVR, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "bootstrap.cluster.x-k8s.io/v1beta1",
+			"kind":       "KubeadmConfigTemplate",
+			"metadata":   map[string]interface{}{"name": workerTemplate, "namespace": namespace},
+			"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+				"joinConfiguration": map[string]interface{}{"nodeRegistration": map[string]interface{}{
+					"kubeletExtraArgs": map[string]interface{}{"node-labels": "tier=worker"},
+				}},
+			}}},
+		}})
+	})
+
+	Context("on Deploy", func() {
+		It("should create the infrastructure template", func() {
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+			template := get(dynClient, awsMachineTemplateGVR, machineSetName)
+			Expect(template.GetLabels()).To(HaveKeyWithValue(ocp.SubmarinerGatewayLabel, "true"))
+
+			spec, _, _ := unstructured.NestedMap(template.Object, "spec", "template", "spec")
+			Expect(spec).To(HaveKeyWithValue("instanceType", "c5d.large"))
+			Expect(spec).To(HaveKeyWithValue("ami", map[string]interface{}{"id": "ami-1234"}))
+			Expect(spec).To(HaveKeyWithValue("publicIP", true))
+			Expect(spec).To(HaveKeyWithValue("additionalTags", map[string]interface{}{"submariner.io": "gateway"}))
+		})
+
+		It("should create a bootstrap template with the gateway node labels and taints", func() {
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+			nodeRegistration, _, _ := unstructured.NestedMap(get(dynClient, capi.KubeadmConfigTemplateGVR, bootstrapTemplate).Object,
+				"spec", "template", "spec", "joinConfiguration", "nodeRegistration")
+			Expect(nodeRegistration).To(HaveKeyWithValue("kubeletExtraArgs",
+				map[string]interface{}{"node-labels": "tier=worker,submariner.io/gateway=true"}))
+			Expect(nodeRegistration).To(HaveKeyWithValue("taints", []interface{}{
+				map[string]interface{}{"key": ocp.GatewayTaintKey, "effect": "NoSchedule"},
+			}))
+		})
+
+		It("should create the MachineDeployment", func() {
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+			machineDeployment := get(dynClient, capi.MachineDeploymentGVR, machineSetName)
+			Expect(machineDeployment.GetLabels()).To(Equal(map[string]string{
+				capi.ClusterNameLabel:      clusterName,
+				ocp.SubmarinerGatewayLabel: "true",
+			}))
+
+			replicas, _, _ := unstructured.NestedInt64(machineDeployment.Object, "spec", "replicas")
+			Expect(replicas).To(Equal(int64(1)))
+
+			labels, _, _ := unstructured.NestedStringMap(machineDeployment.Object, "spec", "template", "metadata", "labels")
+			Expect(labels).To(Equal(map[string]string{
+				capi.ClusterNameLabel:           clusterName,
+				capi.DeploymentNameLabel:        machineSetName,
+				"node-role.kubernetes.io/infra": "",
+			}))
+
+			spec, _, _ := unstructured.NestedMap(machineDeployment.Object, "spec", "template", "spec")
+			Expect(spec).To(HaveKeyWithValue("clusterName", clusterName))
+			Expect(spec).To(HaveKeyWithValue("version", "v1.30.0"))
+			Expect(spec).To(HaveKeyWithValue("failureDomain", "us-east-1a"))
+			Expect(spec).To(HaveKeyWithValue("infrastructureRef", map[string]interface{}{
+				"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta2",
+				"kind":       "AWSMachineTemplate",
+				"name":       machineSetName,
+			}))
+			Expect(spec).To(HaveKeyWithValue("bootstrap", map[string]interface{}{"configRef": map[string]interface{}{
+				"apiVersion": "bootstrap.cluster.x-k8s.io/v1beta1",
+				"kind":       "KubeadmConfigTemplate",
+				"name":       bootstrapTemplate,
+			}}))
+		})
+
+		When("the worker bootstrap template doesn't exist", func() {
+			BeforeEach(func() {
+				Expect(dynClient.Resource(capi.KubeadmConfigTemplateGVR).Namespace(namespace).Delete(context.TODO(), workerTemplate,
+					metav1.DeleteOptions{})).To(Succeed())
+			})
+
+			It("should return an error", func() {
+				Expect(deployer.Deploy(machineSet)).NotTo(Succeed())
+			})
+		})
+
+		When("the providerSpec has no Cluster API equivalent", func() {
+			BeforeEach(func() {
+				Expect(unstructured.SetNestedField(machineSet.Object, "UnknownProviderSpec",
+					"spec", "template", "spec", "providerSpec", "value", "kind")).To(Succeed())
+			})
+
+			It("should return an error", func() {
+				Expect(deployer.Deploy(machineSet)).NotTo(Succeed())
+			})
+		})
+	})
+
+	DescribeTable("on Deploy with other providers, should convert the providerSpec",
+		func(providerSpec interface{}, template, failureDomain string, expected map[string]interface{}) {
+			machineSet, err := ocp.NewGatewayMachineSet("test-infra", machineSetName, providerSpec).ToUnstructured()
+			Expect(err).To(Succeed())
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+			spec, _, _ := unstructured.NestedMap(get(dynClient, capi.MachineDeploymentGVR, machineSetName).Object, "spec", "template", "spec")
+			Expect(spec["infrastructureRef"]).To(HaveKeyWithValue("kind", template))
+
+			if failureDomain == "" {
+				Expect(spec).NotTo(HaveKey("failureDomain"))
+			} else {
+				Expect(spec).To(HaveKeyWithValue("failureDomain", failureDomain))
+			}
+
+			gvr := schema.GroupVersionResource{
+				Group:    "infrastructure.cluster.x-k8s.io",
+				Version:  "v1beta1",
+				Resource: strings.ToLower(template) + "s",
+			}
+
+			machineSpec, _, _ := unstructured.NestedMap(get(dynClient, gvr, machineSetName).Object, "spec", "template", "spec")
+			for key, value := range expected {
+				Expect(machineSpec).To(HaveKeyWithValue(key, value))
+			}
+		},
+		Entry("GCP", func() interface{} {
+			providerSpec := ocp.NewGCPMachineProviderSpec()
+			providerSpec.CanIPForward = true
+			providerSpec.MachineType = "n1-standard-4"
+			providerSpec.Disks = []ocp.GCPDisk{{Boot: true, Image: "rhcos", SizeGB: 128, Type: "pd-ssd"}}
+			providerSpec.NetworkInterfaces = []ocp.GCPNetworkInterface{{PublicIP: true}}
+			providerSpec.Tags = []string{"submariner-io-gateway-node"}
+			providerSpec.Zone = "us-east1-b"
+
+			return providerSpec
+		}(), "GCPMachineTemplate", "us-east1-b", map[string]interface{}{
+			"instanceType":          "n1-standard-4",
+			"image":                 "rhcos",
+			"rootDeviceSize":        int64(128),
+			"rootDeviceType":        "pd-ssd",
+			"ipForwarding":          "Enabled",
+			"publicIP":              true,
+			"additionalNetworkTags": []interface{}{"submariner-io-gateway-node"},
+		}),
+		Entry("Azure", func() interface{} {
+			providerSpec := ocp.NewAzureMachineProviderSpec()
+			providerSpec.VMSize = "Standard_D4s_v3"
+			providerSpec.Image = ocp.AzureImage{ResourceID: "/images/rhcos"}
+			providerSpec.PublicIP = true
+			providerSpec.Tags = map[string]string{"team": "networking"}
+			providerSpec.Zone = "1"
+
+			return providerSpec
+		}(), "AzureMachineTemplate", "1", map[string]interface{}{
+			"vmSize":           "Standard_D4s_v3",
+			"image":            map[string]interface{}{"id": "/images/rhcos"},
+			"allocatePublicIP": true,
+			"additionalTags":   map[string]interface{}{"team": "networking"},
+		}),
+		Entry("OpenStack", func() interface{} {
+			providerSpec := ocp.NewOpenstackProviderSpec()
+			providerSpec.Flavor = "m1.large"
+			providerSpec.Image = "rhcos"
+			providerSpec.SecurityGroups = []ocp.OpenstackSecurityGroup{{Name: "test-infra-submariner-gw-sg"}}
+			providerSpec.ServerMetadata = map[string]string{"openshiftClusterID": "test-infra", "Name": "test-infra-worker"}
+			providerSpec.Trunk = true
+
+			return providerSpec
+		}(), "OpenStackMachineTemplate", "", map[string]interface{}{
+			"flavor":         "m1.large",
+			"image":          map[string]interface{}{"filter": map[string]interface{}{"name": "rhcos"}},
+			"securityGroups": []interface{}{map[string]interface{}{"filter": map[string]interface{}{"name": "test-infra-submariner-gw-sg"}}},
+			"serverMetadata": []interface{}{
+				map[string]interface{}{"key": "Name", "value": "test-infra-worker"},
+				map[string]interface{}{"key": "openshiftClusterID", "value": "test-infra"},
+			},
+			"trunk": true,
+		}),
+	)
+
+	Context("on List", func() {
+		It("should return the deployed MachineSets", func() {
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+			machineSets, err := deployer.List()
+			Expect(err).To(Succeed())
+			Expect(machineSets).To(HaveLen(1))
+			Expect(toJSON(&machineSets[0])).To(MatchJSON(toJSON(machineSet)))
+		})
+	})
+
+	Context("on ListMachines", func() {
+		It("should return the machines of the MachineDeployment", func() {
+			create(dynClient, capi.MachineGVR, &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "cluster.x-k8s.io/v1beta1",
+				"kind":       "Machine",
+				"metadata": map[string]interface{}{
+					"name":      machineSetName + "-abcde",
+					"namespace": namespace,
+					"labels": map[string]interface{}{
+						capi.ClusterNameLabel:    clusterName,
+						capi.DeploymentNameLabel: machineSetName,
+					},
+				},
+				"status": map[string]interface{}{
+					"phase":   "Running",
+					"nodeRef": map[string]interface{}{"name": "gw-node"},
+				},
+			}})
+
+			machines, err := ocp.WithContext(deployer).ListMachinesWithContext(context.TODO(), machineSet)
+			Expect(err).To(Succeed())
+			Expect(machines).To(Equal([]ocp.Machine{{Name: machineSetName + "-abcde", Phase: "Running", NodeName: "gw-node"}}))
+		})
+	})
+
+	Context("on DeployHealthCheck", func() {
+		It("should create a MachineHealthCheck targeting the machines of the MachineDeployment", func() {
+			Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, &api.HealthCheck{
+				MaxUnhealthy: ptr.To(intstr.FromString("50%")),
+			})).To(Succeed())
+
+			machineHealthCheck := get(dynClient, capi.MachineHealthCheckGVR, machineSetName)
+			Expect(machineHealthCheck.GetLabels()).To(HaveKeyWithValue(ocp.SubmarinerGatewayLabel, "true"))
+			Expect(machineHealthCheck.Object["spec"]).To(Equal(map[string]interface{}{
+				"clusterName": clusterName,
+				"selector": map[string]interface{}{"matchLabels": map[string]interface{}{
+					capi.ClusterNameLabel:    clusterName,
+					capi.DeploymentNameLabel: machineSetName,
+				}},
+				"unhealthyConditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False", "timeout": "5m0s"},
+					map[string]interface{}{"type": "Ready", "status": "Unknown", "timeout": "5m0s"},
+				},
+				"maxUnhealthy": "50%",
+			}))
+		})
+
+		When("the health check is nil", func() {
+			It("should delete the MachineHealthCheck", func() {
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, &api.HealthCheck{})).To(Succeed())
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, nil)).To(Succeed())
+
+				_, err := dynClient.Resource(capi.MachineHealthCheckGVR).Namespace(namespace).Get(context.TODO(), machineSetName,
+					metav1.GetOptions{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("on Delete", func() {
+		It("should delete the MachineDeployment, its MachineHealthCheck and its templates", func() {
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+			Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, &api.HealthCheck{})).To(Succeed())
+			Expect(deployer.Delete(machineSet)).To(Succeed())
+
+			for _, gvr := range []schema.GroupVersionResource{
+				capi.MachineDeploymentGVR, capi.MachineHealthCheckGVR, capi.KubeadmConfigTemplateGVR, awsMachineTemplateGVR,
+			} {
+				_, err := dynClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), machineSetName, metav1.GetOptions{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			}
+
+			get(dynClient, capi.KubeadmConfigTemplateGVR, workerTemplate)
+		})
+
+		When("the MachineDeployment doesn't exist", func() {
+			It("should succeed", func() {
+				Expect(deployer.DeleteByName(machineSetName, namespace)).To(Succeed())
+			})
+		})
+
+		When("accessing the MachineHealthChecks is forbidden", func() {
+			It("should still delete the MachineDeployment", func() {
+				Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+				dynClient.PrependReactor("*", capi.MachineHealthCheckGVR.Resource,
+					func(_ testing.Action) (bool, runtime.Object, error) {
+						return true, nil, apierrors.NewForbidden(capi.MachineHealthCheckGVR.GroupResource(), machineSetName,
+							errors.New("fake forbidden"))
+					})
+
+				Expect(deployer.Delete(machineSet)).To(Succeed())
+
+				_, err := dynClient.Resource(capi.MachineDeploymentGVR).Namespace(namespace).Get(context.TODO(), machineSetName,
+					metav1.GetOptions{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("on GetWorkerNodeImage", func() {
+		It("should return the image of a worker infrastructure template", func() {
+			Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+			_, err := deployer.GetWorkerNodeImage(machineSet, "test-infra")
+			Expect(err).NotTo(Succeed())
+
+			create(dynClient, awsMachineTemplateGVR, &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta2",
+				"kind":       "AWSMachineTemplate",
+				"metadata":   map[string]interface{}{"name": workerTemplate, "namespace": namespace},
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"ami": map[string]interface{}{"id": workerImage},
+				}}},
+			}})
+
+			image, err := deployer.GetWorkerNodeImage(machineSet, "test-infra")
+			Expect(err).To(Succeed())
+			Expect(image).To(Equal(workerImage))
+		})
+	})
+})
+
+func create(dynClient *fakeClient.FakeDynamicClient, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	_, err := dynClient.Resource(gvr).Namespace(namespace).Create(context.TODO(), obj, metav1.CreateOptions{})
+	Expect(err).To(Succeed())
+}
+
+func get(dynClient *fakeClient.FakeDynamicClient, gvr schema.GroupVersionResource, name string) *unstructured.Unstructured {
+	obj, err := dynClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	Expect(err).To(Succeed())
+
+	return obj
+}
+
+func toJSON(obj *unstructured.Unstructured) string {
+	data, err := json.Marshal(obj.Object)
+	Expect(err).To(Succeed())
+
+	return string(data)
+}
@@ -0,0 +1,205 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capi
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/set"
+)
+
+type infrastructure struct {
+	gvk schema.GroupVersionKind
+
+	// imagePath is the path of the image in the machine spec of the template.
+	imagePath []string
+
+	// machineSpec converts the providerSpec of a MachineSet to the machine spec of the template, and returns the
+	// failure domain of the machines.
+	machineSpec func(providerSpec map[string]interface{}) (map[string]interface{}, string, error)
+}
+
+func (i *infrastructure) gvr() schema.GroupVersionResource {
+	return templateResource(i.gvk)
+}
+
+// infrastructures maps the kinds of the MachineSet providerSpecs to the Cluster API infrastructure templates. The cluster
+// wide settings, such as the region, project, resource group or network, come from the infrastructure cluster instead.
+var infrastructures = map[string]infrastructure{
+	"AWSMachineProviderConfig": {
+		gvk:         infrastructureGVK("v1beta2", "AWSMachineTemplate"),
+		imagePath:   []string{"ami", "id"},
+		machineSpec: awsMachineSpec,
+	},
+	"GCPMachineProviderSpec": {
+		gvk:         infrastructureGVK("v1beta1", "GCPMachineTemplate"),
+		imagePath:   []string{"image"},
+		machineSpec: gcpMachineSpec,
+	},
+	"AzureMachineProviderSpec": {
+		gvk:         infrastructureGVK("v1beta1", "AzureMachineTemplate"),
+		imagePath:   []string{"image", "id"},
+		machineSpec: azureMachineSpec,
+	},
+	"OpenstackProviderSpec": {
+		gvk:         infrastructureGVK("v1beta1", "OpenStackMachineTemplate"),
+		imagePath:   []string{"image", "filter", "name"},
+		machineSpec: openStackMachineSpec,
+	},
+}
+
+func infrastructureGVK(version, kind string) schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "infrastructure.cluster.x-k8s.io", Version: version, Kind: kind}
+}
+
+func infrastructureFor(machineSet *unstructured.Unstructured) (*infrastructure, map[string]interface{}, error) {
+	providerSpec, _, _ := unstructured.NestedMap(machineSet.Object, "spec", "template", "spec", "providerSpec", "value")
+	if providerSpec == nil {
+		return nil, nil, errors.Errorf("the MachineSet %q has no providerSpec value", machineSet.GetName())
+	}
+
+	kind, _, _ := unstructured.NestedString(providerSpec, "kind")
+
+	infra, found := infrastructures[kind]
+	if !found {
+		return nil, nil, errors.Errorf("the providerSpec kind %q of MachineSet %q has no Cluster API equivalent", kind, machineSet.GetName())
+	}
+
+	return &infra, providerSpec, nil
+}
+
+func awsMachineSpec(value map[string]interface{}) (map[string]interface{}, string, error) {
+	providerSpec := &ocp.AWSMachineProviderConfig{}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(value, providerSpec)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error decoding the AWS providerSpec")
+	}
+
+	tags := map[string]string{}
+	for _, tag := range providerSpec.Tags {
+		tags[tag.Name] = tag.Value
+	}
+
+	spec := map[string]interface{}{
+		"instanceType":             providerSpec.InstanceType,
+		"ami":                      providerSpec.AMI,
+		"publicIP":                 providerSpec.PublicIP,
+		"subnet":                   providerSpec.Subnet,
+		"additionalSecurityGroups": providerSpec.SecurityGroups,
+		"additionalTags":           tags,
+	}
+
+	if providerSpec.IAMInstanceProfile != nil {
+		spec["iamInstanceProfile"] = providerSpec.IAMInstanceProfile.ID
+	}
+
+	return spec, providerSpec.Placement.AvailabilityZone, nil
+}
+
+func gcpMachineSpec(value map[string]interface{}) (map[string]interface{}, string, error) {
+	providerSpec := &ocp.GCPMachineProviderSpec{}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(value, providerSpec)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error decoding the GCP providerSpec")
+	}
+
+	ipForwarding := "Disabled"
+	if providerSpec.CanIPForward {
+		ipForwarding = "Enabled"
+	}
+
+	spec := map[string]interface{}{
+		"instanceType":          providerSpec.MachineType,
+		"ipForwarding":          ipForwarding,
+		"additionalLabels":      providerSpec.Labels,
+		"additionalNetworkTags": providerSpec.Tags,
+	}
+
+	for i := range providerSpec.Disks {
+		if providerSpec.Disks[i].Boot {
+			spec["image"] = providerSpec.Disks[i].Image
+			spec["rootDeviceSize"] = providerSpec.Disks[i].SizeGB
+			spec["rootDeviceType"] = providerSpec.Disks[i].Type
+		}
+	}
+
+	for i := range providerSpec.NetworkInterfaces {
+		if providerSpec.NetworkInterfaces[i].PublicIP {
+			spec["publicIP"] = true
+		}
+	}
+
+	if len(providerSpec.ServiceAccounts) > 0 {
+		spec["serviceAccounts"] = providerSpec.ServiceAccounts[0]
+	}
+
+	return spec, providerSpec.Zone, nil
+}
+
+// azureMachineSpec converts the Azure providerSpec. Cluster API associates the security groups with the subnets rather
+// than the machines, so the gateway security group isn't carried over.
+func azureMachineSpec(value map[string]interface{}) (map[string]interface{}, string, error) {
+	providerSpec := &ocp.AzureMachineProviderSpec{}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(value, providerSpec)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error decoding the Azure providerSpec")
+	}
+
+	return map[string]interface{}{
+		"vmSize":           providerSpec.VMSize,
+		"image":            map[string]interface{}{"id": providerSpec.Image.ResourceID},
+		"osDisk":           providerSpec.OSDisk,
+		"allocatePublicIP": providerSpec.PublicIP,
+		"additionalTags":   providerSpec.Tags,
+	}, providerSpec.Zone, nil
+}
+
+func openStackMachineSpec(value map[string]interface{}) (map[string]interface{}, string, error) {
+	providerSpec := &ocp.OpenstackProviderSpec{}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(value, providerSpec)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error decoding the OpenStack providerSpec")
+	}
+
+	securityGroups := make([]interface{}, len(providerSpec.SecurityGroups))
+	for i := range providerSpec.SecurityGroups {
+		securityGroups[i] = map[string]interface{}{"filter": map[string]interface{}{"name": providerSpec.SecurityGroups[i].Name}}
+	}
+
+	serverMetadata := []interface{}{}
+	for _, key := range set.KeySet(providerSpec.ServerMetadata).SortedList() {
+		serverMetadata = append(serverMetadata, map[string]interface{}{"key": key, "value": providerSpec.ServerMetadata[key]})
+	}
+
+	return map[string]interface{}{
+		"flavor":         providerSpec.Flavor,
+		"image":          map[string]interface{}{"filter": map[string]interface{}{"name": providerSpec.Image}},
+		"securityGroups": securityGroups,
+		"serverMetadata": serverMetadata,
+		"tags":           providerSpec.Tags,
+		"trunk":          providerSpec.Trunk,
+	}, "", nil
+}
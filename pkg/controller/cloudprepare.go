@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Group is the API group of the CloudPrepare resource.
+	Group = "cloud-prepare.submariner.io"
+
+	// Version is the API version of the CloudPrepare resource.
+	Version = "v1alpha1"
+
+	// Kind is the kind of the CloudPrepare resource.
+	Kind = "CloudPrepare"
+
+	// FinalizerName is the finalizer added to the CloudPrepare resources so that the cloud is cleaned up before they're
+	// deleted.
+	FinalizerName = "cloud-prepare.submariner.io/cleanup"
+)
+
+const (
+	// PortsOpenedCondition reports whether the internal ports are open.
+	PortsOpenedCondition = "PortsOpened"
+
+	// GatewaysDeployedCondition reports whether the gateways are deployed.
+	GatewaysDeployedCondition = "GatewaysDeployed"
+
+	// CleanedUpCondition reports whether the gateways and the internal ports were cleaned up, once the resource is deleted.
+	CleanedUpCondition = "CleanedUp"
+)
+
+// The reasons of the conditions.
+const (
+	ReasonSucceeded = "Succeeded"
+	ReasonFailed    = "Failed"
+	ReasonSkipped   = "Skipped"
+)
+
+// GroupVersionResource identifies the CloudPrepare resources.
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    Group,
+	Version:  Version,
+	Resource: "cloudprepares",
+}
+
+// GroupVersionKind is the kind of the CloudPrepare resources.
+var GroupVersionKind = GroupVersionResource.GroupVersion().WithKind(Kind)
+
+// CloudPrepareSpec is the spec of a CloudPrepare resource, describing how the cloud of the cluster the controller runs in
+// is prepared for Submariner.
+type CloudPrepareSpec struct {
+	// InternalPorts are the ports opened for intra-cluster communications, see api.ContextCloud.ReconcilePorts.
+	InternalPorts []api.PortSpec `json:"internalPorts,omitempty"`
+
+	// PublicPorts are the ports opened on the gateways, see api.GatewayDeployInput.
+	PublicPorts []api.PortSpec `json:"publicPorts,omitempty"`
+
+	// Gateways is the number of gateways to deploy, 0 meaning the deployer's default policy.
+	Gateways int `json:"gateways,omitempty"`
+
+	UseLoadBalancer bool         `json:"useLoadBalancer,omitempty"`
+	AirGapped       bool         `json:"airGapped,omitempty"`
+	SourceCIDRs     []string     `json:"sourceCIDRs,omitempty"`
+	IPFamily        api.IPFamily `json:"ipFamily,omitempty"`
+}
+
+// GatewayDeployInput returns the input of GatewayDeployer.Deploy matching the spec.
+func (s *CloudPrepareSpec) GatewayDeployInput() api.GatewayDeployInput {
+	return api.GatewayDeployInput{
+		PublicPorts:     s.PublicPorts,
+		Gateways:        s.Gateways,
+		UseLoadBalancer: s.UseLoadBalancer,
+		AirGapped:       s.AirGapped,
+		SourceCIDRs:     s.SourceCIDRs,
+		IPFamily:        s.IPFamily,
+	}
+}
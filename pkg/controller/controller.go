@@ -0,0 +1,285 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package controller provides a Kubernetes controller which prepares the cloud for Submariner as described by CloudPrepare
+// custom resources: it opens the internal ports and deploys the gateways, reports the outcome in the resources'
+// conditions, and closes the ports and cleans up the gateways when the resources are deleted.
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/finalizer"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/admiral/pkg/resource"
+	"github.com/submariner-io/admiral/pkg/syncer"
+	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/admiral/pkg/watcher"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+)
+
+type Config struct {
+	// RestConfig the REST config used to access the CloudPrepare resources.
+	RestConfig *rest.Config
+
+	// RestMapper and Client are optional and are provided for unit testing in lieu of the RestConfig.
+	RestMapper meta.RESTMapper
+	Client     dynamic.Interface
+
+	// Namespace of the CloudPrepare resources to watch, all the namespaces if empty.
+	Namespace string
+
+	// Cloud and GatewayDeployer prepare the cloud of the cluster.
+	Cloud           api.Cloud
+	GatewayDeployer api.GatewayDeployer
+
+	// Reporter reports the progress of the cloud operations. It defaults to logging through klog.
+	Reporter reporter.Interface
+}
+
+type Controller struct {
+	config  Config
+	client  dynamic.NamespaceableResourceInterface
+	watcher watcher.Interface
+}
+
+// New creates a controller reconciling the CloudPrepare resources with the configured Cloud and GatewayDeployer.
+func New(config *Config) (*Controller, error) {
+	c := &Controller{config: *config}
+
+	if c.config.Reporter == nil {
+		c.config.Reporter = reporter.Klog()
+	}
+
+	client := config.Client
+	if client == nil {
+		var err error
+
+		client, err = dynamic.NewForConfig(config.RestConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating dynamic client")
+		}
+	}
+
+	c.client = client.Resource(GroupVersionResource)
+
+	resourceType := &unstructured.Unstructured{}
+	resourceType.SetGroupVersionKind(GroupVersionKind)
+
+	var err error
+
+	c.watcher, err = watcher.New(&watcher.Config{
+		RestConfig: config.RestConfig,
+		RestMapper: config.RestMapper,
+		Client:     client,
+		ResourceConfigs: []watcher.ResourceConfig{
+			{
+				Name:         "CloudPrepare watcher",
+				ResourceType: resourceType,
+				Handler: watcher.EventHandlerFuncs{
+					OnCreateFunc: c.onCreateOrUpdate,
+					OnUpdateFunc: c.onCreateOrUpdate,
+				},
+				ResourcesEquivalent: resourcesEquivalent,
+				SourceNamespace:     config.Namespace,
+			},
+		},
+	})
+
+	return c, errors.Wrap(err, "error creating the CloudPrepare watcher")
+}
+
+// Start starts the controller, until the given channel is closed.
+func (c *Controller) Start(stopCh <-chan struct{}) error {
+	return errors.Wrap(c.watcher.Start(stopCh), "error starting the CloudPrepare watcher")
+}
+
+// resourcesEquivalent ignores the updates which change neither the spec nor the deletion of the resource, such as the
+// status updates made by the controller itself.
+func resourcesEquivalent(obj1, obj2 *unstructured.Unstructured) bool {
+	return syncer.AreSpecsEquivalent(obj1, obj2) &&
+		obj1.GetDeletionTimestamp().IsZero() == obj2.GetDeletionTimestamp().IsZero()
+}
+
+func (c *Controller) onCreateOrUpdate(obj runtime.Object, _ int) bool {
+	cloudPrepare := obj.(*unstructured.Unstructured)
+
+	err := c.reconcile(context.Background(), cloudPrepare)
+	if err != nil {
+		_ = c.config.Reporter.Error(err, "Error reconciling CloudPrepare %s/%s", cloudPrepare.GetNamespace(), cloudPrepare.GetName())
+	}
+
+	return err != nil
+}
+
+func (c *Controller) reconcile(ctx context.Context, cloudPrepare *unstructured.Unstructured) error {
+	if !cloudPrepare.GetDeletionTimestamp().IsZero() {
+		return c.cleanup(ctx, cloudPrepare)
+	}
+
+	resourceClient := resource.ForDynamic(c.client.Namespace(cloudPrepare.GetNamespace()))
+
+	if _, err := finalizer.Add(ctx, resourceClient, cloudPrepare, FinalizerName); err != nil {
+		return err //nolint:wrapcheck // No need to wrap.
+	}
+
+	spec := &CloudPrepareSpec{}
+
+	rawSpec, _, _ := unstructured.NestedMap(cloudPrepare.Object, "spec")
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawSpec, spec); err != nil {
+		return errors.Wrap(err, "error converting the CloudPrepare spec")
+	}
+
+	openErr := c.openPorts(ctx, spec.InternalPorts)
+	conditions := []metav1.Condition{newCondition(PortsOpenedCondition, openErr)}
+
+	var deployErr error
+
+	if openErr == nil {
+		deployErr = c.deployGateways(ctx, spec.GatewayDeployInput())
+		conditions = append(conditions, newCondition(GatewaysDeployedCondition, deployErr))
+	} else {
+		conditions = append(conditions, metav1.Condition{
+			Type:    GatewaysDeployedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonSkipped,
+			Message: "The internal ports couldn't be opened",
+		})
+	}
+
+	if err := c.updateConditions(ctx, cloudPrepare, conditions...); err != nil {
+		return err
+	}
+
+	if openErr != nil {
+		return errors.WithMessage(openErr, "error opening the internal ports")
+	}
+
+	return errors.WithMessage(deployErr, "error deploying the gateways")
+}
+
+func (c *Controller) cleanup(ctx context.Context, cloudPrepare *unstructured.Unstructured) error {
+	if !finalizer.IsPresent(cloudPrepare, FinalizerName) {
+		return nil
+	}
+
+	err := c.cleanupGateways(ctx)
+	if err == nil {
+		err = c.closePorts(ctx)
+	}
+
+	if err != nil {
+		return utilerrors.NewAggregate([]error{
+			errors.WithMessage(err, "error cleaning up the cloud"),
+			c.updateConditions(ctx, cloudPrepare, newCondition(CleanedUpCondition, err)),
+		})
+	}
+
+	return finalizer.Remove(ctx, resource.ForDynamic(c.client.Namespace(cloudPrepare.GetNamespace())), cloudPrepare,
+		FinalizerName) //nolint:wrapcheck // No need to wrap.
+}
+
+// openPorts, deployGateways, cleanupGateways and closePorts use the context aware operations when the configured Cloud
+// and GatewayDeployer support them, and fall back to the basic operations otherwise. The internal ports are reconciled,
+// rather than only opened, so that the ports removed from the spec are closed as well.
+func (c *Controller) openPorts(ctx context.Context, ports []api.PortSpec) error {
+	if cloud, ok := c.config.Cloud.(api.ContextCloud); ok {
+		_, err := cloud.ReconcilePorts(ctx, ports, c.config.Reporter)
+		return err //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	return c.config.Cloud.OpenPorts(ports, c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (c *Controller) deployGateways(ctx context.Context, input api.GatewayDeployInput) error {
+	if deployer, ok := c.config.GatewayDeployer.(api.ContextGatewayDeployer); ok {
+		return deployer.DeployWithContext(ctx, input, c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	return c.config.GatewayDeployer.Deploy(input, c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (c *Controller) cleanupGateways(ctx context.Context) error {
+	if deployer, ok := c.config.GatewayDeployer.(api.ContextGatewayDeployer); ok {
+		return deployer.CleanupWithContext(ctx, c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	return c.config.GatewayDeployer.Cleanup(c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (c *Controller) closePorts(ctx context.Context) error {
+	if cloud, ok := c.config.Cloud.(api.ContextCloud); ok {
+		return cloud.ClosePortsWithContext(ctx, c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	return c.config.Cloud.ClosePorts(c.config.Reporter) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+// updateConditions sets the given conditions in the status of the CloudPrepare resource, as observed at its current
+// generation.
+func (c *Controller) updateConditions(ctx context.Context, cloudPrepare *unstructured.Unstructured, conditions ...metav1.Condition,
+) error {
+	client := c.client.Namespace(cloudPrepare.GetNamespace())
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := client.Get(ctx, cloudPrepare.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err //nolint:wrapcheck // Wrapped below.
+		}
+
+		existingConditions := util.ConditionsFromUnstructured(existing, "status", "conditions")
+
+		for i := range conditions {
+			conditions[i].ObservedGeneration = existing.GetGeneration()
+			meta.SetStatusCondition(&existingConditions, conditions[i])
+		}
+
+		util.ConditionsToUnstructured(existingConditions, existing, "status", "conditions")
+
+		_, err = client.UpdateStatus(ctx, existing, metav1.UpdateOptions{})
+
+		return err //nolint:wrapcheck // Wrapped below.
+	})
+
+	return errors.Wrapf(err, "error updating the status of CloudPrepare %q", cloudPrepare.GetName())
+}
+
+func newCondition(conditionType string, err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonFailed,
+			Message: err.Error(),
+		}
+	}
+
+	return metav1.Condition{
+		Type:   conditionType,
+		Status: metav1.ConditionTrue,
+		Reason: ReasonSucceeded,
+	}
+}
@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/fake"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/admiral/pkg/syncer/test"
+	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const (
+	namespace = "submariner-operator"
+	name      = "cloud-prepare"
+)
+
+var _ = Describe("Controller", func() {
+	t := newTestDriver()
+
+	When("a CloudPrepare is created", func() {
+		It("should open the ports, deploy the gateways and report it in the conditions", func() {
+			t.awaitCondition(controller.PortsOpenedCondition, metav1.ConditionTrue, controller.ReasonSucceeded)
+			t.awaitCondition(controller.GatewaysDeployedCondition, metav1.ConditionTrue, controller.ReasonSucceeded)
+
+			Expect(t.cloud.get().openedPorts).To(Equal([]api.PortSpec{{Port: 4800, Protocol: "udp"}}))
+			Expect(t.gwDeployer.get().deployInput).To(Equal(api.GatewayDeployInput{
+				PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+				Gateways:    2,
+			}))
+			Expect(test.GetResource(t.client, t.cloudPrepare).GetFinalizers()).To(ContainElement(controller.FinalizerName))
+		})
+	})
+
+	When("opening the ports fails", func() {
+		BeforeEach(func() {
+			t.cloud.openErr = errors.New("fake open error")
+		})
+
+		It("should report it and not deploy the gateways", func() {
+			t.awaitCondition(controller.PortsOpenedCondition, metav1.ConditionFalse, controller.ReasonFailed)
+			t.awaitCondition(controller.GatewaysDeployedCondition, metav1.ConditionFalse, controller.ReasonSkipped)
+
+			Expect(t.gwDeployer.get().deployed).To(BeFalse())
+		})
+	})
+
+	When("deploying the gateways fails", func() {
+		BeforeEach(func() {
+			t.gwDeployer.deployErr = errors.New("fake deploy error")
+		})
+
+		It("should report it", func() {
+			t.awaitCondition(controller.PortsOpenedCondition, metav1.ConditionTrue, controller.ReasonSucceeded)
+			t.awaitCondition(controller.GatewaysDeployedCondition, metav1.ConditionFalse, controller.ReasonFailed)
+		})
+	})
+
+	When("the CloudPrepare spec is updated", func() {
+		It("should open the new ports", func() {
+			t.awaitCondition(controller.PortsOpenedCondition, metav1.ConditionTrue, controller.ReasonSucceeded)
+
+			existing := test.GetResource(t.client, t.cloudPrepare)
+			Expect(unstructured.SetNestedSlice(existing.Object, []interface{}{
+				map[string]interface{}{"port": int64(4490), "protocol": "udp"},
+			}, "spec", "internalPorts")).To(Succeed())
+			test.UpdateResource(t.client, existing)
+
+			Eventually(func() []api.PortSpec {
+				return t.cloud.get().openedPorts
+			}).Should(Equal([]api.PortSpec{{Port: 4490, Protocol: "udp"}}))
+		})
+	})
+
+	When("the CloudPrepare is deleted", func() {
+		JustBeforeEach(func() {
+			t.awaitCondition(controller.GatewaysDeployedCondition, metav1.ConditionTrue, controller.ReasonSucceeded)
+			Expect(t.client.Delete(context.TODO(), name, metav1.DeleteOptions{})).To(Succeed())
+		})
+
+		It("should clean up the gateways, close the ports and remove the finalizer", func() {
+			test.AwaitNoResource(t.client, name)
+
+			Expect(t.gwDeployer.get().cleanedUp).To(BeTrue())
+			Expect(t.cloud.get().closed).To(BeTrue())
+		})
+
+		Context("and the clean up fails", func() {
+			BeforeEach(func() {
+				t.gwDeployer.cleanupErr = errors.New("fake cleanup error")
+			})
+
+			It("should report it and keep the CloudPrepare", func() {
+				t.awaitCondition(controller.CleanedUpCondition, metav1.ConditionFalse, controller.ReasonFailed)
+
+				Expect(t.cloud.get().closed).To(BeFalse())
+				Expect(test.GetResource(t.client, t.cloudPrepare).GetFinalizers()).To(ContainElement(controller.FinalizerName))
+			})
+		})
+	})
+})
+
+type testDriver struct {
+	client       dynamic.ResourceInterface
+	cloudPrepare *unstructured.Unstructured
+	cloud        *fakeCloud
+	gwDeployer   *fakeGatewayDeployer
+	stopCh       chan struct{}
+}
+
+func newTestDriver() *testDriver {
+	t := &testDriver{}
+
+	BeforeEach(func() {
+		t.cloud = &fakeCloud{}
+		t.gwDeployer = &fakeGatewayDeployer{}
+
+		t.cloudPrepare = &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"internalPorts": []interface{}{
+					map[string]interface{}{"port": int64(4800), "protocol": "udp"},
+				},
+				"publicPorts": []interface{}{
+					map[string]interface{}{"port": int64(4500), "protocol": "udp"},
+				},
+				"gateways": int64(2),
+			},
+		}}
+		t.cloudPrepare.SetGroupVersionKind(controller.GroupVersionKind)
+		t.cloudPrepare.SetNamespace(namespace)
+		t.cloudPrepare.SetName(name)
+	})
+
+	JustBeforeEach(func() {
+		restMapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{controller.GroupVersionKind.GroupVersion()})
+		restMapper.Add(controller.GroupVersionKind, meta.RESTScopeNamespace)
+
+		dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{controller.GroupVersionResource: controller.Kind + "List"})
+		fake.AddBasicReactors(&dynamicClient.Fake)
+
+		t.client = dynamicClient.Resource(controller.GroupVersionResource).Namespace(namespace)
+
+		c, err := controller.New(&controller.Config{
+			RestMapper:      restMapper,
+			Client:          dynamicClient,
+			Namespace:       namespace,
+			Cloud:           t.cloud,
+			GatewayDeployer: t.gwDeployer,
+			Reporter:        reporter.Stdout(),
+		})
+		Expect(err).To(Succeed())
+
+		t.stopCh = make(chan struct{})
+		Expect(c.Start(t.stopCh)).To(Succeed())
+
+		test.CreateResource(t.client, t.cloudPrepare)
+	})
+
+	AfterEach(func() {
+		close(t.stopCh)
+	})
+
+	return t
+}
+
+func (t *testDriver) awaitCondition(conditionType string, status metav1.ConditionStatus, reason string) {
+	Eventually(func() *metav1.Condition {
+		obj, err := t.client.Get(context.TODO(), name, metav1.GetOptions{})
+		Expect(err).To(Succeed())
+
+		return meta.FindStatusCondition(util.ConditionsFromUnstructured(obj, "status", "conditions"), conditionType)
+	}).Should(And(Not(BeNil()), HaveField("Status", status), HaveField("Reason", reason)),
+		"Condition %q not found with status %q", conditionType, status)
+}
+
+type fakeCloud struct {
+	api.ContextCloud
+	mutex       sync.Mutex
+	openErr     error
+	openedPorts []api.PortSpec
+	closed      bool
+}
+
+func (f *fakeCloud) ReconcilePorts(_ context.Context, ports []api.PortSpec, _ reporter.Interface) (*api.Plan, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.openedPorts = ports
+
+	return &api.Plan{}, f.openErr
+}
+
+func (f *fakeCloud) ClosePortsWithContext(_ context.Context, _ reporter.Interface) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.closed = true
+
+	return nil
+}
+
+func (f *fakeCloud) get() fakeCloud {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return fakeCloud{openedPorts: f.openedPorts, closed: f.closed}
+}
+
+type fakeGatewayDeployer struct {
+	api.ContextGatewayDeployer
+	mutex       sync.Mutex
+	deployErr   error
+	cleanupErr  error
+	deployInput api.GatewayDeployInput
+	deployed    bool
+	cleanedUp   bool
+}
+
+func (f *fakeGatewayDeployer) DeployWithContext(_ context.Context, input api.GatewayDeployInput, _ reporter.Interface) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.deployInput = input
+	f.deployed = true
+
+	return f.deployErr
+}
+
+func (f *fakeGatewayDeployer) CleanupWithContext(_ context.Context, _ reporter.Interface) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.cleanedUp = true
+
+	return f.cleanupErr
+}
+
+func (f *fakeGatewayDeployer) get() fakeGatewayDeployer {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return fakeGatewayDeployer{deployInput: f.deployInput, deployed: f.deployed, cleanedUp: f.cleanedUp}
+}
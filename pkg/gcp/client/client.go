@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"strings"
 
+	"google.golang.org/api/cloudresourcemanager/v1"
 	compute "google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
@@ -36,42 +37,88 @@ import (
 // Interface wraps an actual GCP library client to allow for easier testing.
 type Interface interface {
 	InsertFirewallRule(projectID string, rule *compute.Firewall) error
+	InsertFirewallRuleWithContext(ctx context.Context, projectID string, rule *compute.Firewall) error
 	GetFirewallRule(projectID, name string) (*compute.Firewall, error)
+	GetFirewallRuleWithContext(ctx context.Context, projectID, name string) (*compute.Firewall, error)
 	DeleteFirewallRule(projectID, name string) error
+	DeleteFirewallRuleWithContext(ctx context.Context, projectID, name string) error
 	UpdateFirewallRule(projectID, name string, rule *compute.Firewall) error
+	UpdateFirewallRuleWithContext(ctx context.Context, projectID, name string, rule *compute.Firewall) error
+	ListFirewallRules(projectID, filter string) ([]*compute.Firewall, error)
+	ListFirewallRulesWithContext(ctx context.Context, projectID, filter string) ([]*compute.Firewall, error)
 	GetInstance(zone string, instance string) (*compute.Instance, error)
+	GetInstanceWithContext(ctx context.Context, zone string, instance string) (*compute.Instance, error)
 	ListInstances(zone string) (*compute.InstanceList, error)
+	ListInstancesWithContext(ctx context.Context, zone string) (*compute.InstanceList, error)
 	ListZones() (*compute.ZoneList, error)
+	ListZonesWithContext(ctx context.Context) (*compute.ZoneList, error)
 	InstanceHasPublicIP(instance *compute.Instance) (bool, error)
 	UpdateInstanceNetworkTags(project, zone, instance string, tags *compute.Tags) error
+	UpdateInstanceNetworkTagsWithContext(ctx context.Context, project, zone, instance string, tags *compute.Tags) error
 	ConfigurePublicIPOnInstance(instance *compute.Instance) error
+	ConfigurePublicIPOnInstanceWithContext(ctx context.Context, instance *compute.Instance) error
 	DeletePublicIPOnInstance(instance *compute.Instance) error
+	DeletePublicIPOnInstanceWithContext(ctx context.Context, instance *compute.Instance) error
+	TestPermissions(permissions []string) ([]string, error)
+	TestPermissionsWithContext(ctx context.Context, permissions []string) ([]string, error)
 }
 
 type gcpClient struct {
-	projectID     string
-	computeClient *compute.Service
+	projectID       string
+	computeClient   *compute.Service
+	resourceManager *cloudresourcemanager.Service
 }
 
 func (g *gcpClient) InsertFirewallRule(projectID string, rule *compute.Firewall) error {
-	_, err := g.computeClient.Firewalls.Insert(projectID, rule).Context(context.TODO()).Do()
+	return g.InsertFirewallRuleWithContext(context.TODO(), projectID, rule)
+}
+
+func (g *gcpClient) InsertFirewallRuleWithContext(ctx context.Context, projectID string, rule *compute.Firewall) error {
+	_, err := g.computeClient.Firewalls.Insert(projectID, rule).Context(ctx).Do()
 	return err
 }
 
 func (g *gcpClient) GetFirewallRule(projectID, name string) (*compute.Firewall, error) {
-	return g.computeClient.Firewalls.Get(projectID, name).Context(context.TODO()).Do()
+	return g.GetFirewallRuleWithContext(context.TODO(), projectID, name)
+}
+
+func (g *gcpClient) GetFirewallRuleWithContext(ctx context.Context, projectID, name string) (*compute.Firewall, error) {
+	return g.computeClient.Firewalls.Get(projectID, name).Context(ctx).Do()
 }
 
 func (g *gcpClient) DeleteFirewallRule(projectID, name string) error {
-	_, err := g.computeClient.Firewalls.Delete(projectID, name).Context(context.TODO()).Do()
+	return g.DeleteFirewallRuleWithContext(context.TODO(), projectID, name)
+}
+
+func (g *gcpClient) DeleteFirewallRuleWithContext(ctx context.Context, projectID, name string) error {
+	_, err := g.computeClient.Firewalls.Delete(projectID, name).Context(ctx).Do()
 	return err
 }
 
 func (g *gcpClient) UpdateFirewallRule(projectID, name string, rule *compute.Firewall) error {
-	_, err := g.computeClient.Firewalls.Update(projectID, name, rule).Context(context.TODO()).Do()
+	return g.UpdateFirewallRuleWithContext(context.TODO(), projectID, name, rule)
+}
+
+func (g *gcpClient) UpdateFirewallRuleWithContext(ctx context.Context, projectID, name string, rule *compute.Firewall) error {
+	_, err := g.computeClient.Firewalls.Update(projectID, name, rule).Context(ctx).Do()
 	return err
 }
 
+func (g *gcpClient) ListFirewallRules(projectID, filter string) ([]*compute.Firewall, error) {
+	return g.ListFirewallRulesWithContext(context.TODO(), projectID, filter)
+}
+
+func (g *gcpClient) ListFirewallRulesWithContext(ctx context.Context, projectID, filter string) ([]*compute.Firewall, error) {
+	var rules []*compute.Firewall
+
+	err := g.computeClient.Firewalls.List(projectID).Filter(filter).Pages(ctx, func(list *compute.FirewallList) error {
+		rules = append(rules, list.Items...)
+		return nil
+	})
+
+	return rules, err
+}
+
 func NewClient(projectID string, options []option.ClientOption) (Interface, error) {
 	ctx := context.TODO()
 
@@ -80,9 +127,15 @@ func NewClient(projectID string, options []option.ClientOption) (Interface, erro
 		return nil, err
 	}
 
+	resourceManager, err := cloudresourcemanager.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &gcpClient{
-		projectID:     projectID,
-		computeClient: computeClient,
+		projectID:       projectID,
+		computeClient:   computeClient,
+		resourceManager: resourceManager,
 	}, nil
 }
 
@@ -96,15 +149,27 @@ func IsGCPNotFoundError(err error) bool {
 }
 
 func (g *gcpClient) GetInstance(zone, instance string) (*compute.Instance, error) {
-	return g.computeClient.Instances.Get(g.projectID, zone, instance).Context(context.TODO()).Do()
+	return g.GetInstanceWithContext(context.TODO(), zone, instance)
+}
+
+func (g *gcpClient) GetInstanceWithContext(ctx context.Context, zone, instance string) (*compute.Instance, error) {
+	return g.computeClient.Instances.Get(g.projectID, zone, instance).Context(ctx).Do()
 }
 
 func (g *gcpClient) ListInstances(zone string) (*compute.InstanceList, error) {
-	return g.computeClient.Instances.List(g.projectID, zone).Context(context.TODO()).Do()
+	return g.ListInstancesWithContext(context.TODO(), zone)
+}
+
+func (g *gcpClient) ListInstancesWithContext(ctx context.Context, zone string) (*compute.InstanceList, error) {
+	return g.computeClient.Instances.List(g.projectID, zone).Context(ctx).Do()
 }
 
 func (g *gcpClient) ListZones() (*compute.ZoneList, error) {
-	return g.computeClient.Zones.List(g.projectID).Context(context.TODO()).Do()
+	return g.ListZonesWithContext(context.TODO())
+}
+
+func (g *gcpClient) ListZonesWithContext(ctx context.Context) (*compute.ZoneList, error) {
+	return g.computeClient.Zones.List(g.projectID).Context(ctx).Do()
 }
 
 func (g *gcpClient) InstanceHasPublicIP(instance *compute.Instance) (bool, error) {
@@ -117,12 +182,22 @@ func (g *gcpClient) InstanceHasPublicIP(instance *compute.Instance) (bool, error
 }
 
 func (g *gcpClient) UpdateInstanceNetworkTags(project, zone, instance string, tags *compute.Tags) error {
-	_, err := g.computeClient.Instances.SetTags(project, zone, instance, tags).Context(context.TODO()).Do()
+	return g.UpdateInstanceNetworkTagsWithContext(context.TODO(), project, zone, instance, tags)
+}
+
+func (g *gcpClient) UpdateInstanceNetworkTagsWithContext(ctx context.Context, project, zone, instance string,
+	tags *compute.Tags,
+) error {
+	_, err := g.computeClient.Instances.SetTags(project, zone, instance, tags).Context(ctx).Do()
 
 	return err
 }
 
 func (g *gcpClient) ConfigurePublicIPOnInstance(instance *compute.Instance) error {
+	return g.ConfigurePublicIPOnInstanceWithContext(context.TODO(), instance)
+}
+
+func (g *gcpClient) ConfigurePublicIPOnInstanceWithContext(ctx context.Context, instance *compute.Instance) error {
 	networkInterface, err := getNetworkInterface(instance)
 	if err != nil {
 		return err
@@ -138,12 +213,16 @@ func (g *gcpClient) ConfigurePublicIPOnInstance(instance *compute.Instance) erro
 
 	_, err = g.computeClient.Instances.AddAccessConfig(g.projectID, zone, instance.Name,
 		networkInterface.Name, &compute.AccessConfig{}).
-		Context(context.TODO()).Do()
+		Context(ctx).Do()
 
 	return err
 }
 
 func (g *gcpClient) DeletePublicIPOnInstance(instance *compute.Instance) error {
+	return g.DeletePublicIPOnInstanceWithContext(context.TODO(), instance)
+}
+
+func (g *gcpClient) DeletePublicIPOnInstanceWithContext(ctx context.Context, instance *compute.Instance) error {
 	networkInterface, err := getNetworkInterface(instance)
 	if err != nil {
 		return err
@@ -153,7 +232,7 @@ func (g *gcpClient) DeletePublicIPOnInstance(instance *compute.Instance) error {
 	zone := instance.Zone[strings.LastIndex(instance.Zone, "/")+1:]
 	_, err = g.computeClient.Instances.DeleteAccessConfig(
 		g.projectID, zone, instance.Name, "External NAT", networkInterface.Name).
-		Context(context.TODO()).Do()
+		Context(ctx).Do()
 
 	return err
 }
@@ -165,3 +244,18 @@ func getNetworkInterface(instance *compute.Instance) (*compute.NetworkInterface,
 
 	return instance.NetworkInterfaces[0], nil
 }
+
+func (g *gcpClient) TestPermissions(permissions []string) ([]string, error) {
+	return g.TestPermissionsWithContext(context.TODO(), permissions)
+}
+
+func (g *gcpClient) TestPermissionsWithContext(ctx context.Context, permissions []string) ([]string, error) {
+	resp, err := g.resourceManager.Projects.TestIamPermissions(g.projectID, &cloudresourcemanager.TestIamPermissionsRequest{
+		Permissions: permissions,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Permissions, nil
+}
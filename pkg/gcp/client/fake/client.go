@@ -21,8 +21,11 @@ limitations under the License.
 package fake
 
 import (
-	mock "github.com/stretchr/testify/mock"
+	context "context"
+
 	compute "google.golang.org/api/compute/v1"
+
+	mock "github.com/stretchr/testify/mock"
 )
 
 // MockInterface is an autogenerated mock type for the Interface type
@@ -84,6 +87,53 @@ func (_c *MockInterface_ConfigurePublicIPOnInstance_Call) RunAndReturn(run func(
 	return _c
 }
 
+// ConfigurePublicIPOnInstanceWithContext provides a mock function with given fields: ctx, instance
+func (_m *MockInterface) ConfigurePublicIPOnInstanceWithContext(ctx context.Context, instance *compute.Instance) error {
+	ret := _m.Called(ctx, instance)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfigurePublicIPOnInstanceWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *compute.Instance) error); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockInterface_ConfigurePublicIPOnInstanceWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConfigurePublicIPOnInstanceWithContext'
+type MockInterface_ConfigurePublicIPOnInstanceWithContext_Call struct {
+	*mock.Call
+}
+
+// ConfigurePublicIPOnInstanceWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - instance *compute.Instance
+func (_e *MockInterface_Expecter) ConfigurePublicIPOnInstanceWithContext(ctx interface{}, instance interface{}) *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call {
+	return &MockInterface_ConfigurePublicIPOnInstanceWithContext_Call{Call: _e.mock.On("ConfigurePublicIPOnInstanceWithContext", ctx, instance)}
+}
+
+func (_c *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call) Run(run func(ctx context.Context, instance *compute.Instance)) *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*compute.Instance))
+	})
+	return _c
+}
+
+func (_c *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call) Return(_a0 error) *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call) RunAndReturn(run func(context.Context, *compute.Instance) error) *MockInterface_ConfigurePublicIPOnInstanceWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeleteFirewallRule provides a mock function with given fields: projectID, name
 func (_m *MockInterface) DeleteFirewallRule(projectID string, name string) error {
 	ret := _m.Called(projectID, name)
@@ -131,6 +181,54 @@ func (_c *MockInterface_DeleteFirewallRule_Call) RunAndReturn(run func(string, s
 	return _c
 }
 
+// DeleteFirewallRuleWithContext provides a mock function with given fields: ctx, projectID, name
+func (_m *MockInterface) DeleteFirewallRuleWithContext(ctx context.Context, projectID string, name string) error {
+	ret := _m.Called(ctx, projectID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFirewallRuleWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, projectID, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockInterface_DeleteFirewallRuleWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteFirewallRuleWithContext'
+type MockInterface_DeleteFirewallRuleWithContext_Call struct {
+	*mock.Call
+}
+
+// DeleteFirewallRuleWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectID string
+//   - name string
+func (_e *MockInterface_Expecter) DeleteFirewallRuleWithContext(ctx interface{}, projectID interface{}, name interface{}) *MockInterface_DeleteFirewallRuleWithContext_Call {
+	return &MockInterface_DeleteFirewallRuleWithContext_Call{Call: _e.mock.On("DeleteFirewallRuleWithContext", ctx, projectID, name)}
+}
+
+func (_c *MockInterface_DeleteFirewallRuleWithContext_Call) Run(run func(ctx context.Context, projectID string, name string)) *MockInterface_DeleteFirewallRuleWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_DeleteFirewallRuleWithContext_Call) Return(_a0 error) *MockInterface_DeleteFirewallRuleWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockInterface_DeleteFirewallRuleWithContext_Call) RunAndReturn(run func(context.Context, string, string) error) *MockInterface_DeleteFirewallRuleWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeletePublicIPOnInstance provides a mock function with given fields: instance
 func (_m *MockInterface) DeletePublicIPOnInstance(instance *compute.Instance) error {
 	ret := _m.Called(instance)
@@ -177,6 +275,53 @@ func (_c *MockInterface_DeletePublicIPOnInstance_Call) RunAndReturn(run func(*co
 	return _c
 }
 
+// DeletePublicIPOnInstanceWithContext provides a mock function with given fields: ctx, instance
+func (_m *MockInterface) DeletePublicIPOnInstanceWithContext(ctx context.Context, instance *compute.Instance) error {
+	ret := _m.Called(ctx, instance)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePublicIPOnInstanceWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *compute.Instance) error); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockInterface_DeletePublicIPOnInstanceWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeletePublicIPOnInstanceWithContext'
+type MockInterface_DeletePublicIPOnInstanceWithContext_Call struct {
+	*mock.Call
+}
+
+// DeletePublicIPOnInstanceWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - instance *compute.Instance
+func (_e *MockInterface_Expecter) DeletePublicIPOnInstanceWithContext(ctx interface{}, instance interface{}) *MockInterface_DeletePublicIPOnInstanceWithContext_Call {
+	return &MockInterface_DeletePublicIPOnInstanceWithContext_Call{Call: _e.mock.On("DeletePublicIPOnInstanceWithContext", ctx, instance)}
+}
+
+func (_c *MockInterface_DeletePublicIPOnInstanceWithContext_Call) Run(run func(ctx context.Context, instance *compute.Instance)) *MockInterface_DeletePublicIPOnInstanceWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*compute.Instance))
+	})
+	return _c
+}
+
+func (_c *MockInterface_DeletePublicIPOnInstanceWithContext_Call) Return(_a0 error) *MockInterface_DeletePublicIPOnInstanceWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockInterface_DeletePublicIPOnInstanceWithContext_Call) RunAndReturn(run func(context.Context, *compute.Instance) error) *MockInterface_DeletePublicIPOnInstanceWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetFirewallRule provides a mock function with given fields: projectID, name
 func (_m *MockInterface) GetFirewallRule(projectID string, name string) (*compute.Firewall, error) {
 	ret := _m.Called(projectID, name)
@@ -236,6 +381,66 @@ func (_c *MockInterface_GetFirewallRule_Call) RunAndReturn(run func(string, stri
 	return _c
 }
 
+// GetFirewallRuleWithContext provides a mock function with given fields: ctx, projectID, name
+func (_m *MockInterface) GetFirewallRuleWithContext(ctx context.Context, projectID string, name string) (*compute.Firewall, error) {
+	ret := _m.Called(ctx, projectID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFirewallRuleWithContext")
+	}
+
+	var r0 *compute.Firewall
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*compute.Firewall, error)); ok {
+		return rf(ctx, projectID, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *compute.Firewall); ok {
+		r0 = rf(ctx, projectID, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.Firewall)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, projectID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_GetFirewallRuleWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFirewallRuleWithContext'
+type MockInterface_GetFirewallRuleWithContext_Call struct {
+	*mock.Call
+}
+
+// GetFirewallRuleWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectID string
+//   - name string
+func (_e *MockInterface_Expecter) GetFirewallRuleWithContext(ctx interface{}, projectID interface{}, name interface{}) *MockInterface_GetFirewallRuleWithContext_Call {
+	return &MockInterface_GetFirewallRuleWithContext_Call{Call: _e.mock.On("GetFirewallRuleWithContext", ctx, projectID, name)}
+}
+
+func (_c *MockInterface_GetFirewallRuleWithContext_Call) Run(run func(ctx context.Context, projectID string, name string)) *MockInterface_GetFirewallRuleWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_GetFirewallRuleWithContext_Call) Return(_a0 *compute.Firewall, _a1 error) *MockInterface_GetFirewallRuleWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_GetFirewallRuleWithContext_Call) RunAndReturn(run func(context.Context, string, string) (*compute.Firewall, error)) *MockInterface_GetFirewallRuleWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetInstance provides a mock function with given fields: zone, instance
 func (_m *MockInterface) GetInstance(zone string, instance string) (*compute.Instance, error) {
 	ret := _m.Called(zone, instance)
@@ -295,6 +500,66 @@ func (_c *MockInterface_GetInstance_Call) RunAndReturn(run func(string, string)
 	return _c
 }
 
+// GetInstanceWithContext provides a mock function with given fields: ctx, zone, instance
+func (_m *MockInterface) GetInstanceWithContext(ctx context.Context, zone string, instance string) (*compute.Instance, error) {
+	ret := _m.Called(ctx, zone, instance)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInstanceWithContext")
+	}
+
+	var r0 *compute.Instance
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*compute.Instance, error)); ok {
+		return rf(ctx, zone, instance)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *compute.Instance); ok {
+		r0 = rf(ctx, zone, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.Instance)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, zone, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_GetInstanceWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetInstanceWithContext'
+type MockInterface_GetInstanceWithContext_Call struct {
+	*mock.Call
+}
+
+// GetInstanceWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zone string
+//   - instance string
+func (_e *MockInterface_Expecter) GetInstanceWithContext(ctx interface{}, zone interface{}, instance interface{}) *MockInterface_GetInstanceWithContext_Call {
+	return &MockInterface_GetInstanceWithContext_Call{Call: _e.mock.On("GetInstanceWithContext", ctx, zone, instance)}
+}
+
+func (_c *MockInterface_GetInstanceWithContext_Call) Run(run func(ctx context.Context, zone string, instance string)) *MockInterface_GetInstanceWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_GetInstanceWithContext_Call) Return(_a0 *compute.Instance, _a1 error) *MockInterface_GetInstanceWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_GetInstanceWithContext_Call) RunAndReturn(run func(context.Context, string, string) (*compute.Instance, error)) *MockInterface_GetInstanceWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // InsertFirewallRule provides a mock function with given fields: projectID, rule
 func (_m *MockInterface) InsertFirewallRule(projectID string, rule *compute.Firewall) error {
 	ret := _m.Called(projectID, rule)
@@ -342,6 +607,54 @@ func (_c *MockInterface_InsertFirewallRule_Call) RunAndReturn(run func(string, *
 	return _c
 }
 
+// InsertFirewallRuleWithContext provides a mock function with given fields: ctx, projectID, rule
+func (_m *MockInterface) InsertFirewallRuleWithContext(ctx context.Context, projectID string, rule *compute.Firewall) error {
+	ret := _m.Called(ctx, projectID, rule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertFirewallRuleWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *compute.Firewall) error); ok {
+		r0 = rf(ctx, projectID, rule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockInterface_InsertFirewallRuleWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertFirewallRuleWithContext'
+type MockInterface_InsertFirewallRuleWithContext_Call struct {
+	*mock.Call
+}
+
+// InsertFirewallRuleWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectID string
+//   - rule *compute.Firewall
+func (_e *MockInterface_Expecter) InsertFirewallRuleWithContext(ctx interface{}, projectID interface{}, rule interface{}) *MockInterface_InsertFirewallRuleWithContext_Call {
+	return &MockInterface_InsertFirewallRuleWithContext_Call{Call: _e.mock.On("InsertFirewallRuleWithContext", ctx, projectID, rule)}
+}
+
+func (_c *MockInterface_InsertFirewallRuleWithContext_Call) Run(run func(ctx context.Context, projectID string, rule *compute.Firewall)) *MockInterface_InsertFirewallRuleWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*compute.Firewall))
+	})
+	return _c
+}
+
+func (_c *MockInterface_InsertFirewallRuleWithContext_Call) Return(_a0 error) *MockInterface_InsertFirewallRuleWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockInterface_InsertFirewallRuleWithContext_Call) RunAndReturn(run func(context.Context, string, *compute.Firewall) error) *MockInterface_InsertFirewallRuleWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // InstanceHasPublicIP provides a mock function with given fields: instance
 func (_m *MockInterface) InstanceHasPublicIP(instance *compute.Instance) (bool, error) {
 	ret := _m.Called(instance)
@@ -398,6 +711,125 @@ func (_c *MockInterface_InstanceHasPublicIP_Call) RunAndReturn(run func(*compute
 	return _c
 }
 
+// ListFirewallRules provides a mock function with given fields: projectID, filter
+func (_m *MockInterface) ListFirewallRules(projectID string, filter string) ([]*compute.Firewall, error) {
+	ret := _m.Called(projectID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListFirewallRules")
+	}
+
+	var r0 []*compute.Firewall
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) ([]*compute.Firewall, error)); ok {
+		return rf(projectID, filter)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) []*compute.Firewall); ok {
+		r0 = rf(projectID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*compute.Firewall)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(projectID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_ListFirewallRules_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListFirewallRules'
+type MockInterface_ListFirewallRules_Call struct {
+	*mock.Call
+}
+
+// ListFirewallRules is a helper method to define mock.On call
+//   - projectID string
+//   - filter string
+func (_e *MockInterface_Expecter) ListFirewallRules(projectID interface{}, filter interface{}) *MockInterface_ListFirewallRules_Call {
+	return &MockInterface_ListFirewallRules_Call{Call: _e.mock.On("ListFirewallRules", projectID, filter)}
+}
+
+func (_c *MockInterface_ListFirewallRules_Call) Run(run func(projectID string, filter string)) *MockInterface_ListFirewallRules_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_ListFirewallRules_Call) Return(_a0 []*compute.Firewall, _a1 error) *MockInterface_ListFirewallRules_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_ListFirewallRules_Call) RunAndReturn(run func(string, string) ([]*compute.Firewall, error)) *MockInterface_ListFirewallRules_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListFirewallRulesWithContext provides a mock function with given fields: ctx, projectID, filter
+func (_m *MockInterface) ListFirewallRulesWithContext(ctx context.Context, projectID string, filter string) ([]*compute.Firewall, error) {
+	ret := _m.Called(ctx, projectID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListFirewallRulesWithContext")
+	}
+
+	var r0 []*compute.Firewall
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*compute.Firewall, error)); ok {
+		return rf(ctx, projectID, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*compute.Firewall); ok {
+		r0 = rf(ctx, projectID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*compute.Firewall)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, projectID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_ListFirewallRulesWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListFirewallRulesWithContext'
+type MockInterface_ListFirewallRulesWithContext_Call struct {
+	*mock.Call
+}
+
+// ListFirewallRulesWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectID string
+//   - filter string
+func (_e *MockInterface_Expecter) ListFirewallRulesWithContext(ctx interface{}, projectID interface{}, filter interface{}) *MockInterface_ListFirewallRulesWithContext_Call {
+	return &MockInterface_ListFirewallRulesWithContext_Call{Call: _e.mock.On("ListFirewallRulesWithContext", ctx, projectID, filter)}
+}
+
+func (_c *MockInterface_ListFirewallRulesWithContext_Call) Run(run func(ctx context.Context, projectID string, filter string)) *MockInterface_ListFirewallRulesWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_ListFirewallRulesWithContext_Call) Return(_a0 []*compute.Firewall, _a1 error) *MockInterface_ListFirewallRulesWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_ListFirewallRulesWithContext_Call) RunAndReturn(run func(context.Context, string, string) ([]*compute.Firewall, error)) *MockInterface_ListFirewallRulesWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListInstances provides a mock function with given fields: zone
 func (_m *MockInterface) ListInstances(zone string) (*compute.InstanceList, error) {
 	ret := _m.Called(zone)
@@ -456,6 +888,65 @@ func (_c *MockInterface_ListInstances_Call) RunAndReturn(run func(string) (*comp
 	return _c
 }
 
+// ListInstancesWithContext provides a mock function with given fields: ctx, zone
+func (_m *MockInterface) ListInstancesWithContext(ctx context.Context, zone string) (*compute.InstanceList, error) {
+	ret := _m.Called(ctx, zone)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInstancesWithContext")
+	}
+
+	var r0 *compute.InstanceList
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*compute.InstanceList, error)); ok {
+		return rf(ctx, zone)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *compute.InstanceList); ok {
+		r0 = rf(ctx, zone)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.InstanceList)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, zone)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_ListInstancesWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListInstancesWithContext'
+type MockInterface_ListInstancesWithContext_Call struct {
+	*mock.Call
+}
+
+// ListInstancesWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - zone string
+func (_e *MockInterface_Expecter) ListInstancesWithContext(ctx interface{}, zone interface{}) *MockInterface_ListInstancesWithContext_Call {
+	return &MockInterface_ListInstancesWithContext_Call{Call: _e.mock.On("ListInstancesWithContext", ctx, zone)}
+}
+
+func (_c *MockInterface_ListInstancesWithContext_Call) Run(run func(ctx context.Context, zone string)) *MockInterface_ListInstancesWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_ListInstancesWithContext_Call) Return(_a0 *compute.InstanceList, _a1 error) *MockInterface_ListInstancesWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_ListInstancesWithContext_Call) RunAndReturn(run func(context.Context, string) (*compute.InstanceList, error)) *MockInterface_ListInstancesWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ListZones provides a mock function with given fields:
 func (_m *MockInterface) ListZones() (*compute.ZoneList, error) {
 	ret := _m.Called()
@@ -513,6 +1004,181 @@ func (_c *MockInterface_ListZones_Call) RunAndReturn(run func() (*compute.ZoneLi
 	return _c
 }
 
+// ListZonesWithContext provides a mock function with given fields: ctx
+func (_m *MockInterface) ListZonesWithContext(ctx context.Context) (*compute.ZoneList, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListZonesWithContext")
+	}
+
+	var r0 *compute.ZoneList
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*compute.ZoneList, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *compute.ZoneList); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.ZoneList)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_ListZonesWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListZonesWithContext'
+type MockInterface_ListZonesWithContext_Call struct {
+	*mock.Call
+}
+
+// ListZonesWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockInterface_Expecter) ListZonesWithContext(ctx interface{}) *MockInterface_ListZonesWithContext_Call {
+	return &MockInterface_ListZonesWithContext_Call{Call: _e.mock.On("ListZonesWithContext", ctx)}
+}
+
+func (_c *MockInterface_ListZonesWithContext_Call) Run(run func(ctx context.Context)) *MockInterface_ListZonesWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockInterface_ListZonesWithContext_Call) Return(_a0 *compute.ZoneList, _a1 error) *MockInterface_ListZonesWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_ListZonesWithContext_Call) RunAndReturn(run func(context.Context) (*compute.ZoneList, error)) *MockInterface_ListZonesWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TestPermissions provides a mock function with given fields: permissions
+func (_m *MockInterface) TestPermissions(permissions []string) ([]string, error) {
+	ret := _m.Called(permissions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TestPermissions")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]string) ([]string, error)); ok {
+		return rf(permissions)
+	}
+	if rf, ok := ret.Get(0).(func([]string) []string); ok {
+		r0 = rf(permissions)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(permissions)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_TestPermissions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TestPermissions'
+type MockInterface_TestPermissions_Call struct {
+	*mock.Call
+}
+
+// TestPermissions is a helper method to define mock.On call
+//   - permissions []string
+func (_e *MockInterface_Expecter) TestPermissions(permissions interface{}) *MockInterface_TestPermissions_Call {
+	return &MockInterface_TestPermissions_Call{Call: _e.mock.On("TestPermissions", permissions)}
+}
+
+func (_c *MockInterface_TestPermissions_Call) Run(run func(permissions []string)) *MockInterface_TestPermissions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_TestPermissions_Call) Return(_a0 []string, _a1 error) *MockInterface_TestPermissions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_TestPermissions_Call) RunAndReturn(run func([]string) ([]string, error)) *MockInterface_TestPermissions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TestPermissionsWithContext provides a mock function with given fields: ctx, permissions
+func (_m *MockInterface) TestPermissionsWithContext(ctx context.Context, permissions []string) ([]string, error) {
+	ret := _m.Called(ctx, permissions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TestPermissionsWithContext")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, permissions)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, permissions)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, permissions)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockInterface_TestPermissionsWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TestPermissionsWithContext'
+type MockInterface_TestPermissionsWithContext_Call struct {
+	*mock.Call
+}
+
+// TestPermissionsWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - permissions []string
+func (_e *MockInterface_Expecter) TestPermissionsWithContext(ctx interface{}, permissions interface{}) *MockInterface_TestPermissionsWithContext_Call {
+	return &MockInterface_TestPermissionsWithContext_Call{Call: _e.mock.On("TestPermissionsWithContext", ctx, permissions)}
+}
+
+func (_c *MockInterface_TestPermissionsWithContext_Call) Run(run func(ctx context.Context, permissions []string)) *MockInterface_TestPermissionsWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *MockInterface_TestPermissionsWithContext_Call) Return(_a0 []string, _a1 error) *MockInterface_TestPermissionsWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockInterface_TestPermissionsWithContext_Call) RunAndReturn(run func(context.Context, []string) ([]string, error)) *MockInterface_TestPermissionsWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateFirewallRule provides a mock function with given fields: projectID, name, rule
 func (_m *MockInterface) UpdateFirewallRule(projectID string, name string, rule *compute.Firewall) error {
 	ret := _m.Called(projectID, name, rule)
@@ -561,6 +1227,55 @@ func (_c *MockInterface_UpdateFirewallRule_Call) RunAndReturn(run func(string, s
 	return _c
 }
 
+// UpdateFirewallRuleWithContext provides a mock function with given fields: ctx, projectID, name, rule
+func (_m *MockInterface) UpdateFirewallRuleWithContext(ctx context.Context, projectID string, name string, rule *compute.Firewall) error {
+	ret := _m.Called(ctx, projectID, name, rule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateFirewallRuleWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *compute.Firewall) error); ok {
+		r0 = rf(ctx, projectID, name, rule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockInterface_UpdateFirewallRuleWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateFirewallRuleWithContext'
+type MockInterface_UpdateFirewallRuleWithContext_Call struct {
+	*mock.Call
+}
+
+// UpdateFirewallRuleWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectID string
+//   - name string
+//   - rule *compute.Firewall
+func (_e *MockInterface_Expecter) UpdateFirewallRuleWithContext(ctx interface{}, projectID interface{}, name interface{}, rule interface{}) *MockInterface_UpdateFirewallRuleWithContext_Call {
+	return &MockInterface_UpdateFirewallRuleWithContext_Call{Call: _e.mock.On("UpdateFirewallRuleWithContext", ctx, projectID, name, rule)}
+}
+
+func (_c *MockInterface_UpdateFirewallRuleWithContext_Call) Run(run func(ctx context.Context, projectID string, name string, rule *compute.Firewall)) *MockInterface_UpdateFirewallRuleWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(*compute.Firewall))
+	})
+	return _c
+}
+
+func (_c *MockInterface_UpdateFirewallRuleWithContext_Call) Return(_a0 error) *MockInterface_UpdateFirewallRuleWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockInterface_UpdateFirewallRuleWithContext_Call) RunAndReturn(run func(context.Context, string, string, *compute.Firewall) error) *MockInterface_UpdateFirewallRuleWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateInstanceNetworkTags provides a mock function with given fields: project, zone, instance, tags
 func (_m *MockInterface) UpdateInstanceNetworkTags(project string, zone string, instance string, tags *compute.Tags) error {
 	ret := _m.Called(project, zone, instance, tags)
@@ -610,6 +1325,56 @@ func (_c *MockInterface_UpdateInstanceNetworkTags_Call) RunAndReturn(run func(st
 	return _c
 }
 
+// UpdateInstanceNetworkTagsWithContext provides a mock function with given fields: ctx, project, zone, instance, tags
+func (_m *MockInterface) UpdateInstanceNetworkTagsWithContext(ctx context.Context, project string, zone string, instance string, tags *compute.Tags) error {
+	ret := _m.Called(ctx, project, zone, instance, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateInstanceNetworkTagsWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *compute.Tags) error); ok {
+		r0 = rf(ctx, project, zone, instance, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockInterface_UpdateInstanceNetworkTagsWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateInstanceNetworkTagsWithContext'
+type MockInterface_UpdateInstanceNetworkTagsWithContext_Call struct {
+	*mock.Call
+}
+
+// UpdateInstanceNetworkTagsWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - project string
+//   - zone string
+//   - instance string
+//   - tags *compute.Tags
+func (_e *MockInterface_Expecter) UpdateInstanceNetworkTagsWithContext(ctx interface{}, project interface{}, zone interface{}, instance interface{}, tags interface{}) *MockInterface_UpdateInstanceNetworkTagsWithContext_Call {
+	return &MockInterface_UpdateInstanceNetworkTagsWithContext_Call{Call: _e.mock.On("UpdateInstanceNetworkTagsWithContext", ctx, project, zone, instance, tags)}
+}
+
+func (_c *MockInterface_UpdateInstanceNetworkTagsWithContext_Call) Run(run func(ctx context.Context, project string, zone string, instance string, tags *compute.Tags)) *MockInterface_UpdateInstanceNetworkTagsWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(*compute.Tags))
+	})
+	return _c
+}
+
+func (_c *MockInterface_UpdateInstanceNetworkTagsWithContext_Call) Return(_a0 error) *MockInterface_UpdateInstanceNetworkTagsWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockInterface_UpdateInstanceNetworkTagsWithContext_Call) RunAndReturn(run func(context.Context, string, string, string, *compute.Tags) error) *MockInterface_UpdateInstanceNetworkTagsWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockInterface creates a new instance of MockInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockInterface(t interface {
@@ -19,9 +19,13 @@ limitations under the License.
 package gcp
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"google.golang.org/api/compute/v1"
 )
 
@@ -30,38 +34,61 @@ type CloudInfo struct {
 	Region    string
 	ProjectID string
 	Client    gcpclient.Interface
+
+	// IPFamily is the default IP family of the gateway firewall rules.
+	IPFamily api.IPFamily
+
+	// Labels are applied to the gateway instances. Since firewall rules can't be labelled, they're listed in the
+	// descriptions of the firewall rules instead.
+	Labels map[string]string
+
+	// Ledger, if set, records the firewall rules and machine sets created. ClosePorts and Cleanup then undo exactly what
+	// it lists instead of looking the objects up by name, unless it lists nothing for them.
+	Ledger *ledger.Ledger
 }
 
 // Open expected ports by creating related firewall rule.
 // - if the firewall rule is not found, we will create it.
 // - if the firewall rule is found and changed, we will update it.
-func (c *CloudInfo) openPorts(rules ...*compute.Firewall) error {
+// The rules are recorded in the ledger, if any, as changes made by the given operation.
+func (c *CloudInfo) openFirewallRules(ctx context.Context, operation ledger.Operation, rules ...*compute.Firewall) error {
 	for _, rule := range rules {
-		_, err := c.Client.GetFirewallRule(c.ProjectID, rule.Name)
-		if gcpclient.IsGCPNotFoundError(err) {
-			if err := c.Client.InsertFirewallRule(c.ProjectID, rule); err != nil {
-				return errors.Wrapf(err, "error inserting firewall rule %#v", rule)
-			}
-
-			continue
-		}
-
+		err := c.openFirewallRule(ctx, rule)
 		if err != nil {
-			return errors.Wrapf(err, "error retrieving firewall rule %q", rule.Name)
+			return err
 		}
 
-		if err := c.Client.UpdateFirewallRule(c.ProjectID, rule.Name, rule); err != nil {
-			return errors.Wrapf(err, "error updating firewall rule %#v", rule)
+		err = c.Ledger.Record(ctx, ledger.Entry{
+			Provider:  ProviderName,
+			Operation: operation,
+			Resource:  api.FirewallRuleResource,
+			ID:        rule.Name,
+		})
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (c *CloudInfo) deleteFirewallRule(name string, status reporter.Interface) error {
+func (c *CloudInfo) openFirewallRule(ctx context.Context, rule *compute.Firewall) error {
+	_, err := c.Client.GetFirewallRuleWithContext(ctx, c.ProjectID, rule.Name)
+	if gcpclient.IsGCPNotFoundError(err) {
+		return errors.Wrapf(c.Client.InsertFirewallRuleWithContext(ctx, c.ProjectID, rule), "error inserting firewall rule %#v", rule)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving firewall rule %q", rule.Name)
+	}
+
+	return errors.Wrapf(c.Client.UpdateFirewallRuleWithContext(ctx, c.ProjectID, rule.Name, rule), "error updating firewall rule %#v", rule)
+}
+
+func (c *CloudInfo) deleteFirewallRule(ctx context.Context, name string, status reporter.Interface) error {
 	status.Start("Deleting firewall rule %q on GCP", name)
 
-	if err := c.Client.DeleteFirewallRule(c.ProjectID, name); err != nil {
+	if err := c.Client.DeleteFirewallRuleWithContext(ctx, c.ProjectID, name); err != nil {
 		if !gcpclient.IsGCPNotFoundError(err) {
 			return status.Error(err, "unable to delete firewall rule %q", name)
 		}
@@ -0,0 +1,62 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"google.golang.org/api/googleapi"
+)
+
+func classifyError(err error) error {
+	return api.ClassifyError(ProviderName, err, errorKind)
+}
+
+// errorKind maps the Compute Engine API errors, by reason when known and otherwise by HTTP status code.
+func errorKind(err error) error {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return nil
+	}
+
+	for _, item := range gerr.Errors {
+		switch item.Reason {
+		case "quotaExceeded", "limitExceeded":
+			return api.ErrQuotaExceeded
+		case "rateLimitExceeded", "userRateLimitExceeded":
+			return api.ErrTransient
+		}
+	}
+
+	switch gerr.Code {
+	case http.StatusNotFound:
+		return api.ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return api.ErrPermissionDenied
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return api.ErrConflict
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return api.ErrTransient
+	}
+
+	return nil
+}
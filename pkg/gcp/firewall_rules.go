@@ -21,36 +21,89 @@ package gcp
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"google.golang.org/api/compute/v1"
+	"k8s.io/utils/set"
 )
 
 const (
 	ingressDirection         = "INGRESS"
 	publicPortsRuleName      = "submariner-public-ports"
+	publicPortsV6RuleName    = "submariner-public-ports-v6"
 	internalPortsRuleName    = "submariner-internal-ports"
 	submarinerGatewayNodeTag = "submariner-io-gateway-node"
 )
 
-func newExternalFirewallRules(projectID, infraID string, ports []api.PortSpec) (ingress *compute.Firewall) {
-	ingressName := generateRuleName(infraID, publicPortsRuleName)
+func newExternalFirewallRules(projectID, infraID string, ports []api.PortSpec, sourceCIDRs []string, labels map[string]string,
+) []*compute.Firewall {
+	rules := []*compute.Firewall{}
 
-	// We want the external firewall rules to be applied only to Gateway nodes. So, we use the TargetTags
-	// field and include submarinerGatewayNodeTag for selection of Gateway nodes. All the Submariner Gateway
-	// instances will be tagged with submarinerGatewayNodeTag.
-	ingressRule := newFirewallRule(projectID, infraID, ingressName, ingressDirection, ports)
-	ingressRule.TargetTags = []string{
-		submarinerGatewayNodeTag,
+	// A firewall rule can't mix IPv4 and IPv6 source ranges, so the IPv6 ones get their own rule.
+	for _, family := range []api.IPFamily{api.IPv4, api.IPv6} {
+		cidrs := api.FilterCIDRs(sourceCIDRs, family)
+		if len(cidrs) == 0 {
+			continue
+		}
+
+		// We want the external firewall rules to be applied only to Gateway nodes. So, we use the TargetTags
+		// field and include submarinerGatewayNodeTag for selection of Gateway nodes. All the Submariner Gateway
+		// instances will be tagged with submarinerGatewayNodeTag.
+		ingressRule := newFirewallRule(projectID, infraID, externalRuleName(infraID, family),
+			firewallRuleDescription("Submariner public ports", labels), ingressDirection, ports)
+		ingressRule.TargetTags = []string{
+			submarinerGatewayNodeTag,
+		}
+		ingressRule.SourceRanges = cidrs
+
+		rules = append(rules, ingressRule)
+	}
+
+	return rules
+}
+
+func externalRuleName(infraID string, family api.IPFamily) string {
+	if family == api.IPv6 {
+		return generateRuleName(infraID, publicPortsV6RuleName)
+	}
+
+	return generateRuleName(infraID, publicPortsRuleName)
+}
+
+// staleExternalRuleNames returns the names of the external firewall rules of the IP families which the given external
+// firewall rules don't cover, which must be deleted if they were created for a previously requested IP family.
+func staleExternalRuleNames(infraID string, rules []*compute.Firewall) []string {
+	requested := set.New[string]()
+	for i := range rules {
+		requested.Insert(rules[i].Name)
+	}
+
+	var names []string
+
+	for _, family := range []api.IPFamily{api.IPv4, api.IPv6} {
+		if name := externalRuleName(infraID, family); !requested.Has(name) {
+			names = append(names, name)
+		}
 	}
 
-	return ingressRule
+	return names
 }
 
-func newInternalFirewallRule(projectID, infraID string, ports []api.PortSpec) *compute.Firewall {
+func ruleNames(rules []*compute.Firewall) string {
+	names := make([]string, len(rules))
+	for i := range rules {
+		names[i] = rules[i].Name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func newInternalFirewallRule(projectID, infraID string, ports []api.PortSpec, labels map[string]string) *compute.Firewall {
 	ingressName := generateRuleName(infraID, internalPortsRuleName)
 
-	rule := newFirewallRule(projectID, infraID, ingressName, ingressDirection, ports)
+	rule := newFirewallRule(projectID, infraID, ingressName, firewallRuleDescription("Submariner internal ports", labels),
+		ingressDirection, ports)
 	rule.TargetTags = []string{
 		fmt.Sprintf("%s-worker", infraID),
 		fmt.Sprintf("%s-master", infraID),
@@ -63,28 +116,52 @@ func newInternalFirewallRule(projectID, infraID string, ports []api.PortSpec) *c
 	return rule
 }
 
-func newFirewallRule(projectID, infraID, name, direction string, ports []api.PortSpec) *compute.Firewall {
+func newFirewallRule(projectID, infraID, name, description, direction string, ports []api.PortSpec) *compute.Firewall {
 	allowedPorts := []*compute.FirewallAllowed{}
 
 	for _, port := range ports {
 		fwRule := &compute.FirewallAllowed{
 			IPProtocol: port.Protocol,
 		}
-		if port.Port != 0 {
-			fwRule.Ports = []string{strconv.Itoa(int(port.Port))}
+
+		if !port.IsPortless() && port.Port != 0 {
+			from, to := port.PortRange()
+			if from == to {
+				fwRule.Ports = []string{strconv.Itoa(int(from))}
+			} else {
+				fwRule.Ports = []string{strconv.Itoa(int(from)) + "-" + strconv.Itoa(int(to))}
+			}
 		}
 
 		allowedPorts = append(allowedPorts, fwRule)
 	}
 
 	return &compute.Firewall{
-		Name:      name,
-		Network:   fmt.Sprintf("projects/%s/global/networks/%s-network", projectID, infraID),
-		Direction: direction,
-		Allowed:   allowedPorts,
+		Name:        name,
+		Description: description,
+		Network:     fmt.Sprintf("projects/%s/global/networks/%s-network", projectID, infraID),
+		Direction:   direction,
+		Allowed:     allowedPorts,
 	}
 }
 
+// firewallRuleDescription returns the description of a firewall rule, listing the user labels since firewall rules
+// can't be labelled.
+func firewallRuleDescription(purpose string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return purpose
+	}
+
+	keys := set.KeySet(labels).SortedList()
+
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		entries[i] = key + "=" + labels[key]
+	}
+
+	return fmt.Sprintf("%s (%s)", purpose, strings.Join(entries, ", "))
+}
+
 func generateRuleName(infraID, name string) (ingressName string) {
 	return fmt.Sprintf("%s-%s-ingress", infraID, name)
 }
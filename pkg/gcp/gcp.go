@@ -19,11 +19,13 @@ limitations under the License.
 package gcp
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 )
 
 type gcpCloud struct {
@@ -36,12 +38,24 @@ func NewCloud(info CloudInfo) api.Cloud {
 }
 
 func (gc *gcpCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface) error {
+	return gc.OpenPortsWithContext(context.TODO(), ports, status)
+}
+
+func (gc *gcpCloud) OpenPortsWithContext(ctx context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	return classifyError(gc.openPorts(ctx, ports, status))
+}
+
+func (gc *gcpCloud) openPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	if err := api.ValidatePorts(ports); err != nil {
+		return errors.WithMessage(err, "invalid internal ports")
+	}
+
 	// Create the inbound firewall rule for submariner internal ports.
 	status.Start("Opening internal ports %q for intra-cluster communications on GCP", formatPorts(ports))
 	defer status.End()
 
-	internalIngress := newInternalFirewallRule(gc.ProjectID, gc.InfraID, ports)
-	if err := gc.openPorts(internalIngress); err != nil {
+	internalIngress := newInternalFirewallRule(gc.ProjectID, gc.InfraID, ports, gc.Labels)
+	if err := gc.openFirewallRules(ctx, ledger.OpenPorts, internalIngress); err != nil {
 		return status.Error(err, "unable to open ports")
 	}
 
@@ -52,16 +66,42 @@ func (gc *gcpCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface) e
 }
 
 func (gc *gcpCloud) ClosePorts(status reporter.Interface) error {
+	return gc.ClosePortsWithContext(context.TODO(), status)
+}
+
+func (gc *gcpCloud) ClosePortsWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(gc.closePorts(ctx, status))
+}
+
+func (gc *gcpCloud) closePorts(ctx context.Context, status reporter.Interface) error {
+	recorded, err := gc.Ledger.Recorded(ctx, ProviderName, ledger.OpenPorts)
+	if err != nil {
+		return status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		status.Start("Deleting the recorded firewall rules on GCP")
+		defer status.End()
+
+		if err := gc.Ledger.Undo(ctx, ProviderName, ledger.OpenPorts, gc.undo); err != nil {
+			return status.Error(err, "unable to close ports")
+		}
+
+		status.Success("Deleted the recorded firewall rules on GCP")
+
+		return nil
+	}
+
 	// Delete the inbound and outbound firewall rules to close submariner internal ports.
 	internalIngressName := generateRuleName(gc.InfraID, internalPortsRuleName)
 
-	return gc.deleteFirewallRule(internalIngressName, status)
+	return gc.deleteFirewallRule(ctx, internalIngressName, status)
 }
 
 func formatPorts(ports []api.PortSpec) string {
 	portStrs := []string{}
 	for _, port := range ports {
-		portStrs = append(portStrs, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+		portStrs = append(portStrs, port.String())
 	}
 
 	return strings.Join(portStrs, ", ")
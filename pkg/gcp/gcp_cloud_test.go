@@ -19,6 +19,7 @@ limitations under the License.
 package gcp_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -37,15 +38,22 @@ const ingressRuleName = "test-infraID-submariner-internal-ports-ingress"
 var _ = Describe("Cloud", func() {
 	Describe("OpenPorts", testOpenPorts)
 	Describe("ClosePorts", testClosePorts)
+	Describe("PlanOpenPorts", testPlanOpenPorts)
+	Describe("PlanClosePorts", testPlanClosePorts)
+	Describe("ReconcilePorts", testReconcilePorts)
+	Describe("Validate", testValidate)
 })
 
 func testOpenPorts() {
 	t := newCloudTestDriver()
 
-	var retError error
+	var (
+		ports    []api.PortSpec
+		retError error
+	)
 
-	JustBeforeEach(func() {
-		retError = t.cloud.OpenPorts([]api.PortSpec{
+	BeforeEach(func() {
+		ports = []api.PortSpec{
 			{
 				Port:     100,
 				Protocol: "TCP",
@@ -54,22 +62,28 @@ func testOpenPorts() {
 				Port:     200,
 				Protocol: "UDP",
 			},
-		}, reporter.Stdout())
+		}
+	})
+
+	JustBeforeEach(func() {
+		retError = t.cloud.OpenPorts(ports, reporter.Stdout())
 	})
 
 	When("the firewall rule doesn't exist", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().GetFirewallRule(projectID, ingressRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(nil, &googleapi.Error{Code: http.StatusNotFound})
 		})
 
 		Context("", func() {
 			var actualRule *compute.Firewall
 
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().InsertFirewallRule(projectID, mock.Anything).RunAndReturn(func(_ string, rule *compute.Firewall) error {
-					actualRule = rule
-					return nil
-				})
+				t.gcpClient.EXPECT().InsertFirewallRuleWithContext(mock.Anything, projectID, mock.Anything).
+					RunAndReturn(func(_ context.Context, _ string, rule *compute.Firewall) error {
+						actualRule = rule
+						return nil
+					})
 			})
 
 			It("should correctly insert it", func() {
@@ -78,32 +92,78 @@ func testOpenPorts() {
 				Expect(actualRule).ToNot(BeNil(), "InsertFirewallRule was not called")
 				assertIngressRule(actualRule)
 			})
+
+			Context("with a port range and a portless protocol", func() {
+				BeforeEach(func() {
+					ports = []api.PortSpec{
+						{
+							Port:     4500,
+							EndPort:  4510,
+							Protocol: "udp",
+						},
+						{
+							Port:     4500,
+							Protocol: "esp",
+						},
+					}
+				})
+
+				It("should insert it with the port range and without ports for the portless protocol", func() {
+					Expect(retError).To(Succeed())
+
+					Expect(actualRule).ToNot(BeNil(), "InsertFirewallRule was not called")
+					Expect(actualRule.Allowed).To(Equal([]*compute.FirewallAllowed{
+						{
+							IPProtocol: "udp",
+							Ports:      []string{"4500-4510"},
+						},
+						{
+							IPProtocol: "esp",
+						},
+					}))
+				})
+			})
 		})
 
 		Context("and insertion fails", func() {
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().InsertFirewallRule(projectID, mock.Anything).Return(errors.New("fake insert error"))
+				t.gcpClient.EXPECT().InsertFirewallRuleWithContext(mock.Anything, projectID, mock.Anything).Return(errors.New("fake insert error"))
 			})
 
 			It("should return an error", func() {
 				Expect(retError).ToNot(Succeed())
 			})
 		})
+
+		Context("and the firewall quota is exceeded", func() {
+			BeforeEach(func() {
+				t.gcpClient.EXPECT().InsertFirewallRuleWithContext(mock.Anything, projectID, mock.Anything).Return(&googleapi.Error{
+					Code:   http.StatusForbidden,
+					Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}},
+				})
+			})
+
+			It("should return a quota exceeded error", func() {
+				Expect(retError).To(MatchError(api.ErrQuotaExceeded))
+				Expect(retError).ToNot(MatchError(api.ErrPermissionDenied))
+			})
+		})
 	})
 
 	When("the firewall rule already exists", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().GetFirewallRule(projectID, ingressRuleName).RunAndReturn(func(_, ruleName string) (*compute.Firewall, error) {
-				return &compute.Firewall{Name: ruleName}, nil
-			})
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				RunAndReturn(func(_ context.Context, _, ruleName string) (*compute.Firewall, error) {
+					return &compute.Firewall{Name: ruleName}, nil
+				})
 		})
 
 		Context("", func() {
 			var actualRule *compute.Firewall
 
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().UpdateFirewallRule(projectID, ingressRuleName, mock.Anything).RunAndReturn(
-					func(_, _ string, rule *compute.Firewall) error {
+				t.gcpClient.EXPECT().UpdateFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName, mock.Anything).RunAndReturn(
+					func(_ context.Context, _, _ string, rule *compute.Firewall) error {
 						actualRule = rule
 						return nil
 					})
@@ -119,7 +179,8 @@ func testOpenPorts() {
 
 		Context("and update fails", func() {
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().UpdateFirewallRule(projectID, ingressRuleName, mock.Anything).Return(errors.New("fake update error"))
+				t.gcpClient.EXPECT().UpdateFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName, mock.Anything).
+					Return(errors.New("fake update error"))
 			})
 
 			It("should return an error", func() {
@@ -130,13 +191,28 @@ func testOpenPorts() {
 
 	When("retrieval of the firewall rule fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().GetFirewallRule(projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
 		})
 
 		It("should return an error", func() {
 			Expect(retError).ToNot(Succeed())
 		})
 	})
+
+	When("retrieval of the firewall rule is forbidden", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(nil, &googleapi.Error{Code: http.StatusForbidden})
+		})
+
+		It("should return a permission denied error", func() {
+			Expect(retError).To(MatchError(api.ErrPermissionDenied))
+
+			var apiErr *api.Error
+			Expect(errors.As(retError, &apiErr)).To(BeTrue())
+			Expect(apiErr.Provider).To(Equal(gcp.ProviderName))
+		})
+	})
 }
 
 func testClosePorts() {
@@ -150,7 +226,7 @@ func testClosePorts() {
 
 	Context("on success", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().DeleteFirewallRule(projectID, ingressRuleName).Return(nil)
+			t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).Return(nil)
 		})
 
 		It("should delete the firewall rule", func() {
@@ -160,7 +236,8 @@ func testClosePorts() {
 
 	When("the firewall rule doesn't exist", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().DeleteFirewallRule(projectID, ingressRuleName).Return(&googleapi.Error{Code: http.StatusNotFound})
+			t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&googleapi.Error{Code: http.StatusNotFound})
 		})
 
 		It("should succeed", func() {
@@ -170,7 +247,235 @@ func testClosePorts() {
 
 	When("deletion fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().DeleteFirewallRule(projectID, ingressRuleName).Return(errors.New("fake delete error"))
+			t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).Return(errors.New("fake delete error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testPlanOpenPorts() {
+	t := newCloudTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		plan, retError = t.cloud.PlanOpenPorts(context.TODO(), []api.PortSpec{
+			{
+				Port:     100,
+				Protocol: "TCP",
+			},
+			{
+				Port:     200,
+				Protocol: "UDP",
+			},
+		}, reporter.Stdout())
+	})
+
+	When("the firewall rule doesn't exist", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+		})
+
+		It("should plan to create it", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(Equal([]api.Change{{
+				Action:   api.ChangeCreate,
+				Resource: api.FirewallRuleResource,
+				Name:     ingressRuleName,
+				Details:  "allow 100/TCP, 200/UDP",
+			}}))
+		})
+	})
+
+	When("the firewall rule already exists with different ports", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&compute.Firewall{Name: ingressRuleName}, nil)
+		})
+
+		It("should plan to update it", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(HaveField("Action", api.ChangeUpdate)))
+		})
+	})
+
+	When("the firewall rule is already up to date", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&compute.Firewall{
+					Name: ingressRuleName,
+					Allowed: []*compute.FirewallAllowed{
+						{IPProtocol: "UDP", Ports: []string{"200"}},
+						{IPProtocol: "TCP", Ports: []string{"100"}},
+					},
+					SourceTags: []string{infraID + "-worker", infraID + "-master"},
+					TargetTags: []string{infraID + "-master", infraID + "-worker"},
+				}, nil)
+		})
+
+		It("should return an empty plan", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.IsEmpty()).To(BeTrue())
+		})
+	})
+
+	When("retrieval of the firewall rule fails", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testPlanClosePorts() {
+	t := newCloudTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		plan, retError = t.cloud.PlanClosePorts(context.TODO(), reporter.Stdout())
+	})
+
+	When("the firewall rule exists", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&compute.Firewall{Name: ingressRuleName}, nil)
+		})
+
+		It("should plan to delete it", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(And(HaveField("Action", api.ChangeDelete),
+				HaveField("Name", ingressRuleName))))
+		})
+	})
+
+	When("the firewall rule doesn't exist", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+		})
+
+		It("should return an empty plan", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.IsEmpty()).To(BeTrue())
+		})
+	})
+}
+
+func testReconcilePorts() {
+	t := newCloudTestDriver()
+
+	var (
+		ports    []api.PortSpec
+		plan     *api.Plan
+		retError error
+	)
+
+	BeforeEach(func() {
+		ports = []api.PortSpec{
+			{
+				Port:     100,
+				Protocol: "TCP",
+			},
+			{
+				Port:     200,
+				Protocol: "UDP",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		plan, retError = t.cloud.ReconcilePorts(context.TODO(), ports, reporter.Stdout())
+	})
+
+	When("the firewall rule allows other ports", func() {
+		var actualRule *compute.Firewall
+
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&compute.Firewall{
+					Name: ingressRuleName,
+					Allowed: []*compute.FirewallAllowed{
+						{IPProtocol: "TCP", Ports: []string{"100"}},
+						{IPProtocol: "UDP", Ports: []string{"300"}},
+					},
+					SourceTags: []string{infraID + "-worker", infraID + "-master"},
+					TargetTags: []string{infraID + "-master", infraID + "-worker"},
+				}, nil)
+
+			t.gcpClient.EXPECT().UpdateFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName, mock.Anything).RunAndReturn(
+				func(_ context.Context, _, _ string, rule *compute.Firewall) error {
+					actualRule = rule
+					return nil
+				})
+		})
+
+		It("should update it with the requested ports and report the differences", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(Equal([]api.Change{{
+				Action:   api.ChangeUpdate,
+				Resource: api.FirewallRuleResource,
+				Name:     ingressRuleName,
+				Details:  "allow 200/UDP; disallow 300/UDP",
+			}}))
+
+			Expect(actualRule).ToNot(BeNil(), "UpdateFirewallRule was not called")
+			assertIngressRule(actualRule)
+		})
+	})
+
+	When("the firewall rule is already up to date", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&compute.Firewall{
+					Name: ingressRuleName,
+					Allowed: []*compute.FirewallAllowed{
+						{IPProtocol: "UDP", Ports: []string{"200"}},
+						{IPProtocol: "TCP", Ports: []string{"100"}},
+					},
+					SourceTags: []string{infraID + "-worker", infraID + "-master"},
+					TargetTags: []string{infraID + "-master", infraID + "-worker"},
+				}, nil)
+		})
+
+		It("should not change anything", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.IsEmpty()).To(BeTrue())
+		})
+	})
+
+	When("no ports are requested", func() {
+		BeforeEach(func() {
+			ports = nil
+
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).
+				Return(&compute.Firewall{Name: ingressRuleName}, nil)
+			t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).Return(nil)
+		})
+
+		It("should delete the firewall rule", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(And(HaveField("Action", api.ChangeDelete),
+				HaveField("Name", ingressRuleName))))
+		})
+	})
+
+	When("retrieval of the firewall rule fails", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
 		})
 
 		It("should return an error", func() {
@@ -181,7 +486,7 @@ func testClosePorts() {
 
 type cloudTestDriver struct {
 	fakeGCPClientBase
-	cloud api.Cloud
+	cloud api.ContextCloud
 }
 
 func newCloudTestDriver() *cloudTestDriver {
@@ -195,7 +500,7 @@ func newCloudTestDriver() *cloudTestDriver {
 			Region:    region,
 			ProjectID: projectID,
 			Client:    t.gcpClient,
-		})
+		}).(api.ContextCloud)
 	})
 
 	AfterEach(t.afterEach)
@@ -216,3 +521,50 @@ func assertIngressRule(rule *compute.Firewall) {
 		Ports:      []string{"200"},
 	}))
 }
+
+func testValidate() {
+	t := newCloudTestDriver()
+
+	var (
+		granted  []string
+		retError error
+	)
+
+	BeforeEach(func() {
+		granted = []string{
+			"compute.firewalls.create", "compute.firewalls.delete", "compute.firewalls.get", "compute.firewalls.update",
+			"compute.networks.updatePolicy",
+		}
+	})
+
+	JustBeforeEach(func() {
+		t.gcpClient.EXPECT().TestPermissionsWithContext(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, permissions []string) ([]string, error) {
+				Expect(permissions).To(ContainElements(granted))
+				return granted, nil
+			})
+
+		retError = t.cloud.Validate(context.TODO(), reporter.Stdout())
+	})
+
+	When("all the permissions are granted", func() {
+		It("should succeed", func() {
+			Expect(retError).To(Succeed())
+		})
+	})
+
+	When("permissions are missing", func() {
+		BeforeEach(func() {
+			granted = []string{"compute.firewalls.get", "compute.networks.updatePolicy"}
+		})
+
+		It("should return all the missing permissions", func() {
+			Expect(retError).To(MatchError(api.ErrPermissionDenied))
+
+			var missingErr *api.MissingPermissionsError
+			Expect(errors.As(retError, &missingErr)).To(BeTrue())
+			Expect(missingErr.Permissions).To(Equal([]string{"compute.firewalls.create", "compute.firewalls.delete",
+				"compute.firewalls.update"}))
+		})
+	})
+}
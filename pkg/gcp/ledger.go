@@ -0,0 +1,65 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+)
+
+func machineSetEntry(name, namespace string) ledger.Entry {
+	return ledger.Entry{
+		Provider:  ProviderName,
+		Operation: ledger.Deploy,
+		Resource:  api.MachineSetResource,
+		ID:        name,
+		Parent:    namespace,
+	}
+}
+
+// undo undoes the change recorded in the given ledger entry. Objects which no longer exist are ignored.
+func (c *CloudInfo) undo(ctx context.Context, entry *ledger.Entry) error {
+	if entry.Resource != api.FirewallRuleResource {
+		return errors.Errorf("unsupported resource %q", entry.Resource)
+	}
+
+	err := c.Client.DeleteFirewallRuleWithContext(ctx, c.ProjectID, entry.ID)
+	if gcpclient.IsGCPNotFoundError(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "error deleting firewall rule %q", entry.ID)
+}
+
+func (d *ocpGatewayDeployer) undo(ctx context.Context, entry *ledger.Entry) error {
+	if entry.Resource == api.MachineSetResource {
+		return errors.Wrapf(d.msDeployer.DeleteByNameWithContext(ctx, entry.ID, entry.Parent), "error deleting machine set %q", entry.ID)
+	}
+
+	return d.CloudInfo.undo(ctx, entry)
+}
+
+// undoChange returns the planned change undoing the given ledger entry, as undo would.
+func undoChange(entry *ledger.Entry) api.Change {
+	return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID}
+}
@@ -0,0 +1,80 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Gateway MachineSet", func() {
+	var labels map[string]string
+
+	BeforeEach(func() {
+		labels = nil
+	})
+
+	build := func() *unstructured.Unstructured {
+		d := &ocpGatewayDeployer{
+			CloudInfo: CloudInfo{
+				InfraID:   "test-infra",
+				Region:    "us-east1",
+				ProjectID: "test-project",
+				Labels:    labels,
+			},
+			instanceType: "n1-standard-4",
+			image:        "projects/rhcos-cloud/global/images/rhcos",
+		}
+
+		machineSet, err := d.initMachineSet("us-east1-b")
+		Expect(err).To(Succeed())
+
+		return machineSet
+	}
+
+	It("should match the golden file", func() {
+		Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-default.yaml")))
+	})
+
+	When("labels are configured", func() {
+		BeforeEach(func() {
+			labels = map[string]string{"team": "a: b", "cost-center": "42"}
+		})
+
+		It("should match the golden file", func() {
+			Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-labels.yaml")))
+		})
+	})
+})
+
+func loadGoldenMachineSet(path string) *unstructured.Unstructured {
+	data, err := os.ReadFile(path)
+	Expect(err).To(Succeed())
+
+	machineSet := &unstructured.Unstructured{}
+
+	_, _, err = yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(data, nil, machineSet)
+	Expect(err).To(Succeed())
+
+	return machineSet
+}
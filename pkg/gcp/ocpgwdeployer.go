@@ -19,28 +19,28 @@ limitations under the License.
 package gcp
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"strings"
-	"text/template"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	"google.golang.org/api/compute/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/utils/set"
 )
 
 type ocpGatewayDeployer struct {
 	CloudInfo
-	msDeployer   ocp.MachineSetDeployer
+	msDeployer   ocp.ContextMachineSetDeployer
 	instanceType string
 	image        string
-	k8sClient    k8s.Interface
+	k8sClient    k8s.ContextInterface
 }
 
 // NewOcpGatewayDeployer returns a GatewayDeployer capable of deploying gateways using OCP.
@@ -49,26 +49,55 @@ func NewOcpGatewayDeployer(info CloudInfo, msDeployer ocp.MachineSetDeployer, in
 ) api.GatewayDeployer {
 	return &ocpGatewayDeployer{
 		CloudInfo:    info,
-		msDeployer:   msDeployer,
+		msDeployer:   ocp.WithContext(msDeployer),
 		instanceType: instanceType,
 		image:        image,
-		k8sClient:    k8sClient,
+		k8sClient:    k8s.WithContext(k8sClient),
 	}
 }
 
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporter.Interface) error {
+	return d.DeployWithContext(context.TODO(), input, status)
+}
+
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	err := d.deploy(ctx, input, status)
+	if err == nil {
+		err = ocp.DeployHealthChecks(ctx, d.msDeployer, input.HealthCheck, status)
+	}
+
+	if err == nil {
+		err = ocp.WaitForGateways(ctx, d.msDeployer, d.k8sClient, input.WaitTimeout, status)
+	}
+
+	return classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	if err := api.ValidatePorts(input.PublicPorts); err != nil {
+		return errors.WithMessage(err, "invalid public ports")
+	}
+
+	if input.IPFamily == "" {
+		input.IPFamily = d.IPFamily
+	}
+
 	status.Start("Configuring the required firewall rules for inter-cluster traffic")
 	defer status.End()
 
-	externalIngress := newExternalFirewallRules(d.ProjectID, d.InfraID, input.PublicPorts)
-	if err := d.openPorts(externalIngress); err != nil {
-		return status.Error(err, "error creating firewall rule %q", externalIngress.Name)
+	externalIngress := newExternalFirewallRules(d.ProjectID, d.InfraID, input.PublicPorts, input.PublicSourceCIDRs(), d.Labels)
+	if err := d.openFirewallRules(ctx, ledger.Deploy, externalIngress...); err != nil {
+		return status.Error(err, "error creating firewall rules %q", ruleNames(externalIngress))
 	}
 
-	status.Success("Opened External ports %q with firewall rule %q on GCP",
-		formatPorts(input.PublicPorts), externalIngress.Name)
+	status.Success("Opened External ports %q with firewall rules %q on GCP",
+		formatPorts(input.PublicPorts), ruleNames(externalIngress))
 
-	numGatewayNodes, eligibleZonesForGW, err := d.parseCurrentGatewayInstances(status)
+	if err := d.deleteStaleExternalFWRules(ctx, externalIngress, status); err != nil {
+		return err
+	}
+
+	numGatewayNodes, eligibleZonesForGW, err := d.parseCurrentGatewayInstances(ctx, status)
 	if err != nil {
 		return status.Error(err, "error parsing current gateway instances")
 	}
@@ -80,18 +109,16 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 		return nil
 	}
 
-	// Currently, we only support increasing the number of Gateway nodes which could be a valid use-case
-	// to convert a non-HA deployment to an HA deployment. We are not supporting decreasing the Gateway
-	// nodes (for now) as it might impact the datapath if we accidentally delete the active GW node.
+	// When decreasing the number of Gateway nodes, only the dedicated ones are removed, and never the active one
+	// as that would impact the datapath.
 	if gatewayNodesToDeploy < 0 {
-		status.Failure("Decreasing the number of Gateway nodes is not currently supported")
-		return nil
+		return d.removeSurplusGateways(ctx, -gatewayNodesToDeploy, status)
 	}
 
-	for _, zone := range eligibleZonesForGW.UnsortedList() {
+	for _, zone := range eligibleZonesForGW.SortedList() {
 		status.Start("Deploying dedicated gateway node in zone %q", zone)
 
-		err = d.deployGateway(zone)
+		err = d.deployGateway(ctx, zone)
 		if err != nil {
 			return status.Error(err, "error deploying gateway for zone %q", zone)
 		}
@@ -112,8 +139,8 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 	return err
 }
 
-func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(status reporter.Interface) (int, set.Set[string], error) {
-	zones, err := d.retrieveZones(status)
+func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(ctx context.Context, status reporter.Interface) (int, set.Set[string], error) {
+	zones, err := d.retrieveZones(ctx, status)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -131,7 +158,7 @@ func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(status reporter.Interf
 			continue
 		}
 
-		instanceList, err := d.Client.ListInstances(zone.Name)
+		instanceList, err := d.Client.ListInstancesWithContext(ctx, zone.Name)
 		if err != nil {
 			return 0, nil, status.Error(err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
 		}
@@ -158,68 +185,110 @@ func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(status reporter.Interf
 	return zonesWithSubmarinerGW.Len(), eligibleZonesForGW, nil
 }
 
-type machineSetConfig struct {
-	AZ                  string
-	InfraID             string
-	ProjectID           string
-	InstanceType        string
-	Region              string
-	Image               string
-	SubmarinerGWNodeTag string
-}
-
-func (d *ocpGatewayDeployer) loadGatewayYAML(zone, image string) ([]byte, error) {
-	var buf bytes.Buffer
+// removeSurplusGateways deletes up to count dedicated gateway machine sets, preferring the passive gateways and never
+// deleting the active one. The gateway instances, and so their public firewall rule targets, go with the machine sets.
+func (d *ocpGatewayDeployer) removeSurplusGateways(ctx context.Context, count int, status reporter.Interface) error {
+	surplus, err := d.selectSurplusMachineSets(ctx, count)
+	if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+		status.Warning("None of the %d surplus gateways were removed: %v", count, err)
+		return nil
+	}
 
-	tpl, err := template.New("").Parse(machineSetYAML)
 	if err != nil {
-		return nil, errors.Wrap(err, "error parsing machine set YAML")
+		return status.Error(err, "error selecting the surplus gateways")
 	}
 
-	tplVars := machineSetConfig{
-		AZ:                  zone,
-		InfraID:             d.InfraID,
-		ProjectID:           d.ProjectID,
-		InstanceType:        d.instanceType,
-		Region:              d.Region,
-		Image:               image,
-		SubmarinerGWNodeTag: submarinerGatewayNodeTag,
+	for i := range surplus {
+		status.Start("Deleting the surplus gateway instance %q", surplus[i].GetName())
+
+		err = d.msDeployer.DeleteWithContext(ctx, &surplus[i])
+		if err != nil {
+			return status.Error(err, "error deleting machine set %q", surplus[i].GetName())
+		}
+
+		err = d.Ledger.Forget(ctx, machineSetEntry(surplus[i].GetName(), surplus[i].GetNamespace()))
+		if err != nil {
+			return status.Error(err, "error updating the ledger")
+		}
+
+		status.Success("Deleted the surplus gateway instance %q", surplus[i].GetName())
 	}
 
-	err = tpl.Execute(&buf, tplVars)
-	if err != nil {
-		return nil, errors.Wrap(err, "error executing the template")
+	if len(surplus) < count {
+		status.Warning("Only %d of the %d surplus gateways were removed as the active gateway and the labeled worker nodes are kept",
+			len(surplus), count)
 	}
 
-	return buf.Bytes(), nil
+	return nil
 }
 
-func (d *ocpGatewayDeployer) initMachineSet(zone string) (*unstructured.Unstructured, error) {
-	gatewayYAML, err := d.loadGatewayYAML(zone, d.image)
+func (d *ocpGatewayDeployer) selectSurplusMachineSets(ctx context.Context, count int) ([]unstructured.Unstructured, error) {
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "error listing the gateway machine sets")
+	}
+
+	if len(machineSets) == 0 {
+		return nil, nil
 	}
 
-	unstructDecoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	gwNodes, err := d.k8sClient.ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
 
-	machineSet := &unstructured.Unstructured{}
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodes.Items)
+	if err != nil {
+		return nil, errors.Wrap(err, "error mapping the gateway nodes to their machine sets")
+	}
 
-	_, _, err = unstructDecoder.Decode(gatewayYAML, nil, machineSet)
+	activeNode, err := d.k8sClient.GetActiveGatewayNodeWithContext(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "error converting YAML to machine set")
+		return nil, errors.Wrap(err, "error determining the active gateway")
 	}
 
-	return machineSet, nil
+	return ocp.SelectSurplusMachineSets(machineSets, mapping, activeNode, count), nil
 }
 
-func (d *ocpGatewayDeployer) deployGateway(zone string) error {
+func (d *ocpGatewayDeployer) initMachineSet(zone string) (*unstructured.Unstructured, error) {
+	providerSpec := ocp.NewGCPMachineProviderSpec()
+	providerSpec.CanIPForward = true
+	providerSpec.CredentialsSecret = &ocp.SecretReference{Name: "gcp-cloud-credentials"}
+	providerSpec.Disks = []ocp.GCPDisk{{
+		AutoDelete: true,
+		Boot:       true,
+		Image:      d.image,
+		SizeGB:     128,
+		Type:       "pd-ssd",
+	}}
+	providerSpec.Labels = d.Labels
+	providerSpec.MachineType = d.instanceType
+	providerSpec.NetworkInterfaces = []ocp.GCPNetworkInterface{{
+		Network:    d.InfraID + "-network",
+		Subnetwork: d.InfraID + "-worker-subnet",
+		PublicIP:   true,
+	}}
+	providerSpec.ProjectID = d.ProjectID
+	providerSpec.Region = d.Region
+	providerSpec.ServiceAccounts = []ocp.GCPServiceAccount{{
+		Email:  fmt.Sprintf("%s-w@%s.iam.gserviceaccount.com", d.InfraID, d.ProjectID),
+		Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+	}}
+	providerSpec.Tags = []string{d.InfraID + "-worker", submarinerGatewayNodeTag}
+	providerSpec.UserDataSecret = &ocp.SecretReference{Name: "worker-user-data"}
+	providerSpec.Zone = zone
+
+	return ocp.NewGatewayMachineSet(d.InfraID, d.InfraID+"-submariner-gw-"+zone, providerSpec).ToUnstructured()
+}
+
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, zone string) error {
 	machineSet, err := d.initMachineSet(zone)
 	if err != nil {
 		return err
 	}
 
 	if d.image == "" {
-		d.image, err = d.msDeployer.GetWorkerNodeImage(machineSet, d.InfraID)
+		d.image, err = d.msDeployer.GetWorkerNodeImageWithContext(ctx, machineSet, d.InfraID)
 		if err != nil {
 			return errors.Wrap(err, "error retrieving worker node image")
 		}
@@ -230,21 +299,44 @@ func (d *ocpGatewayDeployer) deployGateway(zone string) error {
 		}
 	}
 
-	return errors.Wrapf(d.msDeployer.Deploy(machineSet), "error deploying machine set %q", machineSet.GetName())
+	err = d.msDeployer.DeployWithContext(ctx, machineSet)
+	if err != nil {
+		return errors.Wrapf(err, "error deploying machine set %q", machineSet.GetName())
+	}
+
+	return d.Ledger.Record(ctx, machineSetEntry(machineSet.GetName(), machineSet.GetNamespace()))
 }
 
 func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
+	return d.CleanupWithContext(context.TODO(), status)
+}
+
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.cleanup(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) cleanup(ctx context.Context, status reporter.Interface) error {
 	status.Start("Retrieving the Submariner gateway firewall rules")
 	defer status.End()
 
-	err := d.deleteExternalFWRules(status)
+	recorded, err := d.Ledger.Recorded(ctx, ProviderName, ledger.Deploy)
+	if err != nil {
+		return status.Error(err, "failed to read the ledger")
+	}
+
+	if recorded {
+		err = d.Ledger.Undo(ctx, ProviderName, ledger.Deploy, d.undo)
+	} else {
+		err = d.deleteExternalFWRules(ctx, status)
+	}
+
 	if err != nil {
 		return status.Error(err, "failed to delete the gateway firewall rules in the project %q", d.ProjectID)
 	}
 
 	status.Success("Successfully deleted the firewall rules")
 
-	zones, err := d.retrieveZones(status)
+	zones, err := d.retrieveZones(ctx, status)
 	if err != nil {
 		return err
 	}
@@ -254,7 +346,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 			continue
 		}
 
-		instanceList, err := d.Client.ListInstances(zone.Name)
+		instanceList, err := d.Client.ListInstancesWithContext(ctx, zone.Name)
 		if err != nil {
 			return status.Error(err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
 		}
@@ -273,9 +365,14 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 			// the gateway node was deployed using the OCPMachineSet API otherwise it's an existing worker node.
 			prefix := d.InfraID + "-submariner-gw-" + zone.Name
 			if strings.HasPrefix(instance.Name, prefix) {
+				if recorded {
+					// The machine set was deleted along with the other recorded resources.
+					continue
+				}
+
 				status.Start(fmt.Sprintf("Deleting the gateway instance %q", instance.Name))
 
-				err := d.deleteGateway(zone.Name)
+				err := d.deleteGateway(ctx, zone.Name)
 				if err != nil {
 					return status.Error(err, "failed to delete dedicated gateway instance %q", instance.Name)
 				}
@@ -284,7 +381,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 			} else {
 				status.Start(fmt.Sprintf("Removing the gateway configuration from instance %q", instance.Name))
 
-				err = d.resetExistingGWNode(zone.Name, instance)
+				err = d.resetExistingGWNode(ctx, zone.Name, instance)
 				if err != nil {
 					return status.Error(err, "failed to delete gateway instance %q", instance.Name)
 				}
@@ -296,7 +393,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 	status.Start("Removing the Submariner gateway label from worker nodes")
 
-	err = d.k8sClient.RemoveGWLabelFromWorkerNodes()
+	err = d.k8sClient.RemoveGWLabelFromWorkerNodesWithContext(ctx)
 	if err != nil {
 		return status.Error(err, "error removing the gateway label from worker nodes")
 	}
@@ -306,20 +403,55 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 	return nil
 }
 
-func (d *ocpGatewayDeployer) deleteGateway(zone string) error {
+func (d *ocpGatewayDeployer) deleteGateway(ctx context.Context, zone string) error {
 	machineSet, err := d.initMachineSet(zone)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Delete(machineSet), "error deleting machine set %q", machineSet.GetName())
+	return errors.Wrapf(d.msDeployer.DeleteWithContext(ctx, machineSet), "error deleting machine set %q", machineSet.GetName())
 }
 
-func (d *ocpGatewayDeployer) deleteExternalFWRules(status reporter.Interface) error {
-	ingressName := generateRuleName(d.InfraID, publicPortsRuleName)
+func (d *ocpGatewayDeployer) deleteExternalFWRules(ctx context.Context, status reporter.Interface) error {
+	for _, family := range []api.IPFamily{api.IPv4, api.IPv6} {
+		ingressName := externalRuleName(d.InfraID, family)
 
-	if err := d.deleteFirewallRule(ingressName, status); err != nil {
-		return errors.Wrapf(err, "error deleting firewall rule %q", ingressName)
+		if err := d.deleteFirewallRule(ctx, ingressName, status); err != nil {
+			return errors.Wrapf(err, "error deleting firewall rule %q", ingressName)
+		}
+	}
+
+	return nil
+}
+
+// deleteStaleExternalFWRules deletes the external firewall rules of the IP families which are no longer requested, e.g.
+// the IPv6 rule when switching from dual-stack to IPv4.
+func (d *ocpGatewayDeployer) deleteStaleExternalFWRules(ctx context.Context, externalIngress []*compute.Firewall,
+	status reporter.Interface,
+) error {
+	for _, name := range staleExternalRuleNames(d.InfraID, externalIngress) {
+		_, err := d.Client.GetFirewallRuleWithContext(ctx, d.ProjectID, name)
+		if gcpclient.IsGCPNotFoundError(err) {
+			continue
+		}
+
+		if err != nil {
+			return status.Error(err, "error retrieving firewall rule %q", name)
+		}
+
+		if err := d.deleteFirewallRule(ctx, name, status); err != nil {
+			return err
+		}
+
+		err = d.Ledger.Forget(ctx, ledger.Entry{
+			Provider:  ProviderName,
+			Operation: ledger.Deploy,
+			Resource:  api.FirewallRuleResource,
+			ID:        name,
+		})
+		if err != nil {
+			return status.Error(err, "error updating the ledger")
+		}
 	}
 
 	return nil
@@ -345,7 +477,7 @@ func (d *ocpGatewayDeployer) isInstanceGatewayNode(instance *compute.Instance) b
 	return false
 }
 
-func (d *ocpGatewayDeployer) resetExistingGWNode(zone string, instance *compute.Instance) error {
+func (d *ocpGatewayDeployer) resetExistingGWNode(ctx context.Context, zone string, instance *compute.Instance) error {
 	for i := range instance.Tags.Items {
 		if instance.Tags.Items[i] == submarinerGatewayNodeTag {
 			instance.Tags.Items = append(instance.Tags.Items[:i], instance.Tags.Items[i+1:]...)
@@ -357,12 +489,12 @@ func (d *ocpGatewayDeployer) resetExistingGWNode(zone string, instance *compute.
 		Fingerprint: instance.Tags.Fingerprint,
 	}
 
-	err := d.Client.UpdateInstanceNetworkTags(d.ProjectID, zone, instance.Name, tags)
+	err := d.Client.UpdateInstanceNetworkTagsWithContext(ctx, d.ProjectID, zone, instance.Name, tags)
 	if err != nil {
 		return errors.Wrapf(err, "error updating network tags for GCP instance %q in zode %q", instance.Name, zone)
 	}
 
-	err = d.Client.DeletePublicIPOnInstance(instance)
+	err = d.Client.DeletePublicIPOnInstanceWithContext(ctx, instance)
 	if err != nil {
 		return errors.Wrapf(err, "error deleting public IP for GCP instance %q in zode %q", instance.Name, zone)
 	}
@@ -370,11 +502,11 @@ func (d *ocpGatewayDeployer) resetExistingGWNode(zone string, instance *compute.
 	return nil
 }
 
-func (d *ocpGatewayDeployer) retrieveZones(status reporter.Interface) (*compute.ZoneList, error) {
+func (d *ocpGatewayDeployer) retrieveZones(ctx context.Context, status reporter.Interface) (*compute.ZoneList, error) {
 	status.Start("Retrieving the current zones in the project")
 	status.End()
 
-	zones, err := d.Client.ListZones()
+	zones, err := d.Client.ListZonesWithContext(ctx)
 	if err != nil {
 		return nil, status.Error(err, "failed to list the zones in the project %q", d.ProjectID)
 	}
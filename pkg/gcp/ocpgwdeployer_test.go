@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,17 +32,23 @@ import (
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/gcp"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicFake "k8s.io/client-go/dynamic/fake"
 	kubeFake "k8s.io/client-go/kubernetes/fake"
 )
 
 const (
 	publicPortsRuleName      = "test-infraID-submariner-public-ports-ingress"
+	publicPortsV6RuleName    = "test-infraID-submariner-public-ports-v6-ingress"
 	submarinerGatewayNodeTag = "submariner-io-gateway-node"
 	submarinerGWName         = infraID + "-submariner-gw-"
 )
@@ -49,27 +56,50 @@ const (
 var _ = Describe("OCP GatewayDeployer", func() {
 	Context("on Deploy", testDeploy)
 	Context("on Cleanup", testCleanup)
+	Context("on PlanDeploy", testPlanDeploy)
+	Context("on PlanCleanup", testPlanCleanup)
+	Context("on Status", testStatus)
 })
 
 func testDeploy() {
 	t := newGatewayDeployerTestDriver()
 
 	var (
-		actualRule *compute.Firewall
-		retError   error
+		actualRule     *compute.Firewall
+		actualRuleV6   *compute.Firewall
+		existingRuleV6 *compute.Firewall
+		retError       error
 	)
 
 	BeforeEach(func() {
 		actualRule = nil
+		actualRuleV6 = nil
+		existingRuleV6 = nil
 
-		t.gcpClient.EXPECT().GetFirewallRule(projectID, publicPortsRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
-		t.gcpClient.EXPECT().InsertFirewallRule(projectID, mock.Anything).RunAndReturn(func(_ string, rule *compute.Firewall) error {
-			actualRule = rule
-			return nil
-		})
+		t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, mock.Anything).RunAndReturn(
+			func(_ context.Context, _, name string) (*compute.Firewall, error) {
+				if name == publicPortsV6RuleName && existingRuleV6 != nil {
+					return existingRuleV6, nil
+				}
+
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			})
+		t.gcpClient.EXPECT().InsertFirewallRuleWithContext(mock.Anything, projectID, mock.Anything).
+			RunAndReturn(func(_ context.Context, _ string, rule *compute.Firewall) error {
+				if rule.Name == publicPortsV6RuleName {
+					actualRuleV6 = rule
+				} else {
+					actualRule = rule
+				}
+
+				return nil
+			})
 	})
 
 	JustBeforeEach(func() {
+		t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return(nil, nil).Maybe()
+		t.msDeployer.EXPECT().DeployHealthCheckWithContext(mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
 		retError = t.doDeploy()
 	})
 
@@ -78,6 +108,49 @@ func testDeploy() {
 		t.assertIngressRule(actualRule)
 	})
 
+	When("source CIDRs are specified", func() {
+		BeforeEach(func() {
+			t.sourceCIDRs = []string{"10.1.0.0/16", "192.168.1.1/32"}
+		})
+
+		It("should restrict the firewall rule to them", func() {
+			Expect(actualRule).ToNot(BeNil(), "InsertFirewallRule was not called")
+			t.assertIngressRule(actualRule)
+			Expect(actualRuleV6).To(BeNil())
+		})
+	})
+
+	When("dual-stack is requested", func() {
+		BeforeEach(func() {
+			t.ipFamily = api.DualStack
+		})
+
+		It("should insert an IPv4 and an IPv6 firewall rule", func() {
+			Expect(actualRule).ToNot(BeNil(), "InsertFirewallRule was not called for the IPv4 rule")
+			t.assertIngressRule(actualRule)
+
+			Expect(actualRuleV6).ToNot(BeNil(), "InsertFirewallRule was not called for the IPv6 rule")
+			Expect(actualRuleV6.Allowed).To(Equal(actualRule.Allowed))
+			Expect(actualRuleV6.TargetTags).To(Equal(actualRule.TargetTags))
+			Expect(actualRuleV6.SourceRanges).To(Equal([]string{api.AnyIPv6CIDR}))
+		})
+	})
+
+	When("IPv4 is requested and the IPv6 firewall rule exists", func() {
+		BeforeEach(func() {
+			t.ipFamily = api.IPv4
+			existingRuleV6 = &compute.Firewall{Name: publicPortsV6RuleName}
+
+			t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, publicPortsV6RuleName).Return(nil).Once()
+		})
+
+		It("should only insert the IPv4 firewall rule and delete the IPv6 one", func() {
+			Expect(retError).To(Succeed())
+			Expect(actualRule).ToNot(BeNil(), "InsertFirewallRule was not called for the IPv4 rule")
+			Expect(actualRuleV6).To(BeNil())
+		})
+	})
+
 	When("the requested number of gateways is decreased", func() {
 		BeforeEach(func() {
 			t.nodes = []*corev1.Node{
@@ -90,9 +163,60 @@ func testDeploy() {
 			t.numGateways = 1
 		})
 
-		It("should do nothing", func() {
-			Expect(retError).To(Succeed())
-			t.assertLabeledNodes("node-1", "node-2")
+		Context("and the gateways are labeled worker nodes", func() {
+			BeforeEach(func() {
+				t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return(nil, nil)
+			})
+
+			It("should not remove them", func() {
+				Expect(retError).To(Succeed())
+				t.assertLabeledNodes("node-1", "node-2")
+			})
+		})
+
+		Context("and the gateways are dedicated nodes", func() {
+			var deleted map[string]*unstructured.Unstructured
+
+			BeforeEach(func() {
+				t.nodes = []*corev1.Node{
+					labelNode(newNode(submarinerGWName+zone1+"-abcde", zone1, instance1)),
+					labelNode(newNode(submarinerGWName+zone2+"-fghij", zone2, instance2)),
+				}
+
+				t.gateways = []*unstructured.Unstructured{
+					newGateway(submarinerGWName+zone1+"-abcde", "active"),
+					newGateway(submarinerGWName+zone2+"-fghij", "passive"),
+				}
+
+				var ms1, ms2 unstructured.Unstructured
+
+				ms1.SetName(submarinerGWName + zone1)
+				ms2.SetName(submarinerGWName + zone2)
+
+				t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms1, ms2}, nil)
+				t.msDeployer.EXPECT().ListMachinesWithContext(mock.Anything, mock.Anything).RunAndReturn(
+					func(_ context.Context, ms *unstructured.Unstructured) ([]ocp.Machine, error) {
+						nodeName := map[string]string{
+							ms1.GetName(): submarinerGWName + zone1 + "-abcde",
+							ms2.GetName(): submarinerGWName + zone2 + "-fghij",
+						}[ms.GetName()]
+
+						return []ocp.Machine{{Name: ms.GetName() + "-machine", Phase: "Running", NodeName: nodeName}}, nil
+					})
+				t.msDeployer.EXPECT().DeleteWithContext(mock.Anything, mock.Anything).RunAndReturn(
+					func(_ context.Context, ms *unstructured.Unstructured) error {
+						deleted[ms.GetName()] = ms
+						return nil
+					})
+
+				deleted = map[string]*unstructured.Unstructured{}
+			})
+
+			It("should remove the passive gateway", func() {
+				Expect(retError).To(Succeed())
+				Expect(deleted).To(HaveLen(1))
+				Expect(deleted).To(HaveKey(submarinerGWName + zone2))
+			})
 		})
 	})
 
@@ -124,8 +248,8 @@ func testDeploy() {
 		var machineSets map[string]*unstructured.Unstructured
 
 		BeforeEach(func() {
-			t.msDeployer.EXPECT().GetWorkerNodeImage(mock.Anything, infraID).Return("test-image", nil).Maybe()
-			t.msDeployer.EXPECT().Deploy(mock.Anything).RunAndReturn(machineSetFn(&machineSets)).Times(2)
+			t.msDeployer.EXPECT().GetWorkerNodeImageWithContext(mock.Anything, mock.Anything, infraID).Return("test-image", nil).Maybe()
+			t.msDeployer.EXPECT().DeployWithContext(mock.Anything, mock.Anything).RunAndReturn(machineSetFn(&machineSets)).Times(2)
 
 			t.numGateways = 2
 		})
@@ -151,11 +275,29 @@ func testDeploy() {
 				t.assertMachineSet(machineSets[zone2], "custom-image")
 			})
 		})
+
+		Context("with labels", func() {
+			BeforeEach(func() {
+				t.labels = map[string]string{"owner": "team-a", "cost-center": "1234"}
+			})
+
+			It("should apply them to the gateway nodes and list them in the firewall rule description", func() {
+				Expect(retError).To(Succeed())
+
+				Expect(machineSets).To(HaveLen(2))
+				labels, _, _ := unstructured.NestedStringMap(machineSets[zone1].Object, "spec", "template", "spec", "providerSpec",
+					"value", "labels")
+				Expect(labels).To(Equal(t.labels))
+
+				Expect(actualRule).ToNot(BeNil(), "InsertFirewallRule was not called")
+				Expect(actualRule.Description).To(Equal("Submariner public ports (cost-center=1234, owner=team-a)"))
+			})
+		})
 	})
 
 	When("zone retrieval fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().ListZones().Return(nil, errors.New("fake error"))
+			t.gcpClient.EXPECT().ListZonesWithContext(mock.Anything).Return(nil, errors.New("fake error"))
 		})
 
 		It("should return an error", func() {
@@ -177,7 +319,9 @@ func testCleanup() {
 	})
 
 	JustBeforeEach(func() {
-		t.gcpClient.EXPECT().DeleteFirewallRule(projectID, publicPortsRuleName).Return(deleteFirewallRule)
+		t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, publicPortsRuleName).Return(deleteFirewallRule)
+		t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, publicPortsV6RuleName).
+			Return(&googleapi.Error{Code: http.StatusNotFound}).Maybe()
 		retError = t.gwDeployer.Cleanup(reporter.Stdout())
 	})
 
@@ -215,7 +359,7 @@ func testCleanup() {
 			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
 			t.instances[zone2][0].Tags.Items = []string{submarinerGatewayNodeTag}
 
-			t.msDeployer.EXPECT().Delete(mock.Anything).RunAndReturn(machineSetFn(&machineSets)).Times(2)
+			t.msDeployer.EXPECT().DeleteWithContext(mock.Anything, mock.Anything).RunAndReturn(machineSetFn(&machineSets)).Times(2)
 		})
 
 		It("should delete them", func() {
@@ -225,11 +369,22 @@ func testCleanup() {
 			t.assertMachineSet(machineSets[zone1], "")
 			t.assertMachineSet(machineSets[zone2], "")
 		})
+
+		When("the ledger has no entries", func() {
+			BeforeEach(func() {
+				t.ledger = ledger.New(ledger.NewFileStore(filepath.Join(GinkgoT().TempDir(), "ledger.json")))
+			})
+
+			It("should still delete them", func() {
+				Expect(retError).To(Succeed())
+				Expect(machineSets).To(HaveLen(2))
+			})
+		})
 	})
 
 	When("zone retrieval fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().ListZones().Return(nil, errors.New("fake error"))
+			t.gcpClient.EXPECT().ListZonesWithContext(mock.Anything).Return(nil, errors.New("fake error"))
 		})
 
 		It("should return an error", func() {
@@ -248,16 +403,280 @@ func testCleanup() {
 	})
 }
 
+func testPlanDeploy() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	BeforeEach(func() {
+		t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, publicPortsRuleName).
+			Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+	})
+
+	JustBeforeEach(func() {
+		t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, publicPortsV6RuleName).
+			Return(nil, &googleapi.Error{Code: http.StatusNotFound}).Maybe()
+
+		plan, retError = t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{
+			Gateways: t.numGateways,
+			PublicPorts: []api.PortSpec{
+				{
+					Port:     100,
+					Protocol: "TCP",
+				},
+			},
+		}, reporter.Stdout())
+	})
+
+	When("dedicated gateway nodes are requested", func() {
+		BeforeEach(func() {
+			t.msDeployer.EXPECT().GetWorkerNodeImageWithContext(mock.Anything, mock.Anything, infraID).Return("test-image", nil).Maybe()
+
+			t.numGateways = 2
+		})
+
+		It("should plan to create the firewall rule and the gateway machine sets", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(
+				And(HaveField("Action", api.ChangeCreate), HaveField("Resource", api.FirewallRuleResource)),
+				And(HaveField("Action", api.ChangeCreate), HaveField("Resource", api.MachineSetResource)),
+				And(HaveField("Action", api.ChangeCreate), HaveField("Resource", api.MachineSetResource)),
+			))
+		})
+	})
+
+	When("the requested number of gateway nodes are already labeled", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				labelNode(newNode("node-1", zone1, instance1)),
+			}
+
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+			t.numGateways = 1
+		})
+
+		It("should only plan to create the firewall rule", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(HaveField("Resource", api.FirewallRuleResource)))
+		})
+
+		Context("and the IPv6 firewall rule exists", func() {
+			BeforeEach(func() {
+				t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, publicPortsV6RuleName).
+					Return(&compute.Firewall{Name: publicPortsV6RuleName}, nil)
+			})
+
+			It("should also plan to delete it", func() {
+				Expect(retError).To(Succeed())
+				Expect(plan.Changes).To(HaveExactElements(
+					And(HaveField("Action", api.ChangeCreate), HaveField("Name", publicPortsRuleName)),
+					And(HaveField("Action", api.ChangeDelete), HaveField("Name", publicPortsV6RuleName)),
+				))
+			})
+		})
+	})
+
+	When("there's an insufficient number of zones", func() {
+		BeforeEach(func() {
+			t.numGateways = 3
+		})
+
+		It("should return an error", func() {
+			Expect(retError).To(HaveOccurred())
+		})
+	})
+}
+
+func testPlanCleanup() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		// The firewall rules are only looked up by name when nothing is recorded.
+		if t.ledger == nil {
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, publicPortsRuleName).
+				Return(&compute.Firewall{Name: publicPortsRuleName}, nil)
+			t.gcpClient.EXPECT().GetFirewallRuleWithContext(mock.Anything, projectID, publicPortsV6RuleName).
+				Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+		}
+
+		plan, retError = t.gwDeployer.PlanCleanup(context.TODO(), reporter.Stdout())
+	})
+
+	Context("with preexisting nodes labeled as gateways", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				labelNode(newNode("node-1", zone1, instance1)),
+			}
+
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should plan to untag the instances and unlabel the nodes", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(
+				And(HaveField("Action", api.ChangeDelete), HaveField("Resource", api.FirewallRuleResource)),
+				And(HaveField("Action", api.ChangeUpdate), HaveField("Name", instance1)),
+				And(HaveField("Action", api.ChangeDelete), HaveField("Name", "node-1")),
+			))
+		})
+	})
+
+	Context("with dedicated nodes deployed as gateways", func() {
+		BeforeEach(func() {
+			t.instances[zone1][0].Name = submarinerGWName + zone1
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should plan to delete them", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveExactElements(
+				HaveField("Resource", api.FirewallRuleResource),
+				And(HaveField("Action", api.ChangeDelete), HaveField("Resource", api.MachineSetResource)),
+			))
+		})
+
+		When("the ledger has entries", func() {
+			BeforeEach(func() {
+				t.ledger = ledger.New(ledger.NewFileStore(filepath.Join(GinkgoT().TempDir(), "ledger.json")))
+
+				Expect(t.ledger.Record(context.TODO(), ledger.Entry{
+					Provider:  gcp.ProviderName,
+					Operation: ledger.Deploy,
+					Resource:  api.FirewallRuleResource,
+					ID:        publicPortsRuleName,
+				}, ledger.Entry{
+					Provider:  gcp.ProviderName,
+					Operation: ledger.Deploy,
+					Resource:  api.MachineSetResource,
+					ID:        submarinerGWName + zone1,
+					Parent:    "openshift-machine-api",
+				})).To(Succeed())
+			})
+
+			It("should plan to undo the recorded changes", func() {
+				Expect(retError).To(Succeed())
+				Expect(plan.Changes).To(HaveExactElements(
+					api.Change{Action: api.ChangeDelete, Resource: api.MachineSetResource, Name: submarinerGWName + zone1},
+					api.Change{Action: api.ChangeDelete, Resource: api.FirewallRuleResource, Name: publicPortsRuleName},
+				))
+			})
+		})
+	})
+
+	When("zone retrieval fails", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().ListZonesWithContext(mock.Anything).Return(nil, errors.New("fake error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testStatus() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		gateways    []api.GatewayStatus
+		machineSets []unstructured.Unstructured
+		retError    error
+	)
+
+	BeforeEach(func() {
+		machineSets = nil
+	})
+
+	JustBeforeEach(func() {
+		t.msDeployer.EXPECT().ListWithContext(mock.Anything).Return(machineSets, nil)
+
+		gateways, retError = t.gwDeployer.Status(context.TODO(), reporter.Stdout())
+	})
+
+	Context("with a preexisting node labeled as a gateway", func() {
+		BeforeEach(func() {
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+			t.instances[zone1][0].MachineType = "https://compute/zones/" + zone1 + "/machineTypes/" + instanceType
+			t.instances[zone1][0].NetworkInterfaces = []*compute.NetworkInterface{{
+				Subnetwork:    "https://compute/regions/" + region + "/subnetworks/worker-subnet",
+				AccessConfigs: []*compute.AccessConfig{{NatIP: "1.2.3.4"}},
+			}}
+		})
+
+		It("should return it as a labeled worker", func() {
+			Expect(retError).To(Succeed())
+			Expect(gateways).To(Equal([]api.GatewayStatus{{
+				Type:          api.LabeledWorkerGateway,
+				Zone:          zone1,
+				Subnet:        "worker-subnet",
+				InstanceType:  instanceType,
+				NodeName:      instance1,
+				PublicIP:      "1.2.3.4",
+				SecurityGroup: publicPortsRuleName,
+			}}))
+		})
+	})
+
+	Context("with a dedicated node deployed as a gateway", func() {
+		BeforeEach(func() {
+			t.instances[zone1][0].Name = submarinerGWName + zone1 + "-abcde"
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should return it with its machine set", func() {
+			Expect(retError).To(Succeed())
+			Expect(gateways).To(HaveExactElements(And(HaveField("Type", api.DedicatedGateway),
+				HaveField("MachineSet", submarinerGWName+zone1), HaveField("NodeName", submarinerGWName+zone1+"-abcde"))))
+		})
+	})
+
+	Context("with a gateway machine set that hasn't provisioned its instance yet", func() {
+		BeforeEach(func() {
+			ms := unstructured.Unstructured{}
+			ms.SetName(submarinerGWName + zone2)
+			machineSets = []unstructured.Unstructured{ms}
+		})
+
+		It("should return it without node details", func() {
+			Expect(retError).To(Succeed())
+			Expect(gateways).To(HaveExactElements(And(HaveField("MachineSet", submarinerGWName+zone2), HaveField("NodeName", ""))))
+		})
+	})
+
+	When("zone retrieval fails", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().ListZonesWithContext(mock.Anything).Return(nil, errors.New("fake error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
 type gatewayDeployerTestDriver struct {
 	fakeGCPClientBase
 	numGateways int
 	image       string
+	labels      map[string]string
+	sourceCIDRs []string
+	ipFamily    api.IPFamily
 	kubeClient  *kubeFake.Clientset
 	msDeployer  *ocpFake.MockMachineSetDeployer
 	nodes       []*corev1.Node
+	gateways    []*unstructured.Unstructured
 	zones       []*compute.Zone
 	instances   map[string][]*compute.Instance
-	gwDeployer  api.GatewayDeployer
+	gwDeployer  api.ContextGatewayDeployer
+	ledger      *ledger.Ledger
 }
 
 func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
@@ -267,6 +686,7 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 		t.beforeEach()
 
 		t.nodes = []*corev1.Node{}
+		t.gateways = nil
 
 		t.zones = []*compute.Zone{
 			{
@@ -302,31 +722,37 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 		}
 
 		t.image = ""
+		t.labels = nil
+		t.sourceCIDRs = nil
+		t.ipFamily = ""
 		t.msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
 		t.kubeClient = kubeFake.NewClientset()
+		t.ledger = nil
 	})
 
 	JustBeforeEach(func() {
-		t.gcpClient.EXPECT().ListZones().Return(&compute.ZoneList{Items: t.zones}, nil).Maybe()
-		t.gcpClient.EXPECT().ListInstances(mock.Anything).RunAndReturn(func(zone string) (*compute.InstanceList, error) {
-			list := t.instances[zone]
-			if list != nil {
-				return &compute.InstanceList{Items: list}, nil
-			}
+		t.gcpClient.EXPECT().ListZonesWithContext(mock.Anything).Return(&compute.ZoneList{Items: t.zones}, nil).Maybe()
+		t.gcpClient.EXPECT().ListInstancesWithContext(mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, zone string) (*compute.InstanceList, error) {
+				list := t.instances[zone]
+				if list != nil {
+					return &compute.InstanceList{Items: list}, nil
+				}
 
-			return &compute.InstanceList{}, nil
-		}).Maybe()
+				return &compute.InstanceList{}, nil
+			}).Maybe()
 
-		t.gcpClient.EXPECT().GetInstance(mock.Anything, mock.Anything).RunAndReturn(func(zone, instance string) (*compute.Instance, error) {
-			list := t.instances[zone]
-			for _, i := range list {
-				if i.Name == instance {
-					return i, nil
+		t.gcpClient.EXPECT().GetInstanceWithContext(mock.Anything, mock.Anything, mock.Anything).
+			RunAndReturn(func(_ context.Context, zone, instance string) (*compute.Instance, error) {
+				list := t.instances[zone]
+				for _, i := range list {
+					if i.Name == instance {
+						return i, nil
+					}
 				}
-			}
 
-			return nil, fmt.Errorf("instance %q not found", instance)
-		}).Maybe()
+				return nil, fmt.Errorf("instance %q not found", instance)
+			}).Maybe()
 
 		for _, node := range t.nodes {
 			_, err := t.kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
@@ -335,12 +761,23 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 
 		t.kubeClient.ClearActions()
 
+		dynamicClient := dynamicFake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{k8s.GatewayGVR: "GatewayList"})
+
+		for _, gateway := range t.gateways {
+			_, err := dynamicClient.Resource(k8s.GatewayGVR).Namespace(gateway.GetNamespace()).Create(context.TODO(), gateway,
+				metav1.CreateOptions{})
+			Expect(err).To(Succeed())
+		}
+
 		t.gwDeployer = gcp.NewOcpGatewayDeployer(gcp.CloudInfo{
 			InfraID:   infraID,
 			Region:    region,
 			ProjectID: projectID,
 			Client:    t.gcpClient,
-		}, t.msDeployer, instanceType, t.image, k8s.NewInterface(t.kubeClient))
+			Labels:    t.labels,
+			Ledger:    t.ledger,
+		}, t.msDeployer, instanceType, t.image, k8s.NewInterface(t.kubeClient, k8s.WithDynamicClient(dynamicClient))).(api.ContextGatewayDeployer)
 	})
 
 	return t
@@ -348,7 +785,9 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 
 func (t *gatewayDeployerTestDriver) doDeploy() error {
 	return t.gwDeployer.Deploy(api.GatewayDeployInput{
-		Gateways: t.numGateways,
+		Gateways:    t.numGateways,
+		SourceCIDRs: t.sourceCIDRs,
+		IPFamily:    t.ipFamily,
 		PublicPorts: []api.PortSpec{
 			{
 				Port:     100,
@@ -398,14 +837,20 @@ func (t *gatewayDeployerTestDriver) assertIngressRule(rule *compute.Firewall) {
 		IPProtocol: "UDP",
 		Ports:      []string{"200"},
 	}))
+
+	if len(t.sourceCIDRs) == 0 {
+		Expect(rule.SourceRanges).To(Equal([]string{api.AnyIPv4CIDR}))
+	} else {
+		Expect(rule.SourceRanges).To(Equal(t.sourceCIDRs))
+	}
 }
 
 func (t *gatewayDeployerTestDriver) expInstanceUntagged(zone string, instance *compute.Instance) {
-	t.gcpClient.EXPECT().UpdateInstanceNetworkTags(projectID, zone, instance.Name, &compute.Tags{
+	t.gcpClient.EXPECT().UpdateInstanceNetworkTagsWithContext(mock.Anything, projectID, zone, instance.Name, &compute.Tags{
 		Items: []string{},
 	}).Return(nil)
 
-	t.gcpClient.EXPECT().DeletePublicIPOnInstance(instance).Return(nil)
+	t.gcpClient.EXPECT().DeletePublicIPOnInstanceWithContext(mock.Anything, instance).Return(nil)
 }
 
 func (t *gatewayDeployerTestDriver) assertMachineSet(ms *unstructured.Unstructured, expImage string) {
@@ -448,16 +893,28 @@ func newNode(name, withZone, withInstance string) *corev1.Node {
 	}
 }
 
+func newGateway(name, haStatus string) *unstructured.Unstructured {
+	gateway := &unstructured.Unstructured{}
+	gateway.SetAPIVersion("submariner.io/v1")
+	gateway.SetKind("Gateway")
+	gateway.SetName(name)
+	gateway.SetNamespace("submariner-operator")
+
+	Expect(unstructured.SetNestedField(gateway.Object, haStatus, "status", "haStatus")).To(Succeed())
+
+	return gateway
+}
+
 func labelNode(node *corev1.Node) *corev1.Node {
 	node.Labels["submariner.io/gateway"] = "true"
 	return node
 }
 
 //nolint:gocritic // Error: "consider `machineSets' to be of non-pointer type"
-func machineSetFn(machineSets *map[string]*unstructured.Unstructured) func(ms *unstructured.Unstructured) error {
+func machineSetFn(machineSets *map[string]*unstructured.Unstructured) func(context.Context, *unstructured.Unstructured) error {
 	*machineSets = map[string]*unstructured.Unstructured{}
 
-	return func(ms *unstructured.Unstructured) error {
+	return func(_ context.Context, ms *unstructured.Unstructured) error {
 		zone, ok, _ := unstructured.NestedString(ms.Object, "spec", "template", "spec", "providerSpec", "value", "zone")
 		Expect(ok).To(BeTrue())
 
@@ -0,0 +1,103 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+)
+
+// submarinerRuleName matches the names of the firewall rules created by generateRuleName, capturing the infra ID.
+var submarinerRuleName = regexp.MustCompile(fmt.Sprintf("^(.+)-(%s|%s|%s)-ingress$", internalPortsRuleName,
+	publicPortsRuleName, publicPortsV6RuleName))
+
+type orphanScanner struct {
+	CloudInfo
+}
+
+// NewOrphanScanner returns an OrphanScanner finding the Submariner firewall rules in the given project which don't belong
+// to a live cluster.
+func NewOrphanScanner(projectID string, client gcpclient.Interface) api.OrphanScanner {
+	return &orphanScanner{
+		CloudInfo: CloudInfo{
+			ProjectID: projectID,
+			Client:    client,
+		},
+	}
+}
+
+func (s *orphanScanner) Scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	orphans, err := s.scan(ctx, liveInfraIDs, status)
+
+	return orphans, classifyError(err)
+}
+
+func (s *orphanScanner) scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	status.Start("Scanning for orphaned Submariner firewall rules in the project %q", s.ProjectID)
+	defer status.End()
+
+	rules, err := s.Client.ListFirewallRulesWithContext(ctx, s.ProjectID, `name eq ".*-submariner-.*-ingress"`)
+	if err != nil {
+		return nil, status.Error(errors.Wrap(err, "error listing the firewall rules"), "unable to scan the firewall rules")
+	}
+
+	var orphans []api.Orphan
+
+	for _, rule := range rules {
+		matches := submarinerRuleName.FindStringSubmatch(rule.Name)
+		if matches == nil || slices.Contains(liveInfraIDs, matches[1]) {
+			continue
+		}
+
+		orphans = append(orphans, api.Orphan{
+			Resource: api.FirewallRuleResource,
+			Name:     rule.Name,
+			InfraID:  matches[1],
+		})
+	}
+
+	status.Success("Found %d orphaned Submariner firewall rules", len(orphans))
+
+	return orphans, nil
+}
+
+func (s *orphanScanner) Delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	return classifyError(s.delete(ctx, orphans, status))
+}
+
+func (s *orphanScanner) delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	for i := range orphans {
+		if orphans[i].Resource != api.FirewallRuleResource {
+			return errors.Errorf("unsupported resource %q", orphans[i].Resource)
+		}
+
+		if err := s.deleteFirewallRule(ctx, orphans[i].Name, status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"google.golang.org/api/compute/v1"
+)
+
+var _ = Describe("OrphanScanner", func() {
+	const orphanedRuleName = "orphaned-infra-submariner-public-ports-ingress"
+
+	t := &fakeGCPClientBase{}
+
+	var scanner api.OrphanScanner
+
+	BeforeEach(func() {
+		t.beforeEach()
+
+		scanner = gcp.NewOrphanScanner(projectID, t.gcpClient)
+	})
+
+	AfterEach(t.afterEach)
+
+	Context("on Scan", func() {
+		var listErr error
+
+		BeforeEach(func() {
+			listErr = nil
+		})
+
+		JustBeforeEach(func() {
+			t.gcpClient.EXPECT().ListFirewallRulesWithContext(mock.Anything, projectID, mock.Anything).Return([]*compute.Firewall{
+				{Name: infraID + "-submariner-internal-ports-ingress"},
+				{Name: orphanedRuleName},
+				{Name: "orphaned-infra-submariner-other-ingress"},
+			}, listErr)
+		})
+
+		It("should return the firewall rules of the clusters which aren't live", func() {
+			orphans, err := scanner.Scan(context.TODO(), []string{infraID}, reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(orphans).To(Equal([]api.Orphan{{
+				Resource: api.FirewallRuleResource,
+				Name:     orphanedRuleName,
+				InfraID:  "orphaned-infra",
+			}}))
+		})
+
+		When("listing the firewall rules fails", func() {
+			BeforeEach(func() {
+				listErr = errors.New("mock error")
+			})
+
+			It("should return an error", func() {
+				_, err := scanner.Scan(context.TODO(), []string{infraID}, reporter.Stdout())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("on Delete", func() {
+		It("should delete the firewall rules", func() {
+			t.gcpClient.EXPECT().DeleteFirewallRuleWithContext(mock.Anything, projectID, orphanedRuleName).
+				Return(nil)
+
+			Expect(scanner.Delete(context.TODO(), []api.Orphan{{
+				Resource: api.FirewallRuleResource,
+				Name:     orphanedRuleName,
+			}}, reporter.Stdout())).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,300 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/utils/set"
+)
+
+func (gc *gcpCloud) PlanOpenPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	plan, err := gc.planOpenPorts(ctx, ports, status)
+
+	return plan, classifyError(err)
+}
+
+func (gc *gcpCloud) planOpenPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the internal ports firewall rule on GCP")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	internalIngress := newInternalFirewallRule(gc.ProjectID, gc.InfraID, ports, gc.Labels)
+	if err := gc.planFirewallRules(ctx, plan, internalIngress); err != nil {
+		return nil, status.Error(err, "unable to retrieve the firewall rule %q", internalIngress.Name)
+	}
+
+	status.Success("Retrieved the internal ports firewall rule on GCP")
+
+	return plan, nil
+}
+
+func (gc *gcpCloud) PlanClosePorts(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := gc.planClosePorts(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (gc *gcpCloud) planClosePorts(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	recorded, err := gc.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.OpenPorts, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		return plan, nil
+	}
+
+	status.Start("Retrieving the internal ports firewall rule on GCP")
+	defer status.End()
+
+	internalIngressName := generateRuleName(gc.InfraID, internalPortsRuleName)
+	if err := gc.planDeleteFirewallRule(ctx, plan, internalIngressName); err != nil {
+		return nil, status.Error(err, "unable to retrieve the firewall rule %q", internalIngressName)
+	}
+
+	status.Success("Retrieved the internal ports firewall rule on GCP")
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planDeploy(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	if input.IPFamily == "" {
+		input.IPFamily = d.IPFamily
+	}
+
+	status.Start("Retrieving the public ports firewall rules on GCP")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	externalIngress := newExternalFirewallRules(d.ProjectID, d.InfraID, input.PublicPorts, input.PublicSourceCIDRs(), d.Labels)
+	if err := d.planFirewallRules(ctx, plan, externalIngress...); err != nil {
+		return nil, status.Error(err, "unable to retrieve the firewall rules %q", ruleNames(externalIngress))
+	}
+
+	for _, name := range staleExternalRuleNames(d.InfraID, externalIngress) {
+		if err := d.planDeleteFirewallRule(ctx, plan, name); err != nil {
+			return nil, status.Error(err, "unable to retrieve the firewall rule %q", name)
+		}
+	}
+
+	status.Success("Retrieved the public ports firewall rules on GCP")
+
+	numGatewayNodes, eligibleZonesForGW, err := d.parseCurrentGatewayInstances(ctx, status)
+	if err != nil {
+		return nil, status.Error(err, "error parsing current gateway instances")
+	}
+
+	gatewayNodesToDeploy := input.Gateways - numGatewayNodes
+	if gatewayNodesToDeploy < 0 {
+		surplus, err := d.selectSurplusMachineSets(ctx, -gatewayNodesToDeploy)
+		if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+			status.Warning("The surplus gateways won't be removed: %v", err)
+		} else if err != nil {
+			return nil, status.Error(err, "error selecting the surplus gateways")
+		}
+
+		for i := range surplus {
+			plan.Add(api.ChangeDelete, api.MachineSetResource, surplus[i].GetName(), "surplus passive gateway")
+		}
+	}
+
+	if gatewayNodesToDeploy <= 0 {
+		return plan, nil
+	}
+
+	if eligibleZonesForGW.Len() < gatewayNodesToDeploy {
+		return nil, status.Error(fmt.Errorf("there are an insufficient number of zones (%d) to deploy the desired number of gateways (%d)",
+			eligibleZonesForGW.Len(), input.Gateways), "unable to deploy the gateways")
+	}
+
+	for _, zone := range eligibleZonesForGW.SortedList()[:gatewayNodesToDeploy] {
+		machineSet, err := d.initMachineSet(zone)
+		if err != nil {
+			return nil, status.Error(err, "unable to initialize the gateway machine set")
+		}
+
+		plan.Add(api.ChangeCreate, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node in zone %q", zone)
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planCleanup(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the Submariner gateway firewall rules")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	recorded, err := d.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.Deploy, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "failed to read the ledger")
+	}
+
+	if !recorded {
+		for _, family := range []api.IPFamily{api.IPv4, api.IPv6} {
+			ingressName := externalRuleName(d.InfraID, family)
+			if err := d.planDeleteFirewallRule(ctx, plan, ingressName); err != nil {
+				return nil, status.Error(err, "unable to retrieve the firewall rule %q", ingressName)
+			}
+		}
+	}
+
+	status.Success("Retrieved the Submariner gateway firewall rules")
+
+	zones, err := d.retrieveZones(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, zone := range zones.Items {
+		if d.ignoreZone(zone) {
+			continue
+		}
+
+		instanceList, err := d.Client.ListInstancesWithContext(ctx, zone.Name)
+		if err != nil {
+			return nil, status.Error(err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
+		}
+
+		for _, instance := range instanceList.Items {
+			if !strings.HasPrefix(instance.Name, d.InfraID) || !d.isInstanceGatewayNode(instance) {
+				continue
+			}
+
+			prefix := d.InfraID + "-submariner-gw-" + zone.Name
+			if strings.HasPrefix(instance.Name, prefix) {
+				if recorded {
+					// The machine set is deleted along with the other recorded resources.
+					continue
+				}
+
+				machineSet, err := d.initMachineSet(zone.Name)
+				if err != nil {
+					return nil, status.Error(err, "unable to initialize the gateway machine set")
+				}
+
+				plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "dedicated gateway instance %q", instance.Name)
+			} else {
+				plan.Add(api.ChangeUpdate, api.InstanceResource, instance.Name, "remove network tag %q and the public IP in zone %q",
+					submarinerGatewayNodeTag, zone.Name)
+			}
+		}
+	}
+
+	gwNodes, err := d.k8sClient.ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error listing the gateway nodes")
+	}
+
+	for i := range gwNodes.Items {
+		plan.Add(api.ChangeDelete, api.NodeGatewayLabelResource, gwNodes.Items[i].Name, "")
+	}
+
+	return plan, nil
+}
+
+func (c *CloudInfo) planFirewallRules(ctx context.Context, plan *api.Plan, rules ...*compute.Firewall) error {
+	for _, rule := range rules {
+		existing, err := c.Client.GetFirewallRuleWithContext(ctx, c.ProjectID, rule.Name)
+		if gcpclient.IsGCPNotFoundError(err) {
+			plan.Add(api.ChangeCreate, api.FirewallRuleResource, rule.Name, "allow %s", formatAllowed(rule.Allowed))
+			continue
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "error retrieving firewall rule %q", rule.Name)
+		}
+
+		if firewallRuleChanged(existing, rule) {
+			plan.Add(api.ChangeUpdate, api.FirewallRuleResource, rule.Name, "allow %s", formatAllowed(rule.Allowed))
+		}
+	}
+
+	return nil
+}
+
+func (c *CloudInfo) planDeleteFirewallRule(ctx context.Context, plan *api.Plan, name string) error {
+	_, err := c.Client.GetFirewallRuleWithContext(ctx, c.ProjectID, name)
+	if gcpclient.IsGCPNotFoundError(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving firewall rule %q", name)
+	}
+
+	plan.Add(api.ChangeDelete, api.FirewallRuleResource, name, "")
+
+	return nil
+}
+
+func firewallRuleChanged(existing, desired *compute.Firewall) bool {
+	return formatAllowed(existing.Allowed) != formatAllowed(desired.Allowed) ||
+		!set.New(existing.SourceTags...).Equal(set.New(desired.SourceTags...)) ||
+		!set.New(existing.SourceRanges...).Equal(set.New(desired.SourceRanges...)) ||
+		!set.New(existing.TargetTags...).Equal(set.New(desired.TargetTags...))
+}
+
+func formatAllowed(allowed []*compute.FirewallAllowed) string {
+	return strings.Join(allowedEntries(allowed).SortedList(), ", ")
+}
+
+// allowedEntries returns the allowed protocols and ports, formatted as port/protocol.
+func allowedEntries(allowed []*compute.FirewallAllowed) set.Set[string] {
+	entries := set.New[string]()
+
+	for _, a := range allowed {
+		if len(a.Ports) == 0 {
+			entries.Insert(a.IPProtocol)
+			continue
+		}
+
+		for _, port := range a.Ports {
+			entries.Insert(port + "/" + a.IPProtocol)
+		}
+	}
+
+	return entries
+}
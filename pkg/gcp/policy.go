@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"sigs.k8s.io/yaml"
+)
+
+// policyModeCalls are the client.Interface methods called by each policy mode. The plans' read-only calls are included.
+var policyModeCalls = map[api.PolicyMode][]string{
+	api.OpenPortsMode:  {"GetFirewallRule", "InsertFirewallRule", "UpdateFirewallRule"},
+	api.ClosePortsMode: {"GetFirewallRule", "DeleteFirewallRule"},
+	api.ReconcilePortsMode: {
+		"GetFirewallRule", "InsertFirewallRule", "UpdateFirewallRule", "DeleteFirewallRule",
+	},
+	api.DeployMode: {
+		"GetFirewallRule", "InsertFirewallRule", "UpdateFirewallRule", "DeleteFirewallRule", "ListZones", "ListInstances",
+	},
+	api.CleanupMode: {
+		"GetFirewallRule", "DeleteFirewallRule", "ListZones", "ListInstances", "UpdateInstanceNetworkTags",
+		"DeletePublicIPOnInstance",
+	},
+	api.ScanOrphansMode:   {"ListFirewallRules"},
+	api.DeleteOrphansMode: {"DeleteFirewallRule"},
+	// There's no GCP hosted platform.
+	api.PrepareHostedGatewaysMode: {},
+	api.CleanupHostedGatewaysMode: {},
+}
+
+// callPermissions maps each client.Interface method calling the GCP APIs to the IAM permissions it requires.
+var callPermissions = map[string][]string{
+	"InsertFirewallRule":          {"compute.firewalls.create", "compute.networks.updatePolicy"},
+	"GetFirewallRule":             {"compute.firewalls.get"},
+	"DeleteFirewallRule":          {"compute.firewalls.delete", "compute.networks.updatePolicy"},
+	"UpdateFirewallRule":          {"compute.firewalls.update", "compute.networks.updatePolicy"},
+	"ListFirewallRules":           {"compute.firewalls.list"},
+	"GetInstance":                 {"compute.instances.get"},
+	"ListInstances":               {"compute.instances.list"},
+	"ListZones":                   {"compute.zones.list"},
+	"UpdateInstanceNetworkTags":   {"compute.instances.setTags"},
+	"ConfigurePublicIPOnInstance": {"compute.instances.addAccessConfig", "compute.subnetworks.useExternalIp"},
+	"DeletePublicIPOnInstance":    {"compute.instances.deleteAccessConfig"},
+	"TestPermissions":             {},
+}
+
+// customRole is a custom IAM role definition, in the format taken by "gcloud iam roles create --file".
+type customRole struct {
+	Title               string   `json:"title"`
+	Description         string   `json:"description"`
+	Stage               string   `json:"stage"`
+	IncludedPermissions []string `json:"includedPermissions"`
+}
+
+// Permissions returns the IAM permissions needed by the given policy modes, or by all of them if none is given.
+func Permissions(modes ...api.PolicyMode) ([]string, error) {
+	return api.PolicyPermissions(policyModeCalls, callPermissions, modes...)
+}
+
+// CustomRole returns the YAML definition of a custom IAM role granting the least privileges needed by the given policy
+// modes, or by all of them if none is given.
+func CustomRole(modes ...api.PolicyMode) ([]byte, error) {
+	permissions, err := Permissions(modes...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(modes) == 0 {
+		modes = api.PolicyModes
+	}
+
+	role, err := yaml.Marshal(&customRole{
+		Title:               "Submariner cloud preparation",
+		Description:         fmt.Sprintf("Permissions needed by the Submariner cloud preparation for %v", modes),
+		Stage:               "GA",
+		IncludedPermissions: permissions,
+	})
+
+	return role, errors.Wrap(err, "error marshalling the custom role")
+}
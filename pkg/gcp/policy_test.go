@@ -0,0 +1,71 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("CustomRole", func() {
+	type customRole struct {
+		Title               string   `json:"title"`
+		Stage               string   `json:"stage"`
+		IncludedPermissions []string `json:"includedPermissions"`
+	}
+
+	parseRole := func(modes ...api.PolicyMode) *customRole {
+		data, err := gcp.CustomRole(modes...)
+		Expect(err).To(Succeed())
+
+		role := &customRole{}
+		Expect(yaml.Unmarshal(data, role)).To(Succeed())
+		Expect(role.Title).ToNot(BeEmpty())
+		Expect(role.Stage).To(Equal("GA"))
+
+		return role
+	}
+
+	When("the OpenPorts mode is requested", func() {
+		It("should only include the firewall rule permissions", func() {
+			Expect(parseRole(api.OpenPortsMode).IncludedPermissions).To(Equal([]string{
+				"compute.firewalls.create", "compute.firewalls.get", "compute.firewalls.update", "compute.networks.updatePolicy",
+			}))
+		})
+	})
+
+	When("the Cleanup mode is requested", func() {
+		It("should include the permissions to reset the existing gateway instances", func() {
+			Expect(parseRole(api.CleanupMode).IncludedPermissions).To(Equal([]string{
+				"compute.firewalls.delete", "compute.firewalls.get", "compute.instances.deleteAccessConfig",
+				"compute.instances.list", "compute.instances.setTags", "compute.networks.updatePolicy", "compute.zones.list",
+			}))
+		})
+	})
+
+	When("an unknown mode is requested", func() {
+		It("should return an error", func() {
+			_, err := gcp.CustomRole("Unknown")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
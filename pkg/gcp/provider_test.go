@@ -0,0 +1,90 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Provider", func() {
+	t := &fakeGCPClientBase{}
+
+	var config gcp.ProviderConfig
+
+	BeforeEach(func() {
+		t.beforeEach()
+
+		config = gcp.ProviderConfig{
+			CloudInfo: gcp.CloudInfo{
+				InfraID:   infraID,
+				Region:    region,
+				ProjectID: projectID,
+				Client:    t.gcpClient,
+			},
+			MachineSetDeployer: ocpFake.NewMockMachineSetDeployer(GinkgoT()),
+			K8sClient:          k8s.NewInterface(kubeFake.NewClientset()),
+			InstanceType:       instanceType,
+		}
+	})
+
+	It("should be registered", func() {
+		Expect(api.Providers()).To(ContainElement(gcp.ProviderName))
+	})
+
+	When("built with a valid configuration", func() {
+		It("should return the Cloud and GatewayDeployer", func() {
+			cloud, gwDeployer, err := api.NewProvider(gcp.ProviderName, &config)
+			Expect(err).To(Succeed())
+			Expect(cloud).ToNot(BeNil())
+			Expect(gwDeployer).ToNot(BeNil())
+
+			_, _, err = api.NewProvider(gcp.ProviderName, config)
+			Expect(err).To(Succeed())
+		})
+	})
+
+	When("built with a configuration of another type", func() {
+		It("should return an error", func() {
+			_, _, err := api.NewProvider(gcp.ProviderName, "bogus")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the GCP client is missing", func() {
+		It("should return an error", func() {
+			config.Client = nil
+
+			_, _, err := api.NewProvider(gcp.ProviderName, &config)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the provider name is unknown", func() {
+		It("should return an error", func() {
+			_, _, err := api.NewProvider("unknown", &config)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
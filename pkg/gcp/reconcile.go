@@ -0,0 +1,115 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package gcp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"google.golang.org/api/compute/v1"
+)
+
+func (gc *gcpCloud) ReconcilePorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	plan, err := gc.reconcilePorts(ctx, ports, status)
+
+	return plan, classifyError(err)
+}
+
+func (gc *gcpCloud) reconcilePorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	if err := api.ValidatePorts(ports); err != nil {
+		return nil, errors.WithMessage(err, "invalid internal ports")
+	}
+
+	status.Start("Comparing the internal ports %q with the firewall rule on GCP", formatPorts(ports))
+	defer status.End()
+
+	plan := &api.Plan{}
+	internalIngress := newInternalFirewallRule(gc.ProjectID, gc.InfraID, ports, gc.Labels)
+
+	existing, err := gc.Client.GetFirewallRuleWithContext(ctx, gc.ProjectID, internalIngress.Name)
+	if err != nil && !gcpclient.IsGCPNotFoundError(err) {
+		return nil, status.Error(errors.Wrapf(err, "error retrieving firewall rule %q", internalIngress.Name),
+			"unable to retrieve the firewall rule %q", internalIngress.Name)
+	}
+
+	found := err == nil
+
+	switch {
+	case len(ports) == 0:
+		if found {
+			plan.Add(api.ChangeDelete, api.FirewallRuleResource, internalIngress.Name, "")
+		}
+	case !found:
+		plan.Add(api.ChangeCreate, api.FirewallRuleResource, internalIngress.Name, "allow %s", formatAllowed(internalIngress.Allowed))
+	case firewallRuleChanged(existing, internalIngress):
+		plan.Add(api.ChangeUpdate, api.FirewallRuleResource, internalIngress.Name, "%s",
+			describeAllowedDiff(existing.Allowed, internalIngress.Allowed))
+	}
+
+	plan.Report(status)
+
+	if plan.IsEmpty() {
+		return plan, nil
+	}
+
+	// GCP doesn't accept firewall rules allowing nothing, so the rule is deleted when no port remains.
+	if len(ports) == 0 {
+		if err := gc.deleteFirewallRule(ctx, internalIngress.Name, status); err != nil {
+			return nil, err
+		}
+
+		return plan, nil
+	}
+
+	status.Start("Updating the firewall rule %q on GCP", internalIngress.Name)
+
+	if err := gc.openFirewallRules(ctx, ledger.OpenPorts, internalIngress); err != nil {
+		return nil, status.Error(err, "unable to reconcile the internal ports")
+	}
+
+	status.Success("Reconciled the internal ports %q with firewall rule %q on GCP", formatPorts(ports), internalIngress.Name)
+
+	return plan, nil
+}
+
+// describeAllowedDiff describes the ports allowed by the desired rule but not by the existing one, and vice versa.
+func describeAllowedDiff(existing, desired []*compute.FirewallAllowed) string {
+	existingEntries := allowedEntries(existing)
+	desiredEntries := allowedEntries(desired)
+
+	details := []string{}
+
+	if added := desiredEntries.Difference(existingEntries); added.Len() > 0 {
+		details = append(details, "allow "+strings.Join(added.SortedList(), ", "))
+	}
+
+	if removed := existingEntries.Difference(desiredEntries); removed.Len() > 0 {
+		details = append(details, "disallow "+strings.Join(removed.SortedList(), ", "))
+	}
+
+	if len(details) == 0 {
+		return "restore the source and target tags"
+	}
+
+	return strings.Join(details, "; ")
+}
@@ -0,0 +1,139 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/utils/set"
+)
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	statuses, err := d.gatewayStatus(ctx, status)
+
+	return statuses, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) gatewayStatus(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "unable to list the gateway machine sets")
+	}
+
+	zones, err := d.retrieveZones(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Start("Retrieving the Submariner gateway instances")
+	defer status.End()
+
+	ruleName := generateRuleName(d.InfraID, publicPortsRuleName)
+	gateways := []api.GatewayStatus{}
+	provisioned := set.New[string]()
+
+	for _, zone := range zones.Items {
+		if d.ignoreZone(zone) {
+			continue
+		}
+
+		instanceList, err := d.Client.ListInstancesWithContext(ctx, zone.Name)
+		if err != nil {
+			return nil, status.Error(err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
+		}
+
+		for _, instance := range instanceList.Items {
+			if !strings.HasPrefix(instance.Name, d.InfraID) || !d.isInstanceGatewayNode(instance) {
+				continue
+			}
+
+			gateway := instanceStatus(instance)
+			gateway.Zone = zone.Name
+			gateway.SecurityGroup = ruleName
+
+			// Instances deployed using the OCPMachineSet API are named after their machine set.
+			if strings.HasPrefix(instance.Name, d.InfraID+"-submariner-gw-"+zone.Name) {
+				machineSet, err := d.initMachineSet(zone.Name)
+				if err != nil {
+					return nil, status.Error(err, "unable to initialize the gateway machine set")
+				}
+
+				gateway.Type = api.DedicatedGateway
+				gateway.MachineSet = machineSet.GetName()
+				provisioned.Insert(machineSet.GetName())
+			}
+
+			gateways = append(gateways, gateway)
+		}
+	}
+
+	for i := range machineSets {
+		if provisioned.Has(machineSets[i].GetName()) {
+			continue
+		}
+
+		gateways = append(gateways, api.GatewayStatus{
+			Type:          api.DedicatedGateway,
+			MachineSet:    machineSets[i].GetName(),
+			Zone:          ocp.ProviderSpecString(&machineSets[i], "zone"),
+			InstanceType:  ocp.ProviderSpecString(&machineSets[i], "machineType"),
+			SecurityGroup: ruleName,
+		})
+	}
+
+	status.Success("Retrieved %d Submariner gateway(s)", len(gateways))
+
+	return gateways, nil
+}
+
+func instanceStatus(instance *compute.Instance) api.GatewayStatus {
+	gateway := api.GatewayStatus{
+		Type:         api.LabeledWorkerGateway,
+		NodeName:     instance.Name,
+		InstanceType: resourceName(instance.MachineType),
+	}
+
+	if len(instance.NetworkInterfaces) > 0 {
+		gateway.Subnet = resourceName(instance.NetworkInterfaces[0].Subnetwork)
+
+		for _, accessConfig := range instance.NetworkInterfaces[0].AccessConfigs {
+			if accessConfig.NatIP != "" {
+				gateway.PublicIP = accessConfig.NatIP
+				break
+			}
+		}
+	}
+
+	return gateway
+}
+
+// resourceName returns the name of the resource referenced by the given GCP resource URL.
+func resourceName(url string) string {
+	if url == "" {
+		return ""
+	}
+
+	return path.Base(url)
+}
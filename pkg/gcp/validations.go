@@ -0,0 +1,86 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+func (gc *gcpCloud) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(gc.validate(ctx, status))
+}
+
+func (gc *gcpCloud) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start("Validating the permissions to open and close the internal ports")
+	defer status.End()
+
+	if err := gc.validatePermissions(ctx, api.OpenPortsMode, api.ClosePortsMode); err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to open and close the internal ports")
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.validate(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start("Validating the permissions to deploy and clean up the gateways")
+	defer status.End()
+
+	if err := d.validatePermissions(ctx, api.DeployMode, api.CleanupMode); err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the permissions to deploy and clean up the gateways")
+
+	return nil
+}
+
+// validatePermissions tests all the permissions needed by the given policy modes on the project and returns a
+// MissingPermissionsError listing those which aren't granted.
+func (c *CloudInfo) validatePermissions(ctx context.Context, modes ...api.PolicyMode) error {
+	permissions, err := Permissions(modes...)
+	if err != nil {
+		return err
+	}
+
+	granted, err := c.Client.TestPermissionsWithContext(ctx, permissions)
+	if err != nil {
+		return errors.Wrap(err, "error testing the IAM permissions")
+	}
+
+	var missing []string
+
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			missing = append(missing, permission)
+		}
+	}
+
+	return api.NewMissingPermissionsError(ProviderName, missing)
+}
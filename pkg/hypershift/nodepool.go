@@ -0,0 +1,271 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hypershift provides a GatewayDeployer for hosted control plane clusters, such as ROSA HCP, which have no
+// machine API: the gateway nodes are deployed with a dedicated HyperShift NodePool in the management cluster.
+package hypershift
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/admiral/pkg/resource"
+	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	ProviderName = "hypershift"
+
+	// NodePoolLabel is set by HyperShift on the nodes of a NodePool, to the name of the NodePool.
+	NodePoolLabel = "hypershift.openshift.io/nodePool"
+
+	nodePoolSuffix = "-submariner-gw"
+)
+
+var NodePoolGVR = schema.GroupVersionResource{
+	Group:    "hypershift.openshift.io",
+	Version:  "v1beta1",
+	Resource: "nodepools",
+}
+
+// Platform prepares the cloud of a hosted cluster for its gateway nodes. The AWS and Azure providers implement it.
+type Platform interface {
+	// PrepareGateways creates or updates the cloud resources the gateway nodes need, such as the security group opening
+	// the public ports, and adjusts the given platform section of the gateway NodePool spec accordingly. The platform
+	// section starts as a copy of the worker NodePool's.
+	PrepareGateways(ctx context.Context, input api.GatewayDeployInput, platform map[string]interface{}, status reporter.Interface) error
+
+	// PlanPrepareGateways returns the changes that PrepareGateways would make, without applying them.
+	PlanPrepareGateways(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error)
+
+	// CleanupGateways removes the cloud resources created by PrepareGateways, once the gateway NodePool is deleted.
+	CleanupGateways(ctx context.Context, status reporter.Interface) error
+
+	// PlanCleanupGateways returns the changes that CleanupGateways would make, without applying them.
+	PlanCleanupGateways(ctx context.Context, status reporter.Interface) (*api.Plan, error)
+
+	// Validate checks, without changing anything, that the credentials in use have the permissions needed to prepare
+	// and clean up the cloud resources of the gateways.
+	Validate(ctx context.Context, status reporter.Interface) error
+}
+
+// Config identifies the hosted cluster the gateways are deployed for.
+type Config struct {
+	// HostedCluster is the name of the HostedCluster.
+	HostedCluster string
+
+	// Namespace is the namespace of the HostedCluster in the management cluster, where the NodePool is created.
+	Namespace string
+
+	// WorkerNodePool is the name of the NodePool whose platform configuration and release the gateway NodePool is
+	// derived from. If empty, the first NodePool of the HostedCluster is used.
+	WorkerNodePool string
+}
+
+type nodePoolDeployer struct {
+	dynamicClient dynamic.Interface
+	k8sClient     k8s.ContextInterface
+	config        Config
+	platform      Platform
+}
+
+// NewGatewayDeployer returns a GatewayDeployer which deploys the gateways of a hosted cluster with a dedicated NodePool,
+// labelled and tainted for the gateways. The dynamic client accesses the management cluster, while the k8s client
+// accesses the hosted cluster. The platform, if not nil, prepares the cloud for the gateway nodes; without it, for
+// example on KubeVirt, the NodePool uses the worker NodePool's platform configuration as is.
+func NewGatewayDeployer(dynamicClient dynamic.Interface, k8sClient k8s.Interface, config Config, platform Platform) api.GatewayDeployer {
+	return &nodePoolDeployer{
+		dynamicClient: dynamicClient,
+		k8sClient:     k8s.WithContext(k8sClient),
+		config:        config,
+		platform:      platform,
+	}
+}
+
+func (d *nodePoolDeployer) nodePoolName() string {
+	return d.config.HostedCluster + nodePoolSuffix
+}
+
+func (d *nodePoolDeployer) nodePools() dynamic.ResourceInterface {
+	return d.dynamicClient.Resource(NodePoolGVR).Namespace(d.config.Namespace)
+}
+
+func (d *nodePoolDeployer) Deploy(input api.GatewayDeployInput, status reporter.Interface) error {
+	return d.DeployWithContext(context.TODO(), input, status)
+}
+
+func (d *nodePoolDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	err := d.deploy(ctx, input, status)
+	if err == nil {
+		err = d.waitForGateways(ctx, input.WaitTimeout, status)
+	}
+
+	return classifyError(err)
+}
+
+func (d *nodePoolDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	status.Start("Retrieving the worker NodePool of hosted cluster %q", d.config.HostedCluster)
+	defer status.End()
+
+	worker, err := d.workerNodePool(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the worker NodePool")
+	}
+
+	status.Success("Retrieved the worker NodePool %q", worker.GetName())
+
+	platform, _, _ := unstructured.NestedMap(worker.Object, "spec", "platform")
+	if platform == nil {
+		platform = map[string]interface{}{}
+	}
+
+	if d.platform != nil {
+		err = d.platform.PrepareGateways(ctx, input, platform, status)
+		if err != nil {
+			return err //nolint:wrapcheck // The platform reports and classifies its errors.
+		}
+	}
+
+	status.Start("Deploying the gateway NodePool %q", d.nodePoolName())
+
+	nodePool := d.newNodePool(worker, platform, input)
+
+	_, err = util.CreateOrUpdate(ctx, resource.ForDynamic(d.nodePools()), nodePool, util.Replace[*unstructured.Unstructured](nodePool))
+	if err != nil {
+		return status.Error(err, "unable to deploy the gateway NodePool")
+	}
+
+	status.Success("Deployed the gateway NodePool %q with %d replica(s)", d.nodePoolName(), gatewayCount(input))
+
+	return nil
+}
+
+// gatewayCount returns the number of gateway nodes requested; the default policy deploys a single one.
+func gatewayCount(input api.GatewayDeployInput) int64 {
+	if input.Gateways > 0 {
+		return int64(input.Gateways)
+	}
+
+	return 1
+}
+
+// workerNodePool returns the configured worker NodePool, or the first NodePool of the hosted cluster which isn't the
+// gateway NodePool.
+func (d *nodePoolDeployer) workerNodePool(ctx context.Context) (*unstructured.Unstructured, error) {
+	if d.config.WorkerNodePool != "" {
+		nodePool, err := d.nodePools().Get(ctx, d.config.WorkerNodePool, metav1.GetOptions{})
+		return nodePool, errors.Wrapf(err, "error retrieving NodePool %q", d.config.WorkerNodePool)
+	}
+
+	nodePools, err := d.nodePools().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the NodePools")
+	}
+
+	for i := range nodePools.Items {
+		clusterName, _, _ := unstructured.NestedString(nodePools.Items[i].Object, "spec", "clusterName")
+		if clusterName == d.config.HostedCluster && nodePools.Items[i].GetName() != d.nodePoolName() {
+			return &nodePools.Items[i], nil
+		}
+	}
+
+	return nil, errors.Errorf("no NodePool found for hosted cluster %q", d.config.HostedCluster)
+}
+
+func (d *nodePoolDeployer) newNodePool(worker *unstructured.Unstructured, platform map[string]interface{}, input api.GatewayDeployInput,
+) *unstructured.Unstructured {
+	release, _, _ := unstructured.NestedMap(worker.Object, "spec", "release")
+	management, _, _ := unstructured.NestedMap(worker.Object, "spec", "management")
+
+	if management == nil {
+		management = map[string]interface{}{"upgradeType": "Replace"}
+	}
+
+	// HyperShift creates and configures the MachineHealthCheck of an auto-repaired NodePool itself, so the unhealthy
+	// conditions and maxUnhealthy of the health check don't apply. The auto-repair of the worker NodePool isn't inherited.
+	management["autoRepair"] = input.HealthCheck != nil
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": NodePoolGVR.GroupVersion().String(),
+		"kind":       "NodePool",
+		"metadata": map[string]interface{}{
+			"name":      d.nodePoolName(),
+			"namespace": d.config.Namespace,
+			"labels": map[string]interface{}{
+				ocp.SubmarinerGatewayLabel: "true",
+			},
+		},
+		"spec": map[string]interface{}{
+			"clusterName": d.config.HostedCluster,
+			"replicas":    gatewayCount(input),
+			"release":     release,
+			"management":  management,
+			"platform":    platform,
+			"nodeLabels": map[string]interface{}{
+				ocp.SubmarinerGatewayLabel:      "true",
+				"node-role.kubernetes.io/infra": "",
+			},
+			"taints": []interface{}{
+				map[string]interface{}{
+					"key":    ocp.GatewayTaintKey,
+					"effect": string(corev1.TaintEffectNoSchedule),
+				},
+			},
+		},
+	}}
+}
+
+func (d *nodePoolDeployer) Cleanup(status reporter.Interface) error {
+	return d.CleanupWithContext(context.TODO(), status)
+}
+
+func (d *nodePoolDeployer) CleanupWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.cleanup(ctx, status))
+}
+
+func (d *nodePoolDeployer) cleanup(ctx context.Context, status reporter.Interface) error {
+	status.Start("Deleting the gateway NodePool %q", d.nodePoolName())
+	defer status.End()
+
+	err := d.nodePools().Delete(ctx, d.nodePoolName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return status.Error(err, "unable to delete the gateway NodePool")
+	}
+
+	status.Success("Deleted the gateway NodePool %q", d.nodePoolName())
+
+	if d.platform != nil {
+		return d.platform.CleanupGateways(ctx, status) //nolint:wrapcheck // The platform reports and classifies its errors.
+	}
+
+	return nil
+}
+
+func classifyError(err error) error {
+	return api.ClassifyError(ProviderName, err, func(error) error { return nil })
+}
@@ -0,0 +1,318 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hypershift_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/hypershift"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeClient "k8s.io/client-go/dynamic/fake"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	hostedCluster = "test-hc"
+	namespace     = "clusters"
+	workerPool    = "test-hc-workers"
+	gatewayPool   = hostedCluster + "-submariner-gw"
+)
+
+var _ = Describe("NodePool gateway deployer", func() {
+	var (
+		dynClient  *fakeClient.FakeDynamicClient
+		kubeClient *kubeFake.Clientset
+		platform   *fakePlatform
+		deployer   api.ContextGatewayDeployer
+	)
+
+	BeforeEach(func() {
+		dynClient = fakeClient.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme, map[schema.GroupVersionResource]string{
+			hypershift.NodePoolGVR: "NodePoolList",
+		})
+		kubeClient = kubeFake.NewClientset()
+		platform = &fakePlatform{}
+
+		deployer = hypershift.NewGatewayDeployer(dynClient, k8s.NewInterface(kubeClient), hypershift.Config{
+			HostedCluster: hostedCluster,
+			Namespace:     namespace,
+		}, platform).(api.ContextGatewayDeployer)
+
+		createNodePool(dynClient, newNodePool(workerPool, 3))
+	})
+
+	Context("on Deploy", func() {
+		It("should create the gateway NodePool from the worker NodePool", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 2}, reporter.Stdout())).To(Succeed())
+
+			nodePool := getNodePool(dynClient, gatewayPool)
+			Expect(nodePool.GetLabels()).To(HaveKeyWithValue(ocp.SubmarinerGatewayLabel, "true"))
+
+			spec, _, _ := unstructured.NestedMap(nodePool.Object, "spec")
+			Expect(spec).To(HaveKeyWithValue("clusterName", hostedCluster))
+			Expect(spec).To(HaveKeyWithValue("replicas", int64(2)))
+			Expect(spec).To(HaveKeyWithValue("release", map[string]interface{}{"image": "quay.io/openshift-release:4.16"}))
+			Expect(spec).To(HaveKeyWithValue("management", map[string]interface{}{"upgradeType": "InPlace", "autoRepair": false}))
+			Expect(spec).To(HaveKeyWithValue("nodeLabels", map[string]interface{}{
+				ocp.SubmarinerGatewayLabel:      "true",
+				"node-role.kubernetes.io/infra": "",
+			}))
+			Expect(spec).To(HaveKeyWithValue("taints", []interface{}{
+				map[string]interface{}{"key": ocp.GatewayTaintKey, "effect": "NoSchedule"},
+			}))
+		})
+
+		It("should let the platform prepare the gateways and adjust the platform configuration", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+			Expect(platform.prepared).To(BeTrue())
+
+			aws, _, _ := unstructured.NestedMap(getNodePool(dynClient, gatewayPool).Object, "spec", "platform", "aws")
+			Expect(aws).To(HaveKeyWithValue("instanceType", "m5.xlarge"))
+			Expect(aws).To(HaveKeyWithValue("securityGroups", []interface{}{map[string]interface{}{"id": "sg-gw"}}))
+		})
+
+		It("should leave the worker NodePool unchanged", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+
+			aws, _, _ := unstructured.NestedMap(getNodePool(dynClient, workerPool).Object, "spec", "platform", "aws")
+			Expect(aws).ToNot(HaveKey("securityGroups"))
+		})
+
+		It("should enable the auto-repair of the gateway NodePool if a health check is requested", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1, HealthCheck: &api.HealthCheck{}}, reporter.Stdout())).To(Succeed())
+
+			management, _, _ := unstructured.NestedMap(getNodePool(dynClient, gatewayPool).Object, "spec", "management")
+			Expect(management).To(Equal(map[string]interface{}{"upgradeType": "InPlace", "autoRepair": true}))
+		})
+
+		It("should disable the auto-repair of the gateway NodePool if no health check is requested", func() {
+			worker := getNodePool(dynClient, workerPool)
+			Expect(unstructured.SetNestedField(worker.Object, true, "spec", "management", "autoRepair")).To(Succeed())
+			_, err := dynClient.Resource(hypershift.NodePoolGVR).Namespace(namespace).Update(context.TODO(), worker, metav1.UpdateOptions{})
+			Expect(err).To(Succeed())
+
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+
+			autoRepair, _, _ := unstructured.NestedBool(getNodePool(dynClient, gatewayPool).Object, "spec", "management", "autoRepair")
+			Expect(autoRepair).To(BeFalse())
+		})
+
+		It("should scale an existing gateway NodePool", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 2}, reporter.Stdout())).To(Succeed())
+
+			replicas, _, _ := unstructured.NestedInt64(getNodePool(dynClient, gatewayPool).Object, "spec", "replicas")
+			Expect(replicas).To(Equal(int64(2)))
+		})
+
+		When("a wait timeout is given", func() {
+			input := api.GatewayDeployInput{Gateways: 1, WaitTimeout: 100 * time.Millisecond}
+
+			It("should wait for the gateway nodes to be Ready", func() {
+				createNode(kubeClient, "gw-node", gatewayPool, corev1.ConditionTrue)
+				Expect(deployer.Deploy(input, reporter.Stdout())).To(Succeed())
+			})
+
+			It("should time out if the gateway nodes aren't Ready", func() {
+				createNode(kubeClient, "gw-node", gatewayPool, corev1.ConditionFalse)
+
+				err := deployer.Deploy(input, reporter.Stdout())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("0 of 1 gateway node(s) ready"))
+			})
+		})
+
+		When("the hosted cluster has no NodePool", func() {
+			BeforeEach(func() {
+				Expect(dynClient.Resource(hypershift.NodePoolGVR).Namespace(namespace).Delete(context.Background(), workerPool,
+					metav1.DeleteOptions{})).To(Succeed())
+			})
+
+			It("should return an error", func() {
+				Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).ToNot(Succeed())
+			})
+		})
+	})
+
+	Context("on PlanDeploy", func() {
+		It("should plan the creation of the gateway NodePool", func() {
+			plan, err := deployer.PlanDeploy(context.Background(), api.GatewayDeployInput{Gateways: 2}, reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(ContainElement(api.Change{
+				Action:   api.ChangeCreate,
+				Resource: api.NodePoolResource,
+				Name:     gatewayPool,
+				Details:  "with 2 replica(s)",
+			}))
+			Expect(plan.Changes).To(ContainElement(HaveField("Resource", api.SecurityGroupResource)))
+		})
+
+		It("should plan the scaling of an existing gateway NodePool", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+
+			plan, err := deployer.PlanDeploy(context.Background(), api.GatewayDeployInput{Gateways: 3}, reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(ContainElement(api.Change{
+				Action:   api.ChangeUpdate,
+				Resource: api.NodePoolResource,
+				Name:     gatewayPool,
+				Details:  "scale from 1 to 3 replica(s)",
+			}))
+		})
+	})
+
+	Context("on Cleanup", func() {
+		It("should delete the gateway NodePool and let the platform clean up", func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+			Expect(deployer.Cleanup(reporter.Stdout())).To(Succeed())
+
+			_, err := dynClient.Resource(hypershift.NodePoolGVR).Namespace(namespace).Get(context.Background(), gatewayPool,
+				metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(platform.cleanedUp).To(BeTrue())
+		})
+
+		It("should succeed if the gateway NodePool doesn't exist", func() {
+			Expect(deployer.Cleanup(reporter.Stdout())).To(Succeed())
+		})
+	})
+
+	Context("on Status", func() {
+		BeforeEach(func() {
+			Expect(deployer.Deploy(api.GatewayDeployInput{Gateways: 1}, reporter.Stdout())).To(Succeed())
+		})
+
+		It("should report the gateway NodePool before its nodes join", func() {
+			statuses, err := deployer.Status(context.Background(), reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(statuses).To(Equal([]api.GatewayStatus{{
+				Type:         api.DedicatedGateway,
+				NodePool:     gatewayPool,
+				InstanceType: "m5.xlarge",
+			}}))
+		})
+
+		It("should report the nodes of the gateway NodePool as dedicated gateways", func() {
+			node := createNode(kubeClient, "gw-node", gatewayPool, corev1.ConditionTrue)
+			node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeExternalIP, Address: "1.2.3.4"}}
+
+			_, err := kubeClient.CoreV1().Nodes().UpdateStatus(context.Background(), node, metav1.UpdateOptions{})
+			Expect(err).To(Succeed())
+
+			statuses, err := deployer.Status(context.Background(), reporter.Stdout())
+			Expect(err).To(Succeed())
+			Expect(statuses).To(Equal([]api.GatewayStatus{{
+				Type:     api.DedicatedGateway,
+				NodePool: gatewayPool,
+				NodeName: "gw-node",
+				PublicIP: "1.2.3.4",
+			}}))
+		})
+	})
+})
+
+func newNodePool(name string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": hypershift.NodePoolGVR.GroupVersion().String(),
+		"kind":       "NodePool",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec": map[string]interface{}{
+			"clusterName": hostedCluster,
+			"replicas":    replicas,
+			"release":     map[string]interface{}{"image": "quay.io/openshift-release:4.16"},
+			"management":  map[string]interface{}{"upgradeType": "InPlace"},
+			"platform": map[string]interface{}{
+				"type": "AWS",
+				"aws":  map[string]interface{}{"instanceType": "m5.xlarge"},
+			},
+		},
+	}}
+}
+
+func createNodePool(client *fakeClient.FakeDynamicClient, nodePool *unstructured.Unstructured) {
+	_, err := client.Resource(hypershift.NodePoolGVR).Namespace(namespace).Create(context.Background(), nodePool, metav1.CreateOptions{})
+	Expect(err).To(Succeed())
+}
+
+func getNodePool(client *fakeClient.FakeDynamicClient, name string) *unstructured.Unstructured {
+	nodePool, err := client.Resource(hypershift.NodePoolGVR).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	Expect(err).To(Succeed())
+
+	return nodePool
+}
+
+func createNode(kubeClient *kubeFake.Clientset, name, nodePool string, ready corev1.ConditionStatus) *corev1.Node {
+	node, err := kubeClient.CoreV1().Nodes().Create(context.Background(), &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				ocp.SubmarinerGatewayLabel: "true",
+				hypershift.NodePoolLabel:   nodePool,
+			},
+		},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+	}, metav1.CreateOptions{})
+	Expect(err).To(Succeed())
+
+	return node
+}
+
+type fakePlatform struct {
+	prepared  bool
+	cleanedUp bool
+}
+
+func (p *fakePlatform) PrepareGateways(_ context.Context, _ api.GatewayDeployInput, platform map[string]interface{},
+	_ reporter.Interface,
+) error {
+	p.prepared = true
+
+	return unstructured.SetNestedSlice(platform, []interface{}{map[string]interface{}{"id": "sg-gw"}}, "aws", "securityGroups")
+}
+
+func (p *fakePlatform) PlanPrepareGateways(_ context.Context, _ api.GatewayDeployInput, _ reporter.Interface) (*api.Plan, error) {
+	plan := &api.Plan{}
+	plan.Add(api.ChangeCreate, api.SecurityGroupResource, "gw-sg", "")
+
+	return plan, nil
+}
+
+func (p *fakePlatform) CleanupGateways(_ context.Context, _ reporter.Interface) error {
+	p.cleanedUp = true
+	return nil
+}
+
+func (p *fakePlatform) PlanCleanupGateways(_ context.Context, _ reporter.Interface) (*api.Plan, error) {
+	return &api.Plan{}, nil
+}
+
+func (p *fakePlatform) Validate(_ context.Context, _ reporter.Interface) error {
+	return nil
+}
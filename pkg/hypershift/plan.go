@@ -0,0 +1,105 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hypershift
+
+import (
+	"context"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func (d *nodePoolDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planDeploy(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *nodePoolDeployer) planDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if d.platform != nil {
+		platformPlan, err := d.platform.PlanPrepareGateways(ctx, input, status)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // The platform reports and classifies its errors.
+		}
+
+		plan.Changes = append(plan.Changes, platformPlan.Changes...)
+	}
+
+	status.Start("Retrieving the gateway NodePool %q", d.nodePoolName())
+	defer status.End()
+
+	nodePool, err := d.nodePools().Get(ctx, d.nodePoolName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		status.Success("The gateway NodePool %q doesn't exist", d.nodePoolName())
+		plan.Add(api.ChangeCreate, api.NodePoolResource, d.nodePoolName(), "with %d replica(s)", gatewayCount(input))
+
+		return plan, nil
+	}
+
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the gateway NodePool")
+	}
+
+	status.Success("Retrieved the gateway NodePool %q", d.nodePoolName())
+
+	replicas, _, _ := unstructured.NestedInt64(nodePool.Object, "spec", "replicas")
+	if replicas != gatewayCount(input) {
+		plan.Add(api.ChangeUpdate, api.NodePoolResource, d.nodePoolName(), "scale from %d to %d replica(s)", replicas, gatewayCount(input))
+	}
+
+	return plan, nil
+}
+
+func (d *nodePoolDeployer) PlanCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planCleanup(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *nodePoolDeployer) planCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the gateway NodePool %q", d.nodePoolName())
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	_, err := d.nodePools().Get(ctx, d.nodePoolName(), metav1.GetOptions{})
+	if err == nil {
+		plan.Add(api.ChangeDelete, api.NodePoolResource, d.nodePoolName(), "")
+	} else if !apierrors.IsNotFound(err) {
+		return nil, status.Error(err, "unable to retrieve the gateway NodePool")
+	}
+
+	status.Success("Retrieved the gateway NodePool %q", d.nodePoolName())
+
+	if d.platform != nil {
+		platformPlan, err := d.platform.PlanCleanupGateways(ctx, status)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // The platform reports and classifies its errors.
+		}
+
+		plan.Changes = append(plan.Changes, platformPlan.Changes...)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,107 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hypershift
+
+import (
+	"context"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func (d *nodePoolDeployer) Status(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	statuses, err := d.gatewayStatus(ctx, status)
+
+	return statuses, classifyError(err)
+}
+
+func (d *nodePoolDeployer) gatewayStatus(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	status.Start("Retrieving the Submariner gateways")
+	defer status.End()
+
+	nodePool, err := d.nodePools().Get(ctx, d.nodePoolName(), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, status.Error(err, "error getting the gateway NodePool")
+	}
+
+	gwNodes, err := d.k8sClient.ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway nodes")
+	}
+
+	gateways := []api.GatewayStatus{}
+	provisioned := false
+
+	for i := range gwNodes.Items {
+		node := &gwNodes.Items[i]
+		gateway := api.GatewayStatus{
+			Type:         api.LabeledWorkerGateway,
+			Zone:         node.Labels[corev1.LabelTopologyZone],
+			InstanceType: node.Labels[corev1.LabelInstanceTypeStable],
+			NodeName:     node.Name,
+			PublicIP:     nodePublicIP(node),
+		}
+
+		if node.Labels[NodePoolLabel] == d.nodePoolName() {
+			gateway.Type = api.DedicatedGateway
+			gateway.NodePool = d.nodePoolName()
+			provisioned = true
+		}
+
+		gateways = append(gateways, gateway)
+	}
+
+	if nodePool != nil && !provisioned {
+		gateways = append(gateways, api.GatewayStatus{
+			Type:         api.DedicatedGateway,
+			NodePool:     d.nodePoolName(),
+			InstanceType: nodePoolInstanceType(nodePool),
+		})
+	}
+
+	status.Success("Retrieved %d Submariner gateway(s)", len(gateways))
+
+	return gateways, nil
+}
+
+func nodePublicIP(node *corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeExternalIP {
+			return address.Address
+		}
+	}
+
+	return ""
+}
+
+// nodePoolInstanceType returns the instance type configured in the platform section of the given NodePool, if any.
+func nodePoolInstanceType(nodePool *unstructured.Unstructured) string {
+	for _, path := range [][]string{{"aws", "instanceType"}, {"azure", "vmSize"}} {
+		instanceType, _, _ := unstructured.NestedString(nodePool.Object, append([]string{"spec", "platform"}, path...)...)
+		if instanceType != "" {
+			return instanceType
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,47 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hypershift
+
+import (
+	"context"
+
+	"github.com/submariner-io/admiral/pkg/reporter"
+)
+
+func (d *nodePoolDeployer) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.validate(ctx, status))
+}
+
+func (d *nodePoolDeployer) validate(ctx context.Context, status reporter.Interface) error {
+	status.Start("Retrieving the worker NodePool of hosted cluster %q", d.config.HostedCluster)
+	defer status.End()
+
+	worker, err := d.workerNodePool(ctx)
+	if err != nil {
+		return status.Error(err, "unable to retrieve the worker NodePool")
+	}
+
+	status.Success("Retrieved the worker NodePool %q", worker.GetName())
+
+	if d.platform != nil {
+		return d.platform.Validate(ctx, status) //nolint:wrapcheck // The platform reports and classifies its errors.
+	}
+
+	return nil
+}
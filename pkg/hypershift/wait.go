@@ -0,0 +1,123 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hypershift
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/set"
+)
+
+// waitForGateways waits, for up to the given timeout, for the nodes of the gateway NodePool to be Ready and labelled as
+// gateways. An error is returned as soon as the NodePool reports an invalid configuration.
+func (d *nodePoolDeployer) waitForGateways(ctx context.Context, timeout time.Duration, status reporter.Interface) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	status.Start("Waiting for the nodes of the gateway NodePool %q to be ready", d.nodePoolName())
+	defer status.End()
+
+	reported := set.New[string]()
+	pending := ""
+
+	err := wait.PollUntilContextTimeout(ctx, ocp.GatewayPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		nodePool, err := d.nodePools().Get(ctx, d.nodePoolName(), metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "error retrieving the gateway NodePool")
+		}
+
+		if err = invalidCondition(nodePool); err != nil {
+			return false, err
+		}
+
+		gwNodes, err := d.k8sClient.ListGatewayNodesWithContext(ctx)
+		if err != nil {
+			return false, errors.Wrap(err, "error listing the gateway nodes")
+		}
+
+		ready := 0
+
+		for i := range gwNodes.Items {
+			node := &gwNodes.Items[i]
+			if node.Labels[NodePoolLabel] != d.nodePoolName() || !k8s.IsNodeReady(node) {
+				continue
+			}
+
+			ready++
+
+			if !reported.Has(node.Name) {
+				reported.Insert(node.Name)
+				status.Success("Gateway node %q is Ready", node.Name)
+			}
+		}
+
+		replicas, _, _ := unstructured.NestedInt64(nodePool.Object, "spec", "replicas")
+		pending = fmt.Sprintf("%d of %d gateway node(s) ready", ready, replicas)
+
+		return int64(ready) >= replicas, nil
+	})
+
+	if wait.Interrupted(err) {
+		err = errors.Errorf("timed out after %v waiting for the gateways: %s", timeout, pending)
+	}
+
+	if err != nil {
+		return status.Error(err, "the gateways aren't ready")
+	}
+
+	status.Success("The gateway nodes are ready")
+
+	return nil
+}
+
+// invalidCondition returns an error describing the first Valid* condition of the given NodePool which is false, if any,
+// since the NodePool can't provision its nodes until its configuration is fixed.
+func invalidCondition(nodePool *unstructured.Unstructured) error {
+	conditions, _, _ := unstructured.NestedSlice(nodePool.Object, "status", "conditions")
+
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		conditionStatus, _, _ := unstructured.NestedString(condition, "status")
+
+		if strings.HasPrefix(conditionType, "Valid") && conditionStatus == string(metav1.ConditionFalse) {
+			reason, _, _ := unstructured.NestedString(condition, "reason")
+			message, _, _ := unstructured.NestedString(condition, "message")
+
+			return errors.Errorf("gateway NodePool %q reports %s=False: %s: %s", nodePool.GetName(), conditionType, reason, message)
+		}
+	}
+
+	return nil
+}
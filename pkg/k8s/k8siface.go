@@ -26,14 +26,31 @@ import (
 	"github.com/submariner-io/admiral/pkg/resource"
 	"github.com/submariner-io/admiral/pkg/util"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 const (
 	SubmarinerGatewayLabel = "submariner.io/gateway"
+	activeHAStatus         = "active"
 )
 
+// GatewayGVR identifies the Submariner Gateway resources, whose status reports which gateway is active.
+var GatewayGVR = schema.GroupVersionResource{
+	Group:    "submariner.io",
+	Version:  "v1",
+	Resource: "gateways",
+}
+
+// ErrActiveGatewayUnknown is returned by GetActiveGatewayNode when the active gateway can't be determined, either because
+// no dynamic client was provided, because the interface was converted by WithContext, or because Submariner runs gateways
+// but none of them is active. The callers can't then tell which gateways are safe to remove.
+var ErrActiveGatewayUnknown = errors.New("the active gateway can't be determined")
+
 type Interface interface {
 	ListNodesWithLabel(labelSelector string) (*v1.NodeList, error)
 	ListGatewayNodes() (*v1.NodeList, error)
@@ -42,16 +59,100 @@ type Interface interface {
 	RemoveGWLabelFromWorkerNode(node *v1.Node) error
 }
 
+// ContextInterface extends Interface with the operations bound to a context. The Interface returned by NewInterface
+// implements it; it's a separate interface so that the existing implementations of Interface keep compiling. Callers
+// given an Interface convert it using WithContext.
+type ContextInterface interface {
+	Interface
+	ListNodesWithLabelWithContext(ctx context.Context, labelSelector string) (*v1.NodeList, error)
+	ListGatewayNodesWithContext(ctx context.Context) (*v1.NodeList, error)
+	AddGWLabelOnNodeWithContext(ctx context.Context, nodeName string) error
+	RemoveGWLabelFromWorkerNodesWithContext(ctx context.Context) error
+	RemoveGWLabelFromWorkerNodeWithContext(ctx context.Context, node *v1.Node) error
+	// GetActiveGatewayNode returns the name of the node running the active Submariner gateway, as reported by the
+	// Submariner Gateway resources, or an empty string if Submariner runs no gateway. It returns an error wrapping
+	// ErrActiveGatewayUnknown if the active gateway can't be determined.
+	GetActiveGatewayNode() (string, error)
+	GetActiveGatewayNodeWithContext(ctx context.Context) (string, error)
+}
+
+// WithContext returns the given interface as a ContextInterface, or nil if it's nil. If it doesn't implement the operations
+// bound to a context, they call the basic operations instead, ignoring the context, and the active gateway is unknown.
+func WithContext(k Interface) ContextInterface {
+	if k == nil {
+		return nil
+	}
+
+	if contextIface, ok := k.(ContextInterface); ok {
+		return contextIface
+	}
+
+	return &contextFreeIface{Interface: k}
+}
+
+type contextFreeIface struct {
+	Interface
+}
+
+func (k *contextFreeIface) ListNodesWithLabelWithContext(_ context.Context, labelSelector string) (*v1.NodeList, error) {
+	return k.ListNodesWithLabel(labelSelector) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (k *contextFreeIface) ListGatewayNodesWithContext(_ context.Context) (*v1.NodeList, error) {
+	return k.ListGatewayNodes() //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (k *contextFreeIface) AddGWLabelOnNodeWithContext(_ context.Context, nodeName string) error {
+	return k.AddGWLabelOnNode(nodeName) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (k *contextFreeIface) RemoveGWLabelFromWorkerNodesWithContext(_ context.Context) error {
+	return k.RemoveGWLabelFromWorkerNodes() //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (k *contextFreeIface) RemoveGWLabelFromWorkerNodeWithContext(_ context.Context, node *v1.Node) error {
+	return k.RemoveGWLabelFromWorkerNode(node) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (k *contextFreeIface) GetActiveGatewayNode() (string, error) {
+	return k.GetActiveGatewayNodeWithContext(context.TODO())
+}
+
+func (k *contextFreeIface) GetActiveGatewayNodeWithContext(_ context.Context) (string, error) {
+	return "", errors.WithMessage(ErrActiveGatewayUnknown, "the Kubernetes interface doesn't retrieve the active gateway")
+}
+
+type Option func(*k8sIface)
+
+// WithDynamicClient sets the dynamic client used to access the Submariner resources, which is required to retrieve
+// the active gateway.
+func WithDynamicClient(client dynamic.Interface) Option {
+	return func(k *k8sIface) {
+		k.dynamicClient = client
+	}
+}
+
 type k8sIface struct {
-	clientSet kubernetes.Interface
+	clientSet     kubernetes.Interface
+	dynamicClient dynamic.Interface
 }
 
-func NewInterface(clientSet kubernetes.Interface) Interface {
-	return &k8sIface{clientSet: clientSet}
+func NewInterface(clientSet kubernetes.Interface, opts ...Option) Interface {
+	k := &k8sIface{clientSet: clientSet}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	return k
 }
 
 func (k *k8sIface) ListNodesWithLabel(labelSelector string) (*v1.NodeList, error) {
-	nodes, err := k.clientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector})
+	return k.ListNodesWithLabelWithContext(context.TODO(), labelSelector)
+}
+
+func (k *k8sIface) ListNodesWithLabelWithContext(ctx context.Context, labelSelector string) (*v1.NodeList, error) {
+	nodes, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to list the nodes in the cluster")
 	}
@@ -60,9 +161,13 @@ func (k *k8sIface) ListNodesWithLabel(labelSelector string) (*v1.NodeList, error
 }
 
 func (k *k8sIface) ListGatewayNodes() (*v1.NodeList, error) {
+	return k.ListGatewayNodesWithContext(context.TODO())
+}
+
+func (k *k8sIface) ListGatewayNodesWithContext(ctx context.Context) (*v1.NodeList, error) {
 	labelSelector := SubmarinerGatewayLabel + "=true"
 
-	nodes, err := k.clientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector})
+	nodes, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to list the Gateway nodes in the cluster")
 	}
@@ -70,7 +175,7 @@ func (k *k8sIface) ListGatewayNodes() (*v1.NodeList, error) {
 	return nodes, nil
 }
 
-func (k *k8sIface) updateLabel(nodeName string, mutate func(existing *v1.Node)) error {
+func (k *k8sIface) updateLabel(ctx context.Context, nodeName string, mutate func(existing *v1.Node)) error {
 	client := &resource.InterfaceFuncs[*v1.Node]{
 		GetFunc: func(ctx context.Context, name string, options metav1.GetOptions) (*v1.Node, error) {
 			return k.clientSet.CoreV1().Nodes().Get(ctx, name, options)
@@ -80,7 +185,7 @@ func (k *k8sIface) updateLabel(nodeName string, mutate func(existing *v1.Node))
 		},
 	}
 
-	return errors.Wrap(util.Update[*v1.Node](context.TODO(), client, &v1.Node{
+	return errors.Wrap(util.Update[*v1.Node](ctx, client, &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: nodeName,
 		},
@@ -91,7 +196,11 @@ func (k *k8sIface) updateLabel(nodeName string, mutate func(existing *v1.Node))
 }
 
 func (k *k8sIface) AddGWLabelOnNode(nodeName string) error {
-	return k.updateLabel(nodeName, func(existing *v1.Node) {
+	return k.AddGWLabelOnNodeWithContext(context.TODO(), nodeName)
+}
+
+func (k *k8sIface) AddGWLabelOnNodeWithContext(ctx context.Context, nodeName string) error {
+	return k.updateLabel(ctx, nodeName, func(existing *v1.Node) {
 		labels := existing.GetLabels()
 		if labels == nil {
 			labels = map[string]string{}
@@ -103,14 +212,18 @@ func (k *k8sIface) AddGWLabelOnNode(nodeName string) error {
 }
 
 func (k *k8sIface) RemoveGWLabelFromWorkerNodes() error {
-	gwNodeList, err := k.clientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: SubmarinerGatewayLabel})
+	return k.RemoveGWLabelFromWorkerNodesWithContext(context.TODO())
+}
+
+func (k *k8sIface) RemoveGWLabelFromWorkerNodesWithContext(ctx context.Context) error {
+	gwNodeList, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: SubmarinerGatewayLabel})
 	if err != nil {
 		return errors.Wrap(err, "error listing submariner gateway nodes")
 	}
 
 	gwNodes := gwNodeList.Items
 	for i := range gwNodes {
-		err = k.RemoveGWLabelFromWorkerNode(&gwNodes[i])
+		err = k.RemoveGWLabelFromWorkerNodeWithContext(ctx, &gwNodes[i])
 		if err != nil {
 			return errors.Wrap(err, fmt.Sprintf("error removing the label from the gateway node %q", gwNodes[i].Name))
 		}
@@ -120,7 +233,64 @@ func (k *k8sIface) RemoveGWLabelFromWorkerNodes() error {
 }
 
 func (k *k8sIface) RemoveGWLabelFromWorkerNode(node *v1.Node) error {
-	return k.updateLabel(node.Name, func(existing *v1.Node) {
+	return k.RemoveGWLabelFromWorkerNodeWithContext(context.TODO(), node)
+}
+
+func (k *k8sIface) RemoveGWLabelFromWorkerNodeWithContext(ctx context.Context, node *v1.Node) error {
+	return k.updateLabel(ctx, node.Name, func(existing *v1.Node) {
 		delete(existing.Labels, SubmarinerGatewayLabel)
 	})
 }
+
+func (k *k8sIface) GetActiveGatewayNode() (string, error) {
+	return k.GetActiveGatewayNodeWithContext(context.TODO())
+}
+
+func (k *k8sIface) GetActiveGatewayNodeWithContext(ctx context.Context) (string, error) {
+	if k.dynamicClient == nil {
+		return "", errors.WithMessage(ErrActiveGatewayUnknown, "a dynamic client is required to retrieve the active gateway")
+	}
+
+	gateways, err := k.dynamicClient.Resource(GatewayGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		// Submariner isn't installed so there's no active gateway.
+		return "", nil
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, "unable to list the Submariner gateways")
+	}
+
+	for i := range gateways.Items {
+		haStatus, _, _ := unstructured.NestedString(gateways.Items[i].Object, "status", "haStatus")
+		if haStatus != activeHAStatus {
+			continue
+		}
+
+		// The Gateway resources are named after their node, but prefer the node name from the local endpoint if available.
+		hostname, _, _ := unstructured.NestedString(gateways.Items[i].Object, "status", "localEndpoint", "hostname")
+		if hostname == "" {
+			hostname = gateways.Items[i].GetName()
+		}
+
+		return hostname, nil
+	}
+
+	if len(gateways.Items) > 0 {
+		// The gateways may be failing over, any of them may become active.
+		return "", errors.WithMessage(ErrActiveGatewayUnknown, "none of the Submariner gateways is active")
+	}
+
+	return "", nil
+}
+
+// IsNodeReady returns whether the given node reports the Ready condition.
+func IsNodeReady(node *v1.Node) bool {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == v1.NodeReady {
+			return node.Status.Conditions[i].Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -28,6 +28,10 @@ import (
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicFake "k8s.io/client-go/dynamic/fake"
 	kubeFake "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -36,8 +40,115 @@ var _ = Describe("Interface", func() {
 	Describe("ListGatewayNodes", testListGatewayNodes)
 	Describe("AddGWLabelOnNode", testAddGWLabelOnNode)
 	Describe("RemoveGWLabelFromWorkerNodes", testRemoveGWLabelFromWorkerNodes)
+	Describe("GetActiveGatewayNode", testGetActiveGatewayNode)
+	Describe("WithContext", testWithContext)
 })
 
+func testWithContext() {
+	t := newInterfaceTestDriver()
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{
+			newNode("node-1", map[string]string{k8s.SubmarinerGatewayLabel: "true"}),
+			newNode("node-2", nil),
+		}
+	})
+
+	When("the interface implements the operations bound to a context", func() {
+		It("should return it", func() {
+			Expect(k8s.WithContext(t.client)).To(BeIdenticalTo(t.client))
+		})
+	})
+
+	When("the interface only implements Interface", func() {
+		It("should call its basic operations", func() {
+			client := k8s.WithContext(struct{ k8s.Interface }{t.client})
+
+			list, err := client.ListGatewayNodesWithContext(context.TODO())
+			Expect(err).To(Succeed())
+			assertNodeNames(list, "node-1")
+
+			Expect(client.AddGWLabelOnNodeWithContext(context.TODO(), "node-2")).To(Succeed())
+			t.assertLabel("node-2", k8s.SubmarinerGatewayLabel, "true")
+
+			_, err = client.GetActiveGatewayNodeWithContext(context.TODO())
+			Expect(err).To(MatchError(k8s.ErrActiveGatewayUnknown))
+		})
+	})
+
+	When("the interface is nil", func() {
+		It("should return nil", func() {
+			Expect(k8s.WithContext(nil)).To(BeNil())
+		})
+	})
+}
+
+func testGetActiveGatewayNode() {
+	t := newInterfaceTestDriver()
+
+	When("a gateway is active", func() {
+		BeforeEach(func() {
+			t.gateways = []*unstructured.Unstructured{
+				newGateway("node-1", "passive", ""),
+				newGateway("node-2", "active", "node-2.example.com"),
+			}
+		})
+
+		It("should return the node from its local endpoint", func() {
+			Expect(k8s.WithContext(t.client).GetActiveGatewayNode()).To(Equal("node-2.example.com"))
+		})
+	})
+
+	When("the active gateway has no local endpoint", func() {
+		BeforeEach(func() {
+			t.gateways = []*unstructured.Unstructured{newGateway("node-1", "active", "")}
+		})
+
+		It("should return the node it is named after", func() {
+			Expect(k8s.WithContext(t.client).GetActiveGatewayNode()).To(Equal("node-1"))
+		})
+	})
+
+	When("no gateway is active", func() {
+		BeforeEach(func() {
+			t.gateways = []*unstructured.Unstructured{newGateway("node-1", "passive", "")}
+		})
+
+		It("should return ErrActiveGatewayUnknown", func() {
+			_, err := k8s.WithContext(t.client).GetActiveGatewayNode()
+			Expect(err).To(MatchError(k8s.ErrActiveGatewayUnknown))
+		})
+	})
+
+	When("there are no gateways", func() {
+		It("should return an empty node name", func() {
+			Expect(k8s.WithContext(t.client).GetActiveGatewayNode()).To(BeEmpty())
+		})
+	})
+
+	When("no dynamic client is provided", func() {
+		BeforeEach(func() {
+			t.noDynamicClient = true
+		})
+
+		It("should return ErrActiveGatewayUnknown", func() {
+			_, err := k8s.WithContext(t.client).GetActiveGatewayNode()
+			Expect(err).To(MatchError(k8s.ErrActiveGatewayUnknown))
+		})
+	})
+
+	Context("on failure", func() {
+		JustBeforeEach(func() {
+			fake.NewFailingReactorForResource(&t.dynamicClient.Fake, "gateways").SetFailOnList(errors.New("fake error"))
+		})
+
+		It("should return an error", func() {
+			_, err := k8s.WithContext(t.client).GetActiveGatewayNode()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
+
 func testRemoveGWLabelFromWorkerNodes() {
 	t := newInterfaceTestDriver()
 
@@ -205,9 +316,12 @@ func testListNodesWithLabel() {
 }
 
 type interfaceTestDriver struct {
-	kubeClient *kubeFake.Clientset
-	nodes      []*corev1.Node
-	client     k8s.Interface
+	kubeClient      *kubeFake.Clientset
+	dynamicClient   *dynamicFake.FakeDynamicClient
+	noDynamicClient bool
+	nodes           []*corev1.Node
+	gateways        []*unstructured.Unstructured
+	client          k8s.Interface
 }
 
 func newInterfaceTestDriver() *interfaceTestDriver {
@@ -215,6 +329,8 @@ func newInterfaceTestDriver() *interfaceTestDriver {
 
 	BeforeEach(func() {
 		t.kubeClient = kubeFake.NewClientset()
+		t.noDynamicClient = false
+		t.gateways = nil
 	})
 
 	JustBeforeEach(func() {
@@ -225,7 +341,20 @@ func newInterfaceTestDriver() *interfaceTestDriver {
 
 		t.kubeClient.ClearActions()
 
-		t.client = k8s.NewInterface(t.kubeClient)
+		t.dynamicClient = dynamicFake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{k8s.GatewayGVR: "GatewayList"})
+
+		for _, gateway := range t.gateways {
+			_, err := t.dynamicClient.Resource(k8s.GatewayGVR).Namespace(gateway.GetNamespace()).Create(context.TODO(), gateway,
+				metav1.CreateOptions{})
+			Expect(err).To(Succeed())
+		}
+
+		if t.noDynamicClient {
+			t.client = k8s.NewInterface(t.kubeClient)
+		} else {
+			t.client = k8s.NewInterface(t.kubeClient, k8s.WithDynamicClient(t.dynamicClient))
+		}
 	})
 
 	return t
@@ -275,3 +404,19 @@ func newNode(name string, labels map[string]string) *corev1.Node {
 		},
 	}
 }
+
+func newGateway(name, haStatus, hostname string) *unstructured.Unstructured {
+	gateway := &unstructured.Unstructured{}
+	gateway.SetAPIVersion("submariner.io/v1")
+	gateway.SetKind("Gateway")
+	gateway.SetName(name)
+	gateway.SetNamespace("submariner-operator")
+
+	Expect(unstructured.SetNestedField(gateway.Object, haStatus, "status", "haStatus")).To(Succeed())
+
+	if hostname != "" {
+		Expect(unstructured.SetNestedField(gateway.Object, hostname, "status", "localEndpoint", "hostname")).To(Succeed())
+	}
+
+	return gateway
+}
@@ -0,0 +1,203 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ledger records the cloud and cluster objects created or modified by cloud-prepare, so that they can be
+// cleaned up exactly, instead of being rediscovered by naming conventions.
+package ledger
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// Operation is the API operation an entry was recorded by.
+type Operation string
+
+const (
+	// OpenPorts entries are recorded by Cloud.OpenPorts and undone by Cloud.ClosePorts.
+	OpenPorts Operation = "OpenPorts"
+
+	// Deploy entries are recorded by GatewayDeployer.Deploy and undone by GatewayDeployer.Cleanup.
+	Deploy Operation = "Deploy"
+)
+
+// Entry records a single object created, or modified, by cloud-prepare.
+type Entry struct {
+	Provider  string           `json:"provider"`
+	Operation Operation        `json:"operation"`
+	Resource  api.ResourceType `json:"resource"`
+
+	// ID identifies the object, by its cloud ID if it has one, or else by its name.
+	ID string `json:"id"`
+
+	// Parent identifies the object the recorded one belongs to, or was attached to, for example the security group of a rule.
+	Parent string `json:"parent,omitempty"`
+
+	// Data holds the provider-specific information needed to undo the change, which the ID doesn't capture.
+	Data map[string]string `json:"data,omitempty"`
+}
+
+func (e *Entry) sameObject(o Entry) bool {
+	return e.Provider == o.Provider && e.Operation == o.Operation && e.Resource == o.Resource && e.ID == o.ID && e.Parent == o.Parent
+}
+
+// Store persists the ledger entries.
+type Store interface {
+	// Load returns the stored entries, or none if nothing was stored yet.
+	Load(ctx context.Context) ([]Entry, error)
+
+	// Save replaces the stored entries.
+	Save(ctx context.Context, entries []Entry) error
+}
+
+// Ledger records the objects created or modified by cloud-prepare in a Store. A nil Ledger records nothing, so that
+// providers can record unconditionally.
+type Ledger struct {
+	mutex sync.Mutex
+	store Store
+}
+
+// New returns a Ledger persisting its entries in the given store.
+func New(store Store) *Ledger {
+	return &Ledger{store: store}
+}
+
+// Record adds the given entries, unless the same objects are already recorded, in which case their data is updated.
+func (l *Ledger) Record(ctx context.Context, entries ...Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	existing, err := l.store.Load(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "error loading the ledger")
+	}
+
+	for i := range entries {
+		index := slices.IndexFunc(existing, entries[i].sameObject)
+		if index >= 0 {
+			existing[index] = entries[i]
+		} else {
+			existing = append(existing, entries[i])
+		}
+	}
+
+	return errors.WithMessage(l.store.Save(ctx, existing), "error saving the ledger")
+}
+
+// Entries returns the entries recorded by the given provider and operation, most recent first, which is the order
+// they should be undone in.
+func (l *Ledger) Entries(ctx context.Context, provider string, operation Operation) ([]Entry, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	all, err := l.store.Load(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error loading the ledger")
+	}
+
+	var entries []Entry
+
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].Provider == provider && all[i].Operation == operation {
+			entries = append(entries, all[i])
+		}
+	}
+
+	return entries, nil
+}
+
+// Recorded returns whether any entry was recorded by the given provider and operation. Providers use it to fall back to
+// looking up the objects by name when nothing was recorded, for instance because the objects were created before the
+// ledger was configured.
+func (l *Ledger) Recorded(ctx context.Context, provider string, operation Operation) (bool, error) {
+	entries, err := l.Entries(ctx, provider, operation)
+
+	return len(entries) > 0, err
+}
+
+// Forget removes the given entries, typically once they've been undone.
+func (l *Ledger) Forget(ctx context.Context, entries ...Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	existing, err := l.store.Load(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "error loading the ledger")
+	}
+
+	existing = slices.DeleteFunc(existing, func(e Entry) bool {
+		return slices.ContainsFunc(entries, e.sameObject)
+	})
+
+	return errors.WithMessage(l.store.Save(ctx, existing), "error saving the ledger")
+}
+
+// Undo calls the given function on each entry recorded by the given provider and operation, most recent first,
+// forgetting each entry once it's undone. It stops at the first error, leaving the remaining entries recorded.
+func (l *Ledger) Undo(ctx context.Context, provider string, operation Operation, undo func(ctx context.Context, entry *Entry) error,
+) error {
+	entries, err := l.Entries(ctx, provider, operation)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		if err := undo(ctx, &entries[i]); err != nil {
+			return errors.WithMessagef(err, "error undoing %s %q", entries[i].Resource, entries[i].ID)
+		}
+
+		if err := l.Forget(ctx, entries[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PlanUndo adds to the given plan the change undoing each entry recorded by the given provider and operation, in the
+// order Undo undoes them, as described by the given function. It returns whether any entry was recorded, so that
+// providers plan the same lookups by name as they perform when nothing was recorded.
+func (l *Ledger) PlanUndo(ctx context.Context, plan *api.Plan, provider string, operation Operation, change func(entry *Entry) api.Change,
+) (bool, error) {
+	entries, err := l.Entries(ctx, provider, operation)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range entries {
+		plan.Changes = append(plan.Changes, change(&entries[i]))
+	}
+
+	return len(entries) > 0, nil
+}
@@ -0,0 +1,165 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	namespace     = "submariner-operator"
+	configMapName = "cloud-prepare-ledger"
+)
+
+var _ = Describe("Ledger", func() {
+	var (
+		l *ledger.Ledger
+		r *ledger.Ledger
+	)
+
+	rule := ledger.Entry{Provider: "aws", Operation: ledger.OpenPorts, Resource: api.SecurityGroupRuleResource, ID: "rule", Parent: "sg-1"}
+	group := ledger.Entry{Provider: "aws", Operation: ledger.Deploy, Resource: api.SecurityGroupResource, ID: "sg-2"}
+	machineSet := ledger.Entry{Provider: "aws", Operation: ledger.Deploy, Resource: api.MachineSetResource, ID: "ms", Parent: namespace}
+	other := ledger.Entry{Provider: "gcp", Operation: ledger.Deploy, Resource: api.FirewallRuleResource, ID: "fw"}
+
+	testStore := func(newStore func() ledger.Store) {
+		BeforeEach(func() {
+			store := newStore()
+			l = ledger.New(store)
+			r = ledger.New(store)
+
+			Expect(l.Record(context.TODO(), rule, group, other)).To(Succeed())
+			Expect(l.Record(context.TODO(), machineSet)).To(Succeed())
+		})
+
+		It("should return the entries of a provider and operation, most recent first", func() {
+			Expect(r.Entries(context.TODO(), "aws", ledger.Deploy)).To(Equal([]ledger.Entry{machineSet, group}))
+			Expect(r.Entries(context.TODO(), "aws", ledger.OpenPorts)).To(Equal([]ledger.Entry{rule}))
+			Expect(r.Entries(context.TODO(), "gcp", ledger.Deploy)).To(Equal([]ledger.Entry{other}))
+		})
+
+		It("should report whether a provider and operation recorded entries", func() {
+			Expect(r.Recorded(context.TODO(), "aws", ledger.OpenPorts)).To(BeTrue())
+			Expect(r.Recorded(context.TODO(), "gcp", ledger.OpenPorts)).To(BeFalse())
+		})
+
+		It("should not duplicate an entry recorded again", func() {
+			updated := group
+			updated.Data = map[string]string{"name": "gateway"}
+
+			Expect(l.Record(context.TODO(), updated)).To(Succeed())
+			Expect(r.Entries(context.TODO(), "aws", ledger.Deploy)).To(Equal([]ledger.Entry{machineSet, updated}))
+		})
+
+		It("should forget entries", func() {
+			Expect(l.Forget(context.TODO(), group, rule)).To(Succeed())
+			Expect(r.Entries(context.TODO(), "aws", ledger.Deploy)).To(Equal([]ledger.Entry{machineSet}))
+			Expect(r.Entries(context.TODO(), "aws", ledger.OpenPorts)).To(BeEmpty())
+		})
+
+		Context("on Undo", func() {
+			It("should undo the entries, most recent first, and forget them", func() {
+				var undone []ledger.Entry
+
+				Expect(l.Undo(context.TODO(), "aws", ledger.Deploy, func(_ context.Context, entry *ledger.Entry) error {
+					undone = append(undone, *entry)
+					return nil
+				})).To(Succeed())
+
+				Expect(undone).To(Equal([]ledger.Entry{machineSet, group}))
+				Expect(r.Entries(context.TODO(), "aws", ledger.Deploy)).To(BeEmpty())
+				Expect(r.Entries(context.TODO(), "gcp", ledger.Deploy)).To(HaveLen(1))
+			})
+
+			It("should stop at the first failure and keep the remaining entries", func() {
+				Expect(l.Undo(context.TODO(), "aws", ledger.Deploy, func(_ context.Context, entry *ledger.Entry) error {
+					if entry.Resource == api.SecurityGroupResource {
+						return errors.New("fake error")
+					}
+
+					return nil
+				})).ToNot(Succeed())
+
+				Expect(r.Entries(context.TODO(), "aws", ledger.Deploy)).To(Equal([]ledger.Entry{group}))
+			})
+		})
+
+		Context("on PlanUndo", func() {
+			deleteChange := func(entry *ledger.Entry) api.Change {
+				return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID}
+			}
+
+			It("should plan the undo of the entries, most recent first, and keep them", func() {
+				plan := &api.Plan{}
+
+				Expect(r.PlanUndo(context.TODO(), plan, "aws", ledger.Deploy, deleteChange)).To(BeTrue())
+				Expect(plan.Changes).To(Equal([]api.Change{
+					{Action: api.ChangeDelete, Resource: api.MachineSetResource, Name: "ms"},
+					{Action: api.ChangeDelete, Resource: api.SecurityGroupResource, Name: "sg-2"},
+				}))
+				Expect(r.Entries(context.TODO(), "aws", ledger.Deploy)).To(HaveLen(2))
+			})
+
+			It("should report when nothing was recorded", func() {
+				plan := &api.Plan{}
+
+				Expect(r.PlanUndo(context.TODO(), plan, "gcp", ledger.OpenPorts, deleteChange)).To(BeFalse())
+				Expect(plan.IsEmpty()).To(BeTrue())
+			})
+		})
+	}
+
+	Context("with a file store", func() {
+		testStore(func() ledger.Store {
+			return ledger.NewFileStore(filepath.Join(GinkgoT().TempDir(), "ledger.json"))
+		})
+	})
+
+	Context("with a ConfigMap store", func() {
+		testStore(func() ledger.Store {
+			return ledger.NewConfigMapStore(kubeFake.NewClientset(), namespace, configMapName)
+		})
+	})
+
+	When("the ledger is nil", func() {
+		It("should record nothing", func() {
+			var nilLedger *ledger.Ledger
+
+			Expect(nilLedger.Record(context.TODO(), rule)).To(Succeed())
+			Expect(nilLedger.Entries(context.TODO(), "aws", ledger.OpenPorts)).To(BeEmpty())
+			Expect(nilLedger.Recorded(context.TODO(), "aws", ledger.OpenPorts)).To(BeFalse())
+			Expect(nilLedger.Forget(context.TODO(), rule)).To(Succeed())
+		})
+	})
+
+	When("the file doesn't exist yet", func() {
+		It("should have no entries", func() {
+			l := ledger.New(ledger.NewFileStore(filepath.Join(GinkgoT().TempDir(), "missing.json")))
+			Expect(l.Entries(context.TODO(), "aws", ledger.Deploy)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,142 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigMapKey is the key of the ConfigMap data holding the entries, in JSON.
+const ConfigMapKey = "ledger.json"
+
+type fileStore struct {
+	path string
+}
+
+// NewFileStore returns a Store keeping the entries, in JSON, in the local file with the given path.
+func NewFileStore(path string) Store {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Load(_ context.Context) ([]Entry, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the ledger file %q", s.path)
+	}
+
+	return unmarshal(data)
+}
+
+func (s *fileStore) Save(_ context.Context, entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the ledger entries")
+	}
+
+	return errors.Wrapf(os.WriteFile(s.path, data, 0o600), "error writing the ledger file %q", s.path)
+}
+
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapStore returns a Store keeping the entries in the cluster ConfigMap with the given namespace and name,
+// which is created as needed.
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) Store {
+	return &configMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s *configMapStore) Load(ctx context.Context) ([]Entry, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving the ledger ConfigMap %s/%s", s.namespace, s.name)
+	}
+
+	return unmarshal([]byte(configMap.Data[ConfigMapKey]))
+}
+
+func (s *configMapStore) Save(ctx context.Context, entries []Entry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the ledger entries")
+	}
+
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+
+	configMap, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{ConfigMapKey: string(data)},
+		}, metav1.CreateOptions{})
+
+		return errors.Wrapf(err, "error creating the ledger ConfigMap %s/%s", s.namespace, s.name)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving the ledger ConfigMap %s/%s", s.namespace, s.name)
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+
+	configMap.Data[ConfigMapKey] = string(data)
+
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+
+	return errors.Wrapf(err, "error updating the ledger ConfigMap %s/%s", s.namespace, s.name)
+}
+
+func unmarshal(data []byte) ([]Entry, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var entries []Entry
+
+	err := json.Unmarshal(data, &entries)
+
+	return entries, errors.Wrap(err, "error unmarshaling the ledger entries")
+}
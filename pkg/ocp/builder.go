@@ -0,0 +1,152 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	MachineAPINamespace = "openshift-machine-api"
+	GatewayTaintKey     = "node-role.submariner.io/gateway"
+
+	clusterLabel     = "machine.openshift.io/cluster-api-cluster"
+	machineRoleLabel = "machine.openshift.io/cluster-api-machine-role"
+	machineTypeLabel = "machine.openshift.io/cluster-api-machine-type"
+	machineSetLabel  = "machine.openshift.io/cluster-api-machineset"
+	infraRoleLabel   = "node-role.kubernetes.io/infra"
+)
+
+// MachineSet is a machine.openshift.io/v1beta1 MachineSet. Only the fields used for the gateways are modelled, so that
+// the OpenShift API types aren't needed; the providerSpec value is one of the provider specific types below.
+type MachineSet struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Metadata   ObjectMeta     `json:"metadata"`
+	Spec       MachineSetSpec `json:"spec"`
+}
+
+// ObjectMeta is the subset of the Kubernetes object metadata which can be set on a new MachineSet.
+type ObjectMeta struct {
+	Labels    map[string]string `json:"labels,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+}
+
+type MachineSetSpec struct {
+	Replicas int32                `json:"replicas"`
+	Selector metav1.LabelSelector `json:"selector"`
+	Template MachineTemplateSpec  `json:"template"`
+}
+
+type MachineTemplateSpec struct {
+	Metadata ObjectMeta  `json:"metadata"`
+	Spec     MachineSpec `json:"spec"`
+}
+
+type MachineSpec struct {
+	// Metadata is propagated to the nodes created by the machines.
+	Metadata     ObjectMeta     `json:"metadata"`
+	Taints       []corev1.Taint `json:"taints,omitempty"`
+	ProviderSpec ProviderSpec   `json:"providerSpec"`
+}
+
+type ProviderSpec struct {
+	Value interface{} `json:"value"`
+}
+
+// NewGatewayMachineSet returns a MachineSet with the given name which deploys a single gateway node in the cluster
+// identified by infraID, using the given provider specific configuration. The gateway nodes are labelled as infra
+// nodes and as Submariner gateways, and tainted so that only the gateway pods are scheduled on them.
+func NewGatewayMachineSet(infraID, name string, providerSpec interface{}) *MachineSet {
+	return &MachineSet{
+		APIVersion: "machine.openshift.io/v1beta1",
+		Kind:       "MachineSet",
+		Metadata: ObjectMeta{
+			Labels: map[string]string{
+				clusterLabel: infraID,
+			},
+			Name:      name,
+			Namespace: MachineAPINamespace,
+		},
+		Spec: MachineSetSpec{
+			Replicas: 1,
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					clusterLabel:    infraID,
+					machineSetLabel: name,
+				},
+			},
+			Template: MachineTemplateSpec{
+				Metadata: ObjectMeta{
+					Labels: map[string]string{
+						clusterLabel:     infraID,
+						machineRoleLabel: "worker",
+						machineTypeLabel: "worker",
+						machineSetLabel:  name,
+					},
+				},
+				Spec: MachineSpec{
+					Metadata: ObjectMeta{
+						Labels: map[string]string{
+							SubmarinerGatewayLabel: "true",
+							infraRoleLabel:         "",
+						},
+					},
+					Taints: []corev1.Taint{{
+						Key:    GatewayTaintKey,
+						Effect: corev1.TaintEffectNoSchedule,
+					}},
+					ProviderSpec: ProviderSpec{Value: providerSpec},
+				},
+			},
+		},
+	}
+}
+
+// WithWorkerRoleLabels adds the worker machine role and type labels to the MachineSet itself, in addition to its
+// machines.
+func (ms *MachineSet) WithWorkerRoleLabels() *MachineSet {
+	ms.Metadata.Labels[machineRoleLabel] = "worker"
+	ms.Metadata.Labels[machineTypeLabel] = "worker"
+
+	return ms
+}
+
+// ToUnstructured converts the MachineSet to the unstructured form used by the MachineSetDeployer.
+func (ms *MachineSet) ToUnstructured() (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(ms)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling the machine set")
+	}
+
+	machineSet := &unstructured.Unstructured{}
+
+	err = machineSet.UnmarshalJSON(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "error converting the machine set")
+	}
+
+	return machineSet, nil
+}
@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Gateway MachineSet builder", func() {
+	var machineSet *ocp.MachineSet
+
+	BeforeEach(func() {
+		providerSpec := ocp.NewAWSMachineProviderConfig()
+		providerSpec.InstanceType = "c5d.large"
+		providerSpec.PublicIP = true
+
+		machineSet = ocp.NewGatewayMachineSet("test-infra", "test-infra-submariner-gw-a", providerSpec)
+	})
+
+	It("should convert the MachineSet to its unstructured form", func() {
+		result, err := machineSet.ToUnstructured()
+		Expect(err).To(Succeed())
+
+		Expect(result.GetAPIVersion()).To(Equal("machine.openshift.io/v1beta1"))
+		Expect(result.GetKind()).To(Equal("MachineSet"))
+		Expect(result.GetName()).To(Equal("test-infra-submariner-gw-a"))
+		Expect(result.GetNamespace()).To(Equal(ocp.MachineAPINamespace))
+		Expect(result.GetLabels()).To(Equal(map[string]string{"machine.openshift.io/cluster-api-cluster": "test-infra"}))
+
+		replicas, _, _ := unstructured.NestedInt64(result.Object, "spec", "replicas")
+		Expect(replicas).To(Equal(int64(1)))
+
+		nodeLabels, _, _ := unstructured.NestedStringMap(result.Object, "spec", "template", "spec", "metadata", "labels")
+		Expect(nodeLabels).To(HaveKeyWithValue(ocp.SubmarinerGatewayLabel, "true"))
+
+		taints, _, _ := unstructured.NestedSlice(result.Object, "spec", "template", "spec", "taints")
+		Expect(taints).To(Equal([]interface{}{map[string]interface{}{"key": ocp.GatewayTaintKey, "effect": "NoSchedule"}}))
+
+		providerSpec, _, _ := unstructured.NestedMap(result.Object, "spec", "template", "spec", "providerSpec", "value")
+		Expect(providerSpec).To(HaveKeyWithValue("kind", "AWSMachineProviderConfig"))
+		Expect(providerSpec).To(HaveKeyWithValue("instanceType", "c5d.large"))
+		Expect(providerSpec).To(HaveKeyWithValue("publicIp", true))
+	})
+
+	When("the worker role labels are requested", func() {
+		It("should add them to the MachineSet", func() {
+			result, err := machineSet.WithWorkerRoleLabels().ToUnstructured()
+			Expect(err).To(Succeed())
+			Expect(result.GetLabels()).To(Equal(map[string]string{
+				"machine.openshift.io/cluster-api-cluster":      "test-infra",
+				"machine.openshift.io/cluster-api-machine-role": "worker",
+				"machine.openshift.io/cluster-api-machine-type": "worker",
+			}))
+		})
+	})
+})
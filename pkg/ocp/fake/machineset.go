@@ -21,12 +21,18 @@ limitations under the License.
 package fake
 
 import (
+	api "github.com/submariner-io/cloud-prepare/pkg/api"
+
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 
+	ocp "github.com/submariner-io/cloud-prepare/pkg/ocp"
+
 	unstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-// MockMachineSetDeployer is an autogenerated mock type for the MachineSetDeployer type
+// MockMachineSetDeployer is an autogenerated mock type for the ContextMachineSetDeployer type
 type MockMachineSetDeployer struct {
 	mock.Mock
 }
@@ -132,6 +138,101 @@ func (_c *MockMachineSetDeployer_DeleteByName_Call) RunAndReturn(run func(string
 	return _c
 }
 
+// DeleteByNameWithContext provides a mock function with given fields: ctx, name, namespace
+func (_m *MockMachineSetDeployer) DeleteByNameWithContext(ctx context.Context, name string, namespace string) error {
+	ret := _m.Called(ctx, name, namespace)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByNameWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, name, namespace)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMachineSetDeployer_DeleteByNameWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteByNameWithContext'
+type MockMachineSetDeployer_DeleteByNameWithContext_Call struct {
+	*mock.Call
+}
+
+// DeleteByNameWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - namespace string
+func (_e *MockMachineSetDeployer_Expecter) DeleteByNameWithContext(ctx interface{}, name interface{}, namespace interface{}) *MockMachineSetDeployer_DeleteByNameWithContext_Call {
+	return &MockMachineSetDeployer_DeleteByNameWithContext_Call{Call: _e.mock.On("DeleteByNameWithContext", ctx, name, namespace)}
+}
+
+func (_c *MockMachineSetDeployer_DeleteByNameWithContext_Call) Run(run func(ctx context.Context, name string, namespace string)) *MockMachineSetDeployer_DeleteByNameWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeleteByNameWithContext_Call) Return(_a0 error) *MockMachineSetDeployer_DeleteByNameWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeleteByNameWithContext_Call) RunAndReturn(run func(context.Context, string, string) error) *MockMachineSetDeployer_DeleteByNameWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteWithContext provides a mock function with given fields: ctx, machineSet
+func (_m *MockMachineSetDeployer) DeleteWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	ret := _m.Called(ctx, machineSet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured) error); ok {
+		r0 = rf(ctx, machineSet)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMachineSetDeployer_DeleteWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteWithContext'
+type MockMachineSetDeployer_DeleteWithContext_Call struct {
+	*mock.Call
+}
+
+// DeleteWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - machineSet *unstructured.Unstructured
+func (_e *MockMachineSetDeployer_Expecter) DeleteWithContext(ctx interface{}, machineSet interface{}) *MockMachineSetDeployer_DeleteWithContext_Call {
+	return &MockMachineSetDeployer_DeleteWithContext_Call{Call: _e.mock.On("DeleteWithContext", ctx, machineSet)}
+}
+
+func (_c *MockMachineSetDeployer_DeleteWithContext_Call) Run(run func(ctx context.Context, machineSet *unstructured.Unstructured)) *MockMachineSetDeployer_DeleteWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*unstructured.Unstructured))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeleteWithContext_Call) Return(_a0 error) *MockMachineSetDeployer_DeleteWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeleteWithContext_Call) RunAndReturn(run func(context.Context, *unstructured.Unstructured) error) *MockMachineSetDeployer_DeleteWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Deploy provides a mock function with given fields: machineSet
 func (_m *MockMachineSetDeployer) Deploy(machineSet *unstructured.Unstructured) error {
 	ret := _m.Called(machineSet)
@@ -178,6 +279,148 @@ func (_c *MockMachineSetDeployer_Deploy_Call) RunAndReturn(run func(*unstructure
 	return _c
 }
 
+// DeployHealthCheck provides a mock function with given fields: machineSet, healthCheck
+func (_m *MockMachineSetDeployer) DeployHealthCheck(machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error {
+	ret := _m.Called(machineSet, healthCheck)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeployHealthCheck")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*unstructured.Unstructured, *api.HealthCheck) error); ok {
+		r0 = rf(machineSet, healthCheck)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMachineSetDeployer_DeployHealthCheck_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeployHealthCheck'
+type MockMachineSetDeployer_DeployHealthCheck_Call struct {
+	*mock.Call
+}
+
+// DeployHealthCheck is a helper method to define mock.On call
+//   - machineSet *unstructured.Unstructured
+//   - healthCheck *api.HealthCheck
+func (_e *MockMachineSetDeployer_Expecter) DeployHealthCheck(machineSet interface{}, healthCheck interface{}) *MockMachineSetDeployer_DeployHealthCheck_Call {
+	return &MockMachineSetDeployer_DeployHealthCheck_Call{Call: _e.mock.On("DeployHealthCheck", machineSet, healthCheck)}
+}
+
+func (_c *MockMachineSetDeployer_DeployHealthCheck_Call) Run(run func(machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck)) *MockMachineSetDeployer_DeployHealthCheck_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*unstructured.Unstructured), args[1].(*api.HealthCheck))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeployHealthCheck_Call) Return(_a0 error) *MockMachineSetDeployer_DeployHealthCheck_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeployHealthCheck_Call) RunAndReturn(run func(*unstructured.Unstructured, *api.HealthCheck) error) *MockMachineSetDeployer_DeployHealthCheck_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeployHealthCheckWithContext provides a mock function with given fields: ctx, machineSet, healthCheck
+func (_m *MockMachineSetDeployer) DeployHealthCheckWithContext(ctx context.Context, machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error {
+	ret := _m.Called(ctx, machineSet, healthCheck)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeployHealthCheckWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured, *api.HealthCheck) error); ok {
+		r0 = rf(ctx, machineSet, healthCheck)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMachineSetDeployer_DeployHealthCheckWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeployHealthCheckWithContext'
+type MockMachineSetDeployer_DeployHealthCheckWithContext_Call struct {
+	*mock.Call
+}
+
+// DeployHealthCheckWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - machineSet *unstructured.Unstructured
+//   - healthCheck *api.HealthCheck
+func (_e *MockMachineSetDeployer_Expecter) DeployHealthCheckWithContext(ctx interface{}, machineSet interface{}, healthCheck interface{}) *MockMachineSetDeployer_DeployHealthCheckWithContext_Call {
+	return &MockMachineSetDeployer_DeployHealthCheckWithContext_Call{Call: _e.mock.On("DeployHealthCheckWithContext", ctx, machineSet, healthCheck)}
+}
+
+func (_c *MockMachineSetDeployer_DeployHealthCheckWithContext_Call) Run(run func(ctx context.Context, machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck)) *MockMachineSetDeployer_DeployHealthCheckWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*unstructured.Unstructured), args[2].(*api.HealthCheck))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeployHealthCheckWithContext_Call) Return(_a0 error) *MockMachineSetDeployer_DeployHealthCheckWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeployHealthCheckWithContext_Call) RunAndReturn(run func(context.Context, *unstructured.Unstructured, *api.HealthCheck) error) *MockMachineSetDeployer_DeployHealthCheckWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeployWithContext provides a mock function with given fields: ctx, machineSet
+func (_m *MockMachineSetDeployer) DeployWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	ret := _m.Called(ctx, machineSet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeployWithContext")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured) error); ok {
+		r0 = rf(ctx, machineSet)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMachineSetDeployer_DeployWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeployWithContext'
+type MockMachineSetDeployer_DeployWithContext_Call struct {
+	*mock.Call
+}
+
+// DeployWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - machineSet *unstructured.Unstructured
+func (_e *MockMachineSetDeployer_Expecter) DeployWithContext(ctx interface{}, machineSet interface{}) *MockMachineSetDeployer_DeployWithContext_Call {
+	return &MockMachineSetDeployer_DeployWithContext_Call{Call: _e.mock.On("DeployWithContext", ctx, machineSet)}
+}
+
+func (_c *MockMachineSetDeployer_DeployWithContext_Call) Run(run func(ctx context.Context, machineSet *unstructured.Unstructured)) *MockMachineSetDeployer_DeployWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*unstructured.Unstructured))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeployWithContext_Call) Return(_a0 error) *MockMachineSetDeployer_DeployWithContext_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_DeployWithContext_Call) RunAndReturn(run func(context.Context, *unstructured.Unstructured) error) *MockMachineSetDeployer_DeployWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetWorkerNodeImage provides a mock function with given fields: machineSet, infraID
 func (_m *MockMachineSetDeployer) GetWorkerNodeImage(machineSet *unstructured.Unstructured, infraID string) (string, error) {
 	ret := _m.Called(machineSet, infraID)
@@ -235,6 +478,64 @@ func (_c *MockMachineSetDeployer_GetWorkerNodeImage_Call) RunAndReturn(run func(
 	return _c
 }
 
+// GetWorkerNodeImageWithContext provides a mock function with given fields: ctx, machineSet, infraID
+func (_m *MockMachineSetDeployer) GetWorkerNodeImageWithContext(ctx context.Context, machineSet *unstructured.Unstructured, infraID string) (string, error) {
+	ret := _m.Called(ctx, machineSet, infraID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWorkerNodeImageWithContext")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured, string) (string, error)); ok {
+		return rf(ctx, machineSet, infraID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured, string) string); ok {
+		r0 = rf(ctx, machineSet, infraID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *unstructured.Unstructured, string) error); ok {
+		r1 = rf(ctx, machineSet, infraID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetWorkerNodeImageWithContext'
+type MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call struct {
+	*mock.Call
+}
+
+// GetWorkerNodeImageWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - machineSet *unstructured.Unstructured
+//   - infraID string
+func (_e *MockMachineSetDeployer_Expecter) GetWorkerNodeImageWithContext(ctx interface{}, machineSet interface{}, infraID interface{}) *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call {
+	return &MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call{Call: _e.mock.On("GetWorkerNodeImageWithContext", ctx, machineSet, infraID)}
+}
+
+func (_c *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call) Run(run func(ctx context.Context, machineSet *unstructured.Unstructured, infraID string)) *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*unstructured.Unstructured), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call) Return(_a0 string, _a1 error) *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call) RunAndReturn(run func(context.Context, *unstructured.Unstructured, string) (string, error)) *MockMachineSetDeployer_GetWorkerNodeImageWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // List provides a mock function with given fields:
 func (_m *MockMachineSetDeployer) List() ([]unstructured.Unstructured, error) {
 	ret := _m.Called()
@@ -292,6 +593,181 @@ func (_c *MockMachineSetDeployer_List_Call) RunAndReturn(run func() ([]unstructu
 	return _c
 }
 
+// ListMachines provides a mock function with given fields: machineSet
+func (_m *MockMachineSetDeployer) ListMachines(machineSet *unstructured.Unstructured) ([]ocp.Machine, error) {
+	ret := _m.Called(machineSet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListMachines")
+	}
+
+	var r0 []ocp.Machine
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*unstructured.Unstructured) ([]ocp.Machine, error)); ok {
+		return rf(machineSet)
+	}
+	if rf, ok := ret.Get(0).(func(*unstructured.Unstructured) []ocp.Machine); ok {
+		r0 = rf(machineSet)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ocp.Machine)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*unstructured.Unstructured) error); ok {
+		r1 = rf(machineSet)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMachineSetDeployer_ListMachines_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListMachines'
+type MockMachineSetDeployer_ListMachines_Call struct {
+	*mock.Call
+}
+
+// ListMachines is a helper method to define mock.On call
+//   - machineSet *unstructured.Unstructured
+func (_e *MockMachineSetDeployer_Expecter) ListMachines(machineSet interface{}) *MockMachineSetDeployer_ListMachines_Call {
+	return &MockMachineSetDeployer_ListMachines_Call{Call: _e.mock.On("ListMachines", machineSet)}
+}
+
+func (_c *MockMachineSetDeployer_ListMachines_Call) Run(run func(machineSet *unstructured.Unstructured)) *MockMachineSetDeployer_ListMachines_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*unstructured.Unstructured))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_ListMachines_Call) Return(_a0 []ocp.Machine, _a1 error) *MockMachineSetDeployer_ListMachines_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_ListMachines_Call) RunAndReturn(run func(*unstructured.Unstructured) ([]ocp.Machine, error)) *MockMachineSetDeployer_ListMachines_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListMachinesWithContext provides a mock function with given fields: ctx, machineSet
+func (_m *MockMachineSetDeployer) ListMachinesWithContext(ctx context.Context, machineSet *unstructured.Unstructured) ([]ocp.Machine, error) {
+	ret := _m.Called(ctx, machineSet)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListMachinesWithContext")
+	}
+
+	var r0 []ocp.Machine
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured) ([]ocp.Machine, error)); ok {
+		return rf(ctx, machineSet)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *unstructured.Unstructured) []ocp.Machine); ok {
+		r0 = rf(ctx, machineSet)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ocp.Machine)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *unstructured.Unstructured) error); ok {
+		r1 = rf(ctx, machineSet)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMachineSetDeployer_ListMachinesWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListMachinesWithContext'
+type MockMachineSetDeployer_ListMachinesWithContext_Call struct {
+	*mock.Call
+}
+
+// ListMachinesWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - machineSet *unstructured.Unstructured
+func (_e *MockMachineSetDeployer_Expecter) ListMachinesWithContext(ctx interface{}, machineSet interface{}) *MockMachineSetDeployer_ListMachinesWithContext_Call {
+	return &MockMachineSetDeployer_ListMachinesWithContext_Call{Call: _e.mock.On("ListMachinesWithContext", ctx, machineSet)}
+}
+
+func (_c *MockMachineSetDeployer_ListMachinesWithContext_Call) Run(run func(ctx context.Context, machineSet *unstructured.Unstructured)) *MockMachineSetDeployer_ListMachinesWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*unstructured.Unstructured))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_ListMachinesWithContext_Call) Return(_a0 []ocp.Machine, _a1 error) *MockMachineSetDeployer_ListMachinesWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_ListMachinesWithContext_Call) RunAndReturn(run func(context.Context, *unstructured.Unstructured) ([]ocp.Machine, error)) *MockMachineSetDeployer_ListMachinesWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListWithContext provides a mock function with given fields: ctx
+func (_m *MockMachineSetDeployer) ListWithContext(ctx context.Context) ([]unstructured.Unstructured, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWithContext")
+	}
+
+	var r0 []unstructured.Unstructured
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]unstructured.Unstructured, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []unstructured.Unstructured); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]unstructured.Unstructured)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMachineSetDeployer_ListWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListWithContext'
+type MockMachineSetDeployer_ListWithContext_Call struct {
+	*mock.Call
+}
+
+// ListWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockMachineSetDeployer_Expecter) ListWithContext(ctx interface{}) *MockMachineSetDeployer_ListWithContext_Call {
+	return &MockMachineSetDeployer_ListWithContext_Call{Call: _e.mock.On("ListWithContext", ctx)}
+}
+
+func (_c *MockMachineSetDeployer_ListWithContext_Call) Run(run func(ctx context.Context)) *MockMachineSetDeployer_ListWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_ListWithContext_Call) Return(_a0 []unstructured.Unstructured, _a1 error) *MockMachineSetDeployer_ListWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMachineSetDeployer_ListWithContext_Call) RunAndReturn(run func(context.Context) ([]unstructured.Unstructured, error)) *MockMachineSetDeployer_ListWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockMachineSetDeployer creates a new instance of MockMachineSetDeployer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockMachineSetDeployer(t interface {
@@ -0,0 +1,161 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+)
+
+var machineHealthCheckGVR = schema.GroupVersionResource{
+	Group:    "machine.openshift.io",
+	Version:  "v1beta1",
+	Resource: "machinehealthchecks",
+}
+
+// DeployHealthChecks deploys the given health check for each of the gateway machine sets. If the health check is nil, the
+// health checks of the gateway machine sets are deleted instead, so that unsetting it removes those deployed earlier.
+func DeployHealthChecks(ctx context.Context, msDeployer MachineSetDeployer, healthCheck *api.HealthCheck,
+	status reporter.Interface,
+) error {
+	if healthCheck == nil {
+		return deleteHealthChecks(ctx, msDeployer, status)
+	}
+
+	status.Start("Deploying the health checks of the gateway machine sets")
+	defer status.End()
+
+	machineSets, err := WithContext(msDeployer).ListWithContext(ctx)
+	if err != nil {
+		return status.Error(err, "error listing the gateway machine sets")
+	}
+
+	for i := range machineSets {
+		err = WithContext(msDeployer).DeployHealthCheckWithContext(ctx, &machineSets[i], healthCheck)
+		if err != nil {
+			return status.Error(err, "error deploying the health check of machine set %q", machineSets[i].GetName())
+		}
+	}
+
+	status.Success("Deployed the health checks of %d gateway machine set(s)", len(machineSets))
+
+	return nil
+}
+
+func deleteHealthChecks(ctx context.Context, msDeployer MachineSetDeployer, status reporter.Interface) error {
+	machineSets, err := WithContext(msDeployer).ListWithContext(ctx)
+	if err != nil {
+		return status.Error(err, "error listing the gateway machine sets")
+	}
+
+	if len(machineSets) == 0 {
+		return nil
+	}
+
+	status.Start("Removing the health checks of the gateway machine sets")
+	defer status.End()
+
+	for i := range machineSets {
+		err = WithContext(msDeployer).DeployHealthCheckWithContext(ctx, &machineSets[i], nil)
+		if err != nil {
+			return status.Error(err, "error removing the health check of machine set %q", machineSets[i].GetName())
+		}
+	}
+
+	status.Success("Removed the health checks of %d gateway machine set(s)", len(machineSets))
+
+	return nil
+}
+
+// DeleteHealthCheck deletes the named MachineHealthCheck if it's a gateway one, i.e. one created by
+// ContextMachineSetDeployer.DeployHealthCheck. It's considered not to exist if it can't be read because its CRD isn't installed
+// or access to it is forbidden, so that the callers which never requested health checks need no permissions on them.
+func DeleteHealthCheck(ctx context.Context, client dynamic.ResourceInterface, name string) error {
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving the machine health check %q", name)
+	}
+
+	if existing.GetLabels()[SubmarinerGatewayLabel] != "true" {
+		return nil
+	}
+
+	err = client.Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "error deleting the machine health check %q", name)
+}
+
+// HealthCheckSpec returns the spec of a MachineHealthCheck applying the given health check to the machines matched by the
+// given label selector. The machine.openshift.io and cluster.x-k8s.io MachineHealthChecks share these fields.
+func HealthCheckSpec(healthCheck *api.HealthCheck, selector map[string]interface{}) map[string]interface{} {
+	conditions := healthCheck.Conditions()
+	unhealthyConditions := make([]interface{}, len(conditions))
+
+	for i := range conditions {
+		unhealthyConditions[i] = map[string]interface{}{
+			"type":    string(conditions[i].Type),
+			"status":  string(conditions[i].Status),
+			"timeout": conditions[i].Timeout.Duration.String(),
+		}
+	}
+
+	spec := map[string]interface{}{
+		"selector":            selector,
+		"unhealthyConditions": unhealthyConditions,
+	}
+
+	if healthCheck.MaxUnhealthy != nil {
+		if healthCheck.MaxUnhealthy.Type == intstr.Int {
+			spec["maxUnhealthy"] = int64(healthCheck.MaxUnhealthy.IntVal)
+		} else {
+			spec["maxUnhealthy"] = healthCheck.MaxUnhealthy.StrVal
+		}
+	}
+
+	return spec
+}
+
+// machineSetSelector returns the label selector of the machines of the given machine set.
+func machineSetSelector(machineSet *unstructured.Unstructured) map[string]interface{} {
+	selector, _, _ := unstructured.NestedMap(machineSet.Object, "spec", "selector")
+	if len(selector) == 0 {
+		selector = map[string]interface{}{
+			"matchLabels": map[string]interface{}{machineSetLabel: machineSet.GetName()},
+		}
+	}
+
+	return selector
+}
@@ -0,0 +1,91 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("DeployHealthChecks", func() {
+	var (
+		msDeployer  *ocpFake.MockMachineSetDeployer
+		healthCheck *api.HealthCheck
+		deployed    []string
+	)
+
+	BeforeEach(func() {
+		msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
+		healthCheck = &api.HealthCheck{}
+		deployed = nil
+
+		var ms1, ms2 unstructured.Unstructured
+
+		ms1.SetName("test-gw-1")
+		ms2.SetName("test-gw-2")
+
+		msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{ms1, ms2}, nil).Maybe()
+	})
+
+	deployHealthChecks := func() error {
+		return ocp.DeployHealthChecks(context.TODO(), msDeployer, healthCheck, reporter.Stdout())
+	}
+
+	It("should deploy the health check of each gateway machine set", func() {
+		msDeployer.EXPECT().DeployHealthCheckWithContext(mock.Anything, mock.Anything, healthCheck).RunAndReturn(
+			func(_ context.Context, machineSet *unstructured.Unstructured, _ *api.HealthCheck) error {
+				deployed = append(deployed, machineSet.GetName())
+				return nil
+			})
+
+		Expect(deployHealthChecks()).To(Succeed())
+		Expect(deployed).To(Equal([]string{"test-gw-1", "test-gw-2"}))
+	})
+
+	When("no health check is requested", func() {
+		It("should remove the health check of each gateway machine set", func() {
+			healthCheck = nil
+
+			msDeployer.EXPECT().DeployHealthCheckWithContext(mock.Anything, mock.Anything, (*api.HealthCheck)(nil)).RunAndReturn(
+				func(_ context.Context, machineSet *unstructured.Unstructured, _ *api.HealthCheck) error {
+					deployed = append(deployed, machineSet.GetName())
+					return nil
+				})
+
+			Expect(deployHealthChecks()).To(Succeed())
+			Expect(deployed).To(Equal([]string{"test-gw-1", "test-gw-2"}))
+		})
+	})
+
+	When("deploying a health check fails", func() {
+		It("should return an error", func() {
+			msDeployer.EXPECT().DeployHealthCheckWithContext(mock.Anything, mock.Anything, healthCheck).Return(errors.New("fake error"))
+			Expect(deployHealthChecks()).To(MatchError(ContainSubstring("test-gw-1")))
+		})
+	})
+})
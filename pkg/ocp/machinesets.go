@@ -21,11 +21,13 @@ package ocp
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/resource"
 	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -42,6 +44,12 @@ const (
 	SubmarinerGatewayLabel = "submariner.io/gateway"
 )
 
+var machineGVR = schema.GroupVersionResource{
+	Group:    "machine.openshift.io",
+	Version:  "v1beta1",
+	Resource: "machines",
+}
+
 // MachineSetDeployer can deploy and delete machinesets from OCP.
 type MachineSetDeployer interface {
 	// Deploy makes sure to deploy the given machine set (creating or updating it).
@@ -60,6 +68,129 @@ type MachineSetDeployer interface {
 	DeleteByName(name, namespace string) error
 }
 
+// ContextMachineSetDeployer extends MachineSetDeployer with the operations bound to a context. All the MachineSetDeployer
+// implementations of this repository implement it; it's a separate interface so that the existing implementations of
+// MachineSetDeployer keep compiling. Callers given a MachineSetDeployer convert it using WithContext.
+type ContextMachineSetDeployer interface {
+	MachineSetDeployer
+
+	// DeployWithContext is the same as Deploy but bound to the given context.
+	DeployWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error
+
+	// GetWorkerNodeImageWithContext is the same as GetWorkerNodeImage but bound to the given context.
+	GetWorkerNodeImageWithContext(ctx context.Context, machineSet *unstructured.Unstructured, infraID string) (string, error)
+
+	// ListWithContext is the same as List but bound to the given context.
+	ListWithContext(ctx context.Context) ([]unstructured.Unstructured, error)
+
+	// DeleteWithContext is the same as Delete but bound to the given context.
+	DeleteWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error
+
+	// DeleteByNameWithContext is the same as DeleteByName but bound to the given context.
+	DeleteByNameWithContext(ctx context.Context, name, namespace string) error
+
+	// ListMachines returns the machines created for the given machine set.
+	ListMachines(machineSet *unstructured.Unstructured) ([]Machine, error)
+
+	// ListMachinesWithContext is the same as ListMachines but bound to the given context.
+	ListMachinesWithContext(ctx context.Context, machineSet *unstructured.Unstructured) ([]Machine, error)
+
+	// DeployHealthCheck makes sure to deploy a MachineHealthCheck (creating or updating it) which remediates the unhealthy
+	// machines of the given machine set. It's named after the machine set, and deleted with it. If the health check is nil,
+	// the MachineHealthCheck of the machine set is deleted instead, if it exists and was deployed by this method.
+	DeployHealthCheck(machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error
+
+	// DeployHealthCheckWithContext is the same as DeployHealthCheck but bound to the given context.
+	DeployHealthCheckWithContext(ctx context.Context, machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error
+}
+
+// ErrMachinesUnsupported is returned by the machine listing of the deployers converted by WithContext, as a
+// MachineSetDeployer doesn't provide the machines of its machine sets.
+var ErrMachinesUnsupported = errors.New("the machine set deployer doesn't list the machines")
+
+// ErrHealthChecksUnsupported is returned by the health check deployment of the deployers converted by WithContext, as a
+// MachineSetDeployer doesn't deploy health checks.
+var ErrHealthChecksUnsupported = errors.New("the machine set deployer doesn't deploy health checks")
+
+// WithContext returns the given deployer as a ContextMachineSetDeployer, or nil if it's nil. If it doesn't implement the
+// extended operations, those bound to a context call the basic operations instead, ignoring the context, and listing the
+// machines returns ErrMachinesUnsupported. Deploying a health check then returns ErrHealthChecksUnsupported, while removing
+// one succeeds as there can't be any.
+func WithContext(deployer MachineSetDeployer) ContextMachineSetDeployer {
+	if deployer == nil {
+		return nil
+	}
+
+	if contextDeployer, ok := deployer.(ContextMachineSetDeployer); ok {
+		return contextDeployer
+	}
+
+	return &contextFreeMachineSetDeployer{MachineSetDeployer: deployer}
+}
+
+type contextFreeMachineSetDeployer struct {
+	MachineSetDeployer
+}
+
+func (d *contextFreeMachineSetDeployer) DeployWithContext(_ context.Context, machineSet *unstructured.Unstructured) error {
+	return d.Deploy(machineSet) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (d *contextFreeMachineSetDeployer) GetWorkerNodeImageWithContext(_ context.Context, machineSet *unstructured.Unstructured,
+	infraID string,
+) (string, error) {
+	return d.GetWorkerNodeImage(machineSet, infraID) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (d *contextFreeMachineSetDeployer) ListWithContext(_ context.Context) ([]unstructured.Unstructured, error) {
+	return d.List() //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (d *contextFreeMachineSetDeployer) DeleteWithContext(_ context.Context, machineSet *unstructured.Unstructured) error {
+	return d.Delete(machineSet) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (d *contextFreeMachineSetDeployer) DeleteByNameWithContext(_ context.Context, name, namespace string) error {
+	return d.DeleteByName(name, namespace) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+func (d *contextFreeMachineSetDeployer) ListMachines(_ *unstructured.Unstructured) ([]Machine, error) {
+	return nil, ErrMachinesUnsupported
+}
+
+func (d *contextFreeMachineSetDeployer) ListMachinesWithContext(_ context.Context, _ *unstructured.Unstructured) ([]Machine, error) {
+	return nil, ErrMachinesUnsupported
+}
+
+func (d *contextFreeMachineSetDeployer) DeployHealthCheck(machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error {
+	return d.DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)
+}
+
+func (d *contextFreeMachineSetDeployer) DeployHealthCheckWithContext(_ context.Context, _ *unstructured.Unstructured,
+	healthCheck *api.HealthCheck,
+) error {
+	if healthCheck == nil {
+		return nil
+	}
+
+	return ErrHealthChecksUnsupported
+}
+
+// Machine describes the progress of a machine created for a gateway machine set.
+type Machine struct {
+	Name string
+
+	// Phase is the phase reported by the machine API, such as Provisioning, Provisioned or Running.
+	Phase string
+
+	// FailureReason and FailureMessage are set when the machine API reports that the machine can't be provisioned.
+	FailureReason  string
+	FailureMessage string
+
+	// NodeName is the name of the machine's node, once it has joined the cluster.
+	NodeName string
+}
+
 type k8sMachineSetDeployer struct {
 	restMapper    meta.RESTMapper
 	dynamicClient dynamic.Interface
@@ -96,7 +227,13 @@ func (msd *k8sMachineSetDeployer) clientForMsd(nameSpace string) dynamic.Resourc
 
 func (msd *k8sMachineSetDeployer) GetWorkerNodeImage(machineSet *unstructured.Unstructured, infraID string,
 ) (string, error) {
-	machineSetClient := msd.clientForMsd("openshift-machine-api")
+	return msd.GetWorkerNodeImageWithContext(context.TODO(), machineSet, infraID)
+}
+
+func (msd *k8sMachineSetDeployer) GetWorkerNodeImageWithContext(ctx context.Context, machineSet *unstructured.Unstructured,
+	infraID string,
+) (string, error) {
+	machineSetClient := msd.clientForMsd(MachineAPINamespace)
 
 	if machineSet != nil {
 		var err error
@@ -107,14 +244,14 @@ func (msd *k8sMachineSetDeployer) GetWorkerNodeImage(machineSet *unstructured.Un
 		}
 	}
 
-	nodeList, err := machineSetClient.List(context.TODO(), metav1.ListOptions{})
+	nodeList, err := machineSetClient.List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return "", errors.Wrapf(err, "error listing the machineSets")
 	}
 
 	for i := range nodeList.Items {
 		if labels, found, _ := unstructured.NestedStringMap(nodeList.Items[i].Object, "spec", "template", "metadata", "labels"); found {
-			role := labels["machine.openshift.io/cluster-api-machine-role"]
+			role := labels[machineRoleLabel]
 			if strings.Compare(strings.ToLower(role), "worker") != 0 {
 				continue
 			}
@@ -155,24 +292,37 @@ func getImageFromMachineSet(existing *unstructured.Unstructured) string {
 }
 
 func (msd *k8sMachineSetDeployer) Deploy(machineSet *unstructured.Unstructured) error {
+	return msd.DeployWithContext(context.TODO(), machineSet)
+}
+
+func (msd *k8sMachineSetDeployer) DeployWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
 	machineSetClient, err := msd.clientFor(machineSet)
 	if err != nil {
 		return err
 	}
 
-	_, err = util.CreateOrUpdate(context.TODO(), resource.ForDynamic(machineSetClient), machineSet,
+	_, err = util.CreateOrUpdate(ctx, resource.ForDynamic(machineSetClient), machineSet,
 		util.Replace[*unstructured.Unstructured](machineSet))
 
 	return errors.Wrapf(err, "error creating machine set %#v", machineSet)
 }
 
 func (msd *k8sMachineSetDeployer) Delete(machineSet *unstructured.Unstructured) error {
+	return msd.DeleteWithContext(context.TODO(), machineSet)
+}
+
+func (msd *k8sMachineSetDeployer) DeleteWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
 	machineSetClient, err := msd.clientFor(machineSet)
 	if err != nil {
 		return err
 	}
 
-	err = machineSetClient.Delete(context.TODO(), machineSet.GetName(), metav1.DeleteOptions{})
+	err = msd.deleteHealthCheck(ctx, machineSet.GetName(), machineSet.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	err = machineSetClient.Delete(ctx, machineSet.GetName(), metav1.DeleteOptions{})
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
@@ -181,9 +331,16 @@ func (msd *k8sMachineSetDeployer) Delete(machineSet *unstructured.Unstructured)
 }
 
 func (msd *k8sMachineSetDeployer) DeleteByName(name, namespace string) error {
-	machineSetClient := msd.clientForMsd(namespace)
+	return msd.DeleteByNameWithContext(context.TODO(), name, namespace)
+}
+
+func (msd *k8sMachineSetDeployer) DeleteByNameWithContext(ctx context.Context, name, namespace string) error {
+	err := msd.deleteHealthCheck(ctx, name, namespace)
+	if err != nil {
+		return err
+	}
 
-	err := machineSetClient.Delete(context.TODO(), name, metav1.DeleteOptions{})
+	err = msd.clientForMsd(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
@@ -192,9 +349,13 @@ func (msd *k8sMachineSetDeployer) DeleteByName(name, namespace string) error {
 }
 
 func (msd *k8sMachineSetDeployer) List() ([]unstructured.Unstructured, error) {
+	return msd.ListWithContext(context.TODO())
+}
+
+func (msd *k8sMachineSetDeployer) ListWithContext(ctx context.Context) ([]unstructured.Unstructured, error) {
 	machineSetClient := msd.clientForMsd("")
 
-	machineSetList, err := machineSetClient.List(context.TODO(), metav1.ListOptions{})
+	machineSetList, err := machineSetClient.List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to list machinesets")
 	}
@@ -216,14 +377,202 @@ func (msd *k8sMachineSetDeployer) List() ([]unstructured.Unstructured, error) {
 	return resultList, nil
 }
 
+func (msd *k8sMachineSetDeployer) ListMachines(machineSet *unstructured.Unstructured) ([]Machine, error) {
+	return msd.ListMachinesWithContext(context.TODO(), machineSet)
+}
+
+func (msd *k8sMachineSetDeployer) ListMachinesWithContext(ctx context.Context, machineSet *unstructured.Unstructured) ([]Machine, error) {
+	machineList, err := msd.dynamicClient.Resource(machineGVR).Namespace(machineSet.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: machineSetLabel + "=" + machineSet.GetName(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing the machines of machine set %q", machineSet.GetName())
+	}
+
+	machines := make([]Machine, len(machineList.Items))
+
+	for i := range machineList.Items {
+		machines[i] = Machine{
+			Name:           machineList.Items[i].GetName(),
+			Phase:          machineStatus(&machineList.Items[i], "phase"),
+			FailureReason:  machineStatus(&machineList.Items[i], "errorReason"),
+			FailureMessage: machineStatus(&machineList.Items[i], "errorMessage"),
+			NodeName:       machineStatus(&machineList.Items[i], "nodeRef", "name"),
+		}
+	}
+
+	return machines, nil
+}
+
+func (msd *k8sMachineSetDeployer) DeployHealthCheck(machineSet *unstructured.Unstructured, healthCheck *api.HealthCheck) error {
+	return msd.DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)
+}
+
+func (msd *k8sMachineSetDeployer) DeployHealthCheckWithContext(ctx context.Context, machineSet *unstructured.Unstructured,
+	healthCheck *api.HealthCheck,
+) error {
+	if healthCheck == nil {
+		return msd.deleteHealthCheck(ctx, machineSet.GetName(), machineSet.GetNamespace())
+	}
+
+	machineHealthCheck := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": machineHealthCheckGVR.GroupVersion().String(),
+		"kind":       "MachineHealthCheck",
+		"metadata": map[string]interface{}{
+			"name":      machineSet.GetName(),
+			"namespace": machineSet.GetNamespace(),
+			"labels": map[string]interface{}{
+				SubmarinerGatewayLabel: "true",
+			},
+		},
+		"spec": HealthCheckSpec(healthCheck, machineSetSelector(machineSet)),
+	}}
+
+	client := msd.dynamicClient.Resource(machineHealthCheckGVR).Namespace(machineSet.GetNamespace())
+
+	_, err := util.CreateOrUpdate(ctx, resource.ForDynamic(client), machineHealthCheck,
+		util.Replace[*unstructured.Unstructured](machineHealthCheck))
+
+	return errors.Wrapf(err, "error creating the machine health check %q", machineSet.GetName())
+}
+
+func (msd *k8sMachineSetDeployer) deleteHealthCheck(ctx context.Context, name, namespace string) error {
+	return DeleteHealthCheck(ctx, msd.dynamicClient.Resource(machineHealthCheckGVR).Namespace(namespace), name)
+}
+
+func machineStatus(machine *unstructured.Unstructured, fields ...string) string {
+	value, _, _ := unstructured.NestedString(machine.Object, append([]string{"status"}, fields...)...)
+
+	return value
+}
+
+// ProviderSpecString returns the string field at the given path in the providerSpec of the given machine set,
+// or an empty string if it's not set.
+func ProviderSpecString(machineSet *unstructured.Unstructured, fields ...string) string {
+	value, _, _ := unstructured.NestedString(machineSet.Object,
+		append([]string{"spec", "template", "spec", "providerSpec", "value"}, fields...)...)
+
+	return value
+}
+
+// GatewayNodes maps the gateway nodes to the gateway machine sets that provisioned them.
+type GatewayNodes struct {
+	// ByMachineSet holds the nodes provisioned by each gateway machine set, keyed by the machine set name.
+	ByMachineSet map[string][]v1.Node
+
+	// Labeled holds the gateway nodes that weren't provisioned by any of the gateway machine sets, i.e. the
+	// existing worker nodes labeled as gateways.
+	Labeled []v1.Node
+}
+
+// MapGatewayNodes resolves which of the given gateway nodes were provisioned by which of the given gateway machine sets.
+// Nodes are matched through the status.nodeRef of the machines owned by each machine set, or by name if the deployer
+// doesn't list the machines, which relies on the provider naming nodes after their machine set.
+func MapGatewayNodes(ctx context.Context, msDeployer MachineSetDeployer, machineSets []unstructured.Unstructured,
+	nodes []v1.Node,
+) (*GatewayNodes, error) {
+	machineSetByNode := map[string]string{}
+
+	for i := range machineSets {
+		machines, err := WithContext(msDeployer).ListMachinesWithContext(ctx, &machineSets[i])
+		if errors.Is(err, ErrMachinesUnsupported) {
+			for j := range nodes {
+				if strings.Contains(nodes[j].Name, machineSets[i].GetName()) {
+					machineSetByNode[nodes[j].Name] = machineSets[i].GetName()
+				}
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Let the caller wrap it.
+		}
+
+		for j := range machines {
+			if machines[j].NodeName != "" {
+				machineSetByNode[machines[j].NodeName] = machineSets[i].GetName()
+			}
+		}
+	}
+
+	gwNodes := &GatewayNodes{ByMachineSet: map[string][]v1.Node{}}
+
+	for i := range nodes {
+		if name, found := machineSetByNode[nodes[i].Name]; found {
+			gwNodes.ByMachineSet[name] = append(gwNodes.ByMachineSet[name], nodes[i])
+		} else {
+			gwNodes.Labeled = append(gwNodes.Labeled, nodes[i])
+		}
+	}
+
+	return gwNodes, nil
+}
+
+// SelectSurplusMachineSets returns up to count of the given gateway machine sets to delete when scaling down, given the
+// mapping of the gateway nodes and the node running the active gateway. The machine set of the active gateway is never
+// selected. The machine sets without a node, which can't be running a gateway yet, are selected first, then those of
+// passive gateways.
+func SelectSurplusMachineSets(machineSets []unstructured.Unstructured, gwNodes *GatewayNodes, activeNode string, count int,
+) []unstructured.Unstructured {
+	var unprovisioned, passive []unstructured.Unstructured
+
+	for i := range machineSets {
+		msNodes := gwNodes.ByMachineSet[machineSets[i].GetName()]
+
+		switch {
+		case len(msNodes) == 0:
+			unprovisioned = append(unprovisioned, machineSets[i])
+		case slices.ContainsFunc(msNodes, func(node v1.Node) bool { return node.Name == activeNode }):
+			continue
+		default:
+			passive = append(passive, machineSets[i])
+		}
+	}
+
+	byName := func(a, b unstructured.Unstructured) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	}
+
+	slices.SortFunc(unprovisioned, byName)
+	slices.SortFunc(passive, byName)
+
+	selected := append(unprovisioned, passive...)
+	if len(selected) > count {
+		selected = selected[:max(count, 0)]
+	}
+
+	return selected
+}
+
+// MachineSetNodes returns the nodes, among the given ones, that were provisioned by the given machine set. Nodes are
+// matched by name, which relies on the provider naming nodes after their machines, and so after the machine set.
+//
+// Deprecated: Use MapGatewayNodes, which matches the nodes through the machines of the machine set.
+func MachineSetNodes(machineSet *unstructured.Unstructured, nodes []v1.Node) []v1.Node {
+	var result []v1.Node
+
+	for i := range nodes {
+		if strings.Contains(nodes[i].GetName(), machineSet.GetName()) {
+			result = append(result, nodes[i])
+		}
+	}
+
+	return result
+}
+
+// RemoveDuplicates returns the given gateway nodes which weren't provisioned by any of the given machine sets, matching
+// them by name.
+//
+// Deprecated: Use MapGatewayNodes, which returns these nodes as GatewayNodes.Labeled.
 func RemoveDuplicates(machineSets []unstructured.Unstructured, gwNodes []v1.Node) []v1.Node {
 	var resultNode []v1.Node
 
-	for i := 0; i < len(gwNodes); i++ {
+	for i := range gwNodes {
 		addToResult := true
 
-		for i := 0; i < len(machineSets); i++ {
-			if strings.Contains(gwNodes[i].GetName(), machineSets[i].GetName()) {
+		for j := range machineSets {
+			if len(MachineSetNodes(&machineSets[j], gwNodes[i:i+1])) > 0 {
 				addToResult = false
 				break
 			}
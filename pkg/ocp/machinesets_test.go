@@ -21,20 +21,29 @@ package ocp_test
 import (
 	"context"
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
 	"github.com/submariner-io/admiral/pkg/fake"
 	. "github.com/submariner-io/admiral/pkg/gomega"
 	"github.com/submariner-io/admiral/pkg/syncer/test"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/dynamic"
 	fakeClient "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("K8s MachineSetDeployer", func() {
@@ -55,7 +64,8 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 		restMapper, gvr := test.GetRESTMapperAndGroupVersionResourceFor(machineSet)
 
 		dynClient = fakeClient.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme, map[schema.GroupVersionResource]string{
-			*gvr: "MachineSetList",
+			*gvr:       "MachineSetList",
+			machineGVR: "MachineList",
 		})
 		deployer = ocp.NewK8sMachinesetDeployer(restMapper, dynClient)
 		msClient = dynClient.Resource(*gvr).Namespace(machineSet.GetNamespace())
@@ -197,6 +207,317 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 			})
 		})
 	})
+
+	Context("on DeployHealthCheck", func() {
+		var healthCheck *api.HealthCheck
+
+		BeforeEach(func() {
+			machineSet.SetName(machineSetName)
+
+			healthCheck = &api.HealthCheck{}
+		})
+
+		getHealthCheck := func() (*unstructured.Unstructured, error) {
+			return dynClient.Resource(machineHealthCheckGVR).Namespace(machineSet.GetNamespace()).Get(context.TODO(), machineSetName,
+				metav1.GetOptions{})
+		}
+
+		It("should create a machine health check targeting the machine set", func() {
+			Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)).To(Succeed())
+
+			machineHealthCheck, err := getHealthCheck()
+			Expect(err).To(Succeed())
+			Expect(machineHealthCheck.GetLabels()).To(HaveKeyWithValue(ocp.SubmarinerGatewayLabel, "true"))
+			Expect(machineHealthCheck.Object["spec"]).To(Equal(map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{"machine.openshift.io/cluster-api-machineset": machineSetName},
+				},
+				"unhealthyConditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False", "timeout": "5m0s"},
+					map[string]interface{}{"type": "Ready", "status": "Unknown", "timeout": "5m0s"},
+				},
+			}))
+		})
+
+		Context("with custom unhealthy conditions and maxUnhealthy", func() {
+			BeforeEach(func() {
+				healthCheck.UnhealthyConditions = []api.UnhealthyCondition{{
+					Type:    corev1.NodeReady,
+					Status:  corev1.ConditionFalse,
+					Timeout: metav1.Duration{Duration: 10 * time.Minute},
+				}}
+				healthCheck.MaxUnhealthy = ptr.To(intstr.FromInt32(1))
+			})
+
+			It("should configure them", func() {
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)).To(Succeed())
+
+				machineHealthCheck, err := getHealthCheck()
+				Expect(err).To(Succeed())
+				Expect(machineHealthCheck.Object["spec"]).To(HaveKeyWithValue("maxUnhealthy", int64(1)))
+				Expect(machineHealthCheck.Object["spec"]).To(HaveKeyWithValue("unhealthyConditions", []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False", "timeout": "10m0s"},
+				}))
+			})
+		})
+
+		When("the machine set is deleted", func() {
+			It("should delete the machine health check", func() {
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)).To(Succeed())
+				Expect(deployer.DeleteByName(machineSetName, machineSet.GetNamespace())).To(Succeed())
+
+				_, err := getHealthCheck()
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		When("the health check is nil", func() {
+			It("should delete the machine health check", func() {
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)).To(Succeed())
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, nil)).To(Succeed())
+
+				_, err := getHealthCheck()
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+
+			It("should succeed if there's no machine health check", func() {
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, nil)).To(Succeed())
+			})
+
+			It("should keep a machine health check which isn't a gateway one", func() {
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, healthCheck)).To(Succeed())
+
+				machineHealthCheck, err := getHealthCheck()
+				Expect(err).To(Succeed())
+
+				machineHealthCheck.SetLabels(nil)
+				_, err = dynClient.Resource(machineHealthCheckGVR).Namespace(machineSet.GetNamespace()).Update(context.TODO(),
+					machineHealthCheck, metav1.UpdateOptions{})
+				Expect(err).To(Succeed())
+
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, nil)).To(Succeed())
+
+				_, err = getHealthCheck()
+				Expect(err).To(Succeed())
+			})
+
+			It("should succeed if reading the machine health checks is forbidden", func() {
+				dynClient.PrependReactor("*", "machinehealthchecks", func(_ testing.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(machineHealthCheckGVR.GroupResource(), machineSetName,
+						errors.New("fake forbidden"))
+				})
+
+				Expect(ocp.WithContext(deployer).DeployHealthCheckWithContext(context.TODO(), machineSet, nil)).To(Succeed())
+				Expect(deployer.DeleteByName(machineSetName, machineSet.GetNamespace())).To(Succeed())
+			})
+		})
+	})
+
+	Context("on ListMachines", func() {
+		BeforeEach(func() {
+			machineSet.SetName(machineSetName)
+
+			for name, owner := range map[string]string{"test-machine-1": machineSetName, "test-machine-2": "other"} {
+				machine := &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "machine.openshift.io/v1beta1",
+					"kind":       "Machine",
+					"metadata": map[string]interface{}{
+						"name":      name,
+						"namespace": machineSet.GetNamespace(),
+						"labels":    map[string]interface{}{"machine.openshift.io/cluster-api-machineset": owner},
+					},
+					"status": map[string]interface{}{
+						"phase":        "Failed",
+						"errorReason":  "InvalidConfiguration",
+						"errorMessage": "unknown instance type",
+						"nodeRef":      map[string]interface{}{"name": "test-node"},
+					},
+				}}
+
+				_, err := dynClient.Resource(machineGVR).Namespace(machineSet.GetNamespace()).Create(context.TODO(), machine,
+					metav1.CreateOptions{})
+				Expect(err).To(Succeed())
+			}
+		})
+
+		It("should return the machines of the machine set", func() {
+			machines, err := ocp.WithContext(deployer).ListMachinesWithContext(context.TODO(), machineSet)
+			Expect(err).To(Succeed())
+			Expect(machines).To(Equal([]ocp.Machine{{
+				Name:           "test-machine-1",
+				Phase:          "Failed",
+				FailureReason:  "InvalidConfiguration",
+				FailureMessage: "unknown instance type",
+				NodeName:       "test-node",
+			}}))
+		})
+	})
+})
+
+var machineGVR = schema.GroupVersionResource{
+	Group:    "machine.openshift.io",
+	Version:  "v1beta1",
+	Resource: "machines",
+}
+
+var machineHealthCheckGVR = schema.GroupVersionResource{
+	Group:    "machine.openshift.io",
+	Version:  "v1beta1",
+	Resource: "machinehealthchecks",
+}
+
+var _ = Describe("MapGatewayNodes", func() {
+	var (
+		msDeployer  *ocpFake.MockMachineSetDeployer
+		machineSets []unstructured.Unstructured
+		nodes       []corev1.Node
+	)
+
+	BeforeEach(func() {
+		msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
+		machineSets = nil
+
+		for _, name := range []string{"infra-submariner-gw-zone1", "infra-submariner-gw-zone2", "infra-submariner-gw-zone3"} {
+			machineSet := newMachineSet("true")
+			machineSet.SetName(name)
+			machineSets = append(machineSets, *machineSet)
+		}
+
+		// The node names deliberately don't match the machine set names: nodes must be matched through their machines.
+		nodes = []corev1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "gw-node-1"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "gw-node-3"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "infra-submariner-gw-zone2-worker"}},
+		}
+
+		msDeployer.EXPECT().ListMachinesWithContext(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, machineSet *unstructured.Unstructured) ([]ocp.Machine, error) {
+				switch machineSet.GetName() {
+				case "infra-submariner-gw-zone1":
+					return []ocp.Machine{{Name: "machine-1", Phase: "Running", NodeName: "gw-node-1"}}, nil
+				case "infra-submariner-gw-zone3":
+					return []ocp.Machine{{Name: "machine-3", Phase: "Running", NodeName: "gw-node-3"}}, nil
+				}
+
+				return []ocp.Machine{{Name: "machine-2", Phase: "Provisioning"}}, nil
+			}).Maybe()
+	})
+
+	names := func(machineSets []unstructured.Unstructured) []string {
+		result := []string{}
+		for i := range machineSets {
+			result = append(result, machineSets[i].GetName())
+		}
+
+		return result
+	}
+
+	It("should map the nodes to the machine sets through the machines", func() {
+		gwNodes, err := ocp.MapGatewayNodes(context.TODO(), msDeployer, machineSets, nodes)
+		Expect(err).To(Succeed())
+		Expect(gwNodes.ByMachineSet).To(Equal(map[string][]corev1.Node{
+			"infra-submariner-gw-zone1": nodes[0:1],
+			"infra-submariner-gw-zone3": nodes[1:2],
+		}))
+		Expect(gwNodes.Labeled).To(Equal(nodes[2:]))
+	})
+
+	When("listing the machines fails", func() {
+		BeforeEach(func() {
+			msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
+			msDeployer.EXPECT().ListMachinesWithContext(mock.Anything, mock.Anything).Return(nil, errors.New("fake error"))
+		})
+
+		It("should return an error", func() {
+			_, err := ocp.MapGatewayNodes(context.TODO(), msDeployer, machineSets, nodes)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the deployer doesn't list the machines", func() {
+		It("should map the nodes to the machine sets by name", func() {
+			gwNodes, err := ocp.MapGatewayNodes(context.TODO(), struct{ ocp.MachineSetDeployer }{msDeployer}, machineSets, nodes)
+			Expect(err).To(Succeed())
+			Expect(gwNodes.ByMachineSet).To(Equal(map[string][]corev1.Node{
+				"infra-submariner-gw-zone2": nodes[2:],
+			}))
+			Expect(gwNodes.Labeled).To(Equal(nodes[0:2]))
+		})
+	})
+
+	Context("on SelectSurplusMachineSets", func() {
+		var gwNodes *ocp.GatewayNodes
+
+		BeforeEach(func() {
+			var err error
+
+			gwNodes, err = ocp.MapGatewayNodes(context.TODO(), msDeployer, machineSets, nodes)
+			Expect(err).To(Succeed())
+		})
+
+		It("should prefer the machine sets without a node, then the passive gateways", func() {
+			Expect(names(ocp.SelectSurplusMachineSets(machineSets, gwNodes, "", 1))).To(Equal([]string{"infra-submariner-gw-zone2"}))
+			Expect(names(ocp.SelectSurplusMachineSets(machineSets, gwNodes, "", 2))).To(Equal([]string{
+				"infra-submariner-gw-zone2", "infra-submariner-gw-zone1",
+			}))
+		})
+
+		It("should never select the machine set of the active gateway", func() {
+			Expect(names(ocp.SelectSurplusMachineSets(machineSets, gwNodes, "gw-node-1", 3))).To(Equal([]string{
+				"infra-submariner-gw-zone2", "infra-submariner-gw-zone3",
+			}))
+		})
+	})
+
+	Context("on RemoveDuplicates", func() {
+		It("should return the nodes whose names don't match any machine set", func() {
+			//nolint:staticcheck // Deprecated but still supported.
+			Expect(ocp.RemoveDuplicates(machineSets, nodes)).To(Equal(nodes[0:2]))
+		})
+	})
+})
+
+var _ = Describe("WithContext", func() {
+	var msDeployer *ocpFake.MockMachineSetDeployer
+
+	BeforeEach(func() {
+		msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
+	})
+
+	When("the deployer implements the operations bound to a context", func() {
+		It("should return it", func() {
+			Expect(ocp.WithContext(msDeployer)).To(BeIdenticalTo(msDeployer))
+		})
+	})
+
+	When("the deployer only implements MachineSetDeployer", func() {
+		It("should call its basic operations", func() {
+			machineSet := newMachineSet("true")
+
+			msDeployer.EXPECT().List().Return([]unstructured.Unstructured{*machineSet}, nil).Once()
+			msDeployer.EXPECT().Deploy(machineSet).Return(nil).Once()
+			msDeployer.EXPECT().DeleteByName("gw", "ns").Return(nil).Once()
+
+			contextDeployer := ocp.WithContext(struct{ ocp.MachineSetDeployer }{msDeployer})
+
+			Expect(contextDeployer.ListWithContext(context.TODO())).To(HaveLen(1))
+			Expect(contextDeployer.DeployWithContext(context.TODO(), machineSet)).To(Succeed())
+			Expect(contextDeployer.DeleteByNameWithContext(context.TODO(), "gw", "ns")).To(Succeed())
+
+			_, err := contextDeployer.ListMachinesWithContext(context.TODO(), machineSet)
+			Expect(err).To(MatchError(ocp.ErrMachinesUnsupported))
+
+			Expect(contextDeployer.DeployHealthCheckWithContext(context.TODO(), machineSet, &api.HealthCheck{})).To(
+				MatchError(ocp.ErrHealthChecksUnsupported))
+			Expect(contextDeployer.DeployHealthCheckWithContext(context.TODO(), machineSet, nil)).To(Succeed())
+		})
+	})
+
+	When("the deployer is nil", func() {
+		It("should return nil", func() {
+			Expect(ocp.WithContext(nil)).To(BeNil())
+		})
+	})
 })
 
 func newMachineSet(isGateway string) *unstructured.Unstructured {
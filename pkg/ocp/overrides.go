@@ -0,0 +1,353 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+)
+
+// MachineSetOverrides customizes the gateway MachineSets generated by the providers before they're deployed. The fields
+// are applied in their declaration order, so that the patches have the final say.
+type MachineSetOverrides struct {
+	// NodeLabels are added to the labels of the gateway nodes, replacing the generated ones with the same keys.
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// Taints, if not nil, replace the node-role.submariner.io/gateway NoSchedule taint of the gateway nodes; an empty
+	// list removes it.
+	Taints []corev1.Taint `json:"taints,omitempty"`
+
+	// RootVolume customizes the root volume of the gateway nodes.
+	RootVolume *RootVolume `json:"rootVolume,omitempty"`
+
+	// CredentialsSecret is the name of the secret holding the cloud credentials of the machine API.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// UserDataSecret is the name of the secret holding the ignition configuration of the gateway nodes.
+	UserDataSecret string `json:"userDataSecret,omitempty"`
+
+	// ProviderSpecPatch is a JSON merge patch (RFC 7386) applied to the providerSpec value, which can add or replace any
+	// of its fields. The providerSpec is schemaless, so a strategic merge patch is equivalent.
+	ProviderSpecPatch json.RawMessage `json:"providerSpecPatch,omitempty"`
+
+	// JSONPatch is a JSON patch (RFC 6902) applied to the whole MachineSet. It must not change its name or namespace.
+	JSONPatch json.RawMessage `json:"jsonPatch,omitempty"`
+}
+
+// RootVolume describes the root volume of the gateway nodes. Only the fields which are set are overridden.
+type RootVolume struct {
+	SizeGB int64 `json:"sizeGB,omitempty"`
+
+	// Type is the provider's volume type, for example gp3 on AWS, pd-ssd on GCP or Premium_LRS on Azure.
+	Type string `json:"type,omitempty"`
+
+	// Encrypted requests EBS encryption on AWS and encryption at host on Azure. GCP always encrypts the disks, and on
+	// OpenStack the encryption is determined by the volume type, so they only accept true and unset respectively.
+	Encrypted *bool `json:"encrypted,omitempty"`
+}
+
+type overridingMachineSetDeployer struct {
+	ContextMachineSetDeployer
+	overrides *MachineSetOverrides
+}
+
+// NewOverridingMachineSetDeployer returns a MachineSetDeployer which applies the given overrides to the MachineSets
+// before deploying them with the given MachineSetDeployer.
+func NewOverridingMachineSetDeployer(deployer MachineSetDeployer, overrides *MachineSetOverrides) MachineSetDeployer {
+	return &overridingMachineSetDeployer{
+		ContextMachineSetDeployer: WithContext(deployer),
+		overrides:                 overrides,
+	}
+}
+
+func (d *overridingMachineSetDeployer) Deploy(machineSet *unstructured.Unstructured) error {
+	return d.DeployWithContext(context.TODO(), machineSet)
+}
+
+func (d *overridingMachineSetDeployer) DeployWithContext(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	overridden, err := d.overrides.Apply(machineSet)
+	if err != nil {
+		return err
+	}
+
+	return d.ContextMachineSetDeployer.DeployWithContext(ctx, overridden) //nolint:wrapcheck // Let the caller wrap it.
+}
+
+// Apply returns a copy of the given MachineSet with the overrides applied.
+func (o *MachineSetOverrides) Apply(machineSet *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	overridden := machineSet.DeepCopy()
+
+	if o == nil {
+		return overridden, nil
+	}
+
+	for key, value := range o.NodeLabels {
+		err := unstructured.SetNestedField(overridden.Object, value, "spec", "template", "spec", "metadata", "labels", key)
+		if err != nil {
+			return nil, errors.Wrap(err, "error setting the gateway node labels")
+		}
+	}
+
+	if o.Taints != nil {
+		if err := setTaints(overridden, o.Taints); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.applyToProviderSpec(overridden); err != nil {
+		return nil, err
+	}
+
+	if len(o.JSONPatch) > 0 {
+		return applyJSONPatch(overridden, o.JSONPatch)
+	}
+
+	return overridden, nil
+}
+
+func (o *MachineSetOverrides) applyToProviderSpec(machineSet *unstructured.Unstructured) error {
+	providerSpec, _, err := unstructured.NestedMap(machineSet.Object, providerSpecPath...)
+	if err != nil || providerSpec == nil {
+		return errors.Errorf("the MachineSet %q has no providerSpec value", machineSet.GetName())
+	}
+
+	kind, _, _ := unstructured.NestedString(providerSpec, "kind")
+
+	layout, found := providerSpecLayouts[kind]
+	if !found && (o.RootVolume != nil || o.CredentialsSecret != "") {
+		return errors.Errorf("the root volume and credentials secret can't be overridden for providerSpec kind %q", kind)
+	}
+
+	if o.RootVolume != nil {
+		if err := layout.setRootVolume(providerSpec, o.RootVolume); err != nil {
+			return errors.WithMessagef(err, "error overriding the root volume of %s", kind)
+		}
+	}
+
+	if o.CredentialsSecret != "" {
+		providerSpec[layout.credentialsSecretField] = map[string]interface{}{"name": o.CredentialsSecret}
+	}
+
+	if o.UserDataSecret != "" {
+		providerSpec["userDataSecret"] = map[string]interface{}{"name": o.UserDataSecret}
+	}
+
+	if len(o.ProviderSpecPatch) > 0 {
+		providerSpec, err = mergePatch(providerSpec, o.ProviderSpecPatch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(unstructured.SetNestedMap(machineSet.Object, providerSpec, providerSpecPath...),
+		"error setting the providerSpec value")
+}
+
+var providerSpecPath = []string{"spec", "template", "spec", "providerSpec", "value"}
+
+func setTaints(machineSet *unstructured.Unstructured, taints []corev1.Taint) error {
+	values := make([]interface{}, len(taints))
+
+	for i := range taints {
+		value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&taints[i])
+		if err != nil {
+			return errors.Wrapf(err, "error converting taint %q", taints[i].Key)
+		}
+
+		values[i] = value
+	}
+
+	return errors.Wrap(unstructured.SetNestedSlice(machineSet.Object, values, "spec", "template", "spec", "taints"),
+		"error setting the gateway node taints")
+}
+
+func mergePatch(providerSpec map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	original, err := json.Marshal(providerSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling the providerSpec value")
+	}
+
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "error applying the providerSpec patch")
+	}
+
+	result := map[string]interface{}{}
+
+	return result, errors.Wrap(utiljson.Unmarshal(patched, &result), "error unmarshaling the patched providerSpec value")
+}
+
+func applyJSONPatch(machineSet *unstructured.Unstructured, patchJSON []byte) (*unstructured.Unstructured, error) {
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding the MachineSet JSON patch")
+	}
+
+	original, err := machineSet.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling the MachineSet")
+	}
+
+	patched, err := patch.Apply(original)
+	if err != nil {
+		return nil, errors.Wrap(err, "error applying the MachineSet JSON patch")
+	}
+
+	result := &unstructured.Unstructured{}
+
+	err = result.UnmarshalJSON(patched)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling the patched MachineSet")
+	}
+
+	if result.GetName() != machineSet.GetName() || result.GetNamespace() != machineSet.GetNamespace() {
+		return nil, errors.New("the MachineSet JSON patch must not change its name or namespace")
+	}
+
+	return result, nil
+}
+
+// providerSpecLayout describes where the overridable fields are in a kind of providerSpec.
+type providerSpecLayout struct {
+	credentialsSecretField string
+	setRootVolume          func(providerSpec map[string]interface{}, volume *RootVolume) error
+}
+
+var providerSpecLayouts = map[string]providerSpecLayout{
+	"AWSMachineProviderConfig": {credentialsSecretField: "credentialsSecret", setRootVolume: setAWSRootVolume},
+	"GCPMachineProviderSpec":   {credentialsSecretField: "credentialsSecret", setRootVolume: setGCPRootVolume},
+	"AzureMachineProviderSpec": {credentialsSecretField: "credentialsSecret", setRootVolume: setAzureRootVolume},
+	"OpenstackProviderSpec":    {credentialsSecretField: "cloudsSecret", setRootVolume: setOpenStackRootVolume},
+}
+
+func setAWSRootVolume(providerSpec map[string]interface{}, volume *RootVolume) error {
+	blockDevices, _, _ := unstructured.NestedSlice(providerSpec, "blockDevices")
+	if len(blockDevices) == 0 {
+		blockDevices = []interface{}{map[string]interface{}{}}
+	}
+
+	rootDevice, ok := blockDevices[0].(map[string]interface{})
+	if !ok {
+		return errors.New("the first blockDevices entry isn't an object")
+	}
+
+	if volume.SizeGB != 0 {
+		setField(rootDevice, volume.SizeGB, "ebs", "volumeSize")
+	}
+
+	if volume.Type != "" {
+		setField(rootDevice, volume.Type, "ebs", "volumeType")
+	}
+
+	if volume.Encrypted != nil {
+		setField(rootDevice, *volume.Encrypted, "ebs", "encrypted")
+	}
+
+	blockDevices[0] = rootDevice
+
+	return errors.Wrap(unstructured.SetNestedSlice(providerSpec, blockDevices, "blockDevices"), "error setting the blockDevices")
+}
+
+func setGCPRootVolume(providerSpec map[string]interface{}, volume *RootVolume) error {
+	if volume.Encrypted != nil && !*volume.Encrypted {
+		return errors.New("GCP always encrypts the disks")
+	}
+
+	disks, _, _ := unstructured.NestedSlice(providerSpec, "disks")
+
+	for i := range disks {
+		disk, ok := disks[i].(map[string]interface{})
+		if !ok || disk["boot"] != true {
+			continue
+		}
+
+		if volume.SizeGB != 0 {
+			disk["sizeGb"] = volume.SizeGB
+		}
+
+		if volume.Type != "" {
+			disk["type"] = volume.Type
+		}
+
+		return errors.Wrap(unstructured.SetNestedSlice(providerSpec, disks, "disks"), "error setting the disks")
+	}
+
+	return errors.New("no boot disk found")
+}
+
+func setAzureRootVolume(providerSpec map[string]interface{}, volume *RootVolume) error {
+	if volume.SizeGB != 0 {
+		setField(providerSpec, volume.SizeGB, "osDisk", "diskSizeGB")
+	}
+
+	if volume.Type != "" {
+		setField(providerSpec, volume.Type, "osDisk", "managedDisk", "storageAccountType")
+	}
+
+	if volume.Encrypted != nil {
+		setField(providerSpec, *volume.Encrypted, "securityProfile", "encryptionAtHost")
+	}
+
+	return nil
+}
+
+func setOpenStackRootVolume(providerSpec map[string]interface{}, volume *RootVolume) error {
+	if volume.Encrypted != nil {
+		return errors.New("the root volume encryption is determined by its volume type on OpenStack")
+	}
+
+	if volume.SizeGB != 0 {
+		setField(providerSpec, volume.SizeGB, "rootVolume", "diskSize")
+	}
+
+	if volume.Type != "" {
+		setField(providerSpec, volume.Type, "rootVolume", "volumeType")
+	}
+
+	// A root volume is created from the image given as its source rather than from the server's image.
+	sourceUUID, _, _ := unstructured.NestedString(providerSpec, "rootVolume", "sourceUUID")
+	if image, _, _ := unstructured.NestedString(providerSpec, "image"); sourceUUID == "" && image != "" {
+		setField(providerSpec, image, "rootVolume", "sourceUUID")
+	}
+
+	return nil
+}
+
+// setField sets the field at the given path, creating the intermediate objects as needed. Intermediate values which
+// aren't objects are replaced.
+func setField(obj map[string]interface{}, value interface{}, fields ...string) {
+	for _, field := range fields[:len(fields)-1] {
+		child, ok := obj[field].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[field] = child
+		}
+
+		obj = child
+	}
+
+	obj[fields[len(fields)-1]] = value
+}
@@ -0,0 +1,252 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("MachineSetOverrides", func() {
+	var (
+		overrides  *ocp.MachineSetOverrides
+		machineSet *unstructured.Unstructured
+		result     *unstructured.Unstructured
+		err        error
+	)
+
+	BeforeEach(func() {
+		overrides = &ocp.MachineSetOverrides{}
+		machineSet = newProviderMachineSet(map[string]interface{}{
+			"kind":              "AWSMachineProviderConfig",
+			"credentialsSecret": map[string]interface{}{"name": "aws-cloud-credentials"},
+			"userDataSecret":    map[string]interface{}{"name": "worker-user-data"},
+			"instanceType":      "m5n.large",
+		})
+	})
+
+	JustBeforeEach(func() {
+		result, err = overrides.Apply(machineSet)
+	})
+
+	providerSpec := func() map[string]interface{} {
+		value, _, _ := unstructured.NestedMap(result.Object, "spec", "template", "spec", "providerSpec", "value")
+		return value
+	}
+
+	When("no overrides are set", func() {
+		It("should return an unchanged copy", func() {
+			Expect(err).To(Succeed())
+			Expect(result).To(Equal(machineSet))
+			Expect(result).ToNot(BeIdenticalTo(machineSet))
+		})
+	})
+
+	When("node labels and taints are set", func() {
+		BeforeEach(func() {
+			overrides.NodeLabels = map[string]string{"custom": "label", "node-role.kubernetes.io/infra": "false"}
+			overrides.Taints = []corev1.Taint{{Key: "custom", Effect: corev1.TaintEffectNoExecute}}
+		})
+
+		It("should add the labels and replace the taints", func() {
+			Expect(err).To(Succeed())
+
+			labels, _, _ := unstructured.NestedStringMap(result.Object, "spec", "template", "spec", "metadata", "labels")
+			Expect(labels).To(Equal(map[string]string{
+				"submariner.io/gateway":         "true",
+				"node-role.kubernetes.io/infra": "false",
+				"custom":                        "label",
+			}))
+
+			taints, _, _ := unstructured.NestedSlice(result.Object, "spec", "template", "spec", "taints")
+			Expect(taints).To(Equal([]interface{}{map[string]interface{}{"key": "custom", "effect": "NoExecute"}}))
+		})
+	})
+
+	When("an empty list of taints is set", func() {
+		BeforeEach(func() {
+			overrides.Taints = []corev1.Taint{}
+		})
+
+		It("should remove the taints", func() {
+			Expect(err).To(Succeed())
+
+			taints, _, _ := unstructured.NestedSlice(result.Object, "spec", "template", "spec", "taints")
+			Expect(taints).To(BeEmpty())
+		})
+	})
+
+	When("the root volume and secrets are set", func() {
+		BeforeEach(func() {
+			overrides.RootVolume = &ocp.RootVolume{SizeGB: 200, Type: "gp3", Encrypted: ptr.To(true)}
+			overrides.CredentialsSecret = "custom-credentials"
+			overrides.UserDataSecret = "custom-user-data"
+		})
+
+		It("should set them in the providerSpec", func() {
+			Expect(err).To(Succeed())
+			Expect(providerSpec()).To(Equal(map[string]interface{}{
+				"kind":              "AWSMachineProviderConfig",
+				"credentialsSecret": map[string]interface{}{"name": "custom-credentials"},
+				"userDataSecret":    map[string]interface{}{"name": "custom-user-data"},
+				"instanceType":      "m5n.large",
+				"blockDevices": []interface{}{map[string]interface{}{
+					"ebs": map[string]interface{}{"volumeSize": int64(200), "volumeType": "gp3", "encrypted": true},
+				}},
+			}))
+		})
+
+		Context("on GCP", func() {
+			BeforeEach(func() {
+				overrides.RootVolume.Encrypted = nil
+				machineSet = newProviderMachineSet(map[string]interface{}{
+					"kind": "GCPMachineProviderSpec",
+					"disks": []interface{}{map[string]interface{}{
+						"boot": true, "sizeGb": int64(128), "type": "pd-ssd", "image": "test-image",
+					}},
+				})
+			})
+
+			It("should set the boot disk", func() {
+				Expect(err).To(Succeed())
+				Expect(providerSpec()["disks"]).To(Equal([]interface{}{map[string]interface{}{
+					"boot": true, "sizeGb": int64(200), "type": "gp3", "image": "test-image",
+				}}))
+			})
+		})
+
+		Context("on OpenStack with encryption", func() {
+			BeforeEach(func() {
+				machineSet = newProviderMachineSet(map[string]interface{}{"kind": "OpenstackProviderSpec"})
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	When("a providerSpec patch is set", func() {
+		BeforeEach(func() {
+			overrides.ProviderSpecPatch = json.RawMessage(`{"instanceType": "c5.xlarge", "spotMarketOptions": {}, "userDataSecret": null}`)
+		})
+
+		It("should merge it into the providerSpec", func() {
+			Expect(err).To(Succeed())
+			Expect(providerSpec()).To(Equal(map[string]interface{}{
+				"kind":              "AWSMachineProviderConfig",
+				"credentialsSecret": map[string]interface{}{"name": "aws-cloud-credentials"},
+				"instanceType":      "c5.xlarge",
+				"spotMarketOptions": map[string]interface{}{},
+			}))
+		})
+	})
+
+	When("a JSON patch is set", func() {
+		BeforeEach(func() {
+			overrides.JSONPatch = json.RawMessage(`[{"op": "replace", "path": "/spec/replicas", "value": 2}]`)
+		})
+
+		It("should apply it to the MachineSet", func() {
+			Expect(err).To(Succeed())
+
+			replicas, _, _ := unstructured.NestedInt64(result.Object, "spec", "replicas")
+			Expect(replicas).To(Equal(int64(2)))
+		})
+
+		Context("which renames the MachineSet", func() {
+			BeforeEach(func() {
+				overrides.JSONPatch = json.RawMessage(`[{"op": "replace", "path": "/metadata/name", "value": "other"}]`)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
+
+var _ = Describe("Overriding MachineSetDeployer", func() {
+	It("should deploy the overridden MachineSet", func() {
+		inner := fake.NewMockMachineSetDeployer(GinkgoT())
+
+		var deployed *unstructured.Unstructured
+
+		inner.EXPECT().DeployWithContext(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, ms *unstructured.Unstructured) error {
+				deployed = ms
+				return nil
+			})
+
+		machineSet := newProviderMachineSet(map[string]interface{}{"kind": "AzureMachineProviderSpec"})
+
+		deployer := ocp.NewOverridingMachineSetDeployer(inner, &ocp.MachineSetOverrides{
+			RootVolume: &ocp.RootVolume{SizeGB: 256, Type: "StandardSSD_LRS"},
+		})
+		Expect(deployer.Deploy(machineSet)).To(Succeed())
+
+		Expect(deployed).ToNot(BeNil())
+		Expect(deployed.GetName()).To(Equal(machineSet.GetName()))
+
+		osDisk, _, _ := unstructured.NestedMap(deployed.Object, "spec", "template", "spec", "providerSpec", "value", "osDisk")
+		Expect(osDisk).To(Equal(map[string]interface{}{
+			"diskSizeGB":  int64(256),
+			"managedDisk": map[string]interface{}{"storageAccountType": "StandardSSD_LRS"},
+		}))
+	})
+})
+
+func newProviderMachineSet(providerSpec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "machine.openshift.io/v1beta1",
+		"kind":       "MachineSet",
+		"metadata": map[string]interface{}{
+			"name":      "test-infraID-submariner-gw-zone",
+			"namespace": "openshift-machine-api",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": map[string]interface{}{
+							"submariner.io/gateway":         "true",
+							"node-role.kubernetes.io/infra": "",
+						},
+					},
+					"taints": []interface{}{
+						map[string]interface{}{"effect": "NoSchedule", "key": "node-role.submariner.io/gateway"},
+					},
+					"providerSpec": map[string]interface{}{
+						"value": providerSpec,
+					},
+				},
+			},
+		},
+	}}
+}
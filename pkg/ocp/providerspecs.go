@@ -0,0 +1,225 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretReference refers to a secret, in the namespace of the machine unless specified.
+type SecretReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// AWSMachineProviderConfig is the providerSpec value of the AWS machines (awsproviderconfig.openshift.io/v1beta1).
+type AWSMachineProviderConfig struct {
+	APIVersion         string                 `json:"apiVersion"`
+	Kind               string                 `json:"kind"`
+	AMI                AWSResourceReference   `json:"ami"`
+	CredentialsSecret  *SecretReference       `json:"credentialsSecret,omitempty"`
+	DeviceIndex        int64                  `json:"deviceIndex"`
+	IAMInstanceProfile *AWSResourceReference  `json:"iamInstanceProfile,omitempty"`
+	InstanceType       string                 `json:"instanceType"`
+	Placement          AWSPlacement           `json:"placement"`
+	SecurityGroups     []AWSResourceReference `json:"securityGroups,omitempty"`
+	Subnet             AWSResourceReference   `json:"subnet"`
+	Tags               []AWSTag               `json:"tags,omitempty"`
+	UserDataSecret     *SecretReference       `json:"userDataSecret,omitempty"`
+	PublicIP           bool                   `json:"publicIp"`
+}
+
+// AWSResourceReference identifies an AWS resource either by ID or by filters.
+type AWSResourceReference struct {
+	ID      string      `json:"id,omitempty"`
+	Filters []AWSFilter `json:"filters,omitempty"`
+}
+
+type AWSFilter struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type AWSPlacement struct {
+	AvailabilityZone string `json:"availabilityZone"`
+	Region           string `json:"region"`
+}
+
+type AWSTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewAWSMachineProviderConfig returns an AWSMachineProviderConfig with the API version and kind set.
+func NewAWSMachineProviderConfig() *AWSMachineProviderConfig {
+	return &AWSMachineProviderConfig{
+		APIVersion: "awsproviderconfig.openshift.io/v1beta1",
+		Kind:       "AWSMachineProviderConfig",
+	}
+}
+
+// GCPMachineProviderSpec is the providerSpec value of the GCP machines (gcpprovider.openshift.io/v1beta1).
+type GCPMachineProviderSpec struct {
+	APIVersion         string                `json:"apiVersion"`
+	Kind               string                `json:"kind"`
+	CanIPForward       bool                  `json:"canIPForward"`
+	CredentialsSecret  *SecretReference      `json:"credentialsSecret,omitempty"`
+	DeletionProtection bool                  `json:"deletionProtection"`
+	Disks              []GCPDisk             `json:"disks"`
+	Labels             map[string]string     `json:"labels,omitempty"`
+	MachineType        string                `json:"machineType"`
+	Metadata           []GCPMetadata         `json:"metadata"`
+	NetworkInterfaces  []GCPNetworkInterface `json:"networkInterfaces"`
+	ProjectID          string                `json:"projectID"`
+	Region             string                `json:"region"`
+	ServiceAccounts    []GCPServiceAccount   `json:"serviceAccounts"`
+	Tags               []string              `json:"tags,omitempty"`
+	UserDataSecret     *SecretReference      `json:"userDataSecret,omitempty"`
+	Zone               string                `json:"zone"`
+}
+
+type GCPDisk struct {
+	AutoDelete bool              `json:"autoDelete"`
+	Boot       bool              `json:"boot"`
+	Image      string            `json:"image"`
+	Labels     map[string]string `json:"labels"`
+	SizeGB     int64             `json:"sizeGb"`
+	Type       string            `json:"type"`
+}
+
+type GCPMetadata struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value"`
+}
+
+type GCPNetworkInterface struct {
+	Network    string `json:"network"`
+	Subnetwork string `json:"subnetwork"`
+	PublicIP   bool   `json:"publicIP"`
+}
+
+type GCPServiceAccount struct {
+	Email  string   `json:"email"`
+	Scopes []string `json:"scopes"`
+}
+
+// NewGCPMachineProviderSpec returns a GCPMachineProviderSpec with the API version and kind set.
+func NewGCPMachineProviderSpec() *GCPMachineProviderSpec {
+	return &GCPMachineProviderSpec{
+		APIVersion: "gcpprovider.openshift.io/v1beta1",
+		Kind:       "GCPMachineProviderSpec",
+	}
+}
+
+// AzureMachineProviderSpec is the providerSpec value of the Azure machines (azureproviderconfig.openshift.io/v1beta1).
+type AzureMachineProviderSpec struct {
+	APIVersion           string            `json:"apiVersion"`
+	Kind                 string            `json:"kind"`
+	CredentialsSecret    *SecretReference  `json:"credentialsSecret,omitempty"`
+	Image                AzureImage        `json:"image"`
+	InternalLoadBalancer string            `json:"internalLoadBalancer"`
+	Location             string            `json:"location"`
+	ManagedIdentity      string            `json:"managedIdentity"`
+	NatRule              *int64            `json:"natRule"`
+	NetworkResourceGroup string            `json:"networkResourceGroup"`
+	OSDisk               AzureOSDisk       `json:"osDisk"`
+	PublicIP             bool              `json:"publicIP"`
+	PublicLoadBalancer   string            `json:"publicLoadBalancer"`
+	ResourceGroup        string            `json:"resourceGroup"`
+	SecurityGroup        string            `json:"securityGroup"`
+	SSHPrivateKey        string            `json:"sshPrivateKey"`
+	SSHPublicKey         string            `json:"sshPublicKey"`
+	Subnet               string            `json:"subnet"`
+	Tags                 map[string]string `json:"tags,omitempty"`
+	UserDataSecret       *SecretReference  `json:"userDataSecret,omitempty"`
+	VMSize               string            `json:"vmSize"`
+	Vnet                 string            `json:"vnet"`
+	Zone                 string            `json:"zone"`
+}
+
+// AzureImage identifies the image of the machines, either by resource ID or by marketplace offer.
+type AzureImage struct {
+	Offer      string `json:"offer"`
+	Publisher  string `json:"publisher"`
+	ResourceID string `json:"resourceID"`
+	SKU        string `json:"sku"`
+	Version    string `json:"version"`
+}
+
+type AzureOSDisk struct {
+	DiskSizeGB  int32            `json:"diskSizeGB"`
+	ManagedDisk AzureManagedDisk `json:"managedDisk"`
+	OSType      string           `json:"osType"`
+}
+
+type AzureManagedDisk struct {
+	StorageAccountType string `json:"storageAccountType"`
+}
+
+// NewAzureMachineProviderSpec returns an AzureMachineProviderSpec with the API version and kind set.
+func NewAzureMachineProviderSpec() *AzureMachineProviderSpec {
+	return &AzureMachineProviderSpec{
+		APIVersion: "azureproviderconfig.openshift.io/v1beta1",
+		Kind:       "AzureMachineProviderSpec",
+	}
+}
+
+// OpenstackProviderSpec is the providerSpec value of the OpenStack machines (openstackproviderconfig.openshift.io/v1alpha1).
+type OpenstackProviderSpec struct {
+	APIVersion     string                   `json:"apiVersion"`
+	Kind           string                   `json:"kind"`
+	Metadata       metav1.ObjectMeta        `json:"metadata"`
+	CloudName      string                   `json:"cloudName"`
+	CloudsSecret   *SecretReference         `json:"cloudsSecret,omitempty"`
+	Flavor         string                   `json:"flavor"`
+	Image          string                   `json:"image"`
+	Networks       []OpenstackNetwork       `json:"networks,omitempty"`
+	SecurityGroups []OpenstackSecurityGroup `json:"securityGroups,omitempty"`
+	ServerMetadata map[string]string        `json:"serverMetadata,omitempty"`
+	Tags           []string                 `json:"tags,omitempty"`
+	Trunk          bool                     `json:"trunk"`
+	UserDataSecret *SecretReference         `json:"userDataSecret,omitempty"`
+}
+
+type OpenstackNetwork struct {
+	Filter  OpenstackFilter   `json:"filter"`
+	Subnets []OpenstackSubnet `json:"subnets,omitempty"`
+}
+
+type OpenstackSubnet struct {
+	Filter OpenstackFilter `json:"filter"`
+}
+
+// OpenstackFilter selects networks or subnets by name and tags; empty fields match everything.
+type OpenstackFilter struct {
+	Name string `json:"name,omitempty"`
+	Tags string `json:"tags,omitempty"`
+}
+
+type OpenstackSecurityGroup struct {
+	Name string `json:"name"`
+}
+
+// NewOpenstackProviderSpec returns an OpenstackProviderSpec with the API version and kind set.
+func NewOpenstackProviderSpec() *OpenstackProviderSpec {
+	return &OpenstackProviderSpec{
+		APIVersion: "openstackproviderconfig.openshift.io/v1alpha1",
+		Kind:       "OpenstackProviderSpec",
+	}
+}
@@ -0,0 +1,194 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	machinePhaseRunning = "Running"
+	machinePhaseFailed  = "Failed"
+)
+
+// GatewayPollInterval is the interval at which the progress of the gateway machines and nodes is checked.
+var GatewayPollInterval = 5 * time.Second
+
+type gatewayWaiter struct {
+	msDeployer ContextMachineSetDeployer
+	k8sClient  k8s.ContextInterface
+	status     reporter.Interface
+	// reported holds the last progress reported for each machine, so that only changes are reported.
+	reported map[string]string
+	pending  []string
+}
+
+// WaitForGateways waits, for up to the given timeout, for the machines of the gateway machine sets to be Running, and for
+// their nodes to be Ready and labelled as gateways. The progress of each machine is reported as it changes, and an error
+// is returned as soon as a machine reports a failure. If the timeout is zero, it returns immediately.
+func WaitForGateways(ctx context.Context, msDeployer MachineSetDeployer, k8sClient k8s.Interface, timeout time.Duration,
+	status reporter.Interface,
+) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	if k8sClient == nil {
+		return errors.New("a K8sClient is required to wait for the gateway nodes")
+	}
+
+	status.Start("Waiting for the gateway machines and nodes to be ready")
+	defer status.End()
+
+	w := &gatewayWaiter{
+		msDeployer: WithContext(msDeployer),
+		k8sClient:  k8s.WithContext(k8sClient),
+		status:     status,
+		reported:   map[string]string{},
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, GatewayPollInterval, timeout, true, w.ready)
+	if wait.Interrupted(err) {
+		err = errors.Errorf("timed out after %v waiting for the gateways: %s", timeout, strings.Join(w.pending, "; "))
+	}
+
+	if err != nil {
+		return status.Error(err, "the gateways aren't ready")
+	}
+
+	status.Success("The gateway machines and nodes are ready")
+
+	return nil
+}
+
+func (w *gatewayWaiter) ready(ctx context.Context) (bool, error) {
+	w.pending = nil
+
+	machineSets, err := w.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return false, err //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	gwNodes, err := w.k8sClient.ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	readyNodes := map[string]bool{}
+
+	for i := range gwNodes.Items {
+		readyNodes[gwNodes.Items[i].Name] = k8s.IsNodeReady(&gwNodes.Items[i])
+	}
+
+	for i := range machineSets {
+		err = w.checkMachineSet(ctx, &machineSets[i], readyNodes)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return len(w.pending) == 0, nil
+}
+
+func (w *gatewayWaiter) checkMachineSet(ctx context.Context, machineSet *unstructured.Unstructured, readyNodes map[string]bool) error {
+	machines, err := w.msDeployer.ListMachinesWithContext(ctx, machineSet)
+	if err != nil {
+		return err //nolint:wrapcheck // Let the caller wrap it.
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(machineSet.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	if int64(len(machines)) < replicas {
+		w.pending = append(w.pending, fmt.Sprintf("machine set %s has %d of %d machine(s)", machineSet.GetName(), len(machines), replicas))
+	}
+
+	for i := range machines {
+		machine := &machines[i]
+
+		if machine.Phase == machinePhaseFailed || machine.FailureReason != "" {
+			return errors.Errorf("gateway machine %q failed: %s", machine.Name, machine.failure())
+		}
+
+		progress := machine.Phase
+
+		switch {
+		case machine.Phase != machinePhaseRunning || machine.NodeName == "":
+			w.pending = append(w.pending, "machine "+machine.Name+" is "+phaseOrPending(machine.Phase))
+		case !readyNodes[machine.NodeName]:
+			w.pending = append(w.pending, "node "+machine.NodeName+" isn't Ready and labelled as a gateway")
+		default:
+			progress = "ready"
+		}
+
+		w.report(machine, progress)
+	}
+
+	return nil
+}
+
+func (w *gatewayWaiter) report(machine *Machine, progress string) {
+	if progress == "" || w.reported[machine.Name] == progress {
+		return
+	}
+
+	w.reported[machine.Name] = progress
+
+	if progress == "ready" {
+		w.status.Success("Gateway node %q of machine %q is Ready", machine.NodeName, machine.Name)
+	} else {
+		w.status.Success("Gateway machine %q is %s", machine.Name, progress)
+	}
+}
+
+// failure describes why the machine failed, as reported by the machine API.
+func (m *Machine) failure() string {
+	var details []string
+
+	for _, detail := range []string{m.FailureReason, m.FailureMessage} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+
+	if len(details) == 0 {
+		return "no reason reported"
+	}
+
+	return strings.Join(details, ": ")
+}
+
+func phaseOrPending(phase string) string {
+	if phase == "" {
+		return "pending"
+	}
+
+	return phase
+}
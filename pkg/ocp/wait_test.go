@@ -0,0 +1,140 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("WaitForGateways", func() {
+	const (
+		machineName = "test-gw-machine"
+		nodeName    = "test-gw-node"
+	)
+
+	var (
+		msDeployer *ocpFake.MockMachineSetDeployer
+		kubeClient *kubeFake.Clientset
+		machine    ocp.Machine
+		timeout    time.Duration
+		err        error
+	)
+
+	BeforeEach(func() {
+		msDeployer = ocpFake.NewMockMachineSetDeployer(GinkgoT())
+		kubeClient = kubeFake.NewClientset()
+		machine = ocp.Machine{Name: machineName, Phase: "Running", NodeName: nodeName}
+		timeout = 100 * time.Millisecond
+
+		machineSet := newMachineSet("true")
+		machineSet.SetName("test-gw")
+
+		msDeployer.EXPECT().ListWithContext(mock.Anything).Return([]unstructured.Unstructured{*machineSet}, nil).Maybe()
+		msDeployer.EXPECT().ListMachinesWithContext(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, _ *unstructured.Unstructured) ([]ocp.Machine, error) {
+				return []ocp.Machine{machine}, nil
+			}).Maybe()
+	})
+
+	JustBeforeEach(func() {
+		err = ocp.WaitForGateways(context.Background(), msDeployer, k8s.NewInterface(kubeClient), timeout, reporter.Stdout())
+	})
+
+	createNode := func(ready corev1.ConditionStatus) {
+		_, err := kubeClient.CoreV1().Nodes().Create(context.Background(), &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   nodeName,
+				Labels: map[string]string{ocp.SubmarinerGatewayLabel: "true"},
+			},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}, metav1.CreateOptions{})
+		Expect(err).To(Succeed())
+	}
+
+	When("the machine is running and its node is Ready", func() {
+		BeforeEach(func() {
+			createNode(corev1.ConditionTrue)
+		})
+
+		It("should succeed", func() {
+			Expect(err).To(Succeed())
+		})
+	})
+
+	When("the machine is still provisioning", func() {
+		BeforeEach(func() {
+			machine.Phase = "Provisioning"
+			machine.NodeName = ""
+		})
+
+		It("should time out", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("machine " + machineName + " is Provisioning"))
+		})
+	})
+
+	When("the node isn't Ready", func() {
+		BeforeEach(func() {
+			createNode(corev1.ConditionFalse)
+		})
+
+		It("should time out", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("node " + nodeName + " isn't Ready"))
+		})
+	})
+
+	When("the machine reports a failure", func() {
+		BeforeEach(func() {
+			machine.Phase = "Failed"
+			machine.FailureReason = "InvalidConfiguration"
+			machine.FailureMessage = "unknown instance type"
+			timeout = time.Minute
+		})
+
+		It("should return the failure without waiting", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("InvalidConfiguration: unknown instance type"))
+		})
+	})
+
+	When("the timeout is zero", func() {
+		BeforeEach(func() {
+			timeout = 0
+		})
+
+		It("should not wait", func() {
+			Expect(err).To(Succeed())
+			Expect(msDeployer.Calls).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,59 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+func classifyError(err error) error {
+	return api.ClassifyError(ProviderName, err, errorKind)
+}
+
+// errorKind maps the OpenStack API errors by HTTP status code. Nova and Neutron report exceeded quotas with a 403 or a 409
+// whose message mentions the quota, or with a 413.
+func errorKind(err error) error {
+	var codeErr gophercloud.StatusCodeError
+	if !errors.As(err, &codeErr) {
+		return nil
+	}
+
+	code := codeErr.GetStatusCode()
+	quota := strings.Contains(strings.ToLower(codeErr.Error()), "quota")
+
+	switch {
+	case code == http.StatusNotFound:
+		return api.ErrNotFound
+	case code == http.StatusRequestEntityTooLarge, quota && (code == http.StatusForbidden || code == http.StatusConflict):
+		return api.ErrQuotaExceeded
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return api.ErrPermissionDenied
+	case code == http.StatusConflict:
+		return api.ErrConflict
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError:
+		return api.ErrTransient
+	}
+
+	return nil
+}
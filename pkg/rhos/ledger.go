@@ -0,0 +1,81 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+)
+
+func ledgerEntry(operation ledger.Operation, resource api.ResourceType, id, parent string) ledger.Entry {
+	return ledger.Entry{
+		Provider:  ProviderName,
+		Operation: operation,
+		Resource:  resource,
+		ID:        id,
+		Parent:    parent,
+	}
+}
+
+// undo undoes the change recorded in the given ledger entry. Objects which no longer exist are ignored.
+func (c *CloudInfo) undo(_ context.Context, entry *ledger.Entry) error {
+	computeClient, err := openstack.NewComputeV2(c.Client, gophercloud.EndpointOpts{Region: c.Region})
+	if err != nil {
+		return errors.Wrap(err, "error creating the compute client")
+	}
+
+	switch entry.Resource {
+	case api.SecurityGroupResource:
+		return c.deleteSG(entry.ID, computeClient)
+	case api.InstanceResource:
+		err = secgroups.RemoveServer(computeClient, entry.ID, entry.Parent).ExtractErr()
+		if errors.As(err, &gophercloud.ErrDefault404{}) {
+			return nil
+		}
+
+		return errors.WithMessagef(err, "failed to remove the security group %q from the server %q", entry.Parent, entry.ID)
+	}
+
+	return errors.Errorf("unsupported resource %q", entry.Resource)
+}
+
+func (d *ocpGatewayDeployer) undo(ctx context.Context, entry *ledger.Entry) error {
+	if entry.Resource == api.MachineSetResource {
+		return errors.Wrapf(d.msDeployer.DeleteByNameWithContext(ctx, entry.ID, entry.Parent), "error deleting machine set %q", entry.ID)
+	}
+
+	return d.CloudInfo.undo(ctx, entry)
+}
+
+// undoChange returns the planned change undoing the given ledger entry, as undo would.
+func undoChange(entry *ledger.Entry) api.Change {
+	if entry.Resource == api.InstanceResource {
+		return api.Change{Action: api.ChangeUpdate, Resource: entry.Resource, Name: entry.ID,
+			Details: fmt.Sprintf("remove security group %q", entry.Parent)}
+	}
+
+	return api.Change{Action: api.ChangeDelete, Resource: entry.Resource, Name: entry.ID}
+}
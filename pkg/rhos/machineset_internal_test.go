@@ -0,0 +1,113 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+)
+
+var _ = Describe("Gateway MachineSet", func() {
+	var (
+		tags          map[string]string
+		useInternalSG bool
+	)
+
+	BeforeEach(func() {
+		tags = nil
+		useInternalSG = false
+	})
+
+	build := func() *unstructured.Unstructured {
+		d := &ocpGatewayDeployer{
+			CloudInfo: CloudInfo{
+				InfraID: "test-infra",
+				Region:  "regionOne",
+				Tags:    tags,
+			},
+			projectID:    "test-project",
+			instanceType: "m1.large",
+			image:        "rhcos",
+			cloudName:    "openstack",
+		}
+
+		machineSet, err := d.newMachineSet("abcdef", useInternalSG)
+		Expect(err).To(Succeed())
+
+		return machineSet
+	}
+
+	It("should match the golden file", func() {
+		Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-default.yaml")))
+	})
+
+	When("the internal security group is used", func() {
+		BeforeEach(func() {
+			useInternalSG = true
+		})
+
+		It("should match the golden file", func() {
+			Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-default-internal-sg.yaml")))
+		})
+	})
+
+	When("custom tags are configured", func() {
+		BeforeEach(func() {
+			tags = map[string]string{"team": "a: b", "cost-center": "42", "Name": "x"}
+		})
+
+		It("should match the golden file", func() {
+			Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-tags.yaml")))
+		})
+
+		Context("and the internal security group is used", func() {
+			BeforeEach(func() {
+				useInternalSG = true
+			})
+
+			It("should match the golden file", func() {
+				Expect(build()).To(Equal(loadGoldenMachineSet("testdata/gw-machineset-tags-internal-sg.yaml")))
+			})
+		})
+	})
+
+	It("should generate a random name", func() {
+		d := &ocpGatewayDeployer{CloudInfo: CloudInfo{InfraID: "test-infra"}}
+
+		machineSet, err := d.initMachineSet(false)
+		Expect(err).To(Succeed())
+		Expect(machineSet.GetName()).To(MatchRegexp("^test-infra-submariner-gw-[0-9a-f]{6}$"))
+	})
+})
+
+func loadGoldenMachineSet(path string) *unstructured.Unstructured {
+	data, err := os.ReadFile(path)
+	Expect(err).To(Succeed())
+
+	machineSet := &unstructured.Unstructured{}
+
+	_, _, err = yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(data, nil, machineSet)
+	Expect(err).To(Succeed())
+
+	return machineSet
+}
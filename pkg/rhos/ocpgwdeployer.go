@@ -19,20 +19,20 @@ limitations under the License.
 package rhos
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/apimachinery/pkg/util/uuid"
 )
 
@@ -42,7 +42,7 @@ type ocpGatewayDeployer struct {
 	instanceType string
 	image        string
 	cloudName    string
-	msDeployer   ocp.MachineSetDeployer
+	msDeployer   ocp.ContextMachineSetDeployer
 }
 
 // NewOcpGatewayDeployer returns a GatewayDeployer capable of deploying gateways using OCP.
@@ -54,71 +54,59 @@ func NewOcpGatewayDeployer(info CloudInfo, msDeployer ocp.MachineSetDeployer, pr
 		instanceType: instanceType,
 		image:        image,
 		cloudName:    cloudName,
-		msDeployer:   msDeployer,
+		msDeployer:   ocp.WithContext(msDeployer),
 	}
 }
 
-type machineSetConfig struct {
-	UUID                    string
-	InfraID                 string
-	ProjectID               string
-	InstanceType            string
-	Region                  string
-	Image                   string
-	SubmarinerGWNodeTag     string
-	CloudName               string
-	UseSubmarinerInternalSG bool
+func (d *ocpGatewayDeployer) initMachineSet(useInternalSG bool) (*unstructured.Unstructured, error) {
+	return d.newMachineSet(string(uuid.NewUUID())[0:6], useInternalSG)
 }
 
-func (d *ocpGatewayDeployer) loadGatewayYAML(uuidGW, image string, useInternalSG bool) ([]byte, error) {
-	var buf bytes.Buffer
-
-	tpl, err := template.New("").Parse(machineSetYAML)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create machine set template")
+func (d *ocpGatewayDeployer) newMachineSet(uuidGW string, useInternalSG bool) (*unstructured.Unstructured, error) {
+	providerSpec := ocp.NewOpenstackProviderSpec()
+	providerSpec.CloudName = d.cloudName
+	providerSpec.CloudsSecret = &ocp.SecretReference{Name: "openstack-cloud-credentials"}
+	providerSpec.Flavor = d.instanceType
+	providerSpec.Image = d.image
+	providerSpec.Networks = []ocp.OpenstackNetwork{{
+		Subnets: []ocp.OpenstackSubnet{{
+			Filter: ocp.OpenstackFilter{Name: d.InfraID + "-nodes", Tags: "openshiftClusterID=" + d.InfraID},
+		}},
+	}}
+
+	securityGroups := []string{d.InfraID + "-worker"}
+	if useInternalSG {
+		securityGroups = append(securityGroups, d.InfraID+internalSecurityGroupSuffix)
 	}
 
-	tplVars := machineSetConfig{
-		UUID:                    uuidGW,
-		InfraID:                 d.InfraID,
-		ProjectID:               d.projectID,
-		InstanceType:            d.instanceType,
-		Region:                  d.Region,
-		CloudName:               d.cloudName,
-		Image:                   image,
-		SubmarinerGWNodeTag:     submarinerGatewayNodeTag,
-		UseSubmarinerInternalSG: useInternalSG,
+	for _, name := range append(securityGroups, d.InfraID+gwSecurityGroupSuffix) {
+		providerSpec.SecurityGroups = append(providerSpec.SecurityGroups, ocp.OpenstackSecurityGroup{Name: name})
 	}
 
-	err = tpl.Execute(&buf, tplVars)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to execute machine set template")
+	// The custom tags can't replace the metadata identifying the cluster's workers.
+	providerSpec.ServerMetadata = map[string]string{}
+	for name, value := range d.Tags {
+		providerSpec.ServerMetadata[name] = value
 	}
 
-	return buf.Bytes(), nil
-}
+	providerSpec.ServerMetadata["Name"] = d.InfraID + "-worker"
+	providerSpec.ServerMetadata["openshiftClusterID"] = d.InfraID
 
-func (d *ocpGatewayDeployer) initMachineSet(useInternalSG bool) (*unstructured.Unstructured, error) {
-	gatewayYAML, err := d.loadGatewayYAML(string(uuid.NewUUID())[0:6], d.image, useInternalSG)
-	if err != nil {
-		return nil, err
-	}
-
-	unstructDecoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	machineSet := &unstructured.Unstructured{}
-	_, _, err = unstructDecoder.Decode(gatewayYAML, nil, machineSet)
+	providerSpec.Tags = append([]string{"openshiftClusterID=" + d.InfraID, submarinerGatewayNodeTag}, d.tagList()...)
+	providerSpec.Trunk = true
+	providerSpec.UserDataSecret = &ocp.SecretReference{Name: "worker-user-data"}
 
-	return machineSet, errors.Wrap(err, "error decoding message gateway yaml")
+	return ocp.NewGatewayMachineSet(d.InfraID, d.InfraID+"-submariner-gw-"+uuidGW, providerSpec).WithWorkerRoleLabels().ToUnstructured()
 }
 
-func (d *ocpGatewayDeployer) deployGateway(useInternalSG bool) error {
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, useInternalSG bool) error {
 	machineSet, err := d.initMachineSet(useInternalSG)
 	if err != nil {
 		return err
 	}
 
 	if d.image == "" {
-		d.image, err = d.msDeployer.GetWorkerNodeImage(machineSet, d.InfraID)
+		d.image, err = d.msDeployer.GetWorkerNodeImageWithContext(ctx, machineSet, d.InfraID)
 		if err != nil {
 			return errors.Wrap(err, "error getting the worker image")
 		}
@@ -129,10 +117,40 @@ func (d *ocpGatewayDeployer) deployGateway(useInternalSG bool) error {
 		}
 	}
 
-	return errors.Wrap(d.msDeployer.Deploy(machineSet), "failed to deploy submariner gateway node")
+	err = d.msDeployer.DeployWithContext(ctx, machineSet)
+	if err != nil {
+		return errors.Wrap(err, "failed to deploy submariner gateway node")
+	}
+
+	return d.Ledger.Record(ctx, ledgerEntry(ledger.Deploy, api.MachineSetResource, machineSet.GetName(), machineSet.GetNamespace()))
 }
 
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporter.Interface) error {
+	return d.DeployWithContext(context.TODO(), input, status)
+}
+
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	err := d.deploy(ctx, input, status)
+	if err == nil {
+		err = ocp.DeployHealthChecks(ctx, d.msDeployer, input.HealthCheck, status)
+	}
+
+	if err == nil {
+		err = ocp.WaitForGateways(ctx, d.msDeployer, d.K8sClient, input.WaitTimeout, status)
+	}
+
+	return classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) error {
+	if err := api.ValidatePorts(input.PublicPorts); err != nil {
+		return errors.WithMessage(err, "invalid public ports")
+	}
+
+	if input.IPFamily == "" {
+		input.IPFamily = d.IPFamily
+	}
+
 	status.Start("Configuring the required firewall rules for inter-cluster traffic")
 	defer status.End()
 
@@ -147,25 +165,23 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 	}
 
 	groupName := d.InfraID + gwSecurityGroupSuffix
-	if err := d.createGWSecurityGroup(input.PublicPorts, groupName, computeClient, networkClient); err != nil {
+	if err := d.createGWSecurityGroup(ctx, input.PublicPorts, input.PublicSourceCIDRs(), groupName, computeClient, networkClient); err != nil {
 		return status.Error(err, "creating gateway security group failed")
 	}
 
-	machineSets, err := d.msDeployer.List()
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
 	if err != nil {
 		return status.Error(err, "error getting the gateway machinesets")
 	}
 
-	gwNodes, err := d.K8sClient.ListGatewayNodes()
+	gwNodes, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
 	if err != nil {
 		return status.Error(err, "listing the existing gateway nodes failed")
 	}
 
-	gwNodeItems := gwNodes.Items
-
 	gwNodesList := gwNodes.Items
 	for i := range gwNodesList {
-		err := d.openGatewayPort(groupName, gwNodesList[i].Name, computeClient)
+		err := d.openGatewayPort(ctx, groupName, gwNodesList[i].Name, computeClient)
 		if err != nil {
 			return status.Error(err, "failed to open the gateway port in the existing g/w node")
 		}
@@ -174,24 +190,31 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, status reporte
 	status.Success("Opened external ports %q in security group %q on RHOS for existing g/w nodes",
 		formatPorts(input.PublicPorts), groupName)
 
-	taggedExistingNodes := ocp.RemoveDuplicates(machineSets, gwNodeItems)
-	gatewayNodesToDeploy := input.Gateways - len(machineSets) - len(taggedExistingNodes)
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodesList)
+	if err != nil {
+		return status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	gatewayNodesToDeploy := input.Gateways - len(machineSets) - len(mapping.Labeled)
 
 	if gatewayNodesToDeploy == 0 {
 		status.Success("Current Submariner gateways match the required number of Submariner gateways")
 		return nil
 	}
 
-	return d.deployGWNode(input.Gateways, computeClient,
-		len(machineSets)+len(taggedExistingNodes), status)
+	// When decreasing the number of Gateway nodes, only the dedicated ones are removed, and never the active one
+	// as that would impact the datapath.
+	if gatewayNodesToDeploy < 0 {
+		return d.removeSurplusGateways(ctx, machineSets, mapping, -gatewayNodesToDeploy, computeClient, status)
+	}
+
+	return d.deployGWNode(ctx, input.Gateways, computeClient,
+		len(machineSets)+len(mapping.Labeled), status)
 }
 
-func (d *ocpGatewayDeployer) deployGWNode(gatewayCount int,
+func (d *ocpGatewayDeployer) deployGWNode(ctx context.Context, gatewayCount int,
 	computeClient *gophercloud.ServiceClient, numGatewayNodes int, status reporter.Interface,
 ) error {
-	// Currently, we only support increasing the number of Gateway nodes which could be a valid use-case
-	// to convert a non-HA deployment to an HA deployment. We are not supporting decreasing the Gateway
-	// nodes (for now) as it might impact the datapath if we accidentally delete the active GW node.
 	var err error
 
 	if numGatewayNodes < gatewayCount {
@@ -205,20 +228,72 @@ func (d *ocpGatewayDeployer) deployGWNode(gatewayCount int,
 			return errSG
 		}
 
-		err = d.deployDedicatedGWNode(gatewayNodesToDeploy, isFound, status)
+		err = d.deployDedicatedGWNode(ctx, gatewayNodesToDeploy, isFound, status)
 	}
 
 	return err
 }
 
-func (d *ocpGatewayDeployer) deployDedicatedGWNode(gatewayNodesToDeploy int, useInternalSG bool,
+// removeSurplusGateways deletes up to count dedicated gateway machine sets and detaches the gateway security group from
+// their servers, preferring the passive gateways and never deleting the active one.
+func (d *ocpGatewayDeployer) removeSurplusGateways(ctx context.Context, machineSets []unstructured.Unstructured, gwNodes *ocp.GatewayNodes,
+	count int, computeClient *gophercloud.ServiceClient, status reporter.Interface,
+) error {
+	if len(machineSets) == 0 {
+		status.Warning("None of the %d surplus gateways were removed as the labeled worker nodes are kept", count)
+		return nil
+	}
+
+	activeNode, err := d.k8sClient().GetActiveGatewayNodeWithContext(ctx)
+	if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+		status.Warning("None of the %d surplus gateways were removed: %v", count, err)
+		return nil
+	}
+
+	if err != nil {
+		return status.Error(err, "unable to determine the active gateway")
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+	surplus := ocp.SelectSurplusMachineSets(machineSets, gwNodes, activeNode, count)
+
+	for i := range surplus {
+		status.Start("Deleting the surplus gateway node %q", surplus[i].GetName())
+
+		err = d.removeFirewallRulesFromGW(ctx, groupName, surplus[i].GetName(), computeClient)
+		if err != nil {
+			return status.Error(err, "error removing the firewall rules from the gateway node %q", surplus[i].GetName())
+		}
+
+		err = d.msDeployer.DeleteByNameWithContext(ctx, surplus[i].GetName(), surplus[i].GetNamespace())
+		if err != nil {
+			return status.Error(err, "error deleting the gateway node %q", surplus[i].GetName())
+		}
+
+		err = d.Ledger.Forget(ctx, ledgerEntry(ledger.Deploy, api.MachineSetResource, surplus[i].GetName(), surplus[i].GetNamespace()))
+		if err != nil {
+			return status.Error(err, "error updating the ledger")
+		}
+
+		status.Success("Deleted the surplus gateway node %q", surplus[i].GetName())
+	}
+
+	if len(surplus) < count {
+		status.Warning("Only %d of the %d surplus gateways were removed as the active gateway and the labeled worker nodes are kept",
+			len(surplus), count)
+	}
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) deployDedicatedGWNode(ctx context.Context, gatewayNodesToDeploy int, useInternalSG bool,
 	status reporter.Interface,
 ) error {
 	for i := 0; i < gatewayNodesToDeploy; i++ {
 		gwNodeName := d.InfraID + "-submariner-gw" + strconv.Itoa(i)
 		status.Start("Deploying dedicated Submariner gateway node %s", gwNodeName)
 
-		err := d.deployGateway(useInternalSG)
+		err := d.deployGateway(ctx, useInternalSG)
 		if err != nil {
 			return status.Error(err, "unable to deploy gateway")
 		}
@@ -231,6 +306,37 @@ func (d *ocpGatewayDeployer) deployDedicatedGWNode(gatewayNodesToDeploy int, use
 }
 
 func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
+	return d.CleanupWithContext(context.TODO(), status)
+}
+
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.cleanup(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) cleanup(ctx context.Context, status reporter.Interface) error {
+	recorded, err := d.Ledger.Recorded(ctx, ProviderName, ledger.Deploy)
+	if err != nil {
+		return status.Error(err, "error reading the ledger")
+	}
+
+	if recorded {
+		status.Start("Removing the recorded Submariner gateway resources")
+		defer status.End()
+
+		if err := d.Ledger.Undo(ctx, ProviderName, ledger.Deploy, d.undo); err != nil {
+			return status.Error(err, "error removing the gateway resources")
+		}
+
+		// The gateway label isn't added by the deployer, so it isn't recorded.
+		if err := d.k8sClient().RemoveGWLabelFromWorkerNodesWithContext(ctx); err != nil {
+			return status.Error(err, "error removing the gateway label from worker nodes")
+		}
+
+		status.Success("Successfully cleaned up Submariner gateway nodes")
+
+		return nil
+	}
+
 	computeClient, err := openstack.NewComputeV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
 	if err != nil {
 		return status.Error(err, "error creating the compute client for the region: %q", d.Region)
@@ -238,17 +344,28 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 	groupName := d.InfraID + gwSecurityGroupSuffix
 
-	machineSetList, err := d.msDeployer.List()
+	machineSetList, err := d.msDeployer.ListWithContext(ctx)
 	if err != nil {
 		return status.Error(err, "error listing the Submariner gateway nodes")
 	}
 
+	// Map the gateway nodes before deleting the machine sets, while their machines still reference the nodes.
+	gwNodesList, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSetList, gwNodesList.Items)
+	if err != nil {
+		return status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
 	// cleaning up the dedicated g/w nodes
 	for i := range machineSetList {
 		status.Start("Removing the Submariner gateway security group rules from node %q",
 			machineSetList[i].GetName())
 
-		err = d.removeFirewallRulesFromGW(groupName, machineSetList[i].GetName(), computeClient)
+		err = d.removeFirewallRulesFromGW(ctx, groupName, machineSetList[i].GetName(), computeClient)
 		if err != nil {
 			return status.Error(err, "error deleting the security group rules")
 		}
@@ -258,7 +375,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 		status.Start(fmt.Sprintf("Deleting the gateway instance %q", machineSetList[i].GetName()))
 
-		err = d.msDeployer.DeleteByName(machineSetList[i].GetName(), machineSetList[i].GetNamespace())
+		err = d.msDeployer.DeleteByNameWithContext(ctx, machineSetList[i].GetName(), machineSetList[i].GetNamespace())
 		if err != nil {
 			return status.Error(err, "error deleting the gateway instance from node: %q",
 				machineSetList[i].GetName())
@@ -267,17 +384,12 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 		status.Success("Successfully deleted the instance")
 	}
 
-	gwNodesList, err := d.K8sClient.ListGatewayNodes()
-	if err != nil {
-		return status.Error(err, "error listing the Submariner gateway nodes")
-	}
-
-	gwNodes := ocp.RemoveDuplicates(machineSetList, gwNodesList.Items)
+	gwNodes := mapping.Labeled
 
 	for i := range gwNodes {
 		status.Start("Deleting the Submariner gateway security group rules from node %q", gwNodes[i].Name)
 
-		err = d.removeFirewallRulesFromGW(groupName, gwNodes[i].Name, computeClient)
+		err = d.removeFirewallRulesFromGW(ctx, groupName, gwNodes[i].Name, computeClient)
 		if err != nil {
 			return status.Error(err, "error deleting the security group rules")
 		}
@@ -287,7 +399,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 
 		status.Start(fmt.Sprintf("Removing Submariner gateway label from instance %q", gwNodes[i].Name))
 
-		err = d.K8sClient.RemoveGWLabelFromWorkerNode(&gwNodes[i])
+		err = d.k8sClient().RemoveGWLabelFromWorkerNodeWithContext(ctx, &gwNodes[i])
 		if err != nil {
 			return status.Error(err, "failed to cleanup gateway node %q"+gwNodes[i].Name)
 		}
@@ -311,7 +423,7 @@ func (d *ocpGatewayDeployer) Cleanup(status reporter.Interface) error {
 func formatPorts(ports []api.PortSpec) string {
 	portStrs := []string{}
 	for _, port := range ports {
-		portStrs = append(portStrs, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+		portStrs = append(portStrs, port.String())
 	}
 
 	return strings.Join(portStrs, ", ")
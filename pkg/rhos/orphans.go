@@ -0,0 +1,128 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+type orphanScanner struct {
+	CloudInfo
+}
+
+// NewOrphanScanner returns an OrphanScanner finding the Submariner security groups in the given region which don't belong
+// to a live cluster.
+func NewOrphanScanner(client *gophercloud.ProviderClient, region string) api.OrphanScanner {
+	return &orphanScanner{
+		CloudInfo: CloudInfo{
+			Client: client,
+			Region: region,
+		},
+	}
+}
+
+func (s *orphanScanner) Scan(ctx context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	orphans, err := s.scan(ctx, liveInfraIDs, status)
+
+	return orphans, classifyError(err)
+}
+
+func (s *orphanScanner) scan(_ context.Context, liveInfraIDs []string, status reporter.Interface) ([]api.Orphan, error) {
+	status.Start("Scanning for orphaned Submariner security groups in region %q", s.Region)
+	defer status.End()
+
+	computeClient, err := openstack.NewComputeV2(s.Client, gophercloud.EndpointOpts{Region: s.Region})
+	if err != nil {
+		return nil, status.Error(err, "creating compute client failed for region %q", s.Region)
+	}
+
+	var orphans []api.Orphan
+
+	err = secgroups.List(computeClient).EachPage(func(page pagination.Page) (bool, error) {
+		groups, err := secgroups.ExtractSecurityGroups(page)
+		if err != nil {
+			return false, errors.WithMessage(err, "failed to extract the security groups")
+		}
+
+		for i := range groups {
+			infraID, found := strings.CutSuffix(groups[i].Name, gwSecurityGroupSuffix)
+			if !found {
+				infraID, found = strings.CutSuffix(groups[i].Name, internalSecurityGroupSuffix)
+			}
+
+			if found && !slices.Contains(liveInfraIDs, infraID) {
+				orphans = append(orphans, api.Orphan{
+					Resource: api.SecurityGroupResource,
+					Name:     groups[i].Name,
+					ID:       groups[i].ID,
+					InfraID:  infraID,
+				})
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, status.Error(err, "unable to list the security groups")
+	}
+
+	status.Success("Found %d orphaned Submariner security groups", len(orphans))
+
+	return orphans, nil
+}
+
+func (s *orphanScanner) Delete(ctx context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	return classifyError(s.delete(ctx, orphans, status))
+}
+
+func (s *orphanScanner) delete(_ context.Context, orphans []api.Orphan, status reporter.Interface) error {
+	defer status.End()
+
+	computeClient, err := openstack.NewComputeV2(s.Client, gophercloud.EndpointOpts{Region: s.Region})
+	if err != nil {
+		return status.Error(err, "creating compute client failed for region %q", s.Region)
+	}
+
+	for i := range orphans {
+		status.Start("Deleting the orphaned %s", orphans[i])
+
+		if orphans[i].Resource != api.SecurityGroupResource {
+			return status.Error(errors.Errorf("unsupported resource %q", orphans[i].Resource), "unable to delete the orphaned %s",
+				orphans[i])
+		}
+
+		if err := s.deleteSG(orphans[i].Name, computeClient); err != nil {
+			return status.Error(err, "unable to delete the orphaned %s", orphans[i])
+		}
+
+		status.Success("Deleted the orphaned %s", orphans[i])
+	}
+
+	return nil
+}
@@ -0,0 +1,377 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func (rc *rhosCloud) PlanOpenPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	plan, err := rc.planOpenPorts(ctx, ports, status)
+
+	return plan, classifyError(err)
+}
+
+func (rc *rhosCloud) planOpenPorts(_ context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the internal security group on RHOS")
+	defer status.End()
+
+	computeClient, err := openstack.NewComputeV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the compute client")
+	}
+
+	plan := &api.Plan{}
+	groupName := rc.InfraID + internalSecurityGroupSuffix
+
+	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the internal security group")
+	}
+
+	if !isFound {
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "")
+
+		for _, port := range ports {
+			for _, family := range rc.IPFamily.Families() {
+				plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "allow %s ingress %s from security group %q",
+					family, port, groupName)
+			}
+		}
+	}
+
+	err = planServerSecurityGroup(plan, rc.InfraID, groupName, true, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the cluster servers")
+	}
+
+	status.Success("Retrieved the internal security group on RHOS")
+
+	return plan, nil
+}
+
+func (rc *rhosCloud) PlanClosePorts(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := rc.planClosePorts(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (rc *rhosCloud) planClosePorts(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the internal security group on RHOS")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	recorded, err := rc.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.OpenPorts, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		status.Success("Retrieved the recorded intra-cluster communication permissions")
+
+		return plan, nil
+	}
+
+	computeClient, err := openstack.NewComputeV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, status.Error(err, "creating compute client failed for region %q", rc.Region)
+	}
+	groupName := rc.InfraID + internalSecurityGroupSuffix
+
+	err = planServerSecurityGroup(plan, rc.InfraID, groupName, false, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the cluster servers")
+	}
+
+	err = planDeleteSG(plan, groupName, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the internal security group")
+	}
+
+	status.Success("Retrieved the internal security group on RHOS")
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planDeploy(ctx, input, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planDeploy(ctx context.Context, input api.GatewayDeployInput, status reporter.Interface) (*api.Plan, error) {
+	if input.IPFamily == "" {
+		input.IPFamily = d.IPFamily
+	}
+
+	status.Start("Retrieving the gateway security group and nodes on RHOS")
+	defer status.End()
+
+	computeClient, err := openstack.NewComputeV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the compute client")
+	}
+
+	networkClient, err := openstack.NewNetworkV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the network client")
+	}
+
+	plan := &api.Plan{}
+	groupName := d.InfraID + gwSecurityGroupSuffix
+
+	groupID, err := getSecurityGroupID(groupName, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	missing := []sgRuleSpec{}
+
+	if groupID == "" {
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "")
+
+		for _, port := range input.PublicPorts {
+			for _, cidr := range input.PublicSourceCIDRs() {
+				missing = append(missing, sgRuleSpec{port: port, cidr: cidr})
+			}
+		}
+	} else {
+		existing, err := listIngressRules(groupID, networkClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the gateway security group rules")
+		}
+
+		var stale []rules.SecGroupRule
+
+		missing, stale = diffGWSGRules(existing, input.PublicPorts, input.PublicSourceCIDRs())
+
+		for i := range stale {
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, groupName, "revoke %s ingress %s from %s",
+				stale[i].EtherType, formatSGRulePorts(&stale[i]), stale[i].RemoteIPPrefix)
+		}
+	}
+
+	for _, spec := range missing {
+		plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "allow ingress %s from %s", spec.port, spec.cidr)
+	}
+
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway machinesets")
+	}
+
+	gwNodes, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "listing the existing gateway nodes failed")
+	}
+
+	for i := range gwNodes.Items {
+		err = planServerSecurityGroup(plan, gwNodes.Items[i].Name, groupName, true, computeClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the gateway servers")
+		}
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodes.Items)
+	if err != nil {
+		return nil, status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	numGatewayNodes := len(machineSets) + len(mapping.Labeled)
+
+	if numGatewayNodes > input.Gateways && len(machineSets) != 0 {
+		activeNode, err := d.k8sClient().GetActiveGatewayNodeWithContext(ctx)
+		if errors.Is(err, k8s.ErrActiveGatewayUnknown) {
+			status.Warning("The surplus gateways won't be removed: %v", err)
+		} else if err != nil {
+			return nil, status.Error(err, "unable to determine the active gateway")
+		}
+
+		var surplus []unstructured.Unstructured
+		if err == nil {
+			surplus = ocp.SelectSurplusMachineSets(machineSets, mapping, activeNode, numGatewayNodes-input.Gateways)
+		}
+
+		for _, machineSet := range surplus {
+			err = planServerSecurityGroup(plan, machineSet.GetName(), groupName, false, computeClient)
+			if err != nil {
+				return nil, status.Error(err, "unable to retrieve the gateway servers")
+			}
+
+			plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "surplus passive gateway")
+		}
+	}
+
+	if numGatewayNodes < input.Gateways {
+		useInternalSG, err := checkIfSecurityGroupPresent(d.InfraID+internalSecurityGroupSuffix, computeClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the internal security group")
+		}
+
+		for i := numGatewayNodes; i < input.Gateways; i++ {
+			machineSet, err := d.initMachineSet(useInternalSG)
+			if err != nil {
+				return nil, status.Error(err, "unable to initialize the gateway machine set")
+			}
+
+			plan.Add(api.ChangeCreate, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node of flavor %q",
+				d.instanceType)
+		}
+	}
+
+	status.Success("Retrieved the gateway security group and nodes on RHOS")
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	plan, err := d.planCleanup(ctx, status)
+
+	return plan, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) planCleanup(ctx context.Context, status reporter.Interface) (*api.Plan, error) {
+	status.Start("Retrieving the gateway security group and nodes on RHOS")
+	defer status.End()
+
+	plan := &api.Plan{}
+
+	recorded, err := d.Ledger.PlanUndo(ctx, plan, ProviderName, ledger.Deploy, undoChange)
+	if err != nil {
+		return nil, status.Error(err, "error reading the ledger")
+	}
+
+	if recorded {
+		// The gateway label isn't added by the deployer, so it isn't recorded.
+		gwNodes, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+		if err != nil {
+			return nil, status.Error(err, "error listing the Submariner gateway nodes")
+		}
+
+		for i := range gwNodes.Items {
+			plan.Add(api.ChangeDelete, api.NodeGatewayLabelResource, gwNodes.Items[i].Name, "")
+		}
+
+		status.Success("Retrieved the recorded gateway resources and nodes on RHOS")
+
+		return plan, nil
+	}
+
+	computeClient, err := openstack.NewComputeV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the compute client for the region: %q", d.Region)
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+
+	machineSetList, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	for i := range machineSetList {
+		err = planServerSecurityGroup(plan, machineSetList[i].GetName(), groupName, false, computeClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the gateway servers")
+		}
+
+		plan.Add(api.ChangeDelete, api.MachineSetResource, machineSetList[i].GetName(), "")
+	}
+
+	gwNodesList, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error listing the Submariner gateway nodes")
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSetList, gwNodesList.Items)
+	if err != nil {
+		return nil, status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	gwNodes := mapping.Labeled
+
+	for i := range gwNodes {
+		err = planServerSecurityGroup(plan, gwNodes[i].Name, groupName, false, computeClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the gateway servers")
+		}
+
+		plan.Add(api.ChangeDelete, api.NodeGatewayLabelResource, gwNodes[i].Name, "")
+	}
+
+	err = planDeleteSG(plan, groupName, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the gateway security group")
+	}
+
+	status.Success("Retrieved the gateway security group and nodes on RHOS")
+
+	return plan, nil
+}
+
+// planServerSecurityGroup plans adding the security group to, or removing it from, the servers matching the given name.
+func planServerSecurityGroup(plan *api.Plan, serverName, groupName string, add bool, computeClient *gophercloud.ServiceClient) error {
+	allPages, err := servers.List(computeClient, servers.ListOpts{Name: serverName}).AllPages()
+	if err != nil {
+		return errors.WithMessagef(err, "getting the server list failed for %q", serverName)
+	}
+
+	serverList, err := servers.ExtractServers(allPages)
+	if err != nil {
+		return errors.WithMessagef(err, "getting the server list failed for %q", serverName)
+	}
+
+	for i := range serverList {
+		hasGroup := serverHasSecurityGroup(&serverList[i], groupName)
+
+		if add && !hasGroup {
+			plan.Add(api.ChangeUpdate, api.InstanceResource, serverList[i].Name, "add security group %q", groupName)
+		} else if !add && hasGroup {
+			plan.Add(api.ChangeUpdate, api.InstanceResource, serverList[i].Name, "remove security group %q", groupName)
+		}
+	}
+
+	return nil
+}
+
+func planDeleteSG(plan *api.Plan, groupName string, computeClient *gophercloud.ServiceClient) error {
+	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	if err != nil {
+		return err
+	}
+
+	if isFound {
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rhos
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// sgRuleSpec is a security group rule allowing a port for an IP family, from the given CIDR if any.
+type sgRuleSpec struct {
+	port   api.PortSpec
+	family api.IPFamily
+	cidr   string
+}
+
+func (rc *rhosCloud) ReconcilePorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	plan, err := rc.reconcilePorts(ctx, ports, status)
+
+	return plan, classifyError(err)
+}
+
+func (rc *rhosCloud) reconcilePorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) (*api.Plan, error) {
+	if err := api.ValidatePorts(ports); err != nil {
+		return nil, errors.WithMessage(err, "invalid internal ports")
+	}
+
+	status.Start("Comparing the internal ports with the internal security group rules on RHOS")
+	defer status.End()
+
+	computeClient, err := openstack.NewComputeV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the compute client")
+	}
+
+	networkClient, err := openstack.NewNetworkV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the network client")
+	}
+
+	plan := &api.Plan{}
+	groupName := rc.InfraID + internalSecurityGroupSuffix
+
+	groupID, err := getSecurityGroupID(groupName, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the internal security group")
+	}
+
+	var missing []sgRuleSpec
+	var stale []rules.SecGroupRule
+
+	if groupID == "" {
+		// The rules are created along with the group by openInternalPorts.
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "")
+	} else {
+		existing, err := listIngressRules(groupID, networkClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the internal security group rules")
+		}
+
+		missing, stale = diffSGRules(existing, ports, rc.IPFamily.Families(), groupID)
+
+		for i := range stale {
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, groupName, "revoke %s ingress %s from security group %q",
+				stale[i].EtherType, formatSGRulePorts(&stale[i]), groupName)
+		}
+	}
+
+	for _, port := range ports {
+		for _, family := range rc.IPFamily.Families() {
+			if groupID == "" || slices.Contains(missing, sgRuleSpec{port: port, family: family}) {
+				plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "allow %s ingress %s from security group %q",
+					family, port, groupName)
+			}
+		}
+	}
+
+	err = planServerSecurityGroup(plan, rc.InfraID, groupName, true, computeClient)
+	if err != nil {
+		return nil, status.Error(err, "unable to retrieve the cluster servers")
+	}
+
+	plan.Report(status)
+
+	if plan.IsEmpty() {
+		return plan, nil
+	}
+
+	status.Start("Updating the security group %q on RHOS", groupName)
+
+	for i := range stale {
+		err = rules.Delete(networkClient, stale[i].ID).ExtractErr()
+		if err != nil {
+			return nil, status.Error(errors.WithMessagef(err, "error deleting the security group rule %q", stale[i].ID),
+				"unable to revoke the stale ports")
+		}
+	}
+
+	for _, spec := range missing {
+		err = rc.createSGRule(groupID, groupID, "", spec.family, spec.port, networkClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to open the missing ports")
+		}
+	}
+
+	// This creates the group and its rules if it's missing, and adds it to the servers which don't have it.
+	if err := rc.openInternalPorts(ctx, rc.InfraID, ports, computeClient, networkClient); err != nil {
+		return nil, status.Error(err, "unable to open ports")
+	}
+
+	status.Success("Reconciled the internal ports %q for intra-cluster communications on RHOS", formatPorts(ports))
+
+	return plan, nil
+}
+
+// diffSGRules returns the rules missing from the existing ingress rules to allow the given ports from the given remote
+// group, and the existing rules which allow none of them.
+func diffSGRules(existing []rules.SecGroupRule, ports []api.PortSpec, families []api.IPFamily, remoteGroupID string,
+) ([]sgRuleSpec, []rules.SecGroupRule) {
+	var specs []sgRuleSpec
+
+	for _, port := range ports {
+		for _, family := range families {
+			specs = append(specs, sgRuleSpec{port: port, family: family})
+		}
+	}
+
+	return diffSGRuleSpecs(existing, specs, remoteGroupID)
+}
+
+// diffGWSGRules returns the rules missing from the existing ingress rules of the gateway security group to allow the
+// given ports from the given source CIDRs, and the existing rules which allow none of them.
+func diffGWSGRules(existing []rules.SecGroupRule, ports []api.PortSpec, sourceCIDRs []string,
+) ([]sgRuleSpec, []rules.SecGroupRule) {
+	var specs []sgRuleSpec
+
+	for _, port := range ports {
+		for _, cidr := range sourceCIDRs {
+			specs = append(specs, sgRuleSpec{port: port, family: api.CIDRFamily(cidr), cidr: cidr})
+		}
+	}
+
+	return diffSGRuleSpecs(existing, specs, "")
+}
+
+func diffSGRuleSpecs(existing []rules.SecGroupRule, specs []sgRuleSpec, remoteGroupID string,
+) ([]sgRuleSpec, []rules.SecGroupRule) {
+	var missing []sgRuleSpec
+
+	matched := make([]bool, len(existing))
+
+	for _, spec := range specs {
+		found := false
+
+		for i := range existing {
+			if sgRuleMatches(&existing[i], spec, remoteGroupID) {
+				matched[i] = true
+				found = true
+			}
+		}
+
+		if !found {
+			missing = append(missing, spec)
+		}
+	}
+
+	var stale []rules.SecGroupRule
+
+	for i := range existing {
+		if !matched[i] {
+			stale = append(stale, existing[i])
+		}
+	}
+
+	return missing, stale
+}
+
+// sgRuleMatches returns true if the given rule allows exactly the port of the given spec from the given remote group and
+// the spec's CIDR, for the spec's IP family.
+func sgRuleMatches(rule *rules.SecGroupRule, spec sgRuleSpec, remoteGroupID string) bool {
+	from, to := sgRulePortRange(spec.port)
+
+	return rule.RemoteGroupID == remoteGroupID && rule.RemoteIPPrefix == spec.cidr && rule.EtherType == string(etherType(spec.family)) &&
+		rule.Protocol == strings.ToLower(spec.port.Protocol) && rule.PortRangeMin == from && rule.PortRangeMax == to
+}
+
+func formatSGRulePorts(rule *rules.SecGroupRule) string {
+	if rule.PortRangeMin == 0 {
+		return rule.Protocol
+	}
+
+	return strconv.Itoa(rule.PortRangeMin) + "-" + strconv.Itoa(rule.PortRangeMax) + "/" + rule.Protocol
+}
+
+func listIngressRules(groupID string, networkClient *gophercloud.ServiceClient) ([]rules.SecGroupRule, error) {
+	allPages, err := rules.List(networkClient, rules.ListOpts{SecGroupID: groupID, Direction: "ingress"}).AllPages()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "error listing the rules of security group %q", groupID)
+	}
+
+	ingressRules, err := rules.ExtractRules(allPages)
+
+	return ingressRules, errors.WithMessagef(err, "error listing the rules of security group %q", groupID)
+}
+
+// getSecurityGroupID returns the ID of the security group with the given name, or an empty string if there's none.
+func getSecurityGroupID(groupName string, computeClient *gophercloud.ServiceClient) (string, error) {
+	var groupID string
+
+	err := secgroups.List(computeClient).EachPage(func(page pagination.Page) (bool, error) {
+		groups, err := secgroups.ExtractSecurityGroups(page)
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to extract the security group %q from results", groupName)
+		}
+
+		for i := range groups {
+			if groups[i].Name == groupName {
+				groupID = groups[i].ID
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+
+	return groupID, errors.WithMessagef(err, "error getting the security group %q", groupName)
+}
@@ -19,17 +19,20 @@ limitations under the License.
 package rhos
 
 import (
+	"context"
+
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/reporter"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
 )
 
 const (
 	gwSecurityGroupSuffix       = "-submariner-gw-sg"
 	internalSecurityGroupSuffix = "-submariner-internal-sg"
 	submarinerGatewayNodeTag    = "submariner-io-gateway-node"
-	allNetworkCIDR              = "0.0.0.0/0"
 )
 
 type rhosCloud struct {
@@ -44,6 +47,20 @@ func NewCloud(info CloudInfo) api.Cloud {
 }
 
 func (rc *rhosCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface) error {
+	return rc.OpenPortsWithContext(context.TODO(), ports, status)
+}
+
+// OpenPortsWithContext opens the ports as OpenPorts does. The gophercloud client used for RHOS does not
+// support per-request contexts, so the given context is not propagated to the OpenStack API calls.
+func (rc *rhosCloud) OpenPortsWithContext(ctx context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	return classifyError(rc.openPorts(ctx, ports, status))
+}
+
+func (rc *rhosCloud) openPorts(ctx context.Context, ports []api.PortSpec, status reporter.Interface) error {
+	if err := api.ValidatePorts(ports); err != nil {
+		return errors.WithMessage(err, "invalid internal ports")
+	}
+
 	status.Start("Opening internal ports for intra-cluster communications on RHOS")
 	defer status.End()
 
@@ -57,7 +74,7 @@ func (rc *rhosCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface)
 		return status.Error(err, "error creating the network client")
 	}
 
-	if err := rc.openInternalPorts(rc.InfraID, ports, computeClient, networkClient); err != nil {
+	if err := rc.openInternalPorts(ctx, rc.InfraID, ports, computeClient, networkClient); err != nil {
 		return status.Error(err, "unable to open ports")
 	}
 
@@ -67,8 +84,33 @@ func (rc *rhosCloud) OpenPorts(ports []api.PortSpec, status reporter.Interface)
 }
 
 func (rc *rhosCloud) ClosePorts(status reporter.Interface) error {
+	return rc.ClosePortsWithContext(context.TODO(), status)
+}
+
+// ClosePortsWithContext closes the ports as ClosePorts does. As with OpenPortsWithContext, the given context
+// is not propagated to the OpenStack API calls.
+func (rc *rhosCloud) ClosePortsWithContext(ctx context.Context, status reporter.Interface) error {
+	return classifyError(rc.closePorts(ctx, status))
+}
+
+func (rc *rhosCloud) closePorts(ctx context.Context, status reporter.Interface) error {
 	status.Start("Revoking intra-cluster communication permissions")
 
+	recorded, err := rc.Ledger.Recorded(ctx, ProviderName, ledger.OpenPorts)
+	if err != nil {
+		return status.Error(err, "unable to read the ledger")
+	}
+
+	if recorded {
+		if err := rc.Ledger.Undo(ctx, ProviderName, ledger.OpenPorts, rc.undo); err != nil {
+			return status.Error(err, "unable to remove firewall rules")
+		}
+
+		status.Success("Revoked intra-cluster communication permissions")
+
+		return nil
+	}
+
 	computeClient, err := openstack.NewComputeV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
 	if err != nil {
 		return status.Error(err, "creating compute client failed for region %q", rc.Region)
@@ -19,14 +19,20 @@ limitations under the License.
 package rhos
 
 import (
+	"context"
+	"strings"
+
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
 	"github.com/gophercloud/gophercloud/pagination"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"k8s.io/utils/set"
 )
 
 type CloudInfo struct {
@@ -34,37 +40,68 @@ type CloudInfo struct {
 	InfraID   string
 	Region    string
 	K8sClient k8s.Interface
+
+	// IPFamily specifies the IP families the security group rules are created for.
+	IPFamily api.IPFamily
+
+	// Tags are applied as key=value tags to the security groups, created or existing, and both as tags and metadata to the
+	// gateway servers.
+	Tags map[string]string
+
+	// Ledger, if set, records the security groups, their attachments to servers and the machine sets. ClosePorts and
+	// Cleanup then undo exactly what it lists instead of looking the objects up by name, unless it lists nothing for them.
+	Ledger *ledger.Ledger
 }
 
-func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec,
+func (c *CloudInfo) k8sClient() k8s.ContextInterface {
+	return k8s.WithContext(c.K8sClient)
+}
+
+func (c *CloudInfo) openInternalPorts(ctx context.Context, infraID string, ports []api.PortSpec,
 	computeClient, networkClient *gophercloud.ServiceClient,
 ) error {
-	var group *secgroups.SecurityGroup
 	groupName := infraID + internalSecurityGroupSuffix
 	opts := secgroups.CreateOpts{
 		Name:        groupName,
 		Description: "Submariner Internal",
 	}
 
-	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	groupID, err := getSecurityGroupID(groupName, computeClient)
 	if err != nil {
 		return err
 	}
 
-	if !isFound {
-		group, err = secgroups.Create(computeClient, opts).Extract()
+	if groupID != "" {
+		err = c.tagSecurityGroup(groupID, networkClient)
+		if err != nil {
+			return err
+		}
+	} else {
+		group, err := secgroups.Create(computeClient, opts).Extract()
 		if err != nil {
 			return errors.WithMessagef(err, "creating security group failed")
 		}
 
+		err = c.tagSecurityGroup(group.ID, networkClient)
+		if err != nil {
+			return err
+		}
+
 		for _, port := range ports {
-			err = c.createSGRule(group.ID, group.ID, "", port.Port, port.Protocol, networkClient)
-			if err != nil {
-				return errors.WithMessage(err, "creating security group rule failed")
+			for _, family := range c.IPFamily.Families() {
+				err = c.createSGRule(group.ID, group.ID, "", family, port, networkClient)
+				if err != nil {
+					return errors.WithMessage(err, "creating security group rule failed")
+				}
 			}
 		}
 	}
 
+	err = c.Ledger.Record(ctx, ledgerEntry(ledger.OpenPorts, api.SecurityGroupResource, groupName, ""))
+	if err != nil {
+		return err
+	}
+
 	pager := servers.List(computeClient, servers.ListOpts{Name: c.InfraID})
 	err = pager.EachPage(func(page pagination.Page) (bool, error) {
 		serverList, err := servers.ExtractServers(page)
@@ -73,21 +110,16 @@ func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec,
 		}
 
 		for i := range serverList {
-			found := false
-			securityGroups := serverList[i].SecurityGroups
-
-			for j := range securityGroups {
-				existingGroupName, ok := securityGroups[j]["name"]
-				if ok && existingGroupName == groupName {
-					found = true
-				}
-			}
-
-			if !found {
+			if !serverHasSecurityGroup(&serverList[i], groupName) {
 				err := secgroups.AddServer(computeClient, serverList[i].ID, groupName).ExtractErr()
 				if err != nil {
 					return false, errors.WithMessage(err, "failed to add the security group to the server")
 				}
+
+				err = c.Ledger.Record(ctx, ledgerEntry(ledger.OpenPorts, api.InstanceResource, serverList[i].ID, groupName))
+				if err != nil {
+					return false, err
+				}
 			}
 		}
 
@@ -129,16 +161,83 @@ func (c *CloudInfo) removeInternalFirewallRules(infraID string,
 	return errors.WithMessage(err, "failed to remove security group from servers")
 }
 
-func (c *CloudInfo) createGWSecurityGroup(ports []api.PortSpec, groupName string, computeClient *gophercloud.ServiceClient,
-	networkClient *gophercloud.ServiceClient,
+// tagList returns the user tags as key=value strings, sorted by key.
+func (c *CloudInfo) tagList() []string {
+	keys := set.KeySet(c.Tags).SortedList()
+
+	tags := make([]string, len(keys))
+	for i, key := range keys {
+		tags[i] = key + "=" + c.Tags[key]
+	}
+
+	return tags
+}
+
+// tagSecurityGroup applies the user tags to the given security group, new or existing. The tags of the group with the same
+// keys are replaced, and the other ones are kept.
+func (c *CloudInfo) tagSecurityGroup(groupID string, networkClient *gophercloud.ServiceClient) error {
+	if len(c.Tags) == 0 {
+		return nil
+	}
+
+	existing, err := attributestags.List(networkClient, "security-groups", groupID).Extract()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to list the tags of security group %q", groupID)
+	}
+
+	tags, changed := c.mergeTags(existing)
+	if !changed {
+		return nil
+	}
+
+	_, err = attributestags.ReplaceAll(networkClient, "security-groups", groupID, attributestags.ReplaceAllOpts{
+		Tags: tags,
+	}).Extract()
+
+	return errors.WithMessagef(err, "failed to tag security group %q", groupID)
+}
+
+// mergeTags returns the given key=value tags of an existing resource with the user tags added or updated, and whether
+// that changed them.
+func (c *CloudInfo) mergeTags(tags []string) ([]string, bool) {
+	merged := c.tagList()
+
+	for _, tag := range tags {
+		key, _, _ := strings.Cut(tag, "=")
+		if _, found := c.Tags[key]; !found {
+			merged = append(merged, tag)
+		}
+	}
+
+	return merged, !set.New(tags...).Equal(set.New(merged...))
+}
+
+func (c *CloudInfo) createGWSecurityGroup(ctx context.Context, ports []api.PortSpec, sourceCIDRs []string, groupName string,
+	computeClient, networkClient *gophercloud.ServiceClient,
 ) error {
-	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	err := c.createOrUpdateGWSecurityGroup(ports, sourceCIDRs, groupName, computeClient, networkClient)
 	if err != nil {
 		return err
 	}
 
-	if isFound {
-		return nil
+	return c.Ledger.Record(ctx, ledgerEntry(ledger.Deploy, api.SecurityGroupResource, groupName, ""))
+}
+
+func (c *CloudInfo) createOrUpdateGWSecurityGroup(ports []api.PortSpec, sourceCIDRs []string, groupName string,
+	computeClient, networkClient *gophercloud.ServiceClient,
+) error {
+	groupID, err := getSecurityGroupID(groupName, computeClient)
+	if err != nil {
+		return err
+	}
+
+	if groupID != "" {
+		err = c.tagSecurityGroup(groupID, networkClient)
+		if err != nil {
+			return err
+		}
+
+		return c.reconcileGWSecurityGroupRules(groupID, ports, sourceCIDRs, networkClient)
 	}
 
 	opts := secgroups.CreateOpts{
@@ -151,8 +250,44 @@ func (c *CloudInfo) createGWSecurityGroup(ports []api.PortSpec, groupName string
 		return errors.WithMessage(err, "failed to create g/w security group")
 	}
 
+	err = c.tagSecurityGroup(group.ID, networkClient)
+	if err != nil {
+		return err
+	}
+
 	for _, port := range ports {
-		err = c.createSGRule(group.ID, "", allNetworkCIDR, port.Port, port.Protocol, networkClient)
+		for _, cidr := range sourceCIDRs {
+			err = c.createSGRule(group.ID, "", cidr, api.CIDRFamily(cidr), port, networkClient)
+			if err != nil {
+				return errors.WithMessagef(err, "creating security group rule failed")
+			}
+		}
+	}
+
+	return nil
+}
+
+// reconcileGWSecurityGroupRules makes the rules of the existing gateway security group allow exactly the given ports from
+// the given source CIDRs, deleting the rules which are no longer requested such as those allowing any address.
+func (c *CloudInfo) reconcileGWSecurityGroupRules(groupID string, ports []api.PortSpec, sourceCIDRs []string,
+	networkClient *gophercloud.ServiceClient,
+) error {
+	existing, err := listIngressRules(groupID, networkClient)
+	if err != nil {
+		return err
+	}
+
+	missing, stale := diffGWSGRules(existing, ports, sourceCIDRs)
+
+	for i := range stale {
+		err = rules.Delete(networkClient, stale[i].ID).ExtractErr()
+		if err != nil {
+			return errors.WithMessagef(err, "error deleting the security group rule %q", stale[i].ID)
+		}
+	}
+
+	for _, spec := range missing {
+		err = c.createSGRule(groupID, "", spec.cidr, spec.family, spec.port, networkClient)
 		if err != nil {
 			return errors.WithMessagef(err, "creating security group rule failed")
 		}
@@ -180,7 +315,7 @@ func checkIfSecurityGroupPresent(groupName string, computeClient *gophercloud.Se
 	return isFound, errors.WithMessagef(err, "error getting the security group : %q", groupName)
 }
 
-func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *gophercloud.ServiceClient) error {
+func (c *CloudInfo) openGatewayPort(ctx context.Context, groupName, nodeName string, computeClient *gophercloud.ServiceClient) error {
 	opts := servers.ListOpts{Name: nodeName}
 	pager := servers.List(computeClient, opts)
 
@@ -191,12 +326,8 @@ func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *g
 		}
 
 		for i := range serverList {
-			securityGroups := serverList[i].SecurityGroups
-			for j := range securityGroups {
-				existingGroupName, ok := securityGroups[j]["name"]
-				if ok && existingGroupName == groupName {
-					return true, nil
-				}
+			if serverHasSecurityGroup(&serverList[i], groupName) {
+				return true, nil
 			}
 
 			err = secgroups.AddServer(computeClient, serverList[i].ID, groupName).ExtractErr()
@@ -204,6 +335,11 @@ func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *g
 				return false, errors.WithMessagef(err, "adding security group %q to the server %q failed",
 					groupName, serverList[i].Name)
 			}
+
+			err = c.Ledger.Record(ctx, ledgerEntry(ledger.Deploy, api.InstanceResource, serverList[i].ID, groupName))
+			if err != nil {
+				return false, err
+			}
 		}
 
 		return true, nil
@@ -212,7 +348,20 @@ func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *g
 	return errors.WithMessagef(err, "open gateway ports failed")
 }
 
-func (c *CloudInfo) removeFirewallRulesFromGW(groupName, nodeName string, computeClient *gophercloud.ServiceClient) error {
+func serverHasSecurityGroup(server *servers.Server, groupName string) bool {
+	for i := range server.SecurityGroups {
+		existingGroupName, ok := server.SecurityGroups[i]["name"]
+		if ok && existingGroupName == groupName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeFirewallRulesFromGW removes the given security group from the servers of the given node, and forgets their ledger entries.
+func (c *CloudInfo) removeFirewallRulesFromGW(ctx context.Context, groupName, nodeName string, computeClient *gophercloud.ServiceClient,
+) error {
 	opts := servers.ListOpts{Name: nodeName}
 	pager := servers.List(computeClient, opts)
 
@@ -233,6 +382,11 @@ func (c *CloudInfo) removeFirewallRulesFromGW(groupName, nodeName string, comput
 				return false, errors.WithMessagef(err, "failed to remove the firewall for"+
 					" the server: %q", serverList[i].Name)
 			}
+
+			err = c.Ledger.Forget(ctx, ledgerEntry(ledger.Deploy, api.InstanceResource, serverList[i].ID, groupName))
+			if err != nil {
+				return false, err
+			}
 		}
 
 		return true, nil
@@ -271,22 +425,42 @@ func (c *CloudInfo) deleteSG(groupName string, computeClient *gophercloud.Servic
 	return errors.WithMessagef(err, "error deleting the security group %q", groupName)
 }
 
-func (c *CloudInfo) createSGRule(group, remoteGroupID, remoteIPPrefix string, port uint16,
-	protocol string, networkClient *gophercloud.ServiceClient,
+func (c *CloudInfo) createSGRule(group, remoteGroupID, remoteIPPrefix string, family api.IPFamily, port api.PortSpec,
+	networkClient *gophercloud.ServiceClient,
 ) error {
 	opts := rules.CreateOpts{
 		Direction:      "ingress",
-		EtherType:      rules.EtherType4,
+		EtherType:      etherType(family),
 		SecGroupID:     group,
-		PortRangeMax:   int(port),
-		PortRangeMin:   int(port),
-		Protocol:       rules.RuleProtocol(protocol),
+		Protocol:       rules.RuleProtocol(strings.ToLower(port.Protocol)),
 		RemoteGroupID:  remoteGroupID,
 		RemoteIPPrefix: remoteIPPrefix,
 	}
 
+	opts.PortRangeMin, opts.PortRangeMax = sgRulePortRange(port)
+
 	_, err := rules.Create(networkClient, opts).Extract()
 
-	return errors.WithMessagef(err, "failed creating security group rule with port %d , protocol %q,"+
-		"remotegroupID %q, remoteIPprefix %q , in security group %q", port, protocol, remoteGroupID, remoteIPPrefix, group)
+	return errors.WithMessagef(err, "failed creating %s security group rule for %q, "+
+		"remotegroupID %q, remoteIPprefix %q , in security group %q", family, port, remoteGroupID, remoteIPPrefix, group)
+}
+
+func etherType(family api.IPFamily) rules.RuleEtherType {
+	if family == api.IPv6 {
+		return rules.EtherType6
+	}
+
+	return rules.EtherType4
+}
+
+// sgRulePortRange returns the port range of a security group rule for the given port spec. Leaving the port range unset
+// allows all the ports, which is also what portless protocols require.
+func sgRulePortRange(port api.PortSpec) (int, int) {
+	if port.IsPortless() || port.Port == 0 {
+		return 0, 0
+	}
+
+	from, to := port.PortRange()
+
+	return int(from), int(to)
 }
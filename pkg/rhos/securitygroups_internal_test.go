@@ -0,0 +1,39 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Security group tags", func() {
+	cloudInfo := &CloudInfo{Tags: map[string]string{"owner": "team-a", "cost-center": "1234"}}
+
+	It("should add the missing tags, replace those with the same keys and keep the other ones", func() {
+		tags, changed := cloudInfo.mergeTags([]string{"owner=team-b", "other"})
+		Expect(changed).To(BeTrue())
+		Expect(tags).To(Equal([]string{"cost-center=1234", "owner=team-a", "other"}))
+	})
+
+	It("should not change anything if the tags are up to date", func() {
+		_, changed := cloudInfo.mergeTags([]string{"other", "owner=team-a", "cost-center=1234"})
+		Expect(changed).To(BeFalse())
+	})
+})
@@ -0,0 +1,147 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	statuses, err := d.gatewayStatus(ctx, status)
+
+	return statuses, classifyError(err)
+}
+
+func (d *ocpGatewayDeployer) gatewayStatus(ctx context.Context, status reporter.Interface) ([]api.GatewayStatus, error) {
+	status.Start("Retrieving the Submariner gateways on RHOS")
+	defer status.End()
+
+	computeClient, err := openstack.NewComputeV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, status.Error(err, "error creating the compute client")
+	}
+
+	machineSets, err := d.msDeployer.ListWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "error getting the gateway machinesets")
+	}
+
+	gwNodes, err := d.k8sClient().ListGatewayNodesWithContext(ctx)
+	if err != nil {
+		return nil, status.Error(err, "listing the existing gateway nodes failed")
+	}
+
+	mapping, err := ocp.MapGatewayNodes(ctx, d.msDeployer, machineSets, gwNodes.Items)
+	if err != nil {
+		return nil, status.Error(err, "error mapping the gateway nodes to their machinesets")
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+	gateways := []api.GatewayStatus{}
+
+	for i := range machineSets {
+		gateway := api.GatewayStatus{
+			Type:          api.DedicatedGateway,
+			MachineSet:    machineSets[i].GetName(),
+			Subnet:        d.InfraID + "-nodes",
+			InstanceType:  ocp.ProviderSpecString(&machineSets[i], "flavor"),
+			SecurityGroup: groupName,
+		}
+
+		nodes := mapping.ByMachineSet[machineSets[i].GetName()]
+		if len(nodes) == 0 {
+			gateways = append(gateways, gateway)
+			continue
+		}
+
+		for j := range nodes {
+			nodeGateway := gateway
+			nodeGateway.NodeName = nodes[j].Name
+			nodeGateway.Zone = nodes[j].Labels[corev1.LabelTopologyZone]
+
+			nodeGateway.PublicIP, err = floatingIP(nodes[j].Name, computeClient)
+			if err != nil {
+				return nil, status.Error(err, "unable to retrieve the gateway servers")
+			}
+
+			gateways = append(gateways, nodeGateway)
+		}
+	}
+
+	taggedExistingNodes := mapping.Labeled
+
+	for i := range taggedExistingNodes {
+		publicIP, err := floatingIP(taggedExistingNodes[i].Name, computeClient)
+		if err != nil {
+			return nil, status.Error(err, "unable to retrieve the gateway servers")
+		}
+
+		gateways = append(gateways, api.GatewayStatus{
+			Type:          api.LabeledWorkerGateway,
+			Zone:          taggedExistingNodes[i].Labels[corev1.LabelTopologyZone],
+			InstanceType:  taggedExistingNodes[i].Labels[corev1.LabelInstanceTypeStable],
+			NodeName:      taggedExistingNodes[i].Name,
+			PublicIP:      publicIP,
+			SecurityGroup: groupName,
+		})
+	}
+
+	status.Success("Retrieved %d Submariner gateway(s)", len(gateways))
+
+	return gateways, nil
+}
+
+// floatingIP returns the first floating IP address attached to the server with the given name, if any.
+func floatingIP(serverName string, computeClient *gophercloud.ServiceClient) (string, error) {
+	allPages, err := servers.List(computeClient, servers.ListOpts{Name: serverName}).AllPages()
+	if err != nil {
+		return "", errors.WithMessagef(err, "getting the server list failed for %q", serverName)
+	}
+
+	serverList, err := servers.ExtractServers(allPages)
+	if err != nil {
+		return "", errors.WithMessagef(err, "getting the server list failed for %q", serverName)
+	}
+
+	for i := range serverList {
+		for _, networkAddresses := range serverList[i].Addresses {
+			addresses, _ := networkAddresses.([]interface{})
+
+			for _, a := range addresses {
+				address, _ := a.(map[string]interface{})
+				if address["OS-EXT-IPS:type"] == "floating" {
+					if addr, ok := address["addr"].(string); ok {
+						return addr, nil
+					}
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
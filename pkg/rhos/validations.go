@@ -0,0 +1,108 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/reporter"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// memberRoles are the Keystone roles which the default Nova and Neutron policies require to manage security groups and
+// add them to servers. Any one of them is sufficient.
+var memberRoles = []string{"member", "_member_", "admin"}
+
+func (rc *rhosCloud) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(rc.validate(ctx, status))
+}
+
+func (rc *rhosCloud) validate(_ context.Context, status reporter.Interface) error {
+	status.Start("Validating the Keystone roles to open and close the internal ports")
+	defer status.End()
+
+	if err := rc.validateRoles(""); err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the Keystone roles to open and close the internal ports")
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) Validate(ctx context.Context, status reporter.Interface) error {
+	return classifyError(d.validate(ctx, status))
+}
+
+func (d *ocpGatewayDeployer) validate(_ context.Context, status reporter.Interface) error {
+	status.Start("Validating the Keystone roles to deploy and clean up the gateways")
+	defer status.End()
+
+	if err := d.validateRoles(d.projectID); err != nil {
+		return status.Error(err, "permission validation failed")
+	}
+
+	status.Success("Validated the Keystone roles to deploy and clean up the gateways")
+
+	return nil
+}
+
+// validateRoles checks that the token in use is scoped to the given project, if any, and carries one of the member roles.
+// Keystone doesn't evaluate the service policies on behalf of the caller, so the default policies are assumed.
+func (c *CloudInfo) validateRoles(projectID string) error {
+	identityClient, err := openstack.NewIdentityV3(c.Client, gophercloud.EndpointOpts{Region: c.Region})
+	if err != nil {
+		return errors.Wrap(err, "error creating the identity client")
+	}
+
+	result := tokens.Get(identityClient, c.Client.Token())
+
+	project, err := result.ExtractProject()
+	if err != nil {
+		return errors.Wrap(err, "error retrieving the token project")
+	}
+
+	roles, err := result.ExtractRoles()
+	if err != nil {
+		return errors.Wrap(err, "error retrieving the token roles")
+	}
+
+	var missing []string
+
+	if project == nil {
+		missing = append(missing, "project-scoped token")
+	} else if projectID != "" && project.ID != projectID && project.Name != projectID {
+		missing = append(missing, fmt.Sprintf("token scoped to project %q", projectID))
+	}
+
+	if !slices.ContainsFunc(roles, func(role tokens.Role) bool {
+		return slices.Contains(memberRoles, strings.ToLower(role.Name))
+	}) {
+		missing = append(missing, fmt.Sprintf("one of the roles %s", strings.Join(memberRoles, ", ")))
+	}
+
+	return api.NewMissingPermissionsError(ProviderName, missing)
+}
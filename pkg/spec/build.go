@@ -0,0 +1,229 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/azure"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ledger"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+	"google.golang.org/api/option"
+)
+
+// Environment provides what Build needs beyond the spec: the cluster clients and, optionally, prebuilt cloud clients.
+type Environment struct {
+	MachineSetDeployer ocp.MachineSetDeployer
+	K8sClient          k8s.Interface
+
+	// GCPClient, if set, is used instead of building a client from the referenced credentials.
+	GCPClient gcpclient.Interface
+
+	// AzureCredential is required for the azure provider.
+	AzureCredential azcore.TokenCredential
+
+	// RHOSClient, if set, is used instead of authenticating with the OS_* environment variables.
+	RHOSClient *gophercloud.ProviderClient
+
+	// Ledger, if set, records the created resources so that ClosePorts and Cleanup undo exactly those.
+	Ledger *ledger.Ledger
+}
+
+// Build builds the Cloud and GatewayDeployer described by the spec, through the provider registry. The MachineSet
+// overrides, if any, are applied by wrapping the environment's MachineSetDeployer.
+func (s *Spec) Build(ctx context.Context, env *Environment) (api.Cloud, api.GatewayDeployer, error) {
+	if s.MachineSet != nil && env.MachineSetDeployer != nil {
+		overridden := *env
+		overridden.MachineSetDeployer = ocp.NewOverridingMachineSetDeployer(env.MachineSetDeployer, s.MachineSet)
+		env = &overridden
+	}
+
+	config, err := s.providerConfig(ctx, env)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "error building the %s provider configuration", s.Provider)
+	}
+
+	return api.NewProvider(s.Provider, config)
+}
+
+func (s *Spec) providerConfig(ctx context.Context, env *Environment) (any, error) {
+	switch s.Provider {
+	case aws.ProviderName:
+		return s.awsConfig(env), nil
+	case gcp.ProviderName:
+		return s.gcpConfig(env)
+	case azure.ProviderName:
+		return s.azureConfig(env), nil
+	case rhos.ProviderName:
+		return s.rhosConfig(ctx, env)
+	}
+
+	return nil, errors.Errorf("provider %q isn't supported by the spec", s.Provider)
+}
+
+func (s *Spec) awsConfig(env *Environment) *aws.ProviderConfig {
+	var opts []aws.CloudOption
+
+	if s.IPFamily != "" {
+		opts = append(opts, aws.WithIPFamily(s.IPFamily))
+	}
+
+	if env.Ledger != nil {
+		opts = append(opts, aws.WithLedger(env.Ledger))
+	}
+
+	if env.K8sClient != nil {
+		opts = append(opts, aws.WithK8sClient(env.K8sClient))
+	}
+
+	if len(s.Tags) > 0 {
+		opts = append(opts, aws.WithTags(s.Tags))
+	}
+
+	if s.AWS != nil {
+		if s.AWS.VPCName != "" {
+			opts = append(opts, aws.WithVPCName(s.AWS.VPCName))
+		}
+
+		if len(s.AWS.PublicSubnets) > 0 {
+			opts = append(opts, aws.WithPublicSubnetList(s.AWS.PublicSubnets))
+		}
+
+		if s.AWS.WorkerSecurityGroup != "" {
+			opts = append(opts, aws.WithWorkerSecurityGroup(s.AWS.WorkerSecurityGroup))
+		}
+
+		if s.AWS.ControlPlaneSecurityGroup != "" {
+			opts = append(opts, aws.WithControlPlaneSecurityGroup(s.AWS.ControlPlaneSecurityGroup))
+		}
+	}
+
+	return &aws.ProviderConfig{
+		InfraID:            s.InfraID,
+		Region:             s.Region,
+		CredentialsFile:    s.Credentials.File,
+		Profile:            s.Credentials.Profile,
+		CloudOptions:       opts,
+		MachineSetDeployer: env.MachineSetDeployer,
+		InstanceType:       s.InstanceType,
+	}
+}
+
+func (s *Spec) gcpConfig(env *Environment) (*gcp.ProviderConfig, error) {
+	client := env.GCPClient
+	if client == nil {
+		var opts []option.ClientOption
+
+		if s.Credentials.File != "" {
+			opts = append(opts, option.WithCredentialsFile(s.Credentials.File))
+		}
+
+		var err error
+
+		client, err = gcpclient.NewClient(s.GCP.ProjectID, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the GCP client")
+		}
+	}
+
+	return &gcp.ProviderConfig{
+		CloudInfo: gcp.CloudInfo{
+			InfraID:   s.InfraID,
+			Region:    s.Region,
+			ProjectID: s.GCP.ProjectID,
+			Client:    client,
+			IPFamily:  s.IPFamily,
+			Labels:    s.Tags,
+			Ledger:    env.Ledger,
+		},
+		MachineSetDeployer: env.MachineSetDeployer,
+		K8sClient:          env.K8sClient,
+		InstanceType:       s.InstanceType,
+		Image:              s.Image,
+	}, nil
+}
+
+func (s *Spec) azureConfig(env *Environment) *azure.ProviderConfig {
+	return &azure.ProviderConfig{
+		CloudInfo: azure.CloudInfo{
+			SubscriptionID:  s.Azure.SubscriptionID,
+			InfraID:         s.InfraID,
+			Region:          s.Region,
+			BaseGroupName:   s.Azure.BaseGroupName,
+			TokenCredential: env.AzureCredential,
+			K8sClient:       env.K8sClient,
+			IPFamily:        s.IPFamily,
+			Tags:            s.Tags,
+			Ledger:          env.Ledger,
+		},
+		MachineSetDeployer: env.MachineSetDeployer,
+		InstanceType:       s.InstanceType,
+	}
+}
+
+func (s *Spec) rhosConfig(ctx context.Context, env *Environment) (*rhos.ProviderConfig, error) {
+	client := env.RHOSClient
+	if client == nil {
+		authOpts, err := openstack.AuthOptionsFromEnv()
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading the OpenStack credentials from the environment")
+		}
+
+		client, err = openstack.NewClient(authOpts.IdentityEndpoint)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the OpenStack client")
+		}
+
+		// The context only bounds the authentication: the client outlives Build, so it must not keep it.
+		client.Context = ctx
+		err = openstack.Authenticate(client, authOpts)
+		client.Context = nil
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error authenticating with OpenStack")
+		}
+	}
+
+	return &rhos.ProviderConfig{
+		CloudInfo: rhos.CloudInfo{
+			Client:    client,
+			InfraID:   s.InfraID,
+			Region:    s.Region,
+			K8sClient: env.K8sClient,
+			IPFamily:  s.IPFamily,
+			Tags:      s.Tags,
+			Ledger:    env.Ledger,
+		},
+		MachineSetDeployer: env.MachineSetDeployer,
+		ProjectID:          s.RHOS.ProjectID,
+		InstanceType:       s.InstanceType,
+		Image:              s.Image,
+		CloudName:          s.RHOS.CloudName,
+	}, nil
+}
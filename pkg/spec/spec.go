@@ -0,0 +1,321 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package spec provides a declarative, versioned format describing how to prepare the cloud of a cluster for
+// Submariner, so that it can be kept in git, along with a loader building the matching Cloud and GatewayDeployer.
+package spec
+
+import (
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/azure"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the only version of the spec format currently supported.
+	APIVersion = "cloud-prepare.submariner.io/v1alpha1"
+
+	// Kind is the kind of a spec document.
+	Kind = "CloudPreparation"
+)
+
+// Spec describes how to prepare the cloud of a single cluster for Submariner.
+type Spec struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// Provider is the name of the cloud provider, for example "aws", "gcp", "azure" or "rhos".
+	Provider string `json:"provider"`
+
+	InfraID string `json:"infraID"`
+	Region  string `json:"region"`
+
+	Credentials CredentialsReference `json:"credentials,omitempty"`
+
+	// InternalPorts are the ports opened for intra-cluster communications, see api.Cloud.OpenPorts.
+	InternalPorts []api.PortSpec `json:"internalPorts,omitempty"`
+
+	// PublicPorts are the ports opened on the gateways, see api.GatewayDeployInput.
+	PublicPorts []api.PortSpec `json:"publicPorts,omitempty"`
+
+	// Gateways is the number of gateways to deploy, 0 meaning the deployer's default policy.
+	Gateways int `json:"gateways,omitempty"`
+
+	// UseLoadBalancer deploys Submariner with a service of type LoadBalancer, see api.GatewayDeployInput.
+	UseLoadBalancer bool `json:"useLoadBalancer,omitempty"`
+
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// Image is the gateway node image. It's only supported, and accepted, by the gcp and rhos providers, which default to
+	// the image of the worker nodes.
+	Image string `json:"image,omitempty"`
+
+	SourceCIDRs []string     `json:"sourceCIDRs,omitempty"`
+	IPFamily    api.IPFamily `json:"ipFamily,omitempty"`
+	AirGapped   bool         `json:"airGapped,omitempty"`
+
+	// WaitTimeout is how long deploying the gateways waits for their nodes to be ready, see api.GatewayDeployInput.
+	WaitTimeout *metav1.Duration `json:"waitTimeout,omitempty"`
+
+	// HealthCheck, if set, replaces the dedicated gateway nodes which become unhealthy, see api.GatewayDeployInput.
+	HealthCheck *api.HealthCheck `json:"healthCheck,omitempty"`
+
+	// Tags are applied to the cloud resources created: as tags on AWS, Azure and RHOS, and as labels on GCP.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// MachineSet customizes the gateway MachineSets generated by the provider.
+	MachineSet *ocp.MachineSetOverrides `json:"machineSet,omitempty"`
+
+	// The provider overrides. Only the one matching the Provider may be set.
+	AWS   *AWSOverrides   `json:"aws,omitempty"`
+	GCP   *GCPOverrides   `json:"gcp,omitempty"`
+	Azure *AzureOverrides `json:"azure,omitempty"`
+	RHOS  *RHOSOverrides  `json:"rhos,omitempty"`
+}
+
+// CredentialsReference references the credentials used to access the cloud. The credentials themselves are never part
+// of the spec.
+type CredentialsReference struct {
+	// File is the path of the AWS shared credentials file or of the GCP service account key file. It defaults to the
+	// provider's default credentials.
+	File string `json:"file,omitempty"`
+
+	// Profile is the AWS profile to use from the credentials file.
+	Profile string `json:"profile,omitempty"`
+}
+
+// AWSOverrides maps to the AWS CloudOptions.
+type AWSOverrides struct {
+	VPCName                   string   `json:"vpcName,omitempty"`
+	PublicSubnets             []string `json:"publicSubnets,omitempty"`
+	WorkerSecurityGroup       string   `json:"workerSecurityGroup,omitempty"`
+	ControlPlaneSecurityGroup string   `json:"controlPlaneSecurityGroup,omitempty"`
+}
+
+type GCPOverrides struct {
+	ProjectID string `json:"projectID"`
+}
+
+type AzureOverrides struct {
+	SubscriptionID string `json:"subscriptionID"`
+	BaseGroupName  string `json:"baseGroupName"`
+}
+
+type RHOSOverrides struct {
+	ProjectID string `json:"projectID"`
+	CloudName string `json:"cloudName"`
+}
+
+// Load parses and validates a spec in YAML or JSON. Unknown fields are rejected so that typos aren't silently ignored.
+func Load(data []byte) (*Spec, error) {
+	spec := &Spec{}
+
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, errors.Wrap(err, "error parsing the cloud preparation spec")
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid cloud preparation spec")
+	}
+
+	return spec, nil
+}
+
+// LoadFile parses and validates the spec in the given YAML or JSON file.
+func LoadFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the cloud preparation spec %q", path)
+	}
+
+	return Load(data)
+}
+
+// Validate returns all the problems found in the spec, or nil if it's valid.
+func (s *Spec) Validate() error {
+	var errs []error
+
+	if s.APIVersion != APIVersion {
+		errs = append(errs, errors.Errorf("unsupported apiVersion %q, expected %q", s.APIVersion, APIVersion))
+	}
+
+	if s.Kind != Kind {
+		errs = append(errs, errors.Errorf("unsupported kind %q, expected %q", s.Kind, Kind))
+	}
+
+	if !slices.Contains(api.Providers(), s.Provider) {
+		errs = append(errs, errors.Errorf("unsupported provider %q, expected one of %q", s.Provider, api.Providers()))
+	}
+
+	if s.InfraID == "" {
+		errs = append(errs, errors.New("infraID is required"))
+	}
+
+	if s.Region == "" {
+		errs = append(errs, errors.New("region is required"))
+	}
+
+	errs = append(errs, validatePorts("internalPorts", s.InternalPorts)...)
+	errs = append(errs, validatePorts("publicPorts", s.PublicPorts)...)
+
+	if s.Gateways < 0 {
+		errs = append(errs, errors.Errorf("gateways must not be negative, got %d", s.Gateways))
+	}
+
+	if s.WaitTimeout != nil && s.WaitTimeout.Duration < 0 {
+		errs = append(errs, errors.Errorf("waitTimeout must not be negative, got %v", s.WaitTimeout.Duration))
+	}
+
+	errs = append(errs, validateHealthCheck(s.HealthCheck)...)
+
+	switch s.IPFamily {
+	case "", api.IPv4, api.IPv6, api.DualStack:
+	default:
+		errs = append(errs, errors.Errorf("unsupported ipFamily %q, expected %q, %q or %q", s.IPFamily, api.IPv4, api.IPv6, api.DualStack))
+	}
+
+	for _, cidr := range s.SourceCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, errors.Errorf("invalid sourceCIDRs entry %q", cidr))
+		}
+	}
+
+	return utilerrors.NewAggregate(append(errs, s.validateOverrides()...))
+}
+
+func (s *Spec) validateOverrides() []error {
+	var errs []error
+
+	overrides := []struct {
+		provider string
+		set      bool
+	}{
+		{aws.ProviderName, s.AWS != nil},
+		{gcp.ProviderName, s.GCP != nil},
+		{azure.ProviderName, s.Azure != nil},
+		{rhos.ProviderName, s.RHOS != nil},
+	}
+
+	for _, o := range overrides {
+		if o.set && o.provider != s.Provider {
+			errs = append(errs, errors.Errorf("the %s overrides can't be set for provider %q", o.provider, s.Provider))
+		}
+	}
+
+	if s.Image != "" && s.Provider != gcp.ProviderName && s.Provider != rhos.ProviderName {
+		errs = append(errs, errors.Errorf("image can't be set for provider %q, only for %q and %q", s.Provider, gcp.ProviderName,
+			rhos.ProviderName))
+	}
+
+	switch s.Provider {
+	case gcp.ProviderName:
+		if s.GCP == nil || s.GCP.ProjectID == "" {
+			errs = append(errs, errors.New("gcp.projectID is required"))
+		}
+	case azure.ProviderName:
+		if s.Azure == nil || s.Azure.SubscriptionID == "" || s.Azure.BaseGroupName == "" {
+			errs = append(errs, errors.New("azure.subscriptionID and azure.baseGroupName are required"))
+		}
+	case rhos.ProviderName:
+		if s.RHOS == nil || s.RHOS.ProjectID == "" || s.RHOS.CloudName == "" {
+			errs = append(errs, errors.New("rhos.projectID and rhos.cloudName are required"))
+		}
+	}
+
+	return errs
+}
+
+func validatePorts(field string, ports []api.PortSpec) []error {
+	var errs []error
+
+	for i := range ports {
+		if ports[i].Protocol == "" {
+			errs = append(errs, errors.Errorf("%s[%d].protocol is required", field, i))
+		}
+
+		if ports[i].EndPort != 0 && ports[i].EndPort < ports[i].Port {
+			errs = append(errs, errors.Errorf("%s[%d].endPort %d is lower than port %d", field, i, ports[i].EndPort, ports[i].Port))
+		}
+	}
+
+	return errs
+}
+
+func validateHealthCheck(healthCheck *api.HealthCheck) []error {
+	if healthCheck == nil {
+		return nil
+	}
+
+	var errs []error
+
+	for i, condition := range healthCheck.UnhealthyConditions {
+		if condition.Type == "" || condition.Status == "" {
+			errs = append(errs, errors.Errorf("healthCheck.unhealthyConditions[%d].type and status are required", i))
+		}
+
+		if condition.Timeout.Duration < 0 {
+			errs = append(errs, errors.Errorf("healthCheck.unhealthyConditions[%d].timeout must not be negative, got %v", i,
+				condition.Timeout.Duration))
+		}
+	}
+
+	if healthCheck.MaxUnhealthy != nil {
+		maxUnhealthy, err := intstr.GetScaledValueFromIntOrPercent(healthCheck.MaxUnhealthy, 100, false)
+		if err != nil || maxUnhealthy < 0 {
+			errs = append(errs, errors.Errorf("healthCheck.maxUnhealthy must be a non-negative number or percentage, got %q",
+				healthCheck.MaxUnhealthy.String()))
+		}
+	}
+
+	return errs
+}
+
+// GatewayDeployInput returns the input to deploy the gateways with, see api.GatewayDeployer.
+func (s *Spec) GatewayDeployInput() api.GatewayDeployInput {
+	return api.GatewayDeployInput{
+		PublicPorts:     s.PublicPorts,
+		Gateways:        s.Gateways,
+		UseLoadBalancer: s.UseLoadBalancer,
+		AirGapped:       s.AirGapped,
+		SourceCIDRs:     s.SourceCIDRs,
+		IPFamily:        s.IPFamily,
+		WaitTimeout:     s.waitTimeout(),
+		HealthCheck:     s.HealthCheck,
+	}
+}
+
+func (s *Spec) waitTimeout() time.Duration {
+	if s.WaitTimeout == nil {
+		return 0
+	}
+
+	return s.WaitTimeout.Duration
+}
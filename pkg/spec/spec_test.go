@@ -0,0 +1,249 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpFake "github.com/submariner-io/cloud-prepare/pkg/gcp/client/fake"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	"github.com/submariner-io/cloud-prepare/pkg/spec"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+const awsSpec = `
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: aws
+infraID: test-infraID
+region: test-region
+credentials:
+  file: /tmp/credentials
+  profile: test
+internalPorts:
+- port: 4800
+  protocol: udp
+publicPorts:
+- port: 4500
+  protocol: udp
+- protocol: esp
+gateways: 2
+useLoadBalancer: true
+instanceType: m5n.large
+sourceCIDRs:
+- 1.2.3.0/24
+ipFamily: DualStack
+waitTimeout: 10m
+healthCheck:
+  unhealthyConditions:
+  - type: Ready
+    status: "False"
+    timeout: 10m
+  maxUnhealthy: 50%
+tags:
+  owner: team-a
+machineSet:
+  nodeLabels:
+    custom: label
+  rootVolume:
+    sizeGB: 200
+  providerSpecPatch:
+    spotMarketOptions: {}
+aws:
+  vpcName: test-vpc
+  publicSubnets:
+  - subnet-1
+`
+
+var _ = Describe("Load", func() {
+	When("the spec is valid YAML", func() {
+		It("should parse it", func() {
+			s, err := spec.Load([]byte(awsSpec))
+			Expect(err).To(Succeed())
+
+			Expect(s.Provider).To(Equal("aws"))
+			Expect(s.InfraID).To(Equal("test-infraID"))
+			Expect(s.Region).To(Equal("test-region"))
+			Expect(s.Credentials).To(Equal(spec.CredentialsReference{File: "/tmp/credentials", Profile: "test"}))
+			Expect(s.InternalPorts).To(Equal([]api.PortSpec{{Port: 4800, Protocol: "udp"}}))
+			Expect(s.InstanceType).To(Equal("m5n.large"))
+			Expect(s.Tags).To(Equal(map[string]string{"owner": "team-a"}))
+			Expect(s.MachineSet.NodeLabels).To(Equal(map[string]string{"custom": "label"}))
+			Expect(s.MachineSet.RootVolume).To(Equal(&ocp.RootVolume{SizeGB: 200}))
+			Expect(s.MachineSet.ProviderSpecPatch).To(MatchJSON(`{"spotMarketOptions": {}}`))
+			Expect(s.AWS).To(Equal(&spec.AWSOverrides{VPCName: "test-vpc", PublicSubnets: []string{"subnet-1"}}))
+
+			Expect(s.GatewayDeployInput()).To(Equal(api.GatewayDeployInput{
+				PublicPorts:     []api.PortSpec{{Port: 4500, Protocol: "udp"}, {Protocol: "esp"}},
+				Gateways:        2,
+				UseLoadBalancer: true,
+				SourceCIDRs:     []string{"1.2.3.0/24"},
+				IPFamily:        api.DualStack,
+				WaitTimeout:     10 * time.Minute,
+				HealthCheck: &api.HealthCheck{
+					UnhealthyConditions: []api.UnhealthyCondition{{
+						Type:    corev1.NodeReady,
+						Status:  corev1.ConditionFalse,
+						Timeout: metav1.Duration{Duration: 10 * time.Minute},
+					}},
+					MaxUnhealthy: ptr.To(intstr.FromString("50%")),
+				},
+			}))
+		})
+	})
+
+	When("the spec is valid JSON", func() {
+		It("should parse it", func() {
+			s, err := spec.Load([]byte(`{"apiVersion": "cloud-prepare.submariner.io/v1alpha1", "kind": "CloudPreparation",
+				"provider": "gcp", "infraID": "test-infraID", "region": "test-region", "gcp": {"projectID": "test-project"}}`))
+			Expect(err).To(Succeed())
+			Expect(s.GCP).To(Equal(&spec.GCPOverrides{ProjectID: "test-project"}))
+		})
+	})
+
+	When("the spec has an unknown field", func() {
+		It("should return an error", func() {
+			_, err := spec.Load([]byte(awsSpec + "gatways: 3\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the spec is invalid", func() {
+		It("should return all the problems", func() {
+			_, err := spec.Load([]byte(`
+apiVersion: v2
+kind: CloudPreparation
+provider: aws
+region: test-region
+publicPorts:
+- port: 4500
+  endPort: 4400
+  protocol: udp
+- port: 4800
+gateways: -1
+healthCheck:
+  maxUnhealthy: half
+ipFamily: IPv5
+sourceCIDRs:
+- 1.2.3.4
+gcp:
+  projectID: test-project
+`))
+			Expect(err).To(HaveOccurred())
+
+			problems := []string{"apiVersion", "infraID", "endPort", "protocol", "gateways", "maxUnhealthy", "ipFamily", "sourceCIDRs", "gcp"}
+			for _, problem := range problems {
+				Expect(err.Error()).To(ContainSubstring(problem))
+			}
+		})
+	})
+
+	When("the provider is unknown", func() {
+		It("should return an error", func() {
+			_, err := spec.Load([]byte(`
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: other
+infraID: test-infraID
+region: test-region
+`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("an image is set for a provider which doesn't support it", func() {
+		It("should return an error", func() {
+			_, err := spec.Load([]byte(`
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: aws
+infraID: test-infraID
+region: test-region
+image: test-image
+`))
+			Expect(err).To(MatchError(ContainSubstring("image")))
+		})
+	})
+
+	When("the required provider overrides are missing", func() {
+		It("should return an error", func() {
+			_, err := spec.Load([]byte(`
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: rhos
+infraID: test-infraID
+region: test-region
+`))
+			Expect(err).To(MatchError(ContainSubstring("rhos.projectID")))
+		})
+	})
+})
+
+var _ = Describe("Build", func() {
+	It("should build the provider's Cloud and GatewayDeployer", func() {
+		s, err := spec.Load([]byte(`
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: gcp
+infraID: test-infraID
+region: test-region
+gcp:
+  projectID: test-project
+`))
+		Expect(err).To(Succeed())
+
+		cloud, gwDeployer, err := s.Build(context.TODO(), &spec.Environment{
+			MachineSetDeployer: ocpFake.NewMockMachineSetDeployer(GinkgoT()),
+			K8sClient:          k8s.NewInterface(kubeFake.NewClientset()),
+			GCPClient:          gcpFake.NewMockInterface(GinkgoT()),
+		})
+		Expect(err).To(Succeed())
+		Expect(cloud).ToNot(BeNil())
+		Expect(gwDeployer).ToNot(BeNil())
+	})
+
+	When("a required client is missing from the environment", func() {
+		It("should return an error", func() {
+			s, err := spec.Load([]byte(`
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: CloudPreparation
+provider: azure
+infraID: test-infraID
+region: test-region
+azure:
+  subscriptionID: test-subscription
+  baseGroupName: test-group
+`))
+			Expect(err).To(Succeed())
+
+			_, _, err = s.Build(context.TODO(), &spec.Environment{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})